@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sulemankhann/workout-tracker/internal/data"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// sessionListenerPingInterval is how often an idle session listener checks
+// its connection, so that a dead one is noticed and replaced rather than
+// silently waiting for notifications that never come.
+const sessionListenerPingInterval = 90 * time.Second
+
+// sessionEvent is published whenever a live session changes. The workout is
+// included so that subscribers can recompute the session state, for example
+// when a rest timer runs out, without going back to the database.
+type sessionEvent struct {
+	name    string
+	session *data.WorkoutSession
+	workout *data.Workout
+}
+
+// sessionBroker fans session events out to every client streaming that
+// session, whichever instance of the API it is connected to. Events are
+// announced through notify, which sends them on a Postgres channel that
+// every instance listens on; relaySessionEvents then reads the session back
+// and delivers it to the clients connected to that instance. Without notify
+// events only reach this process.
+type sessionBroker struct {
+	notify func(context.Context, data.SessionNotice) error
+
+	mu          sync.Mutex
+	subscribers map[int64]map[chan sessionEvent]struct{}
+	// owners holds the user of each session with subscribers, which is
+	// needed to read the session back.
+	owners map[int64]int64
+}
+
+func newSessionBroker(
+	notify func(context.Context, data.SessionNotice) error,
+) *sessionBroker {
+	return &sessionBroker{
+		notify:      notify,
+		subscribers: make(map[int64]map[chan sessionEvent]struct{}),
+		owners:      make(map[int64]int64),
+	}
+}
+
+// subscribe returns a channel receiving events for the session and a
+// function that must be called to release it.
+func (b *sessionBroker) subscribe(
+	session *data.WorkoutSession,
+) (<-chan sessionEvent, func()) {
+	ch := make(chan sessionEvent, 8)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscribers[session.ID] == nil {
+		b.subscribers[session.ID] = make(map[chan sessionEvent]struct{})
+		b.owners[session.ID] = session.UserID
+	}
+
+	b.subscribers[session.ID][ch] = struct{}{}
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subscribers[session.ID], ch)
+		if len(b.subscribers[session.ID]) == 0 {
+			delete(b.subscribers, session.ID)
+			delete(b.owners, session.ID)
+		}
+	}
+
+	return ch, unsubscribe
+}
+
+// publish announces the event to every instance. If that fails the event
+// still reaches this instance's subscribers, and the error is returned for
+// logging; the change itself has already been saved.
+func (b *sessionBroker) publish(ctx context.Context, event sessionEvent) error {
+	if b.notify == nil {
+		b.deliver(event)
+		return nil
+	}
+
+	err := b.notify(ctx, data.SessionNotice{
+		SessionID: event.session.ID,
+		UserID:    event.session.UserID,
+		Event:     event.name,
+	})
+	if err != nil {
+		b.deliver(event)
+		return err
+	}
+
+	return nil
+}
+
+// deliver never blocks. A subscriber too slow to keep up misses the event,
+// but every event carries the full session state, so it catches up on the
+// next one.
+func (b *sessionBroker) deliver(event sessionEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[event.session.ID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// isWatched reports whether the session has subscribers on this instance.
+func (b *sessionBroker) isWatched(sessionID int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, ok := b.owners[sessionID]
+
+	return ok
+}
+
+// watched returns the sessions with subscribers on this instance, mapped to
+// their users.
+func (b *sessionBroker) watched() map[int64]int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	watched := make(map[int64]int64, len(b.owners))
+	for sessionID, userID := range b.owners {
+		watched[sessionID] = userID
+	}
+
+	return watched
+}
+
+// sessionListener is the part of a pq.Listener that relaySessionEvents
+// uses.
+type sessionListener interface {
+	NotificationChannel() <-chan *pq.Notification
+	Ping() error
+}
+
+// relaySessionEvents delivers the session notices sent by every instance,
+// this one included, to the clients streaming those sessions here. It runs
+// until ctx is cancelled or the listener is closed.
+func (app *application) relaySessionEvents(
+	ctx context.Context,
+	listener sessionListener,
+) {
+	notifications := listener.NotificationChannel()
+
+	ping := time.NewTicker(sessionListenerPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case n, ok := <-notifications:
+			if !ok {
+				return
+			}
+
+			// A nil notification means the connection was lost and has been
+			// re-established, so notices may have been missed. Every watched
+			// session is sent again to bring its clients up to date.
+			if n == nil {
+				for sessionID, userID := range app.sessions.watched() {
+					app.relaySessionEvent(ctx, data.SessionNotice{
+						SessionID: sessionID,
+						UserID:    userID,
+						Event:     "state",
+					})
+				}
+
+				continue
+			}
+
+			var notice data.SessionNotice
+
+			err := json.Unmarshal([]byte(n.Extra), &notice)
+			if err != nil {
+				app.logger.Error(err.Error(), "channel", n.Channel)
+				continue
+			}
+
+			app.relaySessionEvent(ctx, notice)
+
+		case <-ping.C:
+			// Ping blocks until it hears back, and a failure only means
+			// the listener is already reconnecting.
+			go func() {
+				_ = listener.Ping()
+			}()
+		}
+	}
+}
+
+// relaySessionEvent reads the session named in the notice back, if anyone
+// here is streaming it, and delivers it to them.
+func (app *application) relaySessionEvent(
+	ctx context.Context,
+	notice data.SessionNotice,
+) {
+	if !app.sessions.isWatched(notice.SessionID) {
+		return
+	}
+
+	session, err := app.models.Sessions.GetByUser(ctx, notice.SessionID, notice.UserID)
+	if err != nil {
+		app.logger.Error(err.Error(), "session_id", notice.SessionID)
+		return
+	}
+
+	workout, err := app.models.Workouts.GetByUser(ctx, session.WorkoutID, notice.UserID)
+	if err != nil {
+		app.logger.Error(err.Error(), "session_id", notice.SessionID)
+		return
+	}
+
+	app.sessions.deliver(sessionEvent{
+		name:    notice.Event,
+		session: session,
+		workout: workout,
+	})
+}
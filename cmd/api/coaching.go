@@ -0,0 +1,258 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"sulemankhann/workout-tracker/internal/validator"
+)
+
+// inviteAthleteHandler lets the authenticated user invite another user, by
+// email, to be coached by them. The athlete must accept before the coach
+// gains any access.
+func (app *application) inviteAthleteHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	coach := app.contextGetUser(r)
+
+	v := validator.New()
+
+	if data.ValidateEmail(v, input.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	athlete, err := app.models.Users.GetByEmail(r.Context(), input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("email", "no matching user account found")
+			app.failedValidationResponse(w, r, v.Errors)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	v.Check(athlete.ID != coach.ID, "email", "must not be your own")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	link := &data.CoachingLink{
+		CoachID:     coach.ID,
+		CoachName:   coach.Name,
+		AthleteID:   athlete.ID,
+		AthleteName: athlete.Name,
+	}
+
+	err = app.models.Coaching.Invite(r.Context(), link)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateCoachingLink):
+			v.AddError("email", "this athlete has already been invited")
+			app.failedValidationResponse(w, r, v.Errors)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"athlete": link}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listAthletesHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	coach := app.contextGetUser(r)
+
+	links, err := app.models.Coaching.GetAllForCoach(r.Context(), coach.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"athletes": links}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// removeAthleteHandler lets a coach stop coaching an athlete or withdraw an
+// invitation that hasn't been accepted yet.
+func (app *application) removeAthleteHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	athleteID, err := app.readNamedIDParam(r, "athlete_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	coach := app.contextGetUser(r)
+
+	err = app.models.Coaching.Revoke(r.Context(), coach.ID, athleteID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{"message": "athlete successfully removed"},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listCoachesHandler shows the authenticated user's coaches, including
+// invitations still waiting for them to accept.
+func (app *application) listCoachesHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	athlete := app.contextGetUser(r)
+
+	links, err := app.models.Coaching.GetAllForAthlete(r.Context(), athlete.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"coaches": links}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) acceptCoachHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	coachID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	athlete := app.contextGetUser(r)
+
+	err = app.models.Coaching.Accept(r.Context(), coachID, athlete.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{"message": "coach successfully accepted"},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// revokeCoachHandler lets an athlete remove a coach's access, or decline an
+// invitation, at any time. Access ends with the coach's next request.
+func (app *application) revokeCoachHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	coachID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	athlete := app.contextGetUser(r)
+
+	err = app.models.Coaching.Revoke(r.Context(), coachID, athlete.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{"message": "coach access successfully revoked"},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listWorkoutAuditHandler shows who changed a workout and when. It serves
+// both athletes and, through the athlete routes, their coaches.
+func (app *application) listWorkoutAuditHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	owner := app.contextGetWorkoutOwner(r)
+
+	entries, err := app.models.Audit.GetAllForWorkout(r.Context(), id, owner.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"audit": entries}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
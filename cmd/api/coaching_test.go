@@ -0,0 +1,338 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"testing"
+	"time"
+)
+
+func TestCoachingHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	coach, coachToken := createTestUser(t, app, "Carol")
+	athlete, athleteToken := createTestUser(t, app, "Alice")
+	_, bobToken := createTestUser(t, app, "Bob")
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "invite",
+			method:     http.MethodPost,
+			path:       "/v1/athletes",
+			token:      coachToken,
+			body:       map[string]string{"email": athlete.Email},
+			wantStatus: http.StatusCreated,
+			check: checkEnvelope("athlete", func(t *testing.T, link data.CoachingLink) {
+				if link.CoachID != coach.ID || link.AthleteID != athlete.ID ||
+					link.Status != data.CoachingStatusPending {
+					t.Errorf("link = %+v; want a pending invitation from Carol to Alice", link)
+				}
+			}),
+		},
+		{
+			name:       "invite twice",
+			method:     http.MethodPost,
+			path:       "/v1/athletes",
+			token:      coachToken,
+			body:       map[string]string{"email": athlete.Email},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "invite yourself",
+			method:     http.MethodPost,
+			path:       "/v1/athletes",
+			token:      coachToken,
+			body:       map[string]string{"email": coach.Email},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "invite an unknown user",
+			method:     http.MethodPost,
+			path:       "/v1/athletes",
+			token:      coachToken,
+			body:       map[string]string{"email": "nobody@example.com"},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "invite an invalid email",
+			method:     http.MethodPost,
+			path:       "/v1/athletes",
+			token:      coachToken,
+			body:       map[string]string{"email": "nobody"},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "list athletes",
+			method:     http.MethodGet,
+			path:       "/v1/athletes",
+			token:      coachToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("athletes", func(t *testing.T, links []data.CoachingLink) {
+				if len(links) != 1 || links[0].AthleteID != athlete.ID ||
+					links[0].AthleteName != "Alice" {
+					t.Errorf("athletes = %+v; want just Alice", links)
+				}
+			}),
+		},
+		{
+			name:       "list coaches",
+			method:     http.MethodGet,
+			path:       "/v1/coaches",
+			token:      athleteToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("coaches", func(t *testing.T, links []data.CoachingLink) {
+				if len(links) != 1 || links[0].CoachID != coach.ID ||
+					links[0].CoachName != "Carol" {
+					t.Errorf("coaches = %+v; want just Carol", links)
+				}
+			}),
+		},
+		{
+			name:       "accept an unknown coach",
+			method:     http.MethodPost,
+			path:       "/v1/coaches/999/accept",
+			token:      athleteToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "accept",
+			method:     http.MethodPost,
+			path:       fmt.Sprintf("/v1/coaches/%d/accept", coach.ID),
+			token:      athleteToken,
+			wantStatus: http.StatusOK,
+			check:      checkMessage("coach successfully accepted"),
+		},
+		{
+			name:       "accept twice",
+			method:     http.MethodPost,
+			path:       fmt.Sprintf("/v1/coaches/%d/accept", coach.ID),
+			token:      athleteToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "revoke",
+			method:     http.MethodDelete,
+			path:       fmt.Sprintf("/v1/coaches/%d", coach.ID),
+			token:      athleteToken,
+			wantStatus: http.StatusOK,
+			check:      checkMessage("coach access successfully revoked"),
+		},
+		{
+			name:       "revoke twice",
+			method:     http.MethodDelete,
+			path:       fmt.Sprintf("/v1/coaches/%d", coach.ID),
+			token:      athleteToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "invite again after revoking",
+			method:     http.MethodPost,
+			path:       "/v1/athletes",
+			token:      coachToken,
+			body:       map[string]string{"email": athlete.Email},
+			wantStatus: http.StatusCreated,
+			check: checkEnvelope("athlete", func(t *testing.T, link data.CoachingLink) {
+				if link.CoachID != coach.ID || link.AthleteID != athlete.ID ||
+					link.Status != data.CoachingStatusPending {
+					t.Errorf("link = %+v; want a pending invitation from Carol to Alice", link)
+				}
+			}),
+		},
+		{
+			name:       "remove",
+			method:     http.MethodDelete,
+			path:       fmt.Sprintf("/v1/athletes/%d", athlete.ID),
+			token:      coachToken,
+			wantStatus: http.StatusOK,
+			check:      checkMessage("athlete successfully removed"),
+		},
+		{
+			name:       "remove someone who isn't an athlete",
+			method:     http.MethodDelete,
+			path:       fmt.Sprintf("/v1/athletes/%d", athlete.ID),
+			token:      bobToken,
+			wantStatus: http.StatusNotFound,
+		},
+	})
+}
+
+func TestAthleteWorkoutHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	coach, coachToken := createTestUser(t, app, "Carol")
+	athlete, athleteToken := createTestUser(t, app, "Alice")
+	_, bobToken := createTestUser(t, app, "Bob")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+
+	workout := createTestWorkout(t, app, athlete, squat, nil)
+
+	athletePath := fmt.Sprintf("/v1/athletes/%d/workouts", athlete.ID)
+	workoutPath := fmt.Sprintf("%s/%d", athletePath, workout.ID)
+
+	newWorkout := map[string]any{
+		"title": "Squat day",
+		"exercises": []map[string]any{
+			{"exercise_id": squat.ID, "sets": 5, "repetitions": 3, "weight": 110},
+		},
+	}
+
+	app.request(t, http.MethodPost, "/v1/athletes", coachToken, map[string]string{
+		"email": athlete.Email,
+	})
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "list before the invitation is accepted",
+			method:     http.MethodGet,
+			path:       athletePath,
+			token:      coachToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "accept",
+			method:     http.MethodPost,
+			path:       fmt.Sprintf("/v1/coaches/%d/accept", coach.ID),
+			token:      athleteToken,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "list",
+			method:     http.MethodGet,
+			path:       athletePath,
+			token:      coachToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("workouts", func(t *testing.T, workouts []data.Workout) {
+				if len(workouts) != 1 || workouts[0].ID != workout.ID {
+					t.Errorf("workouts = %+v; want just Alice's workout", workouts)
+				}
+			}),
+		},
+		{
+			name:       "list as someone else",
+			method:     http.MethodGet,
+			path:       athletePath,
+			token:      bobToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "create",
+			method:     http.MethodPost,
+			path:       athletePath,
+			token:      coachToken,
+			body:       newWorkout,
+			wantStatus: http.StatusCreated,
+			check: checkEnvelope("workout", func(t *testing.T, got data.Workout) {
+				if got.ID == workout.ID || got.Title != "Squat day" || len(got.Exercises) != 1 ||
+					got.Exercises[0].Weight != 110 {
+					t.Errorf("workout = %+v; want a new Squat day", got)
+				}
+			}),
+		},
+		{
+			name:       "show",
+			method:     http.MethodGet,
+			path:       workoutPath,
+			token:      coachToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("workout", func(t *testing.T, got data.Workout) {
+				if got.ID != workout.ID || got.Title != workout.Title {
+					t.Errorf("workout = %+v; want %+v", got, workout)
+				}
+			}),
+		},
+		{
+			name:       "update",
+			method:     http.MethodPut,
+			path:       workoutPath,
+			token:      coachToken,
+			body:       newWorkout,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("workout", func(t *testing.T, got data.Workout) {
+				if got.ID != workout.ID || got.Title != "Squat day" || len(got.Exercises) != 1 ||
+					got.Exercises[0].Sets != 5 {
+					t.Errorf("workout = %+v; want Squat day", got)
+				}
+			}),
+		},
+		{
+			name:       "schedule",
+			method:     http.MethodPost,
+			path:       workoutPath + "/schedule",
+			token:      coachToken,
+			body:       map[string]any{"scheduled_at": time.Now().Add(72 * time.Hour)},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "comment",
+			method:     http.MethodPost,
+			path:       workoutPath + "/comments",
+			token:      coachToken,
+			body:       map[string]string{"body": "Keep your back straight"},
+			wantStatus: http.StatusCreated,
+			check: checkEnvelope("comment", func(t *testing.T, comment data.Comment) {
+				if comment.WorkoutID != workout.ID || comment.AuthorID != coach.ID ||
+					comment.Body != "Keep your back straight" {
+					t.Errorf("comment = %+v; want the coach's comment", comment)
+				}
+			}),
+		},
+		{
+			name:       "list comments",
+			method:     http.MethodGet,
+			path:       workoutPath + "/comments",
+			token:      coachToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("comments", func(t *testing.T, comments []data.Comment) {
+				if len(comments) != 1 || comments[0].AuthorName != "Carol" {
+					t.Errorf("comments = %+v; want Carol's comment", comments)
+				}
+			}),
+		},
+		{
+			name:       "audit",
+			method:     http.MethodGet,
+			path:       workoutPath + "/audit",
+			token:      coachToken,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "revoke",
+			method:     http.MethodDelete,
+			path:       fmt.Sprintf("/v1/coaches/%d", coach.ID),
+			token:      athleteToken,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "show after revoking",
+			method:     http.MethodGet,
+			path:       workoutPath,
+			token:      coachToken,
+			wantStatus: http.StatusNotFound,
+		},
+	})
+
+	res := app.request(
+		t,
+		http.MethodGet,
+		fmt.Sprintf("/v1/workouts/%d/audit", workout.ID),
+		athleteToken,
+		nil,
+	)
+
+	var body struct {
+		Audit []data.WorkoutAuditEntry `json:"audit"`
+	}
+
+	res.decode(t, &body)
+
+	if len(body.Audit) != 2 {
+		t.Fatalf("got %d audit entries; want 2", len(body.Audit))
+	}
+
+	for _, entry := range body.Audit {
+		if entry.ActorID == nil || *entry.ActorID != coach.ID {
+			t.Errorf("audit entry %+v wasn't attributed to the coach", entry)
+		}
+	}
+}
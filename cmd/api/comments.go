@@ -0,0 +1,319 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"sulemankhann/workout-tracker/internal/validator"
+)
+
+// listWorkoutCommentsHandler serves the workout owner and, through the
+// athlete routes, their coaches.
+func (app *application) listWorkoutCommentsHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	workout, ok := app.loadOwnedWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	app.listComments(w, r, workout)
+}
+
+func (app *application) createWorkoutCommentHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	workout, ok := app.loadOwnedWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	app.createComment(w, r, workout)
+}
+
+// listSharedWorkoutCommentsHandler lets signed-in users holding a share link
+// take part in the discussion of the shared workout.
+func (app *application) listSharedWorkoutCommentsHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	_, workout, ok := app.loadSharedWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	app.listComments(w, r, workout)
+}
+
+func (app *application) createSharedWorkoutCommentHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	_, workout, ok := app.loadSharedWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	app.createComment(w, r, workout)
+}
+
+func (app *application) updateCommentHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	comment, err := app.models.Comments.Get(r.Context(), id)
+	if err == nil && comment.AuthorID != user.ID {
+		err = data.ErrRecordNotFound
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	var input struct {
+		Body string `json:"body"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	comment.Body = input.Body
+
+	v := validator.New()
+
+	if data.ValidateComment(v, comment); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Comments.UpdateByAuthor(r.Context(), comment, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"comment": comment}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteCommentHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Comments.DeleteByAuthor(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{"message": "comment successfully deleted"},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listComments(
+	w http.ResponseWriter,
+	r *http.Request,
+	workout *data.Workout,
+) {
+	qs := r.URL.Query()
+
+	v := validator.New()
+
+	exerciseID := app.readInt(qs, "exercise_id", 0, v)
+
+	filters := data.Filters{
+		Page:     app.readInt(qs, "page", 1, v),
+		PageSize: app.readInt(qs, "page_size", 20, v),
+	}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	comments, metadata, err := app.models.Comments.GetAllForWorkout(
+		r.Context(),
+		workout.ID,
+		int64(exerciseID),
+		filters,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{"comments": comments, "metadata": metadata},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createComment adds a comment to the workout. A reply must belong to the
+// same workout as its parent and, unless it says otherwise, is about the
+// same exercise.
+func (app *application) createComment(
+	w http.ResponseWriter,
+	r *http.Request,
+	workout *data.Workout,
+) {
+	var input struct {
+		Body       string `json:"body"`
+		ExerciseID *int64 `json:"exercise_id"`
+		ParentID   *int64 `json:"parent_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	comment := &data.Comment{
+		WorkoutID:  workout.ID,
+		ExerciseID: input.ExerciseID,
+		ParentID:   input.ParentID,
+		AuthorID:   user.ID,
+		AuthorName: user.Name,
+		Body:       input.Body,
+	}
+
+	v := validator.New()
+
+	data.ValidateComment(v, comment)
+
+	if input.ParentID != nil {
+		parent, err := app.models.Comments.Get(r.Context(), *input.ParentID)
+		if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		v.Check(
+			parent != nil && parent.WorkoutID == workout.ID,
+			"parent_id",
+			"must be a comment on this workout",
+		)
+
+		if parent != nil && comment.ExerciseID == nil {
+			comment.ExerciseID = parent.ExerciseID
+		}
+	}
+
+	if comment.ExerciseID != nil {
+		found := false
+
+		for _, workoutExercise := range workout.Exercises {
+			if workoutExercise.ExerciseID == *comment.ExerciseID {
+				found = true
+				break
+			}
+		}
+
+		v.Check(found, "exercise_id", "must be an exercise in this workout")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Comments.Insert(r.Context(), comment)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"comment": comment}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// loadOwnedWorkout fetches the workout named in the URL from the workout
+// owner's workouts, writing an error response and returning false if it
+// can't be found.
+func (app *application) loadOwnedWorkout(
+	w http.ResponseWriter,
+	r *http.Request,
+) (*data.Workout, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	owner := app.contextGetWorkoutOwner(r)
+
+	workout, err := app.models.Workouts.GetByUser(r.Context(), id, owner.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return nil, false
+	}
+
+	return workout, true
+}
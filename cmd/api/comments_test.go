@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"testing"
+)
+
+func TestWorkoutCommentHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, aliceToken := createTestUser(t, app, "Alice")
+	_, bobToken := createTestUser(t, app, "Bob")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+	bench := createTestExercise(t, app, "Bench Press", data.MeasurementRepsWeight)
+
+	workout := createTestWorkout(t, app, alice, squat, nil)
+	path := fmt.Sprintf("/v1/workouts/%d/comments", workout.ID)
+
+	res := app.request(t, http.MethodPost, path, aliceToken, map[string]any{
+		"body":        "Felt strong today",
+		"exercise_id": squat.ID,
+	})
+	if res.status != http.StatusCreated {
+		t.Fatalf("status = %d; want 201\n%s", res.status, res.body)
+	}
+
+	var body struct {
+		Comment data.Comment `json:"comment"`
+	}
+
+	res.decode(t, &body)
+
+	commentPath := fmt.Sprintf("/v1/comments/%d", body.Comment.ID)
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "reply",
+			method:     http.MethodPost,
+			path:       path,
+			token:      aliceToken,
+			body:       map[string]any{"body": "Try 105 next time", "parent_id": body.Comment.ID},
+			wantStatus: http.StatusCreated,
+			check: checkEnvelope("comment", func(t *testing.T, reply data.Comment) {
+				if reply.ParentID == nil || *reply.ParentID != body.Comment.ID ||
+					reply.AuthorID != alice.ID || reply.Body != "Try 105 next time" {
+					t.Errorf("reply = %+v; want Alice's reply", reply)
+				}
+			}),
+		},
+		{
+			name:       "reply to an unknown comment",
+			method:     http.MethodPost,
+			path:       path,
+			token:      aliceToken,
+			body:       map[string]any{"body": "Hmm", "parent_id": 999},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "comment on an exercise not in the workout",
+			method:     http.MethodPost,
+			path:       path,
+			token:      aliceToken,
+			body:       map[string]any{"body": "Hmm", "exercise_id": bench.ID},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "comment without a body",
+			method:     http.MethodPost,
+			path:       path,
+			token:      aliceToken,
+			body:       map[string]any{"body": ""},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "comment on another user's workout",
+			method:     http.MethodPost,
+			path:       path,
+			token:      bobToken,
+			body:       map[string]any{"body": "Nice"},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "list",
+			method:     http.MethodGet,
+			path:       path,
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("comments", func(t *testing.T, comments []data.Comment) {
+				if len(comments) != 2 {
+					t.Fatalf("got %d comments; want 2", len(comments))
+				}
+
+				for _, comment := range comments {
+					if comment.WorkoutID != workout.ID || comment.AuthorName != "Alice" {
+						t.Errorf("comment = %+v; want one of Alice's", comment)
+					}
+				}
+			}),
+		},
+		{
+			name:       "list for an exercise",
+			method:     http.MethodGet,
+			path:       fmt.Sprintf("%s?exercise_id=%d", path, squat.ID),
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("comments", func(t *testing.T, comments []data.Comment) {
+				if len(comments) == 0 {
+					t.Fatal("got no comments; want the squat comment")
+				}
+
+				for _, comment := range comments {
+					if comment.ExerciseID == nil || *comment.ExerciseID != squat.ID {
+						t.Errorf("comment = %+v; want one on exercise %d", comment, squat.ID)
+					}
+				}
+			}),
+		},
+		{
+			name:       "list with an invalid page",
+			method:     http.MethodGet,
+			path:       path + "?page=0",
+			token:      aliceToken,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "list another user's comments",
+			method:     http.MethodGet,
+			path:       path,
+			token:      bobToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "edit",
+			method:     http.MethodPatch,
+			path:       commentPath,
+			token:      aliceToken,
+			body:       map[string]string{"body": "Felt very strong today"},
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("comment", func(t *testing.T, comment data.Comment) {
+				if comment.ID != body.Comment.ID || comment.Body != "Felt very strong today" ||
+					comment.EditedAt == nil {
+					t.Errorf("comment = %+v; want the edited comment", comment)
+				}
+			}),
+		},
+		{
+			name:       "edit to an empty body",
+			method:     http.MethodPatch,
+			path:       commentPath,
+			token:      aliceToken,
+			body:       map[string]string{"body": ""},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "edit another user's comment",
+			method:     http.MethodPatch,
+			path:       commentPath,
+			token:      bobToken,
+			body:       map[string]string{"body": "Mine now"},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "delete another user's comment",
+			method:     http.MethodDelete,
+			path:       commentPath,
+			token:      bobToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "delete",
+			method:     http.MethodDelete,
+			path:       commentPath,
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check:      checkMessage("comment successfully deleted"),
+		},
+		{
+			name:       "delete twice",
+			method:     http.MethodDelete,
+			path:       commentPath,
+			token:      aliceToken,
+			wantStatus: http.StatusNotFound,
+		},
+	})
+
+	res = app.request(t, http.MethodGet, path, aliceToken, nil)
+
+	var list struct {
+		Comments []data.Comment `json:"comments"`
+		Metadata data.Metadata  `json:"metadata"`
+	}
+
+	res.decode(t, &list)
+
+	if len(list.Comments) != 2 || list.Metadata.TotalRecords != 2 {
+		t.Errorf("got %d comments, metadata %+v; want 2", len(list.Comments), list.Metadata)
+	}
+}
+
+func TestSharedWorkoutCommentHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, aliceToken := createTestUser(t, app, "Alice")
+	bob, bobToken := createTestUser(t, app, "Bob")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+
+	workout := createTestWorkout(t, app, alice, squat, nil)
+
+	res := app.request(
+		t,
+		http.MethodPost,
+		fmt.Sprintf("/v1/workouts/%d/shares", workout.ID),
+		aliceToken,
+		nil,
+	)
+
+	path := res.header.Get("Location") + "/comments"
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "comment",
+			method:     http.MethodPost,
+			path:       path,
+			token:      bobToken,
+			body:       map[string]string{"body": "Great session"},
+			wantStatus: http.StatusCreated,
+			check: checkEnvelope("comment", func(t *testing.T, comment data.Comment) {
+				if comment.WorkoutID != workout.ID || comment.AuthorID != bob.ID {
+					t.Errorf("comment = %+v; want Bob's comment on workout %d", comment, workout.ID)
+				}
+			}),
+		},
+		{
+			name:       "comment anonymously",
+			method:     http.MethodPost,
+			path:       path,
+			body:       map[string]string{"body": "Great session"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "comment with an unknown token",
+			method:     http.MethodPost,
+			path:       "/v1/shared/ABCDEFGHIJKLMNOPQRSTUVWXYZ/comments",
+			token:      bobToken,
+			body:       map[string]string{"body": "Great session"},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "list",
+			method:     http.MethodGet,
+			path:       path,
+			token:      bobToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("comments", func(t *testing.T, comments []data.Comment) {
+				if len(comments) != 1 || comments[0].AuthorName != "Bob" {
+					t.Errorf("comments = %+v; want Bob's comment", comments)
+				}
+			}),
+		},
+	})
+}
@@ -0,0 +1,919 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sulemankhann/workout-tracker/internal/data"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+type config struct {
+	port int
+	env  string
+	db   struct {
+		dsn          string
+		maxOpenConns int
+		maxIdleConns int
+		maxIdleTime  time.Duration
+		maxLifetime  time.Duration
+		queryTimeout time.Duration
+		autoMigrate  bool
+	}
+	server struct {
+		readHeaderTimeout time.Duration
+		readTimeout       time.Duration
+		writeTimeout      time.Duration
+		idleTimeout       time.Duration
+		http2             bool
+	}
+	tls struct {
+		certFile       string
+		keyFile        string
+		reloadInterval time.Duration
+		redirectPort   int
+	}
+	shutdown struct {
+		timeout time.Duration
+		delay   time.Duration
+	}
+	limiter struct {
+		enabled        bool
+		rps            float64
+		burst          int
+		ipRPS          float64
+		ipBurst        int
+		authRPS        float64
+		authBurst      int
+		trustedProxies []string
+	}
+	smtp struct {
+		host     string
+		port     int
+		username string
+		password string
+		sender   string
+	}
+	cors struct {
+		trustedOrigins []string
+	}
+	tokens struct {
+		authenticationTTL time.Duration
+	}
+	outbox struct {
+		retention time.Duration
+	}
+	metrics struct {
+		allowedIPs []string
+	}
+}
+
+var environments = []string{"development", "staging", "production"}
+
+// Settings can come from several places. Each is read from the first of
+// these that provides it:
+//
+//  1. command-line flags, e.g. -db-dsn
+//  2. environment variables, e.g. DB_DSN
+//  3. a .env file, by default ./.env if it exists, or the file given by
+//     -env-file
+//  4. a YAML config file given by -config or CONFIG_FILE, e.g. db.dsn
+//  5. the built-in defaults
+const (
+	sourceDefault = "default"
+	sourceFile    = "config file"
+	sourceDotEnv  = ".env"
+	sourceEnv     = "environment"
+	sourceFlag    = "flag"
+)
+
+// setting describes one configuration value and where it can be set.
+type setting struct {
+	flag   string
+	env    string
+	yaml   string
+	usage  string
+	secret bool
+	value  settingValue
+	source string
+}
+
+type settingValue interface {
+	Set(string) error
+	String() string
+}
+
+// settings returns the configurable settings, bound to the fields of cfg.
+func (cfg *config) settings() []*setting {
+	return []*setting{
+		{
+			flag:  "port",
+			env:   "PORT",
+			yaml:  "port",
+			usage: "API server port",
+			value: (*intValue)(&cfg.port),
+		},
+		{
+			flag:  "env",
+			env:   "ENV",
+			yaml:  "env",
+			usage: "environment (" + strings.Join(environments, "|") + ")",
+			value: (*stringValue)(&cfg.env),
+		},
+		{
+			flag:   "db-dsn",
+			env:    "DB_DSN",
+			yaml:   "db.dsn",
+			usage:  "PostgreSQL DSN",
+			secret: true,
+			value:  (*stringValue)(&cfg.db.dsn),
+		},
+		{
+			flag:  "db-max-open-conns",
+			env:   "DB_MAX_OPEN_CONNS",
+			yaml:  "db.max_open_conns",
+			usage: "maximum open database connections, 0 for no limit",
+			value: (*intValue)(&cfg.db.maxOpenConns),
+		},
+		{
+			flag:  "db-max-idle-conns",
+			env:   "DB_MAX_IDLE_CONNS",
+			yaml:  "db.max_idle_conns",
+			usage: "maximum idle database connections",
+			value: (*intValue)(&cfg.db.maxIdleConns),
+		},
+		{
+			flag:  "db-max-idle-time",
+			env:   "DB_MAX_IDLE_TIME",
+			yaml:  "db.max_idle_time",
+			usage: "close database connections idle for longer than this, 0 to keep them",
+			value: (*durationValue)(&cfg.db.maxIdleTime),
+		},
+		{
+			flag:  "db-max-lifetime",
+			env:   "DB_MAX_LIFETIME",
+			yaml:  "db.max_lifetime",
+			usage: "close database connections older than this, 0 to keep them",
+			value: (*durationValue)(&cfg.db.maxLifetime),
+		},
+		{
+			flag:  "db-query-timeout",
+			env:   "DB_QUERY_TIMEOUT",
+			yaml:  "db.query_timeout",
+			usage: "time allowed for each database query",
+			value: (*durationValue)(&cfg.db.queryTimeout),
+		},
+		{
+			flag:  "db-auto-migrate",
+			env:   "DB_AUTO_MIGRATE",
+			yaml:  "db.auto_migrate",
+			usage: "apply pending database migrations on start",
+			value: (*boolValue)(&cfg.db.autoMigrate),
+		},
+		{
+			flag:  "server-read-header-timeout",
+			env:   "SERVER_READ_HEADER_TIMEOUT",
+			yaml:  "server.read_header_timeout",
+			usage: "time allowed to read request headers",
+			value: (*durationValue)(&cfg.server.readHeaderTimeout),
+		},
+		{
+			flag:  "server-read-timeout",
+			env:   "SERVER_READ_TIMEOUT",
+			yaml:  "server.read_timeout",
+			usage: "time allowed to read a whole request",
+			value: (*durationValue)(&cfg.server.readTimeout),
+		},
+		{
+			flag:  "server-write-timeout",
+			env:   "SERVER_WRITE_TIMEOUT",
+			yaml:  "server.write_timeout",
+			usage: "time allowed to write a response",
+			value: (*durationValue)(&cfg.server.writeTimeout),
+		},
+		{
+			flag:  "server-idle-timeout",
+			env:   "SERVER_IDLE_TIMEOUT",
+			yaml:  "server.idle_timeout",
+			usage: "time to keep idle keep-alive connections open",
+			value: (*durationValue)(&cfg.server.idleTimeout),
+		},
+		{
+			flag:  "http2",
+			env:   "HTTP2",
+			yaml:  "server.http2",
+			usage: "enable HTTP/2 over TLS",
+			value: (*boolValue)(&cfg.server.http2),
+		},
+		{
+			flag:  "tls-cert-file",
+			env:   "TLS_CERT_FILE",
+			yaml:  "tls.cert_file",
+			usage: "TLS certificate file, enables HTTPS",
+			value: (*stringValue)(&cfg.tls.certFile),
+		},
+		{
+			flag:  "tls-key-file",
+			env:   "TLS_KEY_FILE",
+			yaml:  "tls.key_file",
+			usage: "TLS private key file",
+			value: (*stringValue)(&cfg.tls.keyFile),
+		},
+		{
+			flag:  "tls-reload-interval",
+			env:   "TLS_RELOAD_INTERVAL",
+			yaml:  "tls.reload_interval",
+			usage: "how often to check the TLS files for changes",
+			value: (*durationValue)(&cfg.tls.reloadInterval),
+		},
+		{
+			flag:  "http-redirect-port",
+			env:   "HTTP_REDIRECT_PORT",
+			yaml:  "tls.redirect_port",
+			usage: "port for a plain HTTP listener redirecting to HTTPS, 0 to disable",
+			value: (*intValue)(&cfg.tls.redirectPort),
+		},
+		{
+			flag:  "shutdown-timeout",
+			env:   "SHUTDOWN_TIMEOUT",
+			yaml:  "shutdown.timeout",
+			usage: "grace period for in-flight requests and background tasks",
+			value: (*durationValue)(&cfg.shutdown.timeout),
+		},
+		{
+			flag:  "shutdown-delay",
+			env:   "SHUTDOWN_DELAY",
+			yaml:  "shutdown.delay",
+			usage: "time to keep serving after failing health checks",
+			value: (*durationValue)(&cfg.shutdown.delay),
+		},
+		{
+			flag:  "limiter-enabled",
+			env:   "LIMITER_ENABLED",
+			yaml:  "limiter.enabled",
+			usage: "enable rate limiting",
+			value: (*boolValue)(&cfg.limiter.enabled),
+		},
+		{
+			flag:  "limiter-rps",
+			env:   "LIMITER_RPS",
+			yaml:  "limiter.rps",
+			usage: "rate limiter requests per second, per user or per anonymous client IP",
+			value: (*floatValue)(&cfg.limiter.rps),
+		},
+		{
+			flag:  "limiter-burst",
+			env:   "LIMITER_BURST",
+			yaml:  "limiter.burst",
+			usage: "rate limiter burst, per user or per anonymous client IP",
+			value: (*intValue)(&cfg.limiter.burst),
+		},
+		{
+			flag:  "limiter-ip-rps",
+			env:   "LIMITER_IP_RPS",
+			yaml:  "limiter.ip_rps",
+			usage: "rate limiter requests per second per client IP before authentication",
+			value: (*floatValue)(&cfg.limiter.ipRPS),
+		},
+		{
+			flag:  "limiter-ip-burst",
+			env:   "LIMITER_IP_BURST",
+			yaml:  "limiter.ip_burst",
+			usage: "rate limiter burst per client IP before authentication",
+			value: (*intValue)(&cfg.limiter.ipBurst),
+		},
+		{
+			flag:  "limiter-auth-rps",
+			env:   "LIMITER_AUTH_RPS",
+			yaml:  "limiter.auth_rps",
+			usage: "rate limiter requests per second for logging in, per client IP",
+			value: (*floatValue)(&cfg.limiter.authRPS),
+		},
+		{
+			flag:  "limiter-auth-burst",
+			env:   "LIMITER_AUTH_BURST",
+			yaml:  "limiter.auth_burst",
+			usage: "rate limiter burst for logging in, per client IP",
+			value: (*intValue)(&cfg.limiter.authBurst),
+		},
+		{
+			flag:  "limiter-trusted-proxies",
+			env:   "LIMITER_TRUSTED_PROXIES",
+			yaml:  "limiter.trusted_proxies",
+			usage: "proxy IPs or CIDR ranges trusted to report the client IP",
+			value: (*listValue)(&cfg.limiter.trustedProxies),
+		},
+		{
+			flag:  "smtp-host",
+			env:   "SMTP_HOST",
+			yaml:  "smtp.host",
+			usage: "SMTP host, email is disabled if empty",
+			value: (*stringValue)(&cfg.smtp.host),
+		},
+		{
+			flag:  "smtp-port",
+			env:   "SMTP_PORT",
+			yaml:  "smtp.port",
+			usage: "SMTP port",
+			value: (*intValue)(&cfg.smtp.port),
+		},
+		{
+			flag:  "smtp-username",
+			env:   "SMTP_USERNAME",
+			yaml:  "smtp.username",
+			usage: "SMTP username",
+			value: (*stringValue)(&cfg.smtp.username),
+		},
+		{
+			flag:   "smtp-password",
+			env:    "SMTP_PASSWORD",
+			yaml:   "smtp.password",
+			usage:  "SMTP password",
+			secret: true,
+			value:  (*stringValue)(&cfg.smtp.password),
+		},
+		{
+			flag:  "smtp-sender",
+			env:   "SMTP_SENDER",
+			yaml:  "smtp.sender",
+			usage: "SMTP sender address",
+			value: (*stringValue)(&cfg.smtp.sender),
+		},
+		{
+			flag:  "cors-trusted-origins",
+			env:   "CORS_TRUSTED_ORIGINS",
+			yaml:  "cors.trusted_origins",
+			usage: "trusted CORS origins, separated by spaces or commas",
+			value: (*listValue)(&cfg.cors.trustedOrigins),
+		},
+		{
+			flag:  "token-authentication-ttl",
+			env:   "TOKEN_AUTHENTICATION_TTL",
+			yaml:  "tokens.authentication_ttl",
+			usage: "lifetime of authentication tokens",
+			value: (*durationValue)(&cfg.tokens.authenticationTTL),
+		},
+		{
+			flag:  "outbox-retention",
+			env:   "OUTBOX_RETENTION",
+			yaml:  "outbox.retention",
+			usage: "how long to keep dispatched events, 0 to keep them forever",
+			value: (*durationValue)(&cfg.outbox.retention),
+		},
+		{
+			flag:  "metrics-allowed-ips",
+			env:   "METRICS_ALLOWED_IPS",
+			yaml:  "metrics.allowed_ips",
+			usage: "client IPs or CIDR ranges allowed to read /debug/vars",
+			value: (*listValue)(&cfg.metrics.allowedIPs),
+		},
+	}
+}
+
+func defaultConfig() config {
+	var cfg config
+
+	cfg.port = 4000
+	cfg.env = "development"
+
+	cfg.db.maxOpenConns = 25
+	cfg.db.maxIdleConns = 25
+	cfg.db.maxIdleTime = 15 * time.Minute
+	cfg.db.maxLifetime = time.Hour
+	cfg.db.queryTimeout = data.DefaultQueryTimeout
+
+	cfg.server.readHeaderTimeout = 5 * time.Second
+	cfg.server.readTimeout = 30 * time.Second
+	cfg.server.writeTimeout = 30 * time.Second
+	cfg.server.idleTimeout = 2 * time.Minute
+	cfg.server.http2 = true
+
+	cfg.tls.reloadInterval = time.Minute
+
+	cfg.shutdown.timeout = 20 * time.Second
+
+	cfg.limiter.enabled = true
+	cfg.limiter.rps = 2
+	cfg.limiter.burst = 4
+	cfg.limiter.ipRPS = 10
+	cfg.limiter.ipBurst = 20
+	cfg.limiter.authRPS = 0.1
+	cfg.limiter.authBurst = 5
+
+	cfg.smtp.port = 25
+
+	cfg.tokens.authenticationTTL = 24 * time.Hour
+
+	cfg.outbox.retention = 7 * 24 * time.Hour
+
+	cfg.metrics.allowedIPs = []string{"127.0.0.1", "::1"}
+
+	return cfg
+}
+
+// cliOptions are the command-line flags that aren't settings.
+type cliOptions struct {
+	configFile  string
+	envFile     string
+	version     bool
+	printConfig bool
+}
+
+// loadConfig builds the configuration from args, the environment as seen
+// through lookupEnv, the .env file and the config file. Every problem found
+// is reported together in the returned error. The options are returned even
+// if the configuration is invalid, so that -version always works.
+func loadConfig(
+	args []string,
+	lookupEnv func(string) (string, bool),
+) (config, []*setting, cliOptions, error) {
+	cfg := defaultConfig()
+	settings := cfg.settings()
+
+	var opts cliOptions
+
+	flags := flag.NewFlagSet("api", flag.ContinueOnError)
+
+	flags.StringVar(
+		&opts.configFile,
+		"config",
+		"",
+		"YAML config file (env CONFIG_FILE)",
+	)
+	flags.StringVar(
+		&opts.envFile,
+		"env-file",
+		"",
+		"env file to load (default .env if present)",
+	)
+	flags.BoolVar(&opts.version, "version", false, "print the version and exit")
+	flags.BoolVar(
+		&opts.printConfig,
+		"print-config",
+		false,
+		"print the configuration, with secrets redacted, and exit",
+	)
+
+	flagValues := make(map[string]string)
+
+	for _, s := range settings {
+		s.source = sourceDefault
+
+		flags.Var(
+			&rawFlag{name: s.flag, values: flagValues, isBool: isBoolSetting(s)},
+			s.flag,
+			fmt.Sprintf(
+				"%s (env %s, default %q)",
+				s.usage,
+				s.env,
+				s.value.String(),
+			),
+		)
+	}
+
+	err := flags.Parse(args)
+	if err != nil {
+		return cfg, settings, opts, err
+	}
+
+	if flags.NArg() > 0 {
+		err := fmt.Errorf(
+			"unexpected arguments: %s",
+			strings.Join(flags.Args(), " "),
+		)
+
+		return cfg, settings, opts, err
+	}
+
+	var errs []error
+
+	if opts.configFile == "" {
+		opts.configFile, _ = lookupEnv("CONFIG_FILE")
+	}
+
+	fileValues, err := readConfigFile(opts.configFile, settings)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	dotEnvValues, err := readDotEnv(opts.envFile)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, s := range settings {
+		raw, source, ok := lookupSetting(
+			s,
+			flagValues,
+			lookupEnv,
+			dotEnvValues,
+			fileValues,
+		)
+		if !ok {
+			continue
+		}
+
+		err := s.value.Set(raw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s (from %s): %w", s.flag, source, err))
+			continue
+		}
+
+		s.source = source
+	}
+
+	errs = append(errs, cfg.validate()...)
+
+	return cfg, settings, opts, errors.Join(errs...)
+}
+
+// lookupSetting returns the raw value for s from the highest-precedence
+// source that has one.
+func lookupSetting(
+	s *setting,
+	flagValues map[string]string,
+	lookupEnv func(string) (string, bool),
+	dotEnvValues map[string]string,
+	fileValues map[string]string,
+) (string, string, bool) {
+	if v, ok := flagValues[s.flag]; ok {
+		return v, sourceFlag, true
+	}
+
+	if v, ok := lookupEnv(s.env); ok {
+		return v, sourceEnv, true
+	}
+
+	if v, ok := dotEnvValues[s.env]; ok {
+		return v, sourceDotEnv, true
+	}
+
+	if v, ok := fileValues[s.yaml]; ok {
+		return v, sourceFile, true
+	}
+
+	return "", "", false
+}
+
+// readDotEnv reads the env file at path, or .env if path is empty. Only an
+// explicitly requested file has to exist.
+func readDotEnv(path string) (map[string]string, error) {
+	explicit := path != ""
+	if !explicit {
+		path = ".env"
+	}
+
+	values, err := godotenv.Read(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to read env file %s: %w", path, err)
+	}
+
+	return values, nil
+}
+
+// readConfigFile reads the YAML config file at path, if any, flattening
+// nested keys into the dotted form used by the settings.
+func readConfigFile(path string, settings []*setting) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc map[string]any
+
+	err = yaml.Unmarshal(b, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	flattenYAML("", doc, values)
+
+	var errs []error
+
+	for key := range values {
+		known := slices.ContainsFunc(settings, func(s *setting) bool {
+			return s.yaml == key
+		})
+
+		if !known {
+			errs = append(errs, fmt.Errorf("%s: unknown setting %q", path, key))
+		}
+	}
+
+	slices.SortFunc(errs, func(a, b error) int {
+		return strings.Compare(a.Error(), b.Error())
+	})
+
+	return values, errors.Join(errs...)
+}
+
+func flattenYAML(prefix string, node map[string]any, values map[string]string) {
+	for key, value := range node {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		switch value := value.(type) {
+		case map[string]any:
+			flattenYAML(key, value, values)
+
+		case []any:
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = fmt.Sprint(item)
+			}
+
+			values[key] = strings.Join(items, ",")
+
+		case nil:
+			values[key] = ""
+
+		default:
+			values[key] = fmt.Sprint(value)
+		}
+	}
+}
+
+// validate checks the configuration as a whole and returns every problem.
+func (cfg *config) validate() []error {
+	var errs []error
+
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(validPort(cfg.port), "port: must be between 1 and 65535")
+	check(
+		slices.Contains(environments, cfg.env),
+		"env: must be one of %s",
+		strings.Join(environments, ", "),
+	)
+
+	check(cfg.db.dsn != "", "db-dsn: must be provided")
+	check(cfg.db.maxOpenConns >= 0, "db-max-open-conns: must not be negative")
+	check(cfg.db.maxIdleConns >= 0, "db-max-idle-conns: must not be negative")
+	check(
+		cfg.db.maxOpenConns == 0 || cfg.db.maxIdleConns <= cfg.db.maxOpenConns,
+		"db-max-idle-conns: must not be more than db-max-open-conns",
+	)
+	check(cfg.db.maxIdleTime >= 0, "db-max-idle-time: must not be negative")
+	check(cfg.db.maxLifetime >= 0, "db-max-lifetime: must not be negative")
+	check(cfg.db.queryTimeout > 0, "db-query-timeout: must be positive")
+
+	check(cfg.server.readHeaderTimeout > 0, "server-read-header-timeout: must be positive")
+	check(cfg.server.readTimeout > 0, "server-read-timeout: must be positive")
+	check(cfg.server.writeTimeout > 0, "server-write-timeout: must be positive")
+	check(cfg.server.idleTimeout > 0, "server-idle-timeout: must be positive")
+
+	check(
+		(cfg.tls.certFile == "") == (cfg.tls.keyFile == ""),
+		"tls-cert-file, tls-key-file: must be provided together",
+	)
+	check(cfg.tls.reloadInterval > 0, "tls-reload-interval: must be positive")
+	check(
+		cfg.tls.redirectPort == 0 || validPort(cfg.tls.redirectPort),
+		"http-redirect-port: must be between 1 and 65535",
+	)
+	check(
+		cfg.tls.redirectPort == 0 || cfg.tls.certFile != "",
+		"http-redirect-port: requires tls-cert-file and tls-key-file",
+	)
+	check(
+		cfg.tls.redirectPort == 0 || cfg.tls.redirectPort != cfg.port,
+		"http-redirect-port: must differ from port",
+	)
+
+	check(cfg.shutdown.timeout > 0, "shutdown-timeout: must be positive")
+	check(cfg.shutdown.delay >= 0, "shutdown-delay: must not be negative")
+
+	if cfg.limiter.enabled {
+		check(cfg.limiter.rps > 0, "limiter-rps: must be positive")
+		check(cfg.limiter.burst > 0, "limiter-burst: must be positive")
+		check(cfg.limiter.ipRPS > 0, "limiter-ip-rps: must be positive")
+		check(cfg.limiter.ipBurst > 0, "limiter-ip-burst: must be positive")
+		check(cfg.limiter.authRPS > 0, "limiter-auth-rps: must be positive")
+		check(cfg.limiter.authBurst > 0, "limiter-auth-burst: must be positive")
+	}
+
+	for _, proxy := range cfg.limiter.trustedProxies {
+		_, err := parseTrustedProxy(proxy)
+		check(
+			err == nil,
+			"limiter-trusted-proxies: %q must be an IP address or CIDR range",
+			proxy,
+		)
+	}
+
+	check(validPort(cfg.smtp.port), "smtp-port: must be between 1 and 65535")
+	check(
+		cfg.smtp.host == "" || cfg.smtp.sender != "",
+		"smtp-sender: must be provided with smtp-host",
+	)
+
+	for _, origin := range cfg.cors.trustedOrigins {
+		u, err := url.Parse(origin)
+		check(
+			err == nil && u.Scheme != "" && u.Host != "" && u.Path == "",
+			"cors-trusted-origins: %q must be an origin like https://example.com",
+			origin,
+		)
+	}
+
+	check(cfg.tokens.authenticationTTL > 0, "token-authentication-ttl: must be positive")
+	check(cfg.outbox.retention >= 0, "outbox-retention: must not be negative")
+
+	for _, ip := range cfg.metrics.allowedIPs {
+		_, err := parseTrustedProxy(ip)
+		check(
+			err == nil,
+			"metrics-allowed-ips: %q must be an IP address or CIDR range",
+			ip,
+		)
+	}
+
+	return errs
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+// parseTrustedProxy parses a proxy, or any other client, given as an IP
+// address or a CIDR range.
+func parseTrustedProxy(s string) (netip.Prefix, error) {
+	if !strings.Contains(s, "/") {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+
+		addr = addr.Unmap()
+
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	return prefix.Masked(), nil
+}
+
+// printConfig writes every setting with its value and where it came from.
+// Secrets are redacted.
+func printConfig(w io.Writer, settings []*setting) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	for _, s := range settings {
+		value := s.value.String()
+
+		if s.secret {
+			value = redact(value)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t(%s)\n", s.flag, value, s.source)
+	}
+
+	return tw.Flush()
+}
+
+// redact hides a secret. For URLs, such as a DSN, only the password is
+// hidden so the rest can still be checked.
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	u, err := url.Parse(value)
+	if err == nil && u.Scheme != "" && u.User != nil {
+		return u.Redacted()
+	}
+
+	return "xxxxx"
+}
+
+// rawFlag records a setting's flag without parsing it, so that it can be
+// applied in order of precedence with the other sources.
+type rawFlag struct {
+	name   string
+	values map[string]string
+	isBool bool
+}
+
+func (f *rawFlag) String() string { return "" }
+
+func (f *rawFlag) Set(s string) error {
+	f.values[f.name] = s
+	return nil
+}
+
+func (f *rawFlag) IsBoolFlag() bool { return f.isBool }
+
+func isBoolSetting(s *setting) bool {
+	_, ok := s.value.(*boolValue)
+	return ok
+}
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error {
+	*v = stringValue(s)
+	return nil
+}
+
+func (v *stringValue) String() string { return string(*v) }
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	i, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return errors.New("must be an integer")
+	}
+
+	*v = intValue(i)
+	return nil
+}
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+type floatValue float64
+
+func (v *floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return errors.New("must be a number")
+	}
+
+	*v = floatValue(f)
+	return nil
+}
+
+func (v *floatValue) String() string {
+	return strconv.FormatFloat(float64(*v), 'f', -1, 64)
+}
+
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(strings.TrimSpace(s))
+	if err != nil {
+		return errors.New("must be true or false")
+	}
+
+	*v = boolValue(b)
+	return nil
+}
+
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
+
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil {
+		return errors.New("must be a duration such as 30s or 5m")
+	}
+
+	*v = durationValue(d)
+	return nil
+}
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+
+// listValue is a list given as one string separated by spaces or commas.
+type listValue []string
+
+func (v *listValue) Set(s string) error {
+	*v = strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+
+	return nil
+}
+
+func (v *listValue) String() string { return strings.Join(*v, ",") }
@@ -8,7 +8,10 @@ import (
 
 type contextKey string
 
-const userContextKey = contextKey("user")
+const (
+	userContextKey         = contextKey("user")
+	workoutOwnerContextKey = contextKey("workoutOwner")
+)
 
 func (app *application) contextSetUser(
 	r *http.Request,
@@ -26,3 +29,24 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 
 	return user
 }
+
+// contextSetWorkoutOwner records whose workouts the request acts on when that
+// is not the authenticated user, such as a coach managing an athlete.
+func (app *application) contextSetWorkoutOwner(
+	r *http.Request,
+	owner *data.User,
+) *http.Request {
+	ctx := context.WithValue(r.Context(), workoutOwnerContextKey, owner)
+	return r.WithContext(ctx)
+}
+
+// contextGetWorkoutOwner returns the user whose workouts the request acts
+// on, which is the authenticated user unless a coach route has set otherwise.
+func (app *application) contextGetWorkoutOwner(r *http.Request) *data.User {
+	owner, ok := r.Context().Value(workoutOwnerContextKey).(*data.User)
+	if !ok {
+		return app.contextGetUser(r)
+	}
+
+	return owner
+}
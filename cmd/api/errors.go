@@ -1,6 +1,18 @@
 package main
 
-import "net/http"
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// statusClientClosedRequest is the non-standard status nginx uses for
+// requests the client gave up on before a response was sent.
+const statusClientClosedRequest = 499
 
 func (app *application) logError(r *http.Request, err error) {
 	var (
@@ -26,11 +38,24 @@ func (app *application) errorResponse(
 	}
 }
 
+// serverErrorResponse reports an unexpected error. Errors caused by the
+// request being cancelled or a query running out of time are not server
+// faults, so they are passed on to the responses below instead.
 func (app *application) serverErrorResponse(
 	w http.ResponseWriter,
 	r *http.Request,
 	err error,
 ) {
+	if isCancellation(err) {
+		if r.Context().Err() != nil {
+			app.requestCancelledResponse(w, r, err)
+		} else {
+			app.timeoutResponse(w, r, err)
+		}
+
+		return
+	}
+
 	app.logError(r, err)
 
 	message := "the server encountered a problem and could not process your request"
@@ -87,3 +112,73 @@ func (app *application) notFoundResponse(
 	message := "the requested resource could not be found"
 	app.errorResponse(w, r, http.StatusNotFound, message)
 }
+
+func (app *application) editConflictResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	message := "unable to update the record due to an edit conflict, please try again"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+// rateLimitExceededResponse tells the client how many seconds to wait before
+// trying again.
+func (app *application) rateLimitExceededResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	retryAfter time.Duration,
+) {
+	w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+
+	message := "rate limit exceeded"
+	app.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
+
+// requestCancelledResponse is used when the client went away while the
+// request was being handled. Nobody is left to read the response, so this
+// mostly serves the access logs.
+func (app *application) requestCancelledResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	app.logger.Info(
+		"request cancelled",
+		"method", r.Method,
+		"uri", r.URL.RequestURI(),
+		"error", err.Error(),
+	)
+
+	message := "the request was cancelled"
+	app.errorResponse(w, r, statusClientClosedRequest, message)
+}
+
+func (app *application) timeoutResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	app.logger.Warn(
+		"request timed out",
+		"method", r.Method,
+		"uri", r.URL.RequestURI(),
+		"error", err.Error(),
+	)
+
+	message := "the request took too long to process, please try again later"
+	app.errorResponse(w, r, http.StatusServiceUnavailable, message)
+}
+
+// isCancellation reports whether err comes from a context being cancelled
+// or timing out. If that happens while a query is running, PostgreSQL
+// reports it as a query_canceled error rather than the context's error.
+func isCancellation(err error) bool {
+	if errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == "57014"
+}
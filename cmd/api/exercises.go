@@ -6,7 +6,7 @@ func (app *application) listExercisesHandler(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
-	exercises, err := app.models.Exercises.GetAll()
+	exercises, err := app.models.Exercises.GetAll(r.Context())
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
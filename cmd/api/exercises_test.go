@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"testing"
+)
+
+func TestListExercisesHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, token := createTestUser(t, app, "Alice")
+	createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+	createTestExercise(t, app, "Run", data.MeasurementDistanceTime)
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "authenticated",
+			method:     http.MethodGet,
+			path:       "/v1/exercises",
+			token:      token,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("exercises", func(t *testing.T, exercises []data.Exercise) {
+				if len(exercises) != 2 || exercises[0].Name != "Squat" ||
+					exercises[1].Name != "Run" {
+					t.Errorf("exercises = %+v; want Squat and Run", exercises)
+				}
+			}),
+		},
+		{
+			name:       "anonymous",
+			method:     http.MethodGet,
+			path:       "/v1/exercises",
+			wantStatus: http.StatusUnauthorized,
+		},
+	})
+
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sulemankhann/workout-tracker/internal/data"
+	"sulemankhann/workout-tracker/internal/validator"
+	"time"
+)
+
+const exportBatchSize = 100
+
+// exportCSVHeader is part of the public export format. Append new columns to
+// the end rather than reordering existing ones so that spreadsheets and
+// scripts built against older exports keep working.
+var exportCSVHeader = []string{
+	"workout_id",
+	"workout_title",
+	"workout_description",
+	"scheduled_at",
+	"completed_at",
+	"exercise_id",
+	"exercise_name",
+	"exercise_category",
+	"exercise_muscle_group",
+	"sets",
+	"repetitions",
+	"weight",
+	"rest_interval",
+	"weight_unit",
+	"measurement_type",
+	"duration_seconds",
+	"distance",
+	"distance_unit",
+}
+
+// workoutExporter writes workouts one at a time in a particular format so
+// that an export never needs to hold a user's full history in memory.
+type workoutExporter interface {
+	begin() error
+	write(workout *data.Workout) error
+	end() error
+}
+
+func newWorkoutExporter(format string, w io.Writer) workoutExporter {
+	switch format {
+	case "json":
+		return &jsonExporter{w: w}
+	case "ndjson":
+		return &ndjsonExporter{enc: json.NewEncoder(w)}
+	default:
+		return &csvExporter{w: csv.NewWriter(w)}
+	}
+}
+
+type csvExporter struct {
+	w *csv.Writer
+}
+
+func (e *csvExporter) begin() error {
+	return e.w.Write(exportCSVHeader)
+}
+
+func (e *csvExporter) write(workout *data.Workout) error {
+	record := []string{
+		strconv.FormatInt(workout.ID, 10),
+		workout.Title,
+		workout.Description,
+		formatExportTime(&workout.ScheduledAt),
+		formatExportTime(workout.CompletedAt),
+	}
+
+	// A workout without exercises still gets a row so that nothing is lost
+	// from the export.
+	if len(workout.Exercises) == 0 {
+		blank := make([]string, len(exportCSVHeader)-len(record))
+		return e.w.Write(append(record, blank...))
+	}
+
+	for _, we := range workout.Exercises {
+		row := append(
+			record[:len(record):len(record)],
+			strconv.FormatInt(we.Exercise.ID, 10),
+			we.Exercise.Name,
+			we.Exercise.Category,
+			we.Exercise.MuscleGroup,
+			strconv.Itoa(we.Sets),
+			strconv.Itoa(we.Repetitions),
+			strconv.FormatFloat(we.Weight, 'f', -1, 64),
+			strconv.Itoa(we.RestInterval),
+			we.WeightUnit,
+			we.Exercise.MeasurementType,
+			strconv.Itoa(we.DurationSeconds),
+			strconv.FormatFloat(we.Distance, 'f', -1, 64),
+			we.DistanceUnit,
+		)
+
+		err := e.w.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExporter) end() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type jsonExporter struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonExporter) begin() error {
+	_, err := io.WriteString(e.w, "{\"workouts\":[")
+	return err
+}
+
+func (e *jsonExporter) write(workout *data.Workout) error {
+	js, err := json.Marshal(workout)
+	if err != nil {
+		return err
+	}
+
+	if e.count > 0 {
+		_, err = io.WriteString(e.w, ",")
+		if err != nil {
+			return err
+		}
+	}
+
+	e.count++
+
+	_, err = e.w.Write(js)
+	return err
+}
+
+func (e *jsonExporter) end() error {
+	_, err := io.WriteString(e.w, "]}\n")
+	return err
+}
+
+type ndjsonExporter struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonExporter) begin() error {
+	return nil
+}
+
+func (e *ndjsonExporter) write(workout *data.Workout) error {
+	return e.enc.Encode(workout)
+}
+
+func (e *ndjsonExporter) end() error {
+	return nil
+}
+
+func formatExportTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+func (app *application) exportWorkoutsHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+
+	v := validator.New()
+
+	v.Check(
+		format == "csv" || format == "json" || format == "ndjson",
+		"format",
+		"must be one of csv, json or ndjson",
+	)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	contentTypes := map[string]string{
+		"csv":    "text/csv; charset=utf-8",
+		"json":   "application/json",
+		"ndjson": "application/x-ndjson",
+	}
+
+	user := app.contextGetUser(r)
+
+	filename := fmt.Sprintf(
+		"workouts-%s.%s",
+		time.Now().UTC().Format("20060102"),
+		format,
+	)
+
+	w.Header().Set("Content-Type", contentTypes[format])
+	w.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf("attachment; filename=%q", filename),
+	)
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	exporter := newWorkoutExporter(format, w)
+
+	err := exporter.begin()
+	if err == nil {
+		err = app.models.Workouts.ForEachForUser(
+			r.Context(),
+			user.ID,
+			exportBatchSize,
+			func(workout *data.Workout) error {
+				err := exporter.write(workout.InExactUnits(user.Units))
+				if err != nil {
+					return err
+				}
+
+				// Push each workout out to the client as it is written so
+				// that large exports start downloading immediately, and
+				// give the next write a fresh deadline so a long export is
+				// not cut off by the server's write timeout.
+				rc.Flush()
+				_ = rc.SetWriteDeadline(
+					time.Now().Add(app.config.server.writeTimeout),
+				)
+
+				return nil
+			},
+		)
+	}
+	if err == nil {
+		err = exporter.end()
+	}
+
+	// The status code and part of the body have already been sent, so the
+	// best we can do is log the failure; the client will see a truncated
+	// download.
+	if err != nil {
+		app.logError(r, err)
+	}
+}
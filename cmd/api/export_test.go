@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"testing"
+	"time"
+)
+
+func TestExportWorkoutsHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, aliceToken := createTestUser(t, app, "Alice")
+	_, bobToken := createTestUser(t, app, "Bob")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+
+	completedAt := time.Now().Add(-time.Hour)
+
+	createTestWorkout(t, app, alice, squat, &completedAt)
+	createTestWorkout(t, app, alice, squat, nil)
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "csv",
+			method:     http.MethodGet,
+			path:       "/v1/export",
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				contentType := res.header.Get("Content-Type")
+				if contentType != "text/csv; charset=utf-8" {
+					t.Errorf("Content-Type = %q; want text/csv", contentType)
+				}
+			},
+		},
+		{
+			name:       "unknown format",
+			method:     http.MethodGet,
+			path:       "/v1/export?format=xml",
+			token:      aliceToken,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "anonymous",
+			method:     http.MethodGet,
+			path:       "/v1/export",
+			wantStatus: http.StatusUnauthorized,
+		},
+	})
+
+	t.Run("csv rows", func(t *testing.T) {
+		res := app.request(t, http.MethodGet, "/v1/export?format=csv", aliceToken, nil)
+
+		records, err := csv.NewReader(bytes.NewReader(res.body)).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(records) != 3 || len(records[0]) != len(exportCSVHeader) {
+			t.Errorf("got %d records; want a header and 2 rows", len(records))
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		res := app.request(t, http.MethodGet, "/v1/export?format=json", aliceToken, nil)
+
+		if contentType := res.header.Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("Content-Type = %q; want application/json", contentType)
+		}
+
+		var body struct {
+			Workouts []data.Workout `json:"workouts"`
+		}
+
+		res.decode(t, &body)
+
+		if len(body.Workouts) != 2 {
+			t.Errorf("got %d workouts; want 2", len(body.Workouts))
+		}
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		res := app.request(t, http.MethodGet, "/v1/export?format=ndjson", aliceToken, nil)
+
+		dec := json.NewDecoder(bytes.NewReader(res.body))
+
+		count := 0
+		for dec.More() {
+			var workout data.Workout
+
+			err := dec.Decode(&workout)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			count++
+		}
+
+		if count != 2 {
+			t.Errorf("got %d workouts; want 2", count)
+		}
+	})
+
+	t.Run("only the user's workouts", func(t *testing.T) {
+		res := app.request(t, http.MethodGet, "/v1/export?format=json", bobToken, nil)
+
+		var body struct {
+			Workouts []data.Workout `json:"workouts"`
+		}
+
+		res.decode(t, &body)
+
+		if len(body.Workouts) != 0 {
+			t.Errorf("got %d workouts; want 0", len(body.Workouts))
+		}
+	})
+}
+
+func TestExportWorkoutsHandlerIsExact(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, token := createTestUser(t, app, "Alice")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+
+	alice.Units = data.Units{
+		Weight:        data.UnitPounds,
+		Distance:      data.UnitMiles,
+		PlateRounding: true,
+	}
+
+	err := app.models.Users.Update(context.Background(), alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createTestWorkout(t, app, alice, squat, nil)
+
+	res := app.request(t, http.MethodGet, "/v1/export?format=json", token, nil)
+
+	var body struct {
+		Workouts []data.Workout `json:"workouts"`
+	}
+
+	res.decode(t, &body)
+
+	if len(body.Workouts) != 1 || len(body.Workouts[0].Exercises) != 1 {
+		t.Fatalf("got %+v; want one workout with one exercise", body.Workouts)
+	}
+
+	// Plate rounding would export 220 lb.
+	got := body.Workouts[0].Exercises[0]
+	want := data.FromKilograms(100, data.UnitPounds)
+
+	if got.Weight != want || got.WeightUnit != data.UnitPounds {
+		t.Errorf("weight = %v %s; want %v lb", got.Weight, got.WeightUnit, want)
+	}
+}
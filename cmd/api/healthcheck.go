@@ -8,15 +8,23 @@ func (app *application) healthcheckHandler(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
+	status, code := "available", http.StatusOK
+
+	// Report the server as unavailable while it shuts down so that load
+	// balancers take it out of rotation.
+	if app.shuttingDown.Load() {
+		status, code = "shutting down", http.StatusServiceUnavailable
+	}
+
 	env := envelope{
-		"status": "available",
+		"status": status,
 		"system_info": map[string]string{
 			"environment": app.config.env,
 			"version":     version,
 		},
 	}
 
-	err := app.writeJSON(w, http.StatusOK, env, nil)
+	err := app.writeJSON(w, code, env, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
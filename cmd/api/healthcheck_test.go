@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/netip"
+	"testing"
+)
+
+func TestHealthcheckHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "available",
+			method:     http.MethodGet,
+			path:       "/v1/healthcheck",
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("status", func(t *testing.T, status string) {
+				if status != "available" {
+					t.Errorf("status = %q; want available", status)
+				}
+			}),
+		},
+		{
+			name:       "unknown route",
+			method:     http.MethodGet,
+			path:       "/v1/nothing-here",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "wrong method",
+			method:     http.MethodPost,
+			path:       "/v1/healthcheck",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	})
+
+	app.shuttingDown.Store(true)
+
+	res := app.request(t, http.MethodGet, "/v1/healthcheck", "", nil)
+	if res.status != http.StatusServiceUnavailable {
+		t.Errorf("status while shutting down = %d; want 503", res.status)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	// Test requests come from 192.0.2.1, which isn't allowed by default.
+	res := app.request(t, http.MethodGet, "/debug/vars", "", nil)
+	if res.status != http.StatusNotFound {
+		t.Errorf("status for an unlisted client = %d; want 404", res.status)
+	}
+
+	app.metricsAllowed = []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")}
+
+	res = app.request(t, http.MethodGet, "/debug/vars", "", nil)
+	if res.status != http.StatusOK {
+		t.Fatalf("status for an allowed client = %d; want 200", res.status)
+	}
+
+	var vars map[string]json.RawMessage
+
+	res.decode(t, &vars)
+
+	if _, ok := vars["memstats"]; !ok {
+		t.Error("memstats is missing")
+	}
+
+	if _, ok := vars["cmdline"]; ok {
+		t.Error("cmdline is served")
+	}
+}
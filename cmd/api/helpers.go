@@ -6,7 +6,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/netip"
+	"net/url"
 	"strconv"
+	"strings"
+	"sulemankhann/workout-tracker/internal/validator"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 )
@@ -95,12 +100,177 @@ func (app *application) readJSON(
 }
 
 func (app *application) readIDParam(r *http.Request) (int64, error) {
+	return app.readNamedIDParam(r, "id")
+}
+
+// readNamedIDParam reads a positive integer ID from the named URL parameter,
+// for routes that identify more than one record.
+func (app *application) readNamedIDParam(
+	r *http.Request,
+	name string,
+) (int64, error) {
 	params := httprouter.ParamsFromContext(r.Context())
 
-	id, err := strconv.ParseInt(params.ByName("id"), 10, 64)
+	id, err := strconv.ParseInt(params.ByName(name), 10, 64)
 	if err != nil || id < 1 {
-		return 0, errors.New("invalid id parameter")
+		return 0, fmt.Errorf("invalid %s parameter", name)
 	}
 
 	return id, nil
 }
+
+func (app *application) readInt(
+	qs url.Values,
+	key string,
+	defaultValue int,
+	v *validator.Validator,
+) int {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		v.AddError(key, "must be an integer value")
+		return defaultValue
+	}
+
+	return i
+}
+
+func (app *application) readBool(
+	qs url.Values,
+	key string,
+	defaultValue bool,
+	v *validator.Validator,
+) bool {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return defaultValue
+	}
+
+	return b
+}
+
+// readTime reads an RFC 3339 timestamp or a plain YYYY-MM-DD date (taken as
+// midnight in loc) from the query string, returning the zero time if the key
+// is absent.
+func (app *application) readTime(
+	qs url.Values,
+	key string,
+	loc *time.Location,
+	v *validator.Validator,
+) time.Time {
+	s := qs.Get(key)
+
+	if s == "" {
+		return time.Time{}
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err == nil {
+		return t
+	}
+
+	t, err = time.ParseInLocation(time.DateOnly, s, loc)
+	if err != nil {
+		v.AddError(key, "must be an RFC 3339 timestamp or YYYY-MM-DD date")
+		return time.Time{}
+	}
+
+	return t
+}
+
+// background runs fn in a new goroutine, recovering and logging any panic so
+// that a failing background task cannot bring down the whole server. The
+// goroutine is tracked so that shutdown can wait for it to finish.
+func (app *application) background(fn func()) {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.Error(fmt.Sprintf("%v", err))
+			}
+		}()
+
+		fn()
+	}()
+}
+
+// clientIP returns the address of the client that sent the request. When
+// the request comes through trusted proxies the address is taken from
+// X-Forwarded-For, skipping the proxies from the right, or else from
+// X-Real-IP. Headers from anyone else are ignored, as clients can set them
+// to whatever they like.
+func (app *application) clientIP(r *http.Request) netip.Addr {
+	addrPort, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return netip.Addr{}
+	}
+
+	addr := addrPort.Addr().Unmap()
+
+	if !app.trustedProxy(addr) {
+		return addr
+	}
+
+	var hops []string
+
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	if len(hops) == 0 {
+		realIP, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP")))
+		if err == nil {
+			return realIP.Unmap()
+		}
+
+		return addr
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// The proxies wouldn't have added this, so don't trust anything
+			// to its left either.
+			return addr
+		}
+
+		addr = hop.Unmap()
+
+		if !app.trustedProxy(addr) {
+			return addr
+		}
+	}
+
+	return addr
+}
+
+func (app *application) trustedProxy(addr netip.Addr) bool {
+	for _, prefix := range app.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ceilSeconds rounds d up to whole seconds, as used by headers such as
+// Retry-After.
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sulemankhann/workout-tracker/internal/data"
+	"sulemankhann/workout-tracker/internal/importer"
+	"sulemankhann/workout-tracker/internal/validator"
+	"time"
+)
+
+const maxImportFileBytes = 20 << 20
+
+// importTimeout bounds the work of an import. A job that is still pending or
+// running after that long was abandoned by an instance that stopped, and is
+// failed when the next one starts.
+const importTimeout = 10 * time.Minute
+
+func (app *application) createImportHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	// Uploads can be slow, so allow longer than the server's read timeout
+	// for the body to arrive.
+	_ = http.NewResponseController(w).SetReadDeadline(
+		time.Now().Add(uploadReadTimeout),
+	)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileBytes)
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &maxBytesError):
+			app.badRequestResponse(
+				w,
+				r,
+				fmt.Errorf(
+					"file must not be larger than %d bytes",
+					maxBytesError.Limit,
+				),
+			)
+		default:
+			app.badRequestResponse(
+				w,
+				r,
+				errors.New("request must include a CSV export in the \"file\" form field"),
+			)
+		}
+
+		return
+	}
+
+	defer file.Close()
+
+	user := app.contextGetUser(r)
+
+	// Parsing happens up front so that malformed files are rejected straight
+	// away; only the matching and database work is deferred to the job.
+	source, workouts, err := importer.Parse(file, user.Location(), user.Units)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	job := &data.ImportJob{
+		UserID:        user.ID,
+		Source:        source,
+		Status:        data.ImportStatusPending,
+		WorkoutsFound: len(workouts),
+	}
+
+	err = app.models.Imports.Insert(r.Context(), job)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// The import outlives the request, and shutdown waits for it to finish
+	// rather than cancelling it. It works on its own copy of the job so that
+	// its progress updates don't race with writing the response.
+	running := *job
+
+	app.background(func() {
+		app.runImport(context.Background(), &running, workouts)
+	})
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/imports/%d", job.ID))
+
+	err = app.writeJSON(
+		w,
+		http.StatusAccepted,
+		envelope{"import": job},
+		headers,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showImportHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	job, err := app.models.Imports.GetByUser(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"import": job}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// runImport maps the parsed workouts onto the exercise catalogue and creates
+// the valid ones in a single transaction, recording progress on the job as it
+// goes.
+func (app *application) runImport(
+	ctx context.Context,
+	job *data.ImportJob,
+	parsed []*importer.Workout,
+) {
+	job.Status = data.ImportStatusRunning
+
+	err := app.models.Imports.Update(ctx, job)
+	if err != nil {
+		app.logger.Error(err.Error(), "import_id", job.ID)
+		return
+	}
+
+	// The job itself is still updated if the work runs out of time.
+	workCtx, cancel := context.WithTimeout(ctx, importTimeout)
+	defer cancel()
+
+	workouts, err := app.mapImportedWorkouts(workCtx, job, parsed)
+	if err == nil {
+		// Completing the job creates its workouts in the same transaction.
+		err = app.models.Imports.Complete(workCtx, job, workouts)
+		if err == nil {
+			return
+		}
+	}
+
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	job.Status = data.ImportStatusFailed
+	job.Error = "the import could not be completed"
+
+	app.logger.Error(err.Error(), "import_id", job.ID)
+
+	err = app.models.Imports.Update(ctx, job)
+	if err != nil {
+		app.logger.Error(err.Error(), "import_id", job.ID)
+	}
+}
+
+// failStaleImports fails the jobs left unfinished by instances that stopped
+// before their imports did. Nothing was written for them, since an import
+// creates its workouts in a single transaction, so they can be retried.
+func (app *application) failStaleImports(ctx context.Context) error {
+	failed, err := app.models.Imports.FailStale(
+		ctx,
+		importTimeout,
+		"the import was interrupted; please upload the file again",
+	)
+	if err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		app.logger.Info("failed interrupted imports", "count", failed)
+	}
+
+	return nil
+}
+
+// mapImportedWorkouts turns the parsed workouts into the job's user's
+// workouts. It records on the job the exercises it couldn't match, which are
+// left out of their workouts, and the workouts that fail validation, which
+// are left out of the import.
+func (app *application) mapImportedWorkouts(
+	ctx context.Context,
+	job *data.ImportJob,
+	parsed []*importer.Workout,
+) ([]*data.Workout, error) {
+	exercises, err := app.models.Exercises.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	catalogue := make(map[int64]string, len(exercises))
+	exercisesByID := make(map[int64]*data.Exercise, len(exercises))
+	for _, exercise := range exercises {
+		catalogue[exercise.ID] = exercise.Name
+		exercisesByID[exercise.ID] = exercise
+	}
+
+	matcher := importer.NewMatcher(catalogue)
+	unmapped := []string{}
+	skipped := []string{}
+
+	workouts := make([]*data.Workout, 0, len(parsed))
+
+	for _, p := range parsed {
+		completedAt := p.CompletedAt
+
+		workout := &data.Workout{
+			UserID:      job.UserID,
+			Title:       p.Name,
+			Description: p.Notes,
+			ScheduledAt: p.StartedAt,
+			CompletedAt: &completedAt,
+			Exercises:   []data.WorkoutExercise{},
+		}
+
+		if workout.Title == "" {
+			workout.Title = "Imported workout"
+		}
+
+		for _, exercise := range p.Exercises {
+			exerciseID, ok := matcher.Match(exercise.Name)
+			if !ok {
+				if !slices.Contains(unmapped, exercise.Name) {
+					unmapped = append(unmapped, exercise.Name)
+				}
+				continue
+			}
+
+			workout.Exercises = append(
+				workout.Exercises,
+				summarizeImportedSets(exercisesByID[exerciseID], exercise.Sets),
+			)
+		}
+
+		err = validateImportedWorkout(workout)
+		if err != nil {
+			skipped = append(skipped, err.Error())
+			continue
+		}
+
+		workouts = append(workouts, workout)
+	}
+
+	slices.Sort(unmapped)
+
+	job.UnmappedExercises = unmapped
+	job.SkippedWorkouts = skipped
+
+	return workouts, nil
+}
+
+// summarizeImportedSets collapses the individually logged sets from another
+// app into a single workout exercise. Strength exercises keep the number of
+// working sets plus the weight and repetitions of the heaviest one; cardio
+// exercises total their distance and time, and timed exercises keep their
+// longest hold. Warm-up sets are ignored unless they are all that was logged,
+// and drop sets count towards the set they continue.
+func summarizeImportedSets(
+	exercise *data.Exercise,
+	sets []importer.Set,
+) data.WorkoutExercise {
+	working := make([]importer.Set, 0, len(sets))
+	for _, set := range sets {
+		if !set.Warmup {
+			working = append(working, set)
+		}
+	}
+
+	if len(working) == 0 {
+		working = sets
+	}
+
+	count := 0
+	for _, set := range working {
+		if !set.Drop {
+			count++
+		}
+	}
+
+	count = max(count, 1)
+
+	switch exercise.MeasurementType {
+	case data.MeasurementDistanceTime:
+		workoutExercise := data.WorkoutExercise{
+			ExerciseID: exercise.ID,
+			Exercise:   *exercise,
+			Sets:       1,
+		}
+		for _, set := range working {
+			workoutExercise.Distance += set.DistanceMeters
+			workoutExercise.DurationSeconds += set.DurationSeconds
+		}
+		return workoutExercise
+
+	case data.MeasurementTime:
+		workoutExercise := data.WorkoutExercise{
+			ExerciseID: exercise.ID,
+			Exercise:   *exercise,
+			Sets:       count,
+		}
+		for _, set := range working {
+			workoutExercise.DurationSeconds = max(
+				workoutExercise.DurationSeconds,
+				set.DurationSeconds,
+			)
+		}
+		return workoutExercise
+	}
+
+	top := working[0]
+	for _, set := range working[1:] {
+		if set.Weight > top.Weight ||
+			(set.Weight == top.Weight && set.Repetitions > top.Repetitions) {
+			top = set
+		}
+	}
+
+	return data.WorkoutExercise{
+		ExerciseID:  exercise.ID,
+		Exercise:    *exercise,
+		Sets:        count,
+		Repetitions: top.Repetitions,
+		Weight:      math.Round(top.Weight*100) / 100,
+	}
+}
+
+// validateImportedWorkout runs the checks that creating the workout through
+// the API would, describing the first problem found in terms of the file.
+func validateImportedWorkout(workout *data.Workout) error {
+	subject := fmt.Sprintf(
+		"%q on %s",
+		workout.Title,
+		workout.ScheduledAt.Format(time.DateOnly),
+	)
+
+	v := validator.New()
+
+	if data.ValidateWorkout(v, workout); !v.Valid() {
+		return invalidImportError(subject, v)
+	}
+
+	for i := range workout.Exercises {
+		workoutExercise := &workout.Exercises[i]
+
+		if data.ValidateWorkoutEXercise(v, workoutExercise); !v.Valid() {
+			return invalidImportError(
+				subject+", "+workoutExercise.Exercise.Name,
+				v,
+			)
+		}
+	}
+
+	return nil
+}
+
+func invalidImportError(subject string, v *validator.Validator) error {
+	problems := []string{}
+
+	for _, key := range slices.Sorted(maps.Keys(v.Errors)) {
+		problems = append(problems, key+" "+v.Errors[key])
+	}
+
+	return fmt.Errorf("%s: %s", subject, strings.Join(problems, ", "))
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"testing"
+	"time"
+)
+
+const testStrongCSV = `Date,Workout Name,Exercise Name,Set Order,Weight,Reps,Distance,Seconds,Notes,Workout Notes
+2024-05-01 18:00:00,Legs,Squat,1,100,5,,,,
+2024-05-01 18:00:00,Legs,Squat,2,100,5,,,,
+2024-05-01 18:00:00,Legs,Hip Thrust,1,80,10,,,,
+`
+
+func TestImportHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, aliceToken := createTestUser(t, app, "Alice")
+	_, bobToken := createTestUser(t, app, "Bob")
+	createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "import without a file",
+			method:     http.MethodPost,
+			path:       "/v1/imports",
+			token:      aliceToken,
+			body:       multipartForm{},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "import an unknown format",
+			method:     http.MethodPost,
+			path:       "/v1/imports",
+			token:      aliceToken,
+			body:       multipartForm{"file": "a,b,c\n1,2,3\n"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "import anonymously",
+			method:     http.MethodPost,
+			path:       "/v1/imports",
+			body:       multipartForm{"file": testStrongCSV},
+			wantStatus: http.StatusUnauthorized,
+		},
+	})
+
+	res := app.request(
+		t,
+		http.MethodPost,
+		"/v1/imports",
+		aliceToken,
+		multipartForm{"file": testStrongCSV},
+	)
+	if res.status != http.StatusAccepted {
+		t.Fatalf("status = %d; want 202\n%s", res.status, res.body)
+	}
+
+	var body struct {
+		Import data.ImportJob `json:"import"`
+	}
+
+	res.decode(t, &body)
+
+	path := fmt.Sprintf("/v1/imports/%d", body.Import.ID)
+
+	if location := res.header.Get("Location"); location != path {
+		t.Errorf("Location = %q; want %q", location, path)
+	}
+
+	// The import runs in the background, so wait for it before checking
+	// the result.
+	app.wg.Wait()
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "show",
+			method:     http.MethodGet,
+			path:       path,
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("import", func(t *testing.T, job data.ImportJob) {
+				if job.ID != body.Import.ID ||
+					job.Status != data.ImportStatusCompleted ||
+					job.WorkoutsImported != 1 ||
+					len(job.UnmappedExercises) != 1 ||
+					job.UnmappedExercises[0] != "Hip Thrust" {
+					t.Errorf("import = %+v; want one workout with Hip Thrust unmapped", job)
+				}
+			}),
+		},
+		{
+			name:       "show another user's import",
+			method:     http.MethodGet,
+			path:       path,
+			token:      bobToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "show an unknown import",
+			method:     http.MethodGet,
+			path:       "/v1/imports/999",
+			token:      aliceToken,
+			wantStatus: http.StatusNotFound,
+		},
+	})
+
+	// Subscribers hear of the import once rather than of every workout.
+	events, err := app.models.Outbox.Claim(context.Background(), 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 1 || events[0].Type != data.EventImportCompleted {
+		t.Fatalf("got %d events; want a single import.completed", len(events))
+	}
+
+	var payload struct {
+		Data struct {
+			Import data.ImportJob `json:"import"`
+		} `json:"data"`
+	}
+
+	err = json.Unmarshal(events[0].Payload, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if payload.Data.Import.ID != body.Import.ID || payload.Data.Import.WorkoutsImported != 1 {
+		t.Errorf(
+			"import.completed import = %+v; want job %d with one workout",
+			payload.Data.Import,
+			body.Import.ID,
+		)
+	}
+}
+
+func TestImportSkipsInvalidWorkouts(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, token := createTestUser(t, app, "Alice")
+	createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+
+	csv := `Date,Workout Name,Exercise Name,Set Order,Weight,Reps
+2024-05-01 18:00:00,Legs,Squat,1,100,0
+2024-05-03 18:00:00,Legs,Squat,1,100,5
+`
+
+	res := app.request(t, http.MethodPost, "/v1/imports", token, multipartForm{"file": csv})
+	if res.status != http.StatusAccepted {
+		t.Fatalf("status = %d; want 202\n%s", res.status, res.body)
+	}
+
+	var body struct {
+		Import data.ImportJob `json:"import"`
+	}
+
+	res.decode(t, &body)
+
+	app.wg.Wait()
+
+	res = app.request(t, http.MethodGet, fmt.Sprintf("/v1/imports/%d", body.Import.ID), token, nil)
+	res.decode(t, &body)
+
+	// The invalid workout is left out and the rest of the file imported.
+	want := `"Legs" on 2024-05-01, Squat: repetitions must be greater than zero`
+
+	job := body.Import
+	if job.Status != data.ImportStatusCompleted ||
+		job.WorkoutsImported != 1 ||
+		len(job.SkippedWorkouts) != 1 ||
+		job.SkippedWorkouts[0] != want {
+		t.Errorf("import = %+v; want one workout with %q skipped", job, want)
+	}
+
+	workouts, err := app.models.Workouts.GetAllForUser(context.Background(), alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(workouts) != 1 || workouts[0].ScheduledAt.Day() != 3 {
+		t.Errorf("got %d workouts; want the one from 2024-05-03", len(workouts))
+	}
+}
+
+func TestImportUsesUserUnits(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, token := createTestUser(t, app, "Alice")
+	createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+
+	alice.Units = data.Units{Weight: data.UnitPounds, Distance: data.UnitMiles}
+
+	err := app.models.Users.Update(context.Background(), alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// testStrongCSV has no unit columns, so its weights are in the user's
+	// pounds.
+	res := app.request(t, http.MethodPost, "/v1/imports", token, multipartForm{"file": testStrongCSV})
+	if res.status != http.StatusAccepted {
+		t.Fatalf("status = %d; want 202\n%s", res.status, res.body)
+	}
+
+	app.wg.Wait()
+
+	workouts, err := app.models.Workouts.GetAllForUser(context.Background(), alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(workouts) != 1 || len(workouts[0].Exercises) == 0 {
+		t.Fatalf("got %d workouts; want one with squats", len(workouts))
+	}
+
+	want := data.ToKilograms(100, data.UnitPounds)
+
+	for _, exercise := range workouts[0].Exercises {
+		if math.Abs(exercise.Weight-want) > 0.01 {
+			t.Errorf("weight = %v; want %v kg", exercise.Weight, want)
+		}
+	}
+}
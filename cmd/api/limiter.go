@@ -0,0 +1,107 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// limiterSweepInterval is how often idle buckets are looked for and
+// dropped, so that clients seen once don't stay in memory forever.
+const limiterSweepInterval = time.Minute
+
+// rateLimiter keeps a token bucket for each key, such as a client IP or a
+// user. A bucket holds up to burst tokens and refills at rate tokens per
+// second; each request takes one.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limitResult describes a bucket after a request has been counted against
+// it.
+type limitResult struct {
+	allowed   bool
+	limit     int
+	remaining int
+	// reset is the time until the bucket is full again.
+	reset time.Duration
+	// retryAfter is the time until the next request would be allowed, zero
+	// if it would be allowed now.
+	retryAfter time.Duration
+}
+
+func newRateLimiter(rps float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    rps,
+		burst:   float64(burst),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow takes a token from key's bucket if it has one.
+func (l *rateLimiter) allow(key string) limitResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	if now.Sub(l.lastSweep) >= limiterSweepInterval {
+		l.sweep(now)
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	result := limitResult{
+		allowed:   allowed,
+		limit:     int(l.burst),
+		remaining: int(b.tokens),
+		reset:     l.refillTime(l.burst - b.tokens),
+	}
+
+	if b.tokens < 1 {
+		result.retryAfter = l.refillTime(1 - b.tokens)
+	}
+
+	return result
+}
+
+// refillTime returns how long it takes for the given number of tokens to be
+// added to a bucket.
+func (l *rateLimiter) refillTime(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// sweep drops the buckets that have refilled completely, which behave just
+// like the new buckets that would replace them.
+func (l *rateLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.refillTime(l.burst-b.tokens) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock for rate limiters that only moves when told to.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestRateLimiter(rps float64, burst int) (*rateLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+
+	limiter := newRateLimiter(rps, burst)
+	limiter.now = clock.Now
+
+	return limiter, clock
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter, clock := newTestRateLimiter(2, 3)
+
+	for i := range 3 {
+		result := limiter.allow("a")
+		if !result.allowed || result.remaining != 2-i {
+			t.Fatalf("request %d: %+v", i+1, result)
+		}
+	}
+
+	result := limiter.allow("a")
+	if result.allowed || result.retryAfter != 500*time.Millisecond {
+		t.Errorf("request over the burst: %+v", result)
+	}
+
+	if result.reset != 1500*time.Millisecond {
+		t.Errorf("reset = %s; want 1.5s", result.reset)
+	}
+
+	// Other keys have buckets of their own.
+	if !limiter.allow("b").allowed {
+		t.Error("another key was limited")
+	}
+
+	clock.advance(500 * time.Millisecond)
+
+	result = limiter.allow("a")
+	if !result.allowed || result.remaining != 0 {
+		t.Errorf("after refilling one token: %+v", result)
+	}
+
+	// A bucket never holds more than the burst.
+	clock.advance(time.Hour)
+
+	result = limiter.allow("a")
+	if result.remaining != 2 {
+		t.Errorf("after a long pause remaining = %d; want 2", result.remaining)
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	limiter, clock := newTestRateLimiter(1, 10)
+
+	limiter.allow("idle")
+	clock.advance(30 * time.Second)
+	limiter.allow("busy")
+
+	clock.advance(limiterSweepInterval - 30*time.Second + time.Nanosecond)
+
+	for range 5 {
+		limiter.allow("busy")
+	}
+
+	if _, ok := limiter.buckets["idle"]; ok {
+		t.Error("the refilled bucket wasn't dropped")
+	}
+
+	if _, ok := limiter.buckets["busy"]; !ok {
+		t.Error("a bucket in use was dropped")
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	app := newTestApplication(t)
+	app.limiter, _ = newTestRateLimiter(1, 2)
+	app.ipLimiter, _ = newTestRateLimiter(1, 3)
+	app.authLimiter, _ = newTestRateLimiter(1, 1)
+
+	_, aliceToken := createTestUser(t, app, "Alice")
+	_, bobToken := createTestUser(t, app, "Bob")
+
+	routes := app.routes()
+
+	for range 2 {
+		if rr := sendFrom(routes, "203.0.113.1:5000", ""); rr.Code != http.StatusOK {
+			t.Fatalf("anonymous request: status %d", rr.Code)
+		}
+	}
+
+	rr := sendFrom(routes, "203.0.113.1:5000", "")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("anonymous request over the limit: status %d", rr.Code)
+	}
+
+	if got := rr.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q; want 1", got)
+	}
+
+	if got := rr.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("RateLimit-Remaining = %q; want 0", got)
+	}
+
+	// Guessed tokens are limited by IP before they are looked up.
+	guess := strings.Repeat("A", 26)
+
+	for range 3 {
+		if rr := sendFrom(routes, "203.0.113.2:5000", guess); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("request with an unknown token: status %d", rr.Code)
+		}
+	}
+
+	if rr := sendFrom(routes, "203.0.113.2:5000", guess); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("request with an unknown token over the limit: status %d", rr.Code)
+	}
+
+	// Authenticated users are also limited on their own, wherever their
+	// requests come from.
+	for _, remoteAddr := range []string{"203.0.113.3:5000", "203.0.113.4:5000"} {
+		if rr := sendFrom(routes, remoteAddr, aliceToken); rr.Code != http.StatusOK {
+			t.Fatalf("authenticated request: status %d", rr.Code)
+		}
+	}
+
+	rr = sendFrom(routes, "203.0.113.3:5000", aliceToken)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("third request by the same user: status %d", rr.Code)
+	}
+
+	if got := rr.Header().Get("RateLimit-Limit"); got != "2" {
+		t.Errorf("RateLimit-Limit = %q; want 2", got)
+	}
+
+	if rr := sendFrom(routes, "203.0.113.4:5000", bobToken); rr.Code != http.StatusOK {
+		t.Errorf("another user from the same address: status %d", rr.Code)
+	}
+}
+
+func TestRateLimitUsersSharingAnAddress(t *testing.T) {
+	app := newTestApplication(t)
+	app.limiter, _ = newTestRateLimiter(1, 2)
+	app.ipLimiter, _ = newTestRateLimiter(100, 100)
+
+	_, aliceToken := createTestUser(t, app, "Alice")
+	_, bobToken := createTestUser(t, app, "Bob")
+
+	routes := app.routes()
+
+	// Users behind the same address, such as an office NAT, each have their
+	// own limit rather than using up a shared one.
+	for _, token := range []string{aliceToken, aliceToken, bobToken, bobToken} {
+		if rr := sendFrom(routes, "203.0.113.5:5000", token); rr.Code != http.StatusOK {
+			t.Fatalf("request within the user's limit: status %d", rr.Code)
+		}
+	}
+
+	for name, token := range map[string]string{"Alice": aliceToken, "Bob": bobToken} {
+		rr := sendFrom(routes, "203.0.113.5:5000", token)
+		if rr.Code != http.StatusTooManyRequests {
+			t.Errorf("third request by %s: status %d", name, rr.Code)
+		}
+	}
+
+	// Nor do they use up the limit of anonymous clients at that address.
+	if rr := sendFrom(routes, "203.0.113.5:5000", ""); rr.Code != http.StatusOK {
+		t.Errorf("anonymous request from the same address: status %d", rr.Code)
+	}
+}
+
+// sendFrom sends a healthcheck request from remoteAddr, authenticated with
+// token unless it is empty.
+func sendFrom(handler http.Handler, remoteAddr, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/v1/healthcheck", nil)
+	r.RemoteAddr = remoteAddr
+
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, r)
+
+	return rr
+}
+
+func TestRateLimitAuthentication(t *testing.T) {
+	app := newTestApplication(t)
+	app.limiter, _ = newTestRateLimiter(100, 100)
+	app.authLimiter, _ = newTestRateLimiter(0.1, 2)
+
+	createTestUser(t, app, "Alice")
+
+	body := map[string]string{"email": "alice@example.com", "password": "wrong-password"}
+
+	for range 2 {
+		res := app.request(t, http.MethodPost, "/v1/tokens/authentication", "", body)
+		if res.status != http.StatusUnauthorized {
+			t.Fatalf("login attempt: status %d", res.status)
+		}
+	}
+
+	res := app.request(t, http.MethodPost, "/v1/tokens/authentication", "", body)
+	if res.status != http.StatusTooManyRequests {
+		t.Fatalf("third login attempt: status %d", res.status)
+	}
+
+	// The stricter limit's headers replace the general one's.
+	if got := res.header.Get("RateLimit-Limit"); got != "2" {
+		t.Errorf("RateLimit-Limit = %q; want 2", got)
+	}
+
+	if got := res.header.Get("Retry-After"); got != "10" {
+		t.Errorf("Retry-After = %q; want 10", got)
+	}
+
+	// Other routes are only subject to the general limit.
+	res = app.request(t, http.MethodGet, "/v1/healthcheck", "", nil)
+	if res.status != http.StatusOK {
+		t.Errorf("healthcheck after the login limit: status %d", res.status)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	app := newTestApplication(t)
+
+	for _, proxy := range []string{"10.0.0.0/8", "2001:db8::1"} {
+		prefix, err := parseTrustedProxy(proxy)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		app.trustedProxies = append(app.trustedProxies, prefix)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{
+			name:       "direct client",
+			remoteAddr: "203.0.113.7:5123",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "untrusted client sending headers",
+			remoteAddr: "203.0.113.7:5123",
+			forwarded:  []string{"198.51.100.1"},
+			realIP:     "198.51.100.2",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.1.2.3:443",
+			forwarded:  []string{"198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "chain of trusted proxies",
+			remoteAddr: "10.1.2.3:443",
+			forwarded:  []string{"192.0.2.99, 198.51.100.1", "10.9.9.9"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "trusted IPv6 proxy",
+			remoteAddr: "[2001:db8::1]:443",
+			forwarded:  []string{"198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "X-Real-IP",
+			remoteAddr: "10.1.2.3:443",
+			realIP:     "198.51.100.2",
+			want:       "198.51.100.2",
+		},
+		{
+			name:       "garbage in X-Forwarded-For",
+			remoteAddr: "10.1.2.3:443",
+			forwarded:  []string{"198.51.100.1, unknown"},
+			want:       "10.1.2.3",
+		},
+		{
+			name:       "IPv4-mapped address",
+			remoteAddr: "[::ffff:203.0.113.7]:5123",
+			want:       "203.0.113.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/healthcheck", nil)
+			r.RemoteAddr = tt.remoteAddr
+
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			got := app.clientIP(r)
+			if got != netip.MustParseAddr(tt.want) {
+				t.Errorf("clientIP = %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	a := clientKey(netip.MustParseAddr("2001:db8:1:2::a"))
+	b := clientKey(netip.MustParseAddr("2001:db8:1:2::b"))
+
+	if a != b || a != "ip:2001:db8:1:2::/64" {
+		t.Errorf("keys for one IPv6 /64 are %q and %q", a, b)
+	}
+
+	if got := clientKey(netip.MustParseAddr("203.0.113.7")); got != "ip:203.0.113.7" {
+		t.Errorf("IPv4 key = %q", got)
+	}
+}
@@ -3,80 +3,177 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
 	"log/slog"
+	"net/netip"
 	"os"
-	"strconv"
+	"runtime"
+	"strings"
 	"sulemankhann/workout-tracker/internal/data"
+	"sulemankhann/workout-tracker/internal/events"
+	"sulemankhann/workout-tracker/internal/migrate"
+	"sulemankhann/workout-tracker/migrations"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 var version = "1.0.0"
 
-type config struct {
-	port int
-	env  string
-}
-
 type application struct {
-	config config
-	logger *slog.Logger
-	models data.Models
+	config      config
+	logger      *slog.Logger
+	models      data.Models
+	sessions    *sessionBroker
+	subscribers *events.Subscribers
+	wg          sync.WaitGroup
+	// stopping is cancelled when the server starts shutting down, telling
+	// background workers and streaming responses to wrap up.
+	stopping     context.Context
+	stop         context.CancelFunc
+	shuttingDown atomic.Bool
+	// limiter, ipLimiter and authLimiter are nil when rate limiting is
+	// disabled.
+	limiter        *rateLimiter
+	ipLimiter      *rateLimiter
+	authLimiter    *rateLimiter
+	trustedProxies []netip.Prefix
+	// metricsAllowed are the clients allowed to read /debug/vars.
+	metricsAllowed []netip.Prefix
+	// reminderChannels are the channels this server can send reminders
+	// through.
+	reminderChannels map[string]reminderChannel
 }
 
 func main() {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
-	err := godotenv.Load()
+	cfg, settings, opts, err := loadConfig(os.Args[1:], os.LookupEnv)
+
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(0)
+
+	case opts.version:
+		fmt.Printf("Version:\t%s\n", version)
+		fmt.Printf("Go version:\t%s\n", runtime.Version())
+		os.Exit(0)
+
+	case err != nil:
+		logger.Error("invalid configuration")
+
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintln(os.Stderr, "  "+line)
+		}
+
+		os.Exit(2)
+
+	case opts.printConfig:
+		err = printConfig(os.Stdout, settings)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+
+		os.Exit(0)
+	}
+
+	db, err := openDB(cfg)
 	if err != nil {
 		logger.Error(err.Error())
 		os.Exit(1)
 	}
 
-	portStr, exist := os.LookupEnv("PORT")
-	if !exist {
-		portStr = "4000"
-	}
+	defer db.Close()
+
+	logger.Info("database connection pool established")
 
-	env, exist := os.LookupEnv("ENV")
-	if !exist {
-		env = "development"
+	if cfg.db.autoMigrate {
+		err = migrateDB(db, logger)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
 	}
 
-	port, err := strconv.Atoi(portStr)
+	publishMetrics(db)
+
+	listener, err := listenForSessions(cfg, logger)
 	if err != nil {
-		// invalid port, setting default port
-		port = 4000
+		logger.Error(err.Error())
+		os.Exit(1)
 	}
 
-	dbDSN, exist := os.LookupEnv("DB_DSN")
-	if !exist {
-		logger.Error("DB DSN not set")
-		os.Exit(1)
+	defer listener.Close()
+
+	stopping, stop := context.WithCancel(context.Background())
+
+	models := data.NewModels(db, cfg.db.queryTimeout)
+
+	app := &application{
+		config:      cfg,
+		logger:      logger,
+		models:      models,
+		sessions:    newSessionBroker(models.Sessions.Notify),
+		subscribers: events.NewSubscribers(),
+		stopping:    stopping,
+		stop:        stop,
+	}
+
+	app.reminderChannels = app.newReminderChannels()
+
+	if cfg.limiter.enabled {
+		app.limiter = newRateLimiter(cfg.limiter.rps, cfg.limiter.burst)
+		app.ipLimiter = newRateLimiter(cfg.limiter.ipRPS, cfg.limiter.ipBurst)
+		app.authLimiter = newRateLimiter(cfg.limiter.authRPS, cfg.limiter.authBurst)
+	}
+
+	// The proxies and metrics clients were checked when the configuration
+	// was loaded.
+	for _, proxy := range cfg.limiter.trustedProxies {
+		prefix, _ := parseTrustedProxy(proxy)
+		app.trustedProxies = append(app.trustedProxies, prefix)
+	}
+
+	for _, ip := range cfg.metrics.allowedIPs {
+		prefix, _ := parseTrustedProxy(ip)
+		app.metricsAllowed = append(app.metricsAllowed, prefix)
 	}
 
-	db, err := openDB(dbDSN)
+	err = app.failStaleImports(context.Background())
 	if err != nil {
 		logger.Error(err.Error())
 		os.Exit(1)
 	}
 
-	defer db.Close()
-
-	logger.Info("database connection pool established")
+	sinks := []events.Sink{webhookSink{models: app.models}, app.subscribers}
 
-	cfg := config{
-		port: port,
-		env:  env,
+	if cfg.env == "development" {
+		sinks = append(sinks, events.LogSink{Logger: logger})
 	}
 
-	app := application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
-	}
+	dispatcher := events.NewDispatcher(app.models.Outbox, logger, sinks...)
+	dispatcher.Retention = cfg.outbox.retention
+
+	app.background(func() {
+		dispatcher.Run(app.stopping)
+	})
+
+	app.background(func() {
+		app.runWebhookWorker(app.stopping)
+	})
+
+	app.background(func() {
+		app.relaySessionEvents(app.stopping, listener)
+	})
+
+	app.background(func() {
+		app.runReminderScheduler(app.stopping, app.reminderChannels)
+	})
 
 	err = app.serve()
 	if err != nil {
@@ -85,12 +182,19 @@ func main() {
 	}
 }
 
-func openDB(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("postgres", dsn)
+func openDB(cfg config) (*sql.DB, error) {
+	db, err := sql.Open("postgres", cfg.db.dsn)
 	if err != nil {
 		return nil, err
 	}
 
+	// A limit of 0 means no limit for open connections and none kept for
+	// idle ones; durations of 0 keep connections indefinitely.
+	db.SetMaxOpenConns(cfg.db.maxOpenConns)
+	db.SetMaxIdleConns(cfg.db.maxIdleConns)
+	db.SetConnMaxIdleTime(cfg.db.maxIdleTime)
+	db.SetConnMaxLifetime(cfg.db.maxLifetime)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -102,3 +206,50 @@ func openDB(dsn string) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// listenForSessions opens the connection that hears about session changes
+// made through any instance. It reconnects by itself if the connection is
+// lost.
+func listenForSessions(cfg config, logger *slog.Logger) (*pq.Listener, error) {
+	listener := pq.NewListener(
+		cfg.db.dsn,
+		time.Second,
+		time.Minute,
+		func(event pq.ListenerEventType, err error) {
+			if err != nil {
+				logger.Error(err.Error(), "channel", data.SessionChannel)
+			}
+		},
+	)
+
+	err := listener.Listen(data.SessionChannel)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	return listener, nil
+}
+
+// migrateDB applies any pending migrations. Instances starting together
+// queue on the migrator's lock, so only the first one does any work.
+func migrateDB(db *sql.DB, logger *slog.Logger) error {
+	migrator, err := migrate.New(db, migrations.FS, logger)
+	if err != nil {
+		return err
+	}
+
+	err = migrator.Up(context.Background())
+	if err != nil {
+		return err
+	}
+
+	version, _, err := migrator.Version(context.Background())
+	if err != nil {
+		return err
+	}
+
+	logger.Info("database schema up to date", "version", version)
+
+	return nil
+}
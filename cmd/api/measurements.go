@@ -0,0 +1,402 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"sulemankhann/workout-tracker/internal/data"
+	"sulemankhann/workout-tracker/internal/validator"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type circumferencesInput struct {
+	Neck  *float64 `json:"neck"`
+	Chest *float64 `json:"chest"`
+	Waist *float64 `json:"waist"`
+	Hips  *float64 `json:"hips"`
+	Arm   *float64 `json:"arm"`
+	Thigh *float64 `json:"thigh"`
+	Calf  *float64 `json:"calf"`
+}
+
+// apply copies every provided value onto c, converting it from unit into
+// centimetres and leaving the others untouched.
+func (in *circumferencesInput) apply(c *data.Circumferences, unit string) {
+	if in == nil {
+		return
+	}
+
+	fields := []struct {
+		src *float64
+		dst **float64
+	}{
+		{in.Neck, &c.Neck},
+		{in.Chest, &c.Chest},
+		{in.Waist, &c.Waist},
+		{in.Hips, &c.Hips},
+		{in.Arm, &c.Arm},
+		{in.Thigh, &c.Thigh},
+		{in.Calf, &c.Calf},
+	}
+
+	for _, field := range fields {
+		if field.src != nil {
+			*field.dst = data.ToCentimeters(field.src, unit)
+		}
+	}
+}
+
+func (app *application) createMeasurementHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	var input struct {
+		MeasuredAt        *time.Time           `json:"measured_at"`
+		Bodyweight        *float64             `json:"bodyweight"`
+		BodyFatPercentage *float64             `json:"body_fat_percentage"`
+		Circumferences    *circumferencesInput `json:"circumferences"`
+		Notes             string               `json:"notes"`
+		WeightUnit        string               `json:"weight_unit"`
+		LengthUnit        string               `json:"length_unit"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	v := validator.New()
+
+	weightUnit, lengthUnit := app.measurementUnits(
+		v,
+		user,
+		input.WeightUnit,
+		input.LengthUnit,
+	)
+
+	measurement := &data.Measurement{
+		UserID:            user.ID,
+		MeasuredAt:        time.Now(),
+		BodyFatPercentage: input.BodyFatPercentage,
+		Notes:             input.Notes,
+	}
+
+	if input.MeasuredAt != nil {
+		measurement.MeasuredAt = *input.MeasuredAt
+	}
+
+	if input.Bodyweight != nil {
+		bodyweight := data.ToKilograms(*input.Bodyweight, weightUnit)
+		measurement.Bodyweight = &bodyweight
+	}
+
+	input.Circumferences.apply(&measurement.Circumferences, lengthUnit)
+
+	if data.ValidateMeasurement(v, measurement); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Measurements.Insert(r.Context(), measurement)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(
+		w,
+		http.StatusCreated,
+		envelope{"measurement": measurement.InUnits(user.Units)},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listMeasurementsHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	user := app.contextGetUser(r)
+	qs := r.URL.Query()
+
+	v := validator.New()
+
+	from := app.readTime(qs, "from", user.Location(), v)
+	to := app.readTime(qs, "to", user.Location(), v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	measurements, err := app.models.Measurements.GetAllForUser(r.Context(), user.ID, from, to)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{
+			"measurements": data.MeasurementsInUnits(measurements, user.Units),
+		},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showMeasurementHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	measurement, err := app.models.Measurements.GetByUser(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{"measurement": measurement.InUnits(user.Units)},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateMeasurementHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	measurement, err := app.models.Measurements.GetByUser(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	var input struct {
+		MeasuredAt        *time.Time           `json:"measured_at"`
+		Bodyweight        *float64             `json:"bodyweight"`
+		BodyFatPercentage *float64             `json:"body_fat_percentage"`
+		Circumferences    *circumferencesInput `json:"circumferences"`
+		Notes             *string              `json:"notes"`
+		WeightUnit        string               `json:"weight_unit"`
+		LengthUnit        string               `json:"length_unit"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	weightUnit, lengthUnit := app.measurementUnits(
+		v,
+		user,
+		input.WeightUnit,
+		input.LengthUnit,
+	)
+
+	if input.MeasuredAt != nil {
+		measurement.MeasuredAt = *input.MeasuredAt
+	}
+
+	if input.Bodyweight != nil {
+		bodyweight := data.ToKilograms(*input.Bodyweight, weightUnit)
+		measurement.Bodyweight = &bodyweight
+	}
+
+	if input.BodyFatPercentage != nil {
+		measurement.BodyFatPercentage = input.BodyFatPercentage
+	}
+
+	if input.Notes != nil {
+		measurement.Notes = *input.Notes
+	}
+
+	input.Circumferences.apply(&measurement.Circumferences, lengthUnit)
+
+	if data.ValidateMeasurement(v, measurement); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Measurements.Update(r.Context(), measurement)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{"measurement": measurement.InUnits(user.Units)},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteMeasurementHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Measurements.DeleteByUser(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{"message": "measurement successfully deleted"},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showMeasurementTrendHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	metric := httprouter.ParamsFromContext(r.Context()).ByName("metric")
+	if !slices.Contains(data.MeasurementMetrics, metric) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	qs := r.URL.Query()
+
+	v := validator.New()
+
+	windowDays := app.readInt(qs, "window", 7, v)
+	from := app.readTime(qs, "from", user.Location(), v)
+	to := app.readTime(qs, "to", user.Location(), v)
+
+	v.Check(windowDays > 0, "window", "must be greater than zero")
+	v.Check(windowDays <= 365, "window", "must be a maximum of 365 days")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	measurements, err := app.models.Measurements.GetAllForUser(r.Context(), user.ID, from, to)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	trend := data.CalculateTrend(
+		data.MeasurementsInUnits(measurements, user.Units),
+		metric,
+		windowDays,
+	)
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{
+			"metric":      metric,
+			"window_days": windowDays,
+			"units":       user.Units,
+			"series":      trend,
+		},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// measurementUnits resolves the units a measurement was submitted in,
+// defaulting to the user's preferences when none are given.
+func (app *application) measurementUnits(
+	v *validator.Validator,
+	user *data.User,
+	weightUnit, lengthUnit string,
+) (string, string) {
+	if weightUnit == "" {
+		weightUnit = user.Units.Weight
+	}
+
+	if lengthUnit == "" {
+		lengthUnit = user.Units.Length()
+	}
+
+	data.ValidateUnit(v, "weight_unit", weightUnit, data.WeightUnits)
+	data.ValidateUnit(v, "length_unit", lengthUnit, data.LengthUnits)
+
+	return weightUnit, lengthUnit
+}
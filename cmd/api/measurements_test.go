@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"testing"
+	"time"
+)
+
+func TestMeasurementHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, aliceToken := createTestUser(t, app, "Alice")
+	_, bobToken := createTestUser(t, app, "Bob")
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "create as someone else",
+			method:     http.MethodPost,
+			path:       "/v1/measurements",
+			token:      bobToken,
+			body:       map[string]any{"bodyweight": 90},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:   "create",
+			method: http.MethodPost,
+			path:   "/v1/measurements",
+			token:  aliceToken,
+			body: map[string]any{
+				"measured_at":    time.Now().AddDate(0, 0, -7),
+				"bodyweight":     80.5,
+				"circumferences": map[string]float64{"waist": 84},
+			},
+			wantStatus: http.StatusCreated,
+			check: checkEnvelope("measurement", func(t *testing.T, m data.Measurement) {
+				if !roughlyEqual(m.Bodyweight, 80.5) || !roughlyEqual(m.Circumferences.Waist, 84) ||
+					m.WeightUnit != data.UnitKilograms || m.LengthUnit != "cm" {
+					t.Errorf("measurement = %+v; want 80.5 kg and an 84 cm waist", m)
+				}
+			}),
+		},
+		{
+			name:   "create in other units",
+			method: http.MethodPost,
+			path:   "/v1/measurements",
+			token:  aliceToken,
+			body: map[string]any{
+				"bodyweight":     176,
+				"weight_unit":    data.UnitPounds,
+				"circumferences": map[string]float64{"waist": 33},
+				"length_unit":    "in",
+			},
+			wantStatus: http.StatusCreated,
+			// Returned in Alice's units: kilograms and centimetres.
+			check: checkEnvelope("measurement", func(t *testing.T, m data.Measurement) {
+				if !roughlyEqual(m.Bodyweight, 79.83) ||
+					!roughlyEqual(m.Circumferences.Waist, 83.8) ||
+					m.WeightUnit != data.UnitKilograms || m.LengthUnit != "cm" {
+					t.Errorf("measurement = %+v; want 79.83 kg and an 83.8 cm waist", m)
+				}
+			}),
+		},
+		{
+			name:       "create without any values",
+			method:     http.MethodPost,
+			path:       "/v1/measurements",
+			token:      aliceToken,
+			body:       map[string]any{"notes": "Forgot the scales"},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "create in the future",
+			method: http.MethodPost,
+			path:   "/v1/measurements",
+			token:  aliceToken,
+			body: map[string]any{
+				"measured_at": time.Now().Add(time.Hour),
+				"bodyweight":  80,
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "create with an impossible body fat percentage",
+			method:     http.MethodPost,
+			path:       "/v1/measurements",
+			token:      aliceToken,
+			body:       map[string]any{"body_fat_percentage": 120},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "create with an unknown unit",
+			method:     http.MethodPost,
+			path:       "/v1/measurements",
+			token:      aliceToken,
+			body:       map[string]any{"bodyweight": 12, "weight_unit": "stone"},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "list",
+			method:     http.MethodGet,
+			path:       "/v1/measurements",
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("measurements", func(t *testing.T, got []data.Measurement) {
+				if len(got) != 2 {
+					t.Errorf("got %d measurements; want Alice's 2", len(got))
+				}
+			}),
+		},
+		{
+			name:       "list a date range",
+			method:     http.MethodGet,
+			path:       "/v1/measurements?from=2024-01-01&to=2024-12-31",
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("measurements", func(t *testing.T, got []data.Measurement) {
+				if len(got) != 0 {
+					t.Errorf("got %d measurements; want none from 2024", len(got))
+				}
+			}),
+		},
+		{
+			name:       "list with an invalid date",
+			method:     http.MethodGet,
+			path:       "/v1/measurements?from=yesterday",
+			token:      aliceToken,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "trend",
+			method:     http.MethodGet,
+			path:       "/v1/trends/bodyweight?window=14",
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("series", func(t *testing.T, series []data.TrendPoint) {
+				if len(series) != 2 {
+					t.Errorf("got %d points; want one for each measurement", len(series))
+				}
+			}),
+		},
+		{
+			name:       "trend with a window over a year",
+			method:     http.MethodGet,
+			path:       "/v1/trends/bodyweight?window=366",
+			token:      aliceToken,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "trend of an unknown metric",
+			method:     http.MethodGet,
+			path:       "/v1/trends/shoe_size",
+			token:      aliceToken,
+			wantStatus: http.StatusNotFound,
+		},
+	})
+
+	var list struct {
+		Measurements []data.Measurement `json:"measurements"`
+	}
+
+	res := app.request(t, http.MethodGet, "/v1/measurements", aliceToken, nil)
+	res.decode(t, &list)
+
+	if len(list.Measurements) != 2 {
+		t.Fatalf("got %d measurements; want 2", len(list.Measurements))
+	}
+
+	path := fmt.Sprintf("/v1/measurements/%d", list.Measurements[0].ID)
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "show",
+			method:     http.MethodGet,
+			path:       path,
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("measurement", func(t *testing.T, m data.Measurement) {
+				if m.ID != list.Measurements[0].ID || m.WeightUnit != data.UnitKilograms {
+					t.Errorf("measurement = %+v; want %+v", m, list.Measurements[0])
+				}
+			}),
+		},
+		{
+			name:       "show another user's measurement",
+			method:     http.MethodGet,
+			path:       path,
+			token:      bobToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "update",
+			method:     http.MethodPatch,
+			path:       path,
+			token:      aliceToken,
+			body:       map[string]any{"body_fat_percentage": 18.5, "notes": "Morning"},
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("measurement", func(t *testing.T, m data.Measurement) {
+				if !roughlyEqual(m.BodyFatPercentage, 18.5) || m.Notes != "Morning" ||
+					!roughlyEqual(m.Bodyweight, *list.Measurements[0].Bodyweight) {
+					t.Errorf("measurement = %+v; want 18.5%% body fat and the bodyweight kept", m)
+				}
+			}),
+		},
+		{
+			name:       "update to an impossible bodyweight",
+			method:     http.MethodPatch,
+			path:       path,
+			token:      aliceToken,
+			body:       map[string]any{"bodyweight": -1},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "update another user's measurement",
+			method:     http.MethodPatch,
+			path:       path,
+			token:      bobToken,
+			body:       map[string]any{"notes": "Mine now"},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "delete another user's measurement",
+			method:     http.MethodDelete,
+			path:       path,
+			token:      bobToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "delete",
+			method:     http.MethodDelete,
+			path:       path,
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check:      checkMessage("measurement successfully deleted"),
+		},
+		{
+			name:       "show after deleting",
+			method:     http.MethodGet,
+			path:       path,
+			token:      aliceToken,
+			wantStatus: http.StatusNotFound,
+		},
+	})
+}
+
+// roughlyEqual reports whether p is set to want, allowing for unit
+// conversions being rounded.
+func roughlyEqual(p *float64, want float64) bool {
+	return p != nil && math.Abs(*p-want) < 0.01
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"database/sql"
+	"expvar"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// publishMetrics registers the application metrics served by GET
+// /debug/vars, next to the memstats that expvar publishes on its own.
+func publishMetrics(db *sql.DB) {
+	expvar.Publish("build", expvar.Func(buildInfo))
+
+	expvar.Publish("goroutines", expvar.Func(func() any {
+		return runtime.NumGoroutine()
+	}))
+
+	expvar.Publish("database", expvar.Func(func() any {
+		return db.Stats()
+	}))
+
+	expvar.Publish("timestamp", expvar.Func(func() any {
+		return time.Now().Unix()
+	}))
+}
+
+// metricsHandler serves the published variables as expvar.Handler does,
+// except for cmdline: the command line can carry secrets such as the
+// database DSN.
+func (app *application) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	fmt.Fprint(w, "{\n")
+
+	first := true
+
+	expvar.Do(func(kv expvar.KeyValue) {
+		if kv.Key == "cmdline" {
+			return
+		}
+
+		if !first {
+			fmt.Fprint(w, ",\n")
+		}
+
+		first = false
+
+		fmt.Fprintf(w, "%q: %s", kv.Key, kv.Value)
+	})
+
+	fmt.Fprint(w, "\n}\n")
+}
+
+// buildInfo reports the version along with the Go version and, for builds
+// from a git checkout, the commit they were built from.
+func buildInfo() any {
+	info := map[string]string{
+		"version":    version,
+		"go_version": runtime.Version(),
+	}
+
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	for _, s := range build.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info["revision"] = s.Value
+		case "vcs.time":
+			info["revision_time"] = s.Value
+		case "vcs.modified":
+			info["modified"] = s.Value
+		}
+	}
+
+	return info
+}
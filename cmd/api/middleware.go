@@ -4,6 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
 	"strings"
 	"sulemankhann/workout-tracker/internal/data"
 	"sulemankhann/workout-tracker/internal/validator"
@@ -42,6 +45,7 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 		}
 
 		user, err := app.models.Users.GetForToken(
+			r.Context(),
 			data.ScopeAuthentication,
 			token,
 		)
@@ -76,6 +80,63 @@ func (app *application) requireAuthenticatedUser(
 	})
 }
 
+// requireCoachAccess lets the authenticated user act on the workouts of the
+// athlete named by the :athlete_id parameter, provided the athlete has
+// accepted them as a coach. Anyone else gets a not found response so that
+// the existence of the athlete isn't revealed.
+func (app *application) requireCoachAccess(
+	next http.HandlerFunc,
+) http.HandlerFunc {
+	return app.requireAuthenticatedUser(func(w http.ResponseWriter, r *http.Request) {
+		athleteID, err := app.readNamedIDParam(r, "athlete_id")
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		coach := app.contextGetUser(r)
+
+		athlete, err := app.models.Coaching.GetAthlete(r.Context(), coach.ID, athleteID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+
+			return
+		}
+
+		r = app.contextSetWorkoutOwner(r, athlete)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireMetricsAccess only lets the clients in the metrics allow-list
+// through. Anyone else gets a not found response, as if the route didn't
+// exist.
+func (app *application) requireMetricsAccess(
+	next http.HandlerFunc,
+) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := app.clientIP(r)
+
+		allowed := slices.ContainsFunc(app.metricsAllowed, func(prefix netip.Prefix) bool {
+			return prefix.Contains(ip)
+		})
+
+		if !allowed {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (app *application) recoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
@@ -89,3 +150,134 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// enableCORS allows cross-origin requests from the trusted origins, and
+// answers their preflight requests.
+func (app *application) enableCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+
+		origin := r.Header.Get("Origin")
+
+		if origin != "" && slices.Contains(app.config.cors.trustedOrigins, origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+
+			if r.Method == http.MethodOptions &&
+				r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set(
+					"Access-Control-Allow-Methods",
+					"OPTIONS, GET, POST, PUT, PATCH, DELETE",
+				)
+				w.Header().Set(
+					"Access-Control-Allow-Headers",
+					"Authorization, Content-Type",
+				)
+
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitIP applies a looser limit to each client IP. It runs before
+// authenticate, so that floods of requests with made-up tokens are turned
+// away before each of them costs a database lookup, while users sharing an
+// address are still left to their own limits.
+func (app *application) rateLimitIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.ipLimiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !app.takeToken(w, r, app.ipLimiter, clientKey(app.clientIP(r))) {
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimit limits each authenticated user, wherever their requests come
+// from, and each anonymous client IP to the configured rate. Its RateLimit
+// headers replace rateLimitIP's. It needs the user set by authenticate.
+func (app *application) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.limiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := clientKey(app.clientIP(r))
+
+		user := app.contextGetUser(r)
+		if !user.IsAnonymous() {
+			key = fmt.Sprintf("user:%d", user.ID)
+		}
+
+		if !app.takeToken(w, r, app.limiter, key) {
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitAuthentication applies the stricter limit on logging in, per
+// client IP, to slow down password guessing. It comes on top of the general
+// limit, and its RateLimit headers replace that limit's.
+func (app *application) rateLimitAuthentication(
+	next http.HandlerFunc,
+) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.authLimiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !app.takeToken(w, r, app.authLimiter, clientKey(app.clientIP(r))) {
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// takeToken counts the request against key's bucket and sets the RateLimit
+// headers. If the bucket is empty it sends a 429 response and returns false.
+func (app *application) takeToken(
+	w http.ResponseWriter,
+	r *http.Request,
+	limiter *rateLimiter,
+	key string,
+) bool {
+	result := limiter.allow(key)
+
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.reset)))
+
+	if !result.allowed {
+		app.rateLimitExceededResponse(w, r, result.retryAfter)
+		return false
+	}
+
+	return true
+}
+
+// clientKey returns the rate limiter key for a client IP. An IPv6 client
+// usually has a whole /64 to pick addresses from, so that counts as one.
+func clientKey(addr netip.Addr) string {
+	if addr.Is6() {
+		prefix, err := addr.Prefix(64)
+		if err == nil {
+			return "ip:" + prefix.String()
+		}
+	}
+
+	return "ip:" + addr.String()
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"sulemankhann/workout-tracker/internal/validator"
+)
+
+func (app *application) showNotificationPreferencesHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	user := app.contextGetUser(r)
+
+	prefs, err := app.models.Notifications.GetPreferences(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"preferences": prefs}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateNotificationPreferencesHandler replaces the user's reminder lead
+// times and channels. Sending an empty list of lead times turns reminders
+// off.
+func (app *application) updateNotificationPreferencesHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	var input struct {
+		LeadMinutes []int64  `json:"lead_minutes"`
+		Channels    []string `json:"channels"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	prefs := &data.NotificationPreferences{
+		LeadMinutes: input.LeadMinutes,
+		Channels:    input.Channels,
+	}
+
+	v := validator.New()
+
+	data.ValidateNotificationPreferences(v, prefs)
+
+	// Refuse channels this server can't send through, such as email without
+	// an SMTP server, rather than accept reminders that never arrive.
+	for _, channel := range prefs.Channels {
+		_, ok := app.reminderChannels[channel]
+		v.Check(ok, "channels", channel+" reminders are not available on this server")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Notifications.SetPreferences(r.Context(), user.ID, prefs)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"preferences": prefs}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listNotificationsHandler shows the user's in-app inbox, newest first. Pass
+// ?unread=true to only list unread notifications.
+func (app *application) listNotificationsHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	user := app.contextGetUser(r)
+
+	qs := r.URL.Query()
+
+	v := validator.New()
+
+	unreadOnly := app.readBool(qs, "unread", false, v)
+
+	filters := data.Filters{
+		Page:     app.readInt(qs, "page", 1, v),
+		PageSize: app.readInt(qs, "page_size", 20, v),
+	}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	notifications, metadata, err := app.models.Notifications.GetAllForUser(
+		r.Context(),
+		user.ID,
+		unreadOnly,
+		filters,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	unread, err := app.models.Notifications.CountUnread(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{
+			"notifications": notifications,
+			"unread_count":  unread,
+			"metadata":      metadata,
+		},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateNotificationHandler marks a notification as read or unread.
+func (app *application) updateNotificationHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Read *bool `json:"read"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(input.Read != nil, "read", "must be provided")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	notification := &data.Notification{ID: id, UserID: user.ID}
+
+	err = app.models.Notifications.SetRead(r.Context(), notification, *input.Read)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{"notification": notification},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// markAllNotificationsReadHandler marks every unread notification as read.
+func (app *application) markAllNotificationsReadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	user := app.contextGetUser(r)
+
+	count, err := app.models.Notifications.MarkAllRead(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"marked_read": count}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sulemankhann/workout-tracker/internal/data"
+	"testing"
+)
+
+func TestNotificationPreferencesHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, token := createTestUser(t, app, "Alice")
+
+	path := "/v1/users/me/notification-preferences"
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "show the defaults",
+			method:     http.MethodGet,
+			path:       path,
+			token:      token,
+			wantStatus: http.StatusOK,
+			check:      checkPreferences([]int64{60}, data.ChannelInApp),
+		},
+		{
+			name:   "update",
+			method: http.MethodPut,
+			path:   path,
+			token:  token,
+			body: map[string]any{
+				"lead_minutes": []int{30, 24 * 60},
+				"channels":     []string{data.ChannelInApp, data.ChannelWebhook},
+			},
+			wantStatus: http.StatusOK,
+			check: checkPreferences(
+				[]int64{30, 24 * 60},
+				data.ChannelInApp,
+				data.ChannelWebhook,
+			),
+		},
+		{
+			name:   "update with email without an SMTP server",
+			method: http.MethodPut,
+			path:   path,
+			token:  token,
+			body: map[string]any{
+				"lead_minutes": []int{30},
+				"channels":     []string{data.ChannelInApp, data.ChannelEmail},
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "turn reminders off",
+			method: http.MethodPut,
+			path:   path,
+			token:  token,
+			body: map[string]any{
+				"lead_minutes": []int{},
+				"channels":     []string{data.ChannelInApp},
+			},
+			wantStatus: http.StatusOK,
+			check:      checkPreferences(nil, data.ChannelInApp),
+		},
+		{
+			name:       "update without lead times",
+			method:     http.MethodPut,
+			path:       path,
+			token:      token,
+			body:       map[string]any{"channels": []string{data.ChannelInApp}},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "update with duplicate lead times",
+			method: http.MethodPut,
+			path:   path,
+			token:  token,
+			body: map[string]any{
+				"lead_minutes": []int{30, 30},
+				"channels":     []string{data.ChannelInApp},
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "update with a lead time over a week",
+			method: http.MethodPut,
+			path:   path,
+			token:  token,
+			body: map[string]any{
+				"lead_minutes": []int{8 * 24 * 60},
+				"channels":     []string{data.ChannelInApp},
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "update with an unknown channel",
+			method: http.MethodPut,
+			path:   path,
+			token:  token,
+			body: map[string]any{
+				"lead_minutes": []int{30},
+				"channels":     []string{"carrier_pigeon"},
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "show anonymously",
+			method:     http.MethodGet,
+			path:       path,
+			wantStatus: http.StatusUnauthorized,
+		},
+	})
+}
+
+func TestNotificationHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, aliceToken := createTestUser(t, app, "Alice")
+	bob, bobToken := createTestUser(t, app, "Bob")
+
+	var ids []int64
+
+	for _, title := range []string{"Leg day in 1 hour", "Leg day tomorrow"} {
+		notification := &data.Notification{
+			UserID: alice.ID,
+			Kind:   data.NotificationKindWorkoutReminder,
+			Title:  title,
+		}
+
+		err := app.models.Notifications.Insert(context.Background(), notification)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids = append(ids, notification.ID)
+	}
+
+	err := app.models.Notifications.Insert(context.Background(), &data.Notification{
+		UserID: bob.ID,
+		Kind:   data.NotificationKindWorkoutReminder,
+		Title:  "Run tomorrow",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := fmt.Sprintf("/v1/notifications/%d", ids[0])
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "list",
+			method:     http.MethodGet,
+			path:       "/v1/notifications",
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var list struct {
+					Notifications []data.Notification `json:"notifications"`
+					UnreadCount   int                 `json:"unread_count"`
+				}
+
+				res.decode(t, &list)
+
+				if len(list.Notifications) != 2 || list.UnreadCount != 2 {
+					t.Fatalf(
+						"got %d notifications, %d unread; want Alice's 2",
+						len(list.Notifications),
+						list.UnreadCount,
+					)
+				}
+
+				for _, notification := range list.Notifications {
+					if !strings.HasPrefix(notification.Title, "Leg day") {
+						t.Errorf("notification = %+v; want one of Alice's", notification)
+					}
+				}
+			},
+		},
+		{
+			name:       "list unread",
+			method:     http.MethodGet,
+			path:       "/v1/notifications?unread=true",
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "list with an invalid flag",
+			method:     http.MethodGet,
+			path:       "/v1/notifications?unread=maybe",
+			token:      aliceToken,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "mark read",
+			method:     http.MethodPatch,
+			path:       path,
+			token:      aliceToken,
+			body:       map[string]bool{"read": true},
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("notification", func(t *testing.T, got data.Notification) {
+				if got.ID != ids[0] || got.ReadAt == nil {
+					t.Errorf("notification = %+v; want %d read", got, ids[0])
+				}
+			}),
+		},
+		{
+			name:       "mark without saying how",
+			method:     http.MethodPatch,
+			path:       path,
+			token:      aliceToken,
+			body:       map[string]any{},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "mark another user's notification",
+			method:     http.MethodPatch,
+			path:       path,
+			token:      bobToken,
+			body:       map[string]bool{"read": true},
+			wantStatus: http.StatusNotFound,
+		},
+	})
+
+	var list struct {
+		Notifications []data.Notification `json:"notifications"`
+		UnreadCount   int                 `json:"unread_count"`
+	}
+
+	res := app.request(t, http.MethodGet, "/v1/notifications?unread=true", aliceToken, nil)
+	res.decode(t, &list)
+
+	if len(list.Notifications) != 1 || list.UnreadCount != 1 {
+		t.Errorf("got %d unread notifications, count %d; want 1", len(list.Notifications), list.UnreadCount)
+	}
+
+	var marked struct {
+		MarkedRead int `json:"marked_read"`
+	}
+
+	res = app.request(t, http.MethodPatch, "/v1/notifications", aliceToken, nil)
+	res.decode(t, &marked)
+
+	if res.status != http.StatusOK || marked.MarkedRead != 1 {
+		t.Errorf("mark all read: status %d, marked %d; want 200 and 1", res.status, marked.MarkedRead)
+	}
+}
+
+// checkPreferences returns a routeTest check for notification preferences.
+func checkPreferences(leadMinutes []int64, channels ...string) func(*testing.T, testResponse) {
+	return checkEnvelope("preferences", func(t *testing.T, prefs data.NotificationPreferences) {
+		if !slices.Equal(prefs.LeadMinutes, leadMinutes) ||
+			!slices.Equal(prefs.Channels, channels) {
+			t.Errorf(
+				"preferences = %+v; want lead times %v on %v",
+				prefs,
+				leadMinutes,
+				channels,
+			)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"sulemankhann/workout-tracker/internal/validator"
+	"time"
+)
+
+func (app *application) showProgressHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	v := validator.New()
+
+	exerciseID := app.readInt(r.URL.Query(), "exercise_id", 0, v)
+
+	v.Check(exerciseID >= 0, "exercise_id", "must be a positive integer")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	history, err := app.models.Workouts.GetExerciseHistoryForUser(
+		r.Context(),
+		user.ID,
+		int64(exerciseID),
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	measurements, err := app.models.Measurements.GetAllForUser(
+		r.Context(),
+		user.ID,
+		time.Time{},
+		time.Time{},
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	report := data.BuildProgressReport(history, measurements)
+	data.ConvertProgressReport(report, user.Units)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"progress": report}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
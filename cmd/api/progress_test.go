@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"testing"
+	"time"
+)
+
+func TestShowProgressHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, token := createTestUser(t, app, "Alice")
+	bob, _ := createTestUser(t, app, "Bob")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+	bench := createTestExercise(t, app, "Bench Press", data.MeasurementRepsWeight)
+
+	for i := range 3 {
+		completedAt := time.Now().AddDate(0, 0, -7*(i+1))
+		createTestWorkout(t, app, alice, squat, &completedAt)
+	}
+
+	completedAt := time.Now().AddDate(0, 0, -1)
+	createTestWorkout(t, app, alice, bench, &completedAt)
+
+	// Bob's workouts and unfinished ones are left out of Alice's progress.
+	createTestWorkout(t, app, bob, squat, &completedAt)
+	createTestWorkout(t, app, alice, squat, nil)
+
+	app.request(t, http.MethodPost, "/v1/measurements", token, map[string]any{
+		"bodyweight": 80,
+	})
+
+	// checkProgress expects a report on the given exercises, in order, each
+	// performed at 100 kg for a bodyweight of 80 kg, shown in unit.
+	checkProgress := func(
+		weight, bodyweight float64,
+		unit string,
+		exercises ...*data.Exercise,
+	) func(*testing.T, testResponse) {
+		return func(t *testing.T, res testResponse) {
+			var body struct {
+				Progress []data.ExerciseProgress `json:"progress"`
+			}
+
+			res.decode(t, &body)
+
+			if len(body.Progress) != len(exercises) {
+				t.Fatalf("got %d exercises; want %d", len(body.Progress), len(exercises))
+			}
+
+			for i, progress := range body.Progress {
+				exercise := exercises[i]
+
+				if progress.ExerciseID != exercise.ID || progress.ExerciseName != exercise.Name {
+					t.Errorf(
+						"exercise %d = %d %q; want %d %q",
+						i,
+						progress.ExerciseID,
+						progress.ExerciseName,
+						exercise.ID,
+						exercise.Name,
+					)
+				}
+
+				if progress.Best == nil || progress.Latest == nil || len(progress.History) == 0 {
+					t.Fatalf("%s progress = %+v; want a full history", exercise.Name, progress)
+				}
+
+				for _, entry := range progress.History {
+					if entry.Weight != weight || entry.WeightUnit != unit ||
+						entry.Bodyweight == nil || *entry.Bodyweight != bodyweight ||
+						entry.RelativeStrength == nil || *entry.RelativeStrength != 1.25 {
+						t.Errorf(
+							"%s entry = %+v; want %v %s at bodyweight %v, relative strength 1.25",
+							exercise.Name,
+							entry,
+							weight,
+							unit,
+							bodyweight,
+						)
+					}
+				}
+			}
+
+			if exercises[0] == squat && len(body.Progress[0].History) != 3 {
+				t.Errorf("squat history has %d entries; want 3", len(body.Progress[0].History))
+			}
+		}
+	}
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "all exercises",
+			method:     http.MethodGet,
+			path:       "/v1/progress",
+			token:      token,
+			wantStatus: http.StatusOK,
+			check:      checkProgress(100, 80, data.UnitKilograms, squat, bench),
+		},
+		{
+			name:       "one exercise",
+			method:     http.MethodGet,
+			path:       fmt.Sprintf("/v1/progress?exercise_id=%d", squat.ID),
+			token:      token,
+			wantStatus: http.StatusOK,
+			check:      checkProgress(100, 80, data.UnitKilograms, squat),
+		},
+		{
+			name:       "negative exercise",
+			method:     http.MethodGet,
+			path:       "/v1/progress?exercise_id=-1",
+			token:      token,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "non-numeric exercise",
+			method:     http.MethodGet,
+			path:       "/v1/progress?exercise_id=squat",
+			token:      token,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "anonymous",
+			method:     http.MethodGet,
+			path:       "/v1/progress",
+			wantStatus: http.StatusUnauthorized,
+		},
+	})
+
+	alice.Units = data.Units{Weight: data.UnitPounds, Distance: data.UnitMiles}
+
+	err := app.models.Users.Update(context.Background(), alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "in pounds",
+			method:     http.MethodGet,
+			path:       fmt.Sprintf("/v1/progress?exercise_id=%d", squat.ID),
+			token:      token,
+			wantStatus: http.StatusOK,
+			check:      checkProgress(220.46, 176.37, data.UnitPounds, squat),
+		},
+	})
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sulemankhann/workout-tracker/internal/data"
+	"sulemankhann/workout-tracker/internal/mailer"
+	"time"
+)
+
+const (
+	reminderPollInterval = 30 * time.Second
+	reminderBatchSize    = 100
+	// A reminder that keeps failing is given up after reminderMaxAttempts
+	// sends, waiting reminderBackoff longer after each failure.
+	reminderMaxAttempts = 5
+	reminderBackoff     = time.Minute
+)
+
+// reminderChannel sends a workout reminder through one delivery channel.
+type reminderChannel interface {
+	Send(ctx context.Context, reminder *data.Reminder) error
+}
+
+// reminderText returns the title and body used by every channel. The time is
+// shown in the user's own time zone.
+func reminderText(reminder *data.Reminder) (string, string) {
+	scheduledAt := reminder.ScheduledAt.In(reminder.User.Location())
+
+	title := "Upcoming workout: " + reminder.WorkoutTitle
+	body := fmt.Sprintf(
+		"Hi %s, your workout %q is scheduled for %s.",
+		reminder.User.Name,
+		reminder.WorkoutTitle,
+		scheduledAt.Format("Mon 2 Jan 15:04 MST"),
+	)
+
+	return title, body
+}
+
+type emailReminderChannel struct {
+	mailer mailer.Mailer
+}
+
+func (c emailReminderChannel) Send(
+	_ context.Context,
+	reminder *data.Reminder,
+) error {
+	title, body := reminderText(reminder)
+	return c.mailer.Send(reminder.User.Email, title, body)
+}
+
+// inAppReminderChannel adds the reminder to the user's notification inbox.
+type inAppReminderChannel struct {
+	models data.Models
+}
+
+func (c inAppReminderChannel) Send(
+	ctx context.Context,
+	reminder *data.Reminder,
+) error {
+	title, body := reminderText(reminder)
+
+	details, err := json.Marshal(map[string]any{
+		"workout_id":   reminder.WorkoutID,
+		"scheduled_at": reminder.ScheduledAt,
+		"lead_minutes": reminder.LeadMinutes,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.models.Notifications.Insert(ctx, &data.Notification{
+		UserID: reminder.User.ID,
+		Kind:   data.NotificationKindWorkoutReminder,
+		Title:  title,
+		Body:   body,
+		Data:   details,
+	})
+}
+
+// webhookReminderChannel queues a workout.reminder event for the user's
+// webhooks subscribed to it.
+type webhookReminderChannel struct {
+	models data.Models
+}
+
+func (c webhookReminderChannel) Send(
+	ctx context.Context,
+	reminder *data.Reminder,
+) error {
+	key := fmt.Sprintf(
+		"reminder-%d-%d-%d",
+		reminder.WorkoutID,
+		reminder.ScheduledAt.Unix(),
+		reminder.LeadMinutes,
+	)
+
+	payload, err := json.Marshal(map[string]any{
+		"id":         key,
+		"event":      data.EventWorkoutReminder,
+		"created_at": time.Now().UTC(),
+		"data": map[string]any{
+			"workout": map[string]any{
+				"id":           reminder.WorkoutID,
+				"title":        reminder.WorkoutTitle,
+				"scheduled_at": reminder.ScheduledAt,
+			},
+			"lead_minutes": reminder.LeadMinutes,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.models.Webhooks.Enqueue(
+		ctx,
+		reminder.User.ID,
+		data.EventWorkoutReminder,
+		key,
+		payload,
+	)
+}
+
+// runReminderScheduler sends due workout reminders until ctx is cancelled.
+func (app *application) runReminderScheduler(
+	ctx context.Context,
+	channels map[string]reminderChannel,
+) {
+	ticker := time.NewTicker(reminderPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reminders, err := app.models.Reminders.GetDue(ctx, reminderBatchSize)
+		if err != nil {
+			app.logger.Error(err.Error())
+			continue
+		}
+
+		for _, reminder := range reminders {
+			app.sendReminder(ctx, channels, reminder)
+		}
+	}
+}
+
+// sendReminder claims the reminder and sends it. A reminder that fails to
+// send is retried with exponential backoff until reminderMaxAttempts sends
+// have failed, when it is given up.
+func (app *application) sendReminder(
+	ctx context.Context,
+	channels map[string]reminderChannel,
+	reminder *data.Reminder,
+) {
+	claimed, err := app.models.Reminders.Claim(ctx, reminder)
+	if err != nil {
+		app.logger.Error(err.Error(), "workout_id", reminder.WorkoutID)
+		return
+	}
+
+	if !claimed {
+		return
+	}
+
+	// The preferences handler only accepts configured channels, but a
+	// channel can stop being configured after it was chosen, such as email
+	// once the SMTP server is removed. Retrying wouldn't help then.
+	channel, ok := channels[reminder.Channel]
+	if !ok {
+		reminder.Attempts = reminderMaxAttempts
+		err = errors.New("reminder channel not configured")
+	} else {
+		err = channel.Send(ctx, reminder)
+	}
+
+	if err == nil {
+		return
+	}
+
+	app.logger.Error(
+		err.Error(),
+		"channel", reminder.Channel,
+		"workout_id", reminder.WorkoutID,
+		"attempts", reminder.Attempts,
+	)
+
+	reminder.LastError = err.Error()
+	reminder.RetryAt = nil
+
+	if reminder.Attempts < reminderMaxAttempts {
+		retryAt := time.Now().Add(reminderBackoff << (reminder.Attempts - 1))
+		reminder.RetryAt = &retryAt
+	}
+
+	// Record the failure even if the send failed because ctx was cancelled,
+	// so the reminder is retried after a restart.
+	err = app.models.Reminders.RecordFailure(context.WithoutCancel(ctx), reminder)
+	if err != nil {
+		app.logger.Error(err.Error(), "workout_id", reminder.WorkoutID)
+	}
+}
+
+// newReminderChannels returns the channels reminders can be sent through.
+// Email is only available if an SMTP server is configured.
+func (app *application) newReminderChannels() map[string]reminderChannel {
+	channels := map[string]reminderChannel{
+		data.ChannelInApp:   inAppReminderChannel{models: app.models},
+		data.ChannelWebhook: webhookReminderChannel{models: app.models},
+	}
+
+	if app.config.smtp.host != "" {
+		channels[data.ChannelEmail] = emailReminderChannel{
+			mailer: mailer.New(
+				app.config.smtp.host,
+				app.config.smtp.port,
+				app.config.smtp.username,
+				app.config.smtp.password,
+				app.config.smtp.sender,
+			),
+		}
+	}
+
+	return channels
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sulemankhann/workout-tracker/internal/data"
+	"testing"
+	"time"
+)
+
+// failingReminderChannel is a channel whose sends always fail.
+type failingReminderChannel struct{}
+
+func (failingReminderChannel) Send(context.Context, *data.Reminder) error {
+	return errors.New("channel unavailable")
+}
+
+func TestSendReminderRetries(t *testing.T) {
+	app := newTestApplication(t)
+	ctx := context.Background()
+
+	alice, _ := createTestUser(t, app, "Alice")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+
+	// Remind two days ahead so the test workout, a day away, is due.
+	err := app.models.Notifications.SetPreferences(ctx, alice.ID, &data.NotificationPreferences{
+		LeadMinutes: []int64{2 * 24 * 60},
+		Channels:    []string{data.ChannelInApp},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createTestWorkout(t, app, alice, squat, nil)
+
+	channels := map[string]reminderChannel{data.ChannelInApp: failingReminderChannel{}}
+
+	getDue := func() []*data.Reminder {
+		t.Helper()
+
+		reminders, err := app.models.Reminders.GetDue(ctx, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return reminders
+	}
+
+	for attempt := 1; attempt <= reminderMaxAttempts; attempt++ {
+		reminders := getDue()
+		if len(reminders) != 1 {
+			t.Fatalf("attempt %d: got %d due reminders; want 1", attempt, len(reminders))
+		}
+
+		reminder := reminders[0]
+
+		app.sendReminder(ctx, channels, reminder)
+
+		if reminder.Attempts != attempt || reminder.LastError != "channel unavailable" {
+			t.Errorf(
+				"attempt %d: reminder = %+v; want the attempt and its error recorded",
+				attempt,
+				reminder,
+			)
+		}
+
+		if attempt == reminderMaxAttempts {
+			if reminder.RetryAt != nil {
+				t.Errorf("the last attempt was retried at %v; want it given up", reminder.RetryAt)
+			}
+
+			break
+		}
+
+		wantDelay := reminderBackoff << (attempt - 1)
+		if reminder.RetryAt == nil || time.Until(*reminder.RetryAt) > wantDelay ||
+			time.Until(*reminder.RetryAt) < wantDelay-time.Minute/2 {
+			t.Fatalf("attempt %d: retry at %v; want in %v", attempt, reminder.RetryAt, wantDelay)
+		}
+
+		if n := len(getDue()); n != 0 {
+			t.Fatalf("attempt %d: got %d due reminders while backing off; want 0", attempt, n)
+		}
+
+		// Skip the wait.
+		past := time.Now().Add(-time.Second)
+		reminder.RetryAt = &past
+
+		err = app.models.Reminders.RecordFailure(ctx, reminder)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := len(getDue()); n != 0 {
+		t.Errorf("got %d due reminders after giving up; want 0", n)
+	}
+}
+
+func TestSendReminderUnconfiguredChannel(t *testing.T) {
+	app := newTestApplication(t)
+	ctx := context.Background()
+
+	alice, _ := createTestUser(t, app, "Alice")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+
+	err := app.models.Notifications.SetPreferences(ctx, alice.ID, &data.NotificationPreferences{
+		LeadMinutes: []int64{2 * 24 * 60},
+		Channels:    []string{data.ChannelEmail},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createTestWorkout(t, app, alice, squat, nil)
+
+	reminders, err := app.models.Reminders.GetDue(ctx, 10)
+	if err != nil || len(reminders) != 1 {
+		t.Fatalf("GetDue = %d reminders, %v; want 1", len(reminders), err)
+	}
+
+	app.sendReminder(ctx, app.reminderChannels, reminders[0])
+
+	if r := reminders[0]; r.RetryAt != nil || r.LastError == "" {
+		t.Errorf("reminder = %+v; want it given up with the reason recorded", r)
+	}
+
+	reminders, err = app.models.Reminders.GetDue(ctx, 10)
+	if err != nil || len(reminders) != 0 {
+		t.Errorf("GetDue = %d reminders, %v; want 0", len(reminders), err)
+	}
+}
@@ -15,16 +15,33 @@ func (app *application) routes() http.Handler {
 		app.healthcheckHandler,
 	)
 
+	router.HandlerFunc(
+		http.MethodGet,
+		"/debug/vars",
+		app.requireMetricsAccess(app.metricsHandler),
+	)
+
 	router.HandlerFunc(
 		http.MethodPost,
 		"/v1/users",
 		app.registerUserHandler,
 	)
 
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/users/me",
+		app.requireAuthenticatedUser(app.showCurrentUserHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPatch,
+		"/v1/users/me",
+		app.requireAuthenticatedUser(app.updateCurrentUserHandler),
+	)
+
 	router.HandlerFunc(
 		http.MethodPost,
 		"/v1/tokens/authentication",
-		app.createAuthenticationTokenHandler,
+		app.rateLimitAuthentication(app.createAuthenticationTokenHandler),
 	)
 
 	router.HandlerFunc(http.MethodGet,
@@ -60,6 +77,345 @@ func (app *application) routes() http.Handler {
 		"/v1/workouts/:id/schedule",
 		app.requireAuthenticatedUser(app.scheduleWorkoutHandler),
 	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/workouts/:id/complete",
+		app.requireAuthenticatedUser(app.completeWorkoutHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/workouts/:id/tracks",
+		app.requireAuthenticatedUser(app.createWorkoutTrackHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/workouts/:id/tracks",
+		app.requireAuthenticatedUser(app.listWorkoutTracksHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/workouts/:id/sessions",
+		app.requireAuthenticatedUser(app.createSessionHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/workouts/:id/shares",
+		app.requireAuthenticatedUser(app.createWorkoutShareHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/workouts/:id/shares",
+		app.requireAuthenticatedUser(app.listWorkoutSharesHandler),
+	)
+	router.HandlerFunc(
+		http.MethodDelete,
+		"/v1/workouts/:id/shares/:share_id",
+		app.requireAuthenticatedUser(app.revokeWorkoutShareHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/shared/:token",
+		app.showSharedWorkoutHandler,
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/shared/:token/copy",
+		app.requireAuthenticatedUser(app.copySharedWorkoutHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/shared/:token/comments",
+		app.requireAuthenticatedUser(app.listSharedWorkoutCommentsHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/shared/:token/comments",
+		app.requireAuthenticatedUser(app.createSharedWorkoutCommentHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodPatch,
+		"/v1/comments/:id",
+		app.requireAuthenticatedUser(app.updateCommentHandler),
+	)
+	router.HandlerFunc(
+		http.MethodDelete,
+		"/v1/comments/:id",
+		app.requireAuthenticatedUser(app.deleteCommentHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/sessions/:id",
+		app.requireAuthenticatedUser(app.showSessionHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/sessions/:id/sets/complete",
+		app.requireAuthenticatedUser(app.completeSessionSetHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/sessions/:id/rest/start",
+		app.requireAuthenticatedUser(app.startSessionRestHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/sessions/:id/rest/stop",
+		app.requireAuthenticatedUser(app.stopSessionRestHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/sessions/:id/finish",
+		app.requireAuthenticatedUser(app.finishSessionHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/sessions/:id/events",
+		app.requireAuthenticatedUser(app.sessionEventsHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/workouts/:id/audit",
+		app.requireAuthenticatedUser(app.listWorkoutAuditHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/workouts/:id/comments",
+		app.requireAuthenticatedUser(app.listWorkoutCommentsHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/workouts/:id/comments",
+		app.requireAuthenticatedUser(app.createWorkoutCommentHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/athletes",
+		app.requireAuthenticatedUser(app.inviteAthleteHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/athletes",
+		app.requireAuthenticatedUser(app.listAthletesHandler),
+	)
+	router.HandlerFunc(
+		http.MethodDelete,
+		"/v1/athletes/:athlete_id",
+		app.requireAuthenticatedUser(app.removeAthleteHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/athletes/:athlete_id/workouts",
+		app.requireCoachAccess(app.listWorkoutsHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/athletes/:athlete_id/workouts",
+		app.requireCoachAccess(app.createWorkoutHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/athletes/:athlete_id/workouts/:id",
+		app.requireCoachAccess(app.showWorkoutHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPut,
+		"/v1/athletes/:athlete_id/workouts/:id",
+		app.requireCoachAccess(app.updateWorkoutHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/athletes/:athlete_id/workouts/:id/schedule",
+		app.requireCoachAccess(app.scheduleWorkoutHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/athletes/:athlete_id/workouts/:id/audit",
+		app.requireCoachAccess(app.listWorkoutAuditHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/athletes/:athlete_id/workouts/:id/comments",
+		app.requireCoachAccess(app.listWorkoutCommentsHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/athletes/:athlete_id/workouts/:id/comments",
+		app.requireCoachAccess(app.createWorkoutCommentHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/coaches",
+		app.requireAuthenticatedUser(app.listCoachesHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/coaches/:id/accept",
+		app.requireAuthenticatedUser(app.acceptCoachHandler),
+	)
+	router.HandlerFunc(
+		http.MethodDelete,
+		"/v1/coaches/:id",
+		app.requireAuthenticatedUser(app.revokeCoachHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/following",
+		app.requireAuthenticatedUser(app.listFollowingHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPut,
+		"/v1/following/:id",
+		app.requireAuthenticatedUser(app.followUserHandler),
+	)
+	router.HandlerFunc(
+		http.MethodDelete,
+		"/v1/following/:id",
+		app.requireAuthenticatedUser(app.unfollowUserHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/followers",
+		app.requireAuthenticatedUser(app.listFollowersHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/followers/:id/approve",
+		app.requireAuthenticatedUser(app.approveFollowerHandler),
+	)
+	router.HandlerFunc(
+		http.MethodDelete,
+		"/v1/followers/:id",
+		app.requireAuthenticatedUser(app.removeFollowerHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/feed",
+		app.requireAuthenticatedUser(app.showFeedHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/profiles/:id/workouts",
+		app.requireAuthenticatedUser(app.listProfileWorkoutsHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/users/me/notification-preferences",
+		app.requireAuthenticatedUser(app.showNotificationPreferencesHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPut,
+		"/v1/users/me/notification-preferences",
+		app.requireAuthenticatedUser(app.updateNotificationPreferencesHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/notifications",
+		app.requireAuthenticatedUser(app.listNotificationsHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPatch,
+		"/v1/notifications",
+		app.requireAuthenticatedUser(app.markAllNotificationsReadHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPatch,
+		"/v1/notifications/:id",
+		app.requireAuthenticatedUser(app.updateNotificationHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/webhooks",
+		app.requireAuthenticatedUser(app.createWebhookHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/webhooks",
+		app.requireAuthenticatedUser(app.listWebhooksHandler),
+	)
+	router.HandlerFunc(
+		http.MethodDelete,
+		"/v1/webhooks/:id",
+		app.requireAuthenticatedUser(app.deleteWebhookHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/webhooks/:id/deliveries",
+		app.requireAuthenticatedUser(app.listWebhookDeliveriesHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/measurements",
+		app.requireAuthenticatedUser(app.createMeasurementHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/measurements",
+		app.requireAuthenticatedUser(app.listMeasurementsHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/measurements/:id",
+		app.requireAuthenticatedUser(app.showMeasurementHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPatch,
+		"/v1/measurements/:id",
+		app.requireAuthenticatedUser(app.updateMeasurementHandler),
+	)
+	router.HandlerFunc(
+		http.MethodDelete,
+		"/v1/measurements/:id",
+		app.requireAuthenticatedUser(app.deleteMeasurementHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/trends/:metric",
+		app.requireAuthenticatedUser(app.showMeasurementTrendHandler),
+	)
 
-	return app.recoverPanic(app.authenticate(router))
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/progress",
+		app.requireAuthenticatedUser(app.showProgressHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/imports",
+		app.requireAuthenticatedUser(app.createImportHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/imports/:id",
+		app.requireAuthenticatedUser(app.showImportHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/export",
+		app.requireAuthenticatedUser(app.exportWorkoutsHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/stats",
+		app.requireAuthenticatedUser(app.showStatsHandler),
+	)
+
+	return app.recoverPanic(
+		app.enableCORS(app.rateLimitIP(app.authenticate(app.rateLimit(router)))),
+	)
 }
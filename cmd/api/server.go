@@ -1,19 +1,218 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 )
 
+// uploadReadTimeout replaces the server's read timeout for handlers that
+// accept file uploads, which can take a while on slow connections.
+const uploadReadTimeout = 2 * time.Minute
+
 func (app *application) serve() error {
 	srv := &http.Server{
-		Addr:     fmt.Sprintf(":%d", app.config.port),
-		Handler:  app.routes(),
-		ErrorLog: slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+		Addr:              fmt.Sprintf(":%d", app.config.port),
+		Handler:           app.routes(),
+		ReadHeaderTimeout: app.config.server.readHeaderTimeout,
+		ReadTimeout:       app.config.server.readTimeout,
+		WriteTimeout:      app.config.server.writeTimeout,
+		IdleTimeout:       app.config.server.idleTimeout,
+		ErrorLog:          slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+	}
+
+	useTLS := app.config.tls.certFile != "" || app.config.tls.keyFile != ""
+
+	if useTLS {
+		certs, err := newCertReloader(
+			app.config.tls.certFile,
+			app.config.tls.keyFile,
+			app.logger,
+		)
+		if err != nil {
+			return err
+		}
+
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+		}
+
+		app.background(func() {
+			certs.watch(app.stopping, app.config.tls.reloadInterval)
+		})
+	}
+
+	// HTTP/2 is negotiated automatically over TLS; a non-nil, empty
+	// TLSNextProto turns it off.
+	if !app.config.server.http2 {
+		srv.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+
+	var redirectSrv *http.Server
+
+	if useTLS && app.config.tls.redirectPort != 0 {
+		redirectSrv = &http.Server{
+			Addr:              fmt.Sprintf(":%d", app.config.tls.redirectPort),
+			Handler:           app.redirectToHTTPS(),
+			ReadHeaderTimeout: app.config.server.readHeaderTimeout,
+			ReadTimeout:       app.config.server.readTimeout,
+			WriteTimeout:      app.config.server.writeTimeout,
+			IdleTimeout:       app.config.server.idleTimeout,
+			ErrorLog:          slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+		}
+	}
+
+	shutdownError := make(chan error)
+
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+		s := <-quit
+
+		app.logger.Info("shutting down server", "signal", s.String())
+
+		// Fail health checks straight away so load balancers stop sending
+		// new requests, and keep serving for the configured delay while
+		// they notice. Otherwise requests still routed here after the
+		// listener closes are refused and show up as 502s.
+		app.shuttingDown.Store(true)
+
+		if app.config.shutdown.delay > 0 {
+			app.logger.Info(
+				"draining traffic before shutdown",
+				"delay", app.config.shutdown.delay.String(),
+			)
+
+			time.Sleep(app.config.shutdown.delay)
+		}
+
+		ctx, cancel := context.WithTimeout(
+			context.Background(),
+			app.config.shutdown.timeout,
+		)
+		defer cancel()
+
+		// Stop background workers from picking up new work and end
+		// streaming responses, which would otherwise hold Shutdown up
+		// until the grace period runs out.
+		app.stop()
+
+		app.logger.Info("waiting for in-flight requests", "addr", srv.Addr)
+
+		if redirectSrv != nil {
+			err := redirectSrv.Shutdown(ctx)
+			if err != nil {
+				shutdownError <- err
+				return
+			}
+		}
+
+		err := srv.Shutdown(ctx)
+		if err != nil {
+			shutdownError <- err
+			return
+		}
+
+		app.logger.Info("completing background tasks", "addr", srv.Addr)
+
+		done := make(chan struct{})
+
+		go func() {
+			app.wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			shutdownError <- nil
+		case <-ctx.Done():
+			shutdownError <- fmt.Errorf(
+				"background tasks did not finish: %w",
+				ctx.Err(),
+			)
+		}
+	}()
+
+	if redirectSrv != nil {
+		go func() {
+			app.logger.Info("starting HTTP redirect server", "addr", redirectSrv.Addr)
+
+			err := redirectSrv.ListenAndServe()
+			if !errors.Is(err, http.ErrServerClosed) {
+				app.logger.Error(err.Error(), "addr", redirectSrv.Addr)
+			}
+		}()
+	}
+
+	app.logger.Info(
+		"starting server",
+		"addr", srv.Addr,
+		"env", app.config.env,
+		"tls", useTLS,
+	)
+
+	var err error
+
+	if useTLS {
+		// The certificate comes from TLSConfig.GetCertificate.
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		err = srv.ListenAndServe()
+	}
+
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
 	}
 
-	app.logger.Info("starting server", "addr", srv.Addr, "env", app.config.env)
+	err = <-shutdownError
+	if err != nil {
+		return err
+	}
+
+	app.logger.Info("stopped server", "addr", srv.Addr)
+
+	return nil
+}
+
+// redirectToHTTPS returns the handler for the plain HTTP listener, which
+// sends every request to the same URL on the HTTPS port. A 308 keeps the
+// method and body, so clients that POST over plain HTTP are not silently
+// turned into GETs.
+func (app *application) redirectToHTTPS() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host == "" {
+			http.Error(w, "missing Host header", http.StatusBadRequest)
+			return
+		}
+
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = strings.TrimSuffix(strings.TrimPrefix(r.Host, "["), "]")
+		}
+
+		switch {
+		case app.config.port != 443:
+			host = net.JoinHostPort(host, strconv.Itoa(app.config.port))
+		case strings.Contains(host, ":"):
+			// An IPv6 address keeps its brackets without a port too.
+			host = "[" + host + "]"
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
 
-	return srv.ListenAndServe()
+		w.Header().Set("Connection", "close")
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
 }
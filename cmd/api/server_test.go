@@ -0,0 +1,225 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestServeWaitsForBackgroundWork(t *testing.T) {
+	tests := []struct {
+		name     string
+		work     time.Duration
+		timeout  time.Duration
+		wantErr  string
+		wantDone bool
+	}{
+		{
+			name:     "work finishes within the timeout",
+			work:     100 * time.Millisecond,
+			timeout:  5 * time.Second,
+			wantDone: true,
+		},
+		{
+			name:    "work outlives the timeout",
+			work:    500 * time.Millisecond,
+			timeout: 100 * time.Millisecond,
+			wantErr: "background tasks did not finish",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.config.port = freePort(t)
+			app.config.shutdown.timeout = tt.timeout
+
+			var done atomic.Bool
+
+			// The work carries on after being told to stop, like an import
+			// part way through writing its workouts.
+			app.background(func() {
+				<-app.stopping.Done()
+				time.Sleep(tt.work)
+				done.Store(true)
+			})
+
+			served := make(chan error, 1)
+
+			go func() {
+				served <- app.serve()
+			}()
+
+			shutDown(t, app)
+
+			select {
+			case err := <-served:
+				if tt.wantErr == "" && err != nil {
+					t.Fatalf("serve = %v; want nil", err)
+				}
+
+				if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+					t.Fatalf("serve = %v; want %q", err, tt.wantErr)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatal("serve didn't return")
+			}
+
+			if done.Load() != tt.wantDone {
+				t.Errorf("work finished = %t; want %t", done.Load(), tt.wantDone)
+			}
+		})
+	}
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		name         string
+		port         int
+		method       string
+		host         string
+		target       string
+		wantStatus   int
+		wantLocation string
+	}{
+		{
+			name:         "default port",
+			port:         443,
+			method:       http.MethodGet,
+			host:         "api.example.com",
+			target:       "/v1/healthcheck",
+			wantStatus:   http.StatusPermanentRedirect,
+			wantLocation: "https://api.example.com/v1/healthcheck",
+		},
+		{
+			name:         "other port",
+			port:         8443,
+			method:       http.MethodGet,
+			host:         "api.example.com",
+			target:       "/v1/healthcheck",
+			wantStatus:   http.StatusPermanentRedirect,
+			wantLocation: "https://api.example.com:8443/v1/healthcheck",
+		},
+		{
+			name:         "host with the HTTP port",
+			port:         8443,
+			method:       http.MethodGet,
+			host:         "api.example.com:8080",
+			target:       "/v1/workouts",
+			wantStatus:   http.StatusPermanentRedirect,
+			wantLocation: "https://api.example.com:8443/v1/workouts",
+		},
+		{
+			name:         "IPv6 host",
+			port:         443,
+			method:       http.MethodGet,
+			host:         "[2001:db8::1]:80",
+			target:       "/v1/workouts",
+			wantStatus:   http.StatusPermanentRedirect,
+			wantLocation: "https://[2001:db8::1]/v1/workouts",
+		},
+		{
+			name:         "IPv6 host on another port",
+			port:         8443,
+			method:       http.MethodGet,
+			host:         "[2001:db8::1]",
+			target:       "/v1/workouts",
+			wantStatus:   http.StatusPermanentRedirect,
+			wantLocation: "https://[2001:db8::1]:8443/v1/workouts",
+		},
+		{
+			name:         "query string",
+			port:         443,
+			method:       http.MethodGet,
+			host:         "api.example.com",
+			target:       "/v1/workouts?page=2&sort=-scheduled_at",
+			wantStatus:   http.StatusPermanentRedirect,
+			wantLocation: "https://api.example.com/v1/workouts?page=2&sort=-scheduled_at",
+		},
+		{
+			name:         "POST keeps its method",
+			port:         443,
+			method:       http.MethodPost,
+			host:         "api.example.com",
+			target:       "/v1/tokens/authentication",
+			wantStatus:   http.StatusPermanentRedirect,
+			wantLocation: "https://api.example.com/v1/tokens/authentication",
+		},
+		{
+			name:       "missing Host",
+			port:       443,
+			method:     http.MethodGet,
+			target:     "/v1/healthcheck",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.config.port = tt.port
+
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			r.Host = tt.host
+
+			rr := httptest.NewRecorder()
+
+			app.redirectToHTTPS().ServeHTTP(rr, r)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d; want %d", rr.Code, tt.wantStatus)
+			}
+
+			if location := rr.Header().Get("Location"); location != tt.wantLocation {
+				t.Errorf("Location = %q; want %q", location, tt.wantLocation)
+			}
+		})
+	}
+}
+
+// freePort returns a TCP port that nothing is listening on.
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// shutDown sends the test process SIGTERM until the server begins shutting
+// down. serve only starts listening for the signal once it is running, so
+// the test listens for it too; otherwise an early signal would end the
+// test binary.
+func shutDown(t *testing.T, app *application) {
+	t.Helper()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for !app.shuttingDown.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("the server didn't start shutting down")
+		}
+
+		err := syscall.Kill(os.Getpid(), syscall.SIGTERM)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,452 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"sulemankhann/workout-tracker/internal/validator"
+	"time"
+)
+
+const sessionKeepAliveInterval = 15 * time.Second
+
+func (app *application) createSessionHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	workout, err := app.models.Workouts.GetByUser(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(
+		len(workout.Exercises) > 0,
+		"workout",
+		"must have at least one exercise",
+	)
+	v.Check(
+		workout.CompletedAt == nil,
+		"workout",
+		"has already been completed",
+	)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	session := &data.WorkoutSession{
+		WorkoutID: workout.ID,
+		UserID:    user.ID,
+		Status:    data.SessionStatusActive,
+		SetNumber: 1,
+	}
+
+	err = app.models.Sessions.Insert(r.Context(), session)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrActiveSession):
+			v.AddError("workout", "already has an active session")
+			app.failedValidationResponse(w, r, v.Errors)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/sessions/%d", session.ID))
+
+	err = app.writeJSON(
+		w,
+		http.StatusCreated,
+		envelope{
+			"session": session.State(workout.InUnits(user.Units), time.Now()),
+		},
+		headers,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showSessionHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	session, workout, ok := app.loadSession(w, r)
+	if !ok {
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err := app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{"session": session.State(workout.InUnits(user.Units), time.Now())},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) completeSessionSetHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	session, workout, ok := app.loadActiveSession(w, r)
+	if !ok {
+		return
+	}
+
+	session.CompleteSet(workout, time.Now())
+
+	app.saveSession(w, r, "set_completed", session, workout)
+}
+
+func (app *application) startSessionRestHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	session, workout, ok := app.loadActiveSession(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Seconds *int `json:"seconds"`
+	}
+
+	// The body is optional; without one the rest interval configured for the
+	// current exercise is used.
+	if r.ContentLength != 0 {
+		err := app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	seconds := 0
+	if state := session.State(workout, time.Now()); state.Exercise != nil {
+		seconds = state.Exercise.RestInterval
+	}
+
+	if input.Seconds != nil {
+		seconds = *input.Seconds
+	}
+
+	v := validator.New()
+
+	v.Check(seconds > 0, "seconds", "must be greater than zero")
+	v.Check(seconds <= 3600, "seconds", "must not be more than one hour")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	session.StartRest(time.Now(), time.Duration(seconds)*time.Second)
+
+	app.saveSession(w, r, "rest_started", session, workout)
+}
+
+func (app *application) stopSessionRestHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	session, workout, ok := app.loadActiveSession(w, r)
+	if !ok {
+		return
+	}
+
+	session.StopRest()
+
+	app.saveSession(w, r, "rest_stopped", session, workout)
+}
+
+// finishSessionHandler ends the session and marks its workout as completed,
+// both in one transaction.
+func (app *application) finishSessionHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	session, workout, ok := app.loadActiveSession(w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+
+	session.Finish(now)
+
+	err := app.models.Sessions.Finish(app.auditContext(r), session, workout)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.sessions.publish(r.Context(), sessionEvent{
+		name:    "finished",
+		session: session,
+		workout: workout,
+	})
+	if err != nil {
+		app.logError(r, err)
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{
+			"session": session.State(workout.InUnits(user.Units), now),
+			"workout": workout.InUnits(user.Units),
+		},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// sessionEventsHandler streams the session to the client as Server-Sent
+// Events. The current state is sent on connect, followed by an event for
+// every change made from any device and a rest_finished event when a rest
+// timer runs out.
+func (app *application) sessionEventsHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	session, workout, ok := app.loadSession(w, r)
+	if !ok {
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	// Subscribe before sending the initial state so that no change made in
+	// between is missed.
+	events, unsubscribe := app.sessions.subscribe(session)
+	defer unsubscribe()
+
+	rc := http.NewResponseController(w)
+
+	// The stream lasts as long as the session, so it must not be cut off by
+	// the server's write timeout.
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(name string, session *data.WorkoutSession, workout *data.Workout) error {
+		js, err := json.Marshal(session.State(workout.InUnits(user.Units), time.Now()))
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", session.Version, name, js)
+		if err != nil {
+			return err
+		}
+
+		return rc.Flush()
+	}
+
+	keepAlive := time.NewTicker(sessionKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	var restTimer *time.Timer
+	var restFinished <-chan time.Time
+
+	defer func() {
+		if restTimer != nil {
+			restTimer.Stop()
+		}
+	}()
+
+	// watchRest arms a timer for the session's rest period, if one is
+	// running, replacing any earlier timer.
+	watchRest := func(session *data.WorkoutSession) {
+		if restTimer != nil {
+			restTimer.Stop()
+			restTimer, restFinished = nil, nil
+		}
+
+		if session.RestEndsAt != nil && session.RestEndsAt.After(time.Now()) {
+			restTimer = time.NewTimer(time.Until(*session.RestEndsAt))
+			restFinished = restTimer.C
+		}
+	}
+
+	err := send("state", session, workout)
+	watchRest(session)
+
+	for err == nil && session.IsActive() {
+		select {
+		case <-r.Context().Done():
+			return
+
+		// End the stream when the server shuts down so it doesn't hold up
+		// the shutdown; EventSource clients reconnect by themselves.
+		case <-app.stopping.Done():
+			return
+
+		case event := <-events:
+			session, workout = event.session, event.workout
+			err = send(event.name, session, workout)
+			watchRest(session)
+
+		case <-restFinished:
+			restTimer, restFinished = nil, nil
+			err = send("rest_finished", session, workout)
+
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+			if err == nil {
+				err = rc.Flush()
+			}
+		}
+	}
+
+	// Write errors here almost always mean the client has gone away, so they
+	// are only worth logging if the request wasn't cancelled.
+	if err != nil && r.Context().Err() == nil {
+		app.logError(r, err)
+	}
+}
+
+// loadSession fetches the session named in the URL along with its workout as
+// stored, writing an error response and returning false if either
+// can't be found.
+func (app *application) loadSession(
+	w http.ResponseWriter,
+	r *http.Request,
+) (*data.WorkoutSession, *data.Workout, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, nil, false
+	}
+
+	user := app.contextGetUser(r)
+
+	session, err := app.models.Sessions.GetByUser(r.Context(), id, user.ID)
+	if err == nil {
+		var workout *data.Workout
+
+		workout, err = app.models.Workouts.GetByUser(r.Context(), session.WorkoutID, user.ID)
+		if err == nil {
+			return session, workout, true
+		}
+	}
+
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		app.notFoundResponse(w, r)
+
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+
+	return nil, nil, false
+}
+
+// loadActiveSession is loadSession for handlers that change the session,
+// which is only allowed until it has been finished.
+func (app *application) loadActiveSession(
+	w http.ResponseWriter,
+	r *http.Request,
+) (*data.WorkoutSession, *data.Workout, bool) {
+	session, workout, ok := app.loadSession(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+
+	if !session.IsActive() {
+		v := validator.New()
+		v.AddError("session", "has already been finished")
+		app.failedValidationResponse(w, r, v.Errors)
+		return nil, nil, false
+	}
+
+	return session, workout, true
+}
+
+// saveSession stores a changed session, notifies every client streaming it
+// and writes the new state as the response.
+func (app *application) saveSession(
+	w http.ResponseWriter,
+	r *http.Request,
+	event string,
+	session *data.WorkoutSession,
+	workout *data.Workout,
+) {
+	err := app.models.Sessions.Update(r.Context(), session)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.sessions.publish(r.Context(), sessionEvent{
+		name:    event,
+		session: session,
+		workout: workout,
+	})
+	if err != nil {
+		app.logError(r, err)
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{"session": session.State(workout.InUnits(user.Units), time.Now())},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
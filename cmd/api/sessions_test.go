@@ -0,0 +1,459 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sulemankhann/workout-tracker/internal/data"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestCreateSessionHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, aliceToken := createTestUser(t, app, "Alice")
+	_, bobToken := createTestUser(t, app, "Bob")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+
+	workout := createTestWorkout(t, app, alice, squat, nil)
+	path := fmt.Sprintf("/v1/workouts/%d/sessions", workout.ID)
+
+	completedAt := time.Now().Add(-time.Hour)
+	completed := createTestWorkout(t, app, alice, squat, &completedAt)
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "start",
+			method:     http.MethodPost,
+			path:       path,
+			token:      aliceToken,
+			wantStatus: http.StatusCreated,
+			check: checkEnvelope("session", func(t *testing.T, state data.SessionState) {
+				if state.WorkoutSession == nil || state.WorkoutID != workout.ID ||
+					state.Status != data.SessionStatusActive || state.SetNumber != 1 ||
+					state.Exercise == nil || state.Exercise.Exercise.ID != squat.ID {
+					t.Errorf("session = %+v; want the first set of workout %d", state, workout.ID)
+				}
+			}),
+		},
+		{
+			name:       "start a second session",
+			method:     http.MethodPost,
+			path:       path,
+			token:      aliceToken,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "start a completed workout",
+			method:     http.MethodPost,
+			path:       fmt.Sprintf("/v1/workouts/%d/sessions", completed.ID),
+			token:      aliceToken,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "start another user's workout",
+			method:     http.MethodPost,
+			path:       path,
+			token:      bobToken,
+			wantStatus: http.StatusNotFound,
+		},
+	})
+}
+
+func TestSessionHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, aliceToken := createTestUser(t, app, "Alice")
+	_, bobToken := createTestUser(t, app, "Bob")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+
+	workout := createTestWorkout(t, app, alice, squat, nil)
+
+	res := app.request(
+		t,
+		http.MethodPost,
+		fmt.Sprintf("/v1/workouts/%d/sessions", workout.ID),
+		aliceToken,
+		nil,
+	)
+
+	path := res.header.Get("Location")
+	if path == "" {
+		t.Fatalf("no Location header in %d response\n%s", res.status, res.body)
+	}
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "show",
+			method:     http.MethodGet,
+			path:       path,
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("session", func(t *testing.T, state data.SessionState) {
+				if state.WorkoutSession == nil || state.WorkoutID != workout.ID ||
+					state.TotalSets != 3 || state.TotalExercises != 1 {
+					t.Errorf("session = %+v; want workout %d with 3 sets", state, workout.ID)
+				}
+			}),
+		},
+		{
+			name:       "show another user's session",
+			method:     http.MethodGet,
+			path:       path,
+			token:      bobToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "show an unknown session",
+			method:     http.MethodGet,
+			path:       "/v1/sessions/999",
+			token:      aliceToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "complete a set",
+			method:     http.MethodPost,
+			path:       path + "/sets/complete",
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("session", func(t *testing.T, state data.SessionState) {
+				if state.WorkoutSession == nil || state.SetNumber != 2 {
+					t.Errorf("session = %+v; want the second set", state)
+				}
+			}),
+		},
+		{
+			name:       "rest without an interval",
+			method:     http.MethodPost,
+			path:       path + "/rest/start",
+			token:      aliceToken,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "rest for too long",
+			method:     http.MethodPost,
+			path:       path + "/rest/start",
+			token:      aliceToken,
+			body:       map[string]int{"seconds": 7200},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "rest",
+			method:     http.MethodPost,
+			path:       path + "/rest/start",
+			token:      aliceToken,
+			body:       map[string]int{"seconds": 90},
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("session", func(t *testing.T, state data.SessionState) {
+				if state.WorkoutSession == nil || state.RestEndsAt == nil ||
+					state.RestRemainingSeconds < 89 || state.RestRemainingSeconds > 90 {
+					t.Errorf("session = %+v; want 90 seconds of rest", state)
+				}
+			}),
+		},
+		{
+			name:       "stop resting",
+			method:     http.MethodPost,
+			path:       path + "/rest/stop",
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("session", func(t *testing.T, state data.SessionState) {
+				if state.WorkoutSession == nil || state.RestEndsAt != nil ||
+					state.RestRemainingSeconds != 0 {
+					t.Errorf("session = %+v; want no rest", state)
+				}
+			}),
+		},
+		{
+			name:       "finish another user's session",
+			method:     http.MethodPost,
+			path:       path + "/finish",
+			token:      bobToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "finish",
+			method:     http.MethodPost,
+			path:       path + "/finish",
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, res testResponse) {
+				var body struct {
+					Session data.SessionState `json:"session"`
+					Workout data.Workout      `json:"workout"`
+				}
+
+				res.decode(t, &body)
+
+				session := body.Session
+				if session.WorkoutSession == nil || session.Status != data.SessionStatusFinished ||
+					session.FinishedAt == nil {
+					t.Errorf("session = %+v; want it finished", body.Session)
+				}
+
+				if body.Workout.ID != workout.ID || body.Workout.CompletedAt == nil {
+					t.Errorf("workout = %+v; want it completed", body.Workout)
+				}
+			},
+		},
+		{
+			name:       "complete a set after finishing",
+			method:     http.MethodPost,
+			path:       path + "/sets/complete",
+			token:      aliceToken,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "finish twice",
+			method:     http.MethodPost,
+			path:       path + "/finish",
+			token:      aliceToken,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	})
+
+	stored, err := app.models.Workouts.GetByUser(
+		context.Background(),
+		workout.ID,
+		alice.ID,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.CompletedAt == nil {
+		t.Error("finishing the session didn't complete the workout")
+	}
+}
+
+func TestFinishSessionConvertsUnitsOnce(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, token := createTestUser(t, app, "Alice")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+
+	alice.Units = data.Units{Weight: data.UnitPounds, Distance: data.UnitMiles}
+
+	err := app.models.Users.Update(context.Background(), alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workout := createTestWorkout(t, app, alice, squat, nil)
+
+	res := app.request(
+		t,
+		http.MethodPost,
+		fmt.Sprintf("/v1/workouts/%d/sessions", workout.ID),
+		token,
+		nil,
+	)
+
+	res = app.request(t, http.MethodPost, res.header.Get("Location")+"/finish", token, nil)
+	if res.status != http.StatusOK {
+		t.Fatalf("status = %d; want 200\n%s", res.status, res.body)
+	}
+
+	want := alice.Units.DisplayWeight(100)
+
+	var body struct {
+		Workout data.Workout `json:"workout"`
+	}
+
+	res.decode(t, &body)
+
+	if len(body.Workout.Exercises) != 1 || body.Workout.Exercises[0].Weight != want {
+		t.Errorf("response workout = %+v; want a weight of %v lb", body.Workout.Exercises, want)
+	}
+
+	events, err := app.models.Outbox.Claim(context.Background(), 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 2 || events[1].Type != data.EventWorkoutCompleted {
+		t.Fatalf("got %d events; want workout.created then workout.completed", len(events))
+	}
+
+	var payload struct {
+		Data struct {
+			Workout data.Workout `json:"workout"`
+		} `json:"data"`
+	}
+
+	err = json.Unmarshal(events[1].Payload, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := payload.Data.Workout.Exercises
+	if len(got) != 1 || got[0].Weight != want || got[0].WeightUnit != data.UnitPounds {
+		t.Errorf("workout.completed exercises = %+v; want a weight of %v lb", got, want)
+	}
+}
+
+func TestSessionEventsHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, token := createTestUser(t, app, "Alice")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+
+	workout := createTestWorkout(t, app, alice, squat, nil)
+
+	res := app.request(
+		t,
+		http.MethodPost,
+		fmt.Sprintf("/v1/workouts/%d/sessions", workout.ID),
+		token,
+		nil,
+	)
+
+	path := res.header.Get("Location")
+
+	// A finished session's stream ends after the initial state, so the
+	// request returns rather than waiting for changes.
+	app.request(t, http.MethodPost, path+"/finish", token, nil)
+
+	res = app.request(t, http.MethodGet, path+"/events", token, nil)
+
+	if res.status != http.StatusOK {
+		t.Fatalf("status = %d; want 200\n%s", res.status, res.body)
+	}
+
+	if contentType := res.header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("Content-Type = %q; want text/event-stream", contentType)
+	}
+
+	if !strings.Contains(string(res.body), "event: state\n") {
+		t.Errorf("body = %q; want a state event", res.body)
+	}
+
+	res = app.request(t, http.MethodGet, "/v1/sessions/999/events", token, nil)
+	if res.status != http.StatusNotFound {
+		t.Errorf("unknown session: status = %d; want 404", res.status)
+	}
+}
+
+// fakeSessionListener stands in for the pq.Listener of one instance.
+type fakeSessionListener struct {
+	notifications chan *pq.Notification
+}
+
+func (l *fakeSessionListener) NotificationChannel() <-chan *pq.Notification {
+	return l.notifications
+}
+
+func (l *fakeSessionListener) Ping() error {
+	return nil
+}
+
+func TestSessionEventsAcrossInstances(t *testing.T) {
+	app := newTestApplication(t)
+
+	// A second instance sharing the same database.
+	other := newTestApplication(t)
+	other.models = app.models
+
+	listeners := []*fakeSessionListener{
+		{notifications: make(chan *pq.Notification, 8)},
+		{notifications: make(chan *pq.Notification, 8)},
+	}
+
+	// Like Postgres, deliver every notice to every listening instance.
+	notify := func(_ context.Context, notice data.SessionNotice) error {
+		payload, err := json.Marshal(notice)
+		if err != nil {
+			return err
+		}
+
+		for _, listener := range listeners {
+			listener.notifications <- &pq.Notification{
+				Channel: data.SessionChannel,
+				Extra:   string(payload),
+			}
+		}
+
+		return nil
+	}
+
+	for i, instance := range []*application{app, other} {
+		instance.sessions = newSessionBroker(notify)
+		instance.background(func() {
+			instance.relaySessionEvents(instance.stopping, listeners[i])
+		})
+	}
+
+	alice, token := createTestUser(t, app, "Alice")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+
+	workout := createTestWorkout(t, app, alice, squat, nil)
+
+	res := app.request(
+		t,
+		http.MethodPost,
+		fmt.Sprintf("/v1/workouts/%d/sessions", workout.ID),
+		token,
+		nil,
+	)
+
+	var body struct {
+		Session data.WorkoutSession `json:"session"`
+	}
+
+	res.decode(t, &body)
+
+	session, err := app.models.Sessions.GetByUser(context.Background(), body.Session.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A client streaming the session from the other instance.
+	events, unsubscribe := other.sessions.subscribe(session)
+	defer unsubscribe()
+
+	receive := func() sessionEvent {
+		t.Helper()
+
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("no event reached the other instance")
+			return sessionEvent{}
+		}
+	}
+
+	path := res.header.Get("Location")
+
+	res = app.request(t, http.MethodPost, path+"/sets/complete", token, nil)
+	if res.status != http.StatusOK {
+		t.Fatalf("complete set: status = %d\n%s", res.status, res.body)
+	}
+
+	event := receive()
+	if event.name != "set_completed" || event.session.Version != session.Version+1 {
+		t.Errorf(
+			"event = %s at version %d; want set_completed at version %d",
+			event.name,
+			event.session.Version,
+			session.Version+1,
+		)
+	}
+
+	// After the listener reconnects, watched sessions are sent again in case
+	// notices were missed in between.
+	listeners[1].notifications <- nil
+
+	if event := receive(); event.name != "state" || event.session.ID != session.ID {
+		t.Errorf(
+			"event after reconnecting = %s for session %d; want state",
+			event.name,
+			event.session.ID,
+		)
+	}
+}
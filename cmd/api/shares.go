@@ -0,0 +1,288 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"sulemankhann/workout-tracker/internal/validator"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func (app *application) createWorkoutShareHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	workout, err := app.models.Workouts.GetByUser(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	var input struct {
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	// The body is optional; without one the link never expires.
+	if r.ContentLength != 0 {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	v := validator.New()
+
+	if data.ValidateShare(v, &data.WorkoutShare{ExpiresAt: input.ExpiresAt}); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	share, err := app.models.Shares.New(r.Context(), workout.ID, user.ID, input.ExpiresAt)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/shared/%s", share.Plaintext))
+
+	err = app.writeJSON(
+		w,
+		http.StatusCreated,
+		envelope{"share": share},
+		headers,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listWorkoutSharesHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	shares, err := app.models.Shares.GetAllForWorkout(r.Context(), id, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"shares": shares}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) revokeWorkoutShareHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	shareID, err := app.readNamedIDParam(r, "share_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Shares.Revoke(r.Context(), shareID, id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{"message": "share link successfully revoked"},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showSharedWorkoutHandler is public. Signed-in viewers see the workout in
+// their own units; anyone else sees it in the owner's.
+func (app *application) showSharedWorkoutHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	share, workout, ok := app.loadSharedWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	units := share.OwnerUnits
+
+	user := app.contextGetUser(r)
+	if !user.IsAnonymous() {
+		units = user.Units
+	}
+
+	// A revoked link must stop working immediately, so don't let browsers or
+	// proxies keep a copy.
+	headers := make(http.Header)
+	headers.Set("Cache-Control", "no-store")
+
+	err := app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{"workout": data.NewSharedWorkout(workout.InUnits(units))},
+		headers,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// copySharedWorkoutHandler adds a copy of a shared workout to the
+// authenticated user's own workouts. The copy is not completed, whatever the
+// state of the original.
+func (app *application) copySharedWorkoutHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	_, shared, ok := app.loadSharedWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Title       *string   `json:"title"`
+		ScheduledAt time.Time `json:"scheduled_at"`
+	}
+
+	// The body is optional; without one the copy keeps the original title
+	// and is left unscheduled.
+	if r.ContentLength != 0 {
+		err := app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	user := app.contextGetUser(r)
+
+	workout := &data.Workout{
+		UserID:      user.ID,
+		Title:       shared.Title,
+		Description: shared.Description,
+		ScheduledAt: input.ScheduledAt,
+		Exercises:   make([]data.WorkoutExercise, len(shared.Exercises)),
+	}
+
+	if input.Title != nil {
+		workout.Title = *input.Title
+	}
+
+	for i, workoutExercise := range shared.Exercises {
+		workoutExercise.ID = 0
+		workoutExercise.WorkoutID = 0
+		workout.Exercises[i] = workoutExercise
+	}
+
+	v := validator.New()
+
+	if data.ValidateWorkout(v, workout); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err := app.models.Workouts.CreateWorkoutWithExercises(app.auditContext(r), workout)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/workouts/%d", workout.ID))
+
+	err = app.writeJSON(
+		w,
+		http.StatusCreated,
+		envelope{"workout": workout.InUnits(user.Units)},
+		headers,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// loadSharedWorkout resolves the share token in the URL to its workout.
+// Unknown, expired and revoked tokens all get the same not found response so
+// that nothing is revealed about links that no longer work.
+func (app *application) loadSharedWorkout(
+	w http.ResponseWriter,
+	r *http.Request,
+) (*data.WorkoutShare, *data.Workout, bool) {
+	token := httprouter.ParamsFromContext(r.Context()).ByName("token")
+
+	v := validator.New()
+
+	if data.ValidateTokenPlaintext(v, token); !v.Valid() {
+		app.notFoundResponse(w, r)
+		return nil, nil, false
+	}
+
+	share, err := app.models.Shares.GetActiveByToken(r.Context(), token)
+	if err == nil {
+		var workout *data.Workout
+
+		workout, err = app.models.Workouts.GetByUser(r.Context(), share.WorkoutID, share.UserID)
+		if err == nil {
+			return share, workout, true
+		}
+	}
+
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		app.notFoundResponse(w, r)
+
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+
+	return nil, nil, false
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"testing"
+	"time"
+)
+
+func TestWorkoutShareHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, aliceToken := createTestUser(t, app, "Alice")
+	bob, bobToken := createTestUser(t, app, "Bob")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+
+	// Signed-in viewers see shared workouts in their own units.
+	bob.Units.Weight = data.UnitPounds
+
+	err := app.models.Users.Update(context.Background(), bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workout := createTestWorkout(t, app, alice, squat, nil)
+	path := fmt.Sprintf("/v1/workouts/%d/shares", workout.ID)
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "share with an expiry",
+			method:     http.MethodPost,
+			path:       path,
+			token:      aliceToken,
+			body:       map[string]any{"expires_at": time.Now().Add(time.Hour)},
+			wantStatus: http.StatusCreated,
+			check: checkEnvelope("share", func(t *testing.T, share data.WorkoutShare) {
+				if share.WorkoutID != workout.ID || share.Plaintext == "" ||
+					share.ExpiresAt == nil {
+					t.Errorf("share = %+v; want an expiring link to workout %d", share, workout.ID)
+				}
+			}),
+		},
+		{
+			name:       "share with an expiry in the past",
+			method:     http.MethodPost,
+			path:       path,
+			token:      aliceToken,
+			body:       map[string]any{"expires_at": time.Now().Add(-time.Hour)},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "share another user's workout",
+			method:     http.MethodPost,
+			path:       path,
+			token:      bobToken,
+			wantStatus: http.StatusNotFound,
+		},
+	})
+
+	res := app.request(t, http.MethodPost, path, aliceToken, nil)
+	if res.status != http.StatusCreated {
+		t.Fatalf("status = %d; want 201\n%s", res.status, res.body)
+	}
+
+	var body struct {
+		Share data.WorkoutShare `json:"share"`
+	}
+
+	res.decode(t, &body)
+
+	sharedPath := res.header.Get("Location")
+	if want := "/v1/shared/" + body.Share.Plaintext; sharedPath != want {
+		t.Fatalf("Location = %q; want %q", sharedPath, want)
+	}
+
+	revokePath := fmt.Sprintf("%s/%d", path, body.Share.ID)
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "list",
+			method:     http.MethodGet,
+			path:       path,
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("shares", func(t *testing.T, shares []data.WorkoutShare) {
+				if len(shares) != 2 {
+					t.Fatalf("got %d shares; want 2", len(shares))
+				}
+
+				// The token is only shown when the link is created.
+				for _, share := range shares {
+					if share.WorkoutID != workout.ID || share.Plaintext != "" {
+						t.Errorf("share = %+v; want one without its token", share)
+					}
+				}
+			}),
+		},
+		{
+			name:       "show anonymously",
+			method:     http.MethodGet,
+			path:       sharedPath,
+			wantStatus: http.StatusOK,
+			check:      checkSharedWorkout(100, data.UnitKilograms),
+		},
+		{
+			name:       "show signed in",
+			method:     http.MethodGet,
+			path:       sharedPath,
+			token:      bobToken,
+			wantStatus: http.StatusOK,
+			check:      checkSharedWorkout(220.46, data.UnitPounds),
+		},
+		{
+			name:       "show a malformed token",
+			method:     http.MethodGet,
+			path:       "/v1/shared/abc",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "show an unknown token",
+			method:     http.MethodGet,
+			path:       "/v1/shared/ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "copy",
+			method:     http.MethodPost,
+			path:       sharedPath + "/copy",
+			token:      bobToken,
+			body:       map[string]string{"title": "Alice's leg day"},
+			wantStatus: http.StatusCreated,
+			check: checkEnvelope("workout", func(t *testing.T, copied data.Workout) {
+				if copied.ID == workout.ID || copied.Title != "Alice's leg day" ||
+					copied.CompletedAt != nil || len(copied.Exercises) != 1 {
+					t.Errorf("workout = %+v; want a new copy titled Alice's leg day", copied)
+				}
+			}),
+		},
+		{
+			name:       "copy with an empty title",
+			method:     http.MethodPost,
+			path:       sharedPath + "/copy",
+			token:      bobToken,
+			body:       map[string]string{"title": ""},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "copy anonymously",
+			method:     http.MethodPost,
+			path:       sharedPath + "/copy",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "revoke another user's share",
+			method:     http.MethodDelete,
+			path:       revokePath,
+			token:      bobToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "revoke",
+			method:     http.MethodDelete,
+			path:       revokePath,
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check:      checkMessage("share link successfully revoked"),
+		},
+		{
+			name:       "revoke twice",
+			method:     http.MethodDelete,
+			path:       revokePath,
+			token:      aliceToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "show after revoking",
+			method:     http.MethodGet,
+			path:       sharedPath,
+			wantStatus: http.StatusNotFound,
+		},
+	})
+
+	res = app.request(t, http.MethodGet, "/v1/workouts", bobToken, nil)
+
+	var workouts struct {
+		Workouts []data.Workout `json:"workouts"`
+	}
+
+	res.decode(t, &workouts)
+
+	if len(workouts.Workouts) != 1 || workouts.Workouts[0].CompletedAt != nil {
+		t.Errorf("Bob's workouts = %+v; want one uncompleted copy", workouts.Workouts)
+	}
+}
+
+// checkSharedWorkout returns a routeTest check for the public view of a
+// workout created by createTestWorkout, which leaves out the owner's IDs.
+func checkSharedWorkout(wantWeight float64, wantUnit string) func(*testing.T, testResponse) {
+	return checkEnvelope("workout", func(t *testing.T, workout map[string]json.RawMessage) {
+		if _, ok := workout["id"]; ok {
+			t.Error("shared workout includes its ID")
+		}
+
+		var exercises []data.WorkoutExercise
+
+		err := json.Unmarshal(workout["exercises"], &exercises)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(exercises) != 1 || exercises[0].Weight != wantWeight ||
+			exercises[0].WeightUnit != wantUnit {
+			t.Errorf("exercises = %+v; want %v %s", exercises, wantWeight, wantUnit)
+		}
+	})
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"sulemankhann/workout-tracker/internal/validator"
+)
+
+// followUserHandler follows the user in the URL. It is idempotent, so
+// following someone twice leaves the original follow in place.
+func (app *application) followUserHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	v := validator.New()
+
+	v.Check(id != user.ID, "id", "must not be your own")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	follow, err := app.models.Follows.Follow(r.Context(), user.ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"following": follow}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) unfollowUserHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Follows.Unfollow(r.Context(), user.ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{"message": "user successfully unfollowed"},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listFollowingHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	user := app.contextGetUser(r)
+
+	following, err := app.models.Follows.GetFollowing(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"following": following}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listFollowersHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	user := app.contextGetUser(r)
+
+	followers, err := app.models.Follows.GetFollowers(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"followers": followers}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) approveFollowerHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Follows.Approve(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{"message": "follower successfully approved"},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// removeFollowerHandler removes a follower or declines a follow request.
+func (app *application) removeFollowerHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Follows.Unfollow(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{"message": "follower successfully removed"},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showFeedHandler lists recently completed workouts by the users the
+// authenticated user follows, newest first. Pass the returned next_cursor as
+// ?cursor= to fetch the following page.
+func (app *application) showFeedHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	user := app.contextGetUser(r)
+
+	after, limit, ok := app.readFeedPage(w, r)
+	if !ok {
+		return
+	}
+
+	entries, next, err := app.models.Workouts.GetFeedForViewer(r.Context(), user.ID, after, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeFeedPage(w, r, "feed", entries, next)
+}
+
+// listProfileWorkoutsHandler lists the completed workouts of the user in the
+// URL that the authenticated user is allowed to see.
+func (app *application) listProfileWorkoutsHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	after, limit, ok := app.readFeedPage(w, r)
+	if !ok {
+		return
+	}
+
+	entries, next, err := app.models.Workouts.GetVisibleForViewer(
+		r.Context(),
+		id,
+		user.ID,
+		after,
+		limit,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeFeedPage(w, r, "workouts", entries, next)
+}
+
+// readFeedPage reads the cursor and limit query string parameters, writing
+// an error response and returning false if either is invalid.
+func (app *application) readFeedPage(
+	w http.ResponseWriter,
+	r *http.Request,
+) (*data.FeedCursor, int, bool) {
+	qs := r.URL.Query()
+
+	v := validator.New()
+
+	limit := app.readInt(qs, "limit", 20, v)
+
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= 100, "limit", "must be a maximum of 100")
+
+	var after *data.FeedCursor
+
+	if s := qs.Get("cursor"); s != "" {
+		var err error
+
+		after, err = data.ParseFeedCursor(s)
+		if err != nil {
+			v.AddError("cursor", "must be a cursor returned by a previous request")
+		}
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return nil, 0, false
+	}
+
+	return after, limit, true
+}
+
+func (app *application) writeFeedPage(
+	w http.ResponseWriter,
+	r *http.Request,
+	key string,
+	entries []*data.FeedEntry,
+	next *data.FeedCursor,
+) {
+	user := app.contextGetUser(r)
+
+	for _, entry := range entries {
+		entry.Workout = entry.Workout.InUnits(user.Units)
+	}
+
+	var nextCursor *string
+
+	if next != nil {
+		s := next.String()
+		nextCursor = &s
+	}
+
+	err := app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{key: entries, "next_cursor": nextCursor},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sulemankhann/workout-tracker/internal/data"
+	"testing"
+	"time"
+)
+
+func TestFollowHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, aliceToken := createTestUser(t, app, "Alice")
+	bob, bobToken := createTestUser(t, app, "Bob")
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "follow",
+			method:     http.MethodPut,
+			path:       fmt.Sprintf("/v1/following/%d", alice.ID),
+			token:      bobToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("following", func(t *testing.T, follow data.Follow) {
+				if follow.UserID != alice.ID || follow.Status != data.FollowStatusPending {
+					t.Errorf("following = %+v; want a pending follow of Alice", follow)
+				}
+			}),
+		},
+		{
+			name:       "follow twice",
+			method:     http.MethodPut,
+			path:       fmt.Sprintf("/v1/following/%d", alice.ID),
+			token:      bobToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("following", func(t *testing.T, follow data.Follow) {
+				if follow.UserID != alice.ID || follow.Status != data.FollowStatusPending {
+					t.Errorf("following = %+v; want a pending follow of Alice", follow)
+				}
+			}),
+		},
+		{
+			name:       "follow yourself",
+			method:     http.MethodPut,
+			path:       fmt.Sprintf("/v1/following/%d", bob.ID),
+			token:      bobToken,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "follow an unknown user",
+			method:     http.MethodPut,
+			path:       "/v1/following/999",
+			token:      bobToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "list following",
+			method:     http.MethodGet,
+			path:       "/v1/following",
+			token:      bobToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("following", func(t *testing.T, following []data.Follow) {
+				if len(following) != 1 || following[0].UserID != alice.ID ||
+					following[0].Name != "Alice" {
+					t.Errorf("following = %+v; want just Alice", following)
+				}
+			}),
+		},
+		{
+			name:       "list followers",
+			method:     http.MethodGet,
+			path:       "/v1/followers",
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("followers", func(t *testing.T, followers []data.Follow) {
+				if len(followers) != 1 || followers[0].UserID != bob.ID ||
+					followers[0].Name != "Bob" {
+					t.Errorf("followers = %+v; want just Bob", followers)
+				}
+			}),
+		},
+		{
+			name:       "approve",
+			method:     http.MethodPost,
+			path:       fmt.Sprintf("/v1/followers/%d/approve", bob.ID),
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check:      checkMessage("follower successfully approved"),
+		},
+		{
+			name:       "approve twice",
+			method:     http.MethodPost,
+			path:       fmt.Sprintf("/v1/followers/%d/approve", bob.ID),
+			token:      aliceToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "remove follower",
+			method:     http.MethodDelete,
+			path:       fmt.Sprintf("/v1/followers/%d", bob.ID),
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check:      checkMessage("follower successfully removed"),
+		},
+		{
+			name:       "unfollow after being removed",
+			method:     http.MethodDelete,
+			path:       fmt.Sprintf("/v1/following/%d", alice.ID),
+			token:      bobToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "follow again",
+			method:     http.MethodPut,
+			path:       fmt.Sprintf("/v1/following/%d", alice.ID),
+			token:      bobToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("following", func(t *testing.T, follow data.Follow) {
+				if follow.UserID != alice.ID || follow.Status != data.FollowStatusPending {
+					t.Errorf("following = %+v; want a pending follow of Alice", follow)
+				}
+			}),
+		},
+		{
+			name:       "unfollow",
+			method:     http.MethodDelete,
+			path:       fmt.Sprintf("/v1/following/%d", alice.ID),
+			token:      bobToken,
+			wantStatus: http.StatusOK,
+			check:      checkMessage("user successfully unfollowed"),
+		},
+	})
+}
+
+func TestFollowHandlerAcceptsPublicProfiles(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, aliceToken := createTestUser(t, app, "Alice")
+	_, bobToken := createTestUser(t, app, "Bob")
+	carol, _ := createTestUser(t, app, "Carol")
+
+	app.request(t, http.MethodPatch, "/v1/users/me", aliceToken, map[string]string{
+		"privacy": data.PrivacyPublic,
+	})
+
+	for _, follow := range []struct {
+		token, path, want string
+	}{
+		{bobToken, fmt.Sprintf("/v1/following/%d", alice.ID), data.FollowStatusAccepted},
+		{aliceToken, fmt.Sprintf("/v1/following/%d", carol.ID), data.FollowStatusPending},
+	} {
+		res := app.request(t, http.MethodPut, follow.path, follow.token, nil)
+
+		var body struct {
+			Following data.Follow `json:"following"`
+		}
+
+		res.decode(t, &body)
+
+		if body.Following.Status != follow.want {
+			t.Errorf("PUT %s: status = %q; want %q", follow.path, body.Following.Status, follow.want)
+		}
+	}
+}
+
+func TestFeedHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, aliceToken := createTestUser(t, app, "Alice")
+	bob, bobToken := createTestUser(t, app, "Bob")
+	_, carolToken := createTestUser(t, app, "Carol")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+
+	for i := range 3 {
+		completedAt := time.Now().Add(-time.Duration(i+1) * time.Hour)
+		createTestWorkout(t, app, alice, squat, &completedAt)
+	}
+
+	createTestWorkout(t, app, alice, squat, nil)
+
+	app.request(t, http.MethodPatch, "/v1/users/me", aliceToken, map[string]string{
+		"privacy": data.PrivacyFollowers,
+	})
+	app.request(t, http.MethodPut, fmt.Sprintf("/v1/following/%d", alice.ID), bobToken, nil)
+	app.request(
+		t,
+		http.MethodPost,
+		fmt.Sprintf("/v1/followers/%d/approve", bob.ID),
+		aliceToken,
+		nil,
+	)
+
+	profilePath := fmt.Sprintf("/v1/profiles/%d/workouts", alice.ID)
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "feed",
+			method:     http.MethodGet,
+			path:       "/v1/feed",
+			token:      bobToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("feed", func(t *testing.T, feed []data.FeedEntry) {
+				// Alice's workout that is still to do stays out of the feed.
+				if len(feed) != 3 {
+					t.Fatalf("got %d entries; want Alice's 3 completed workouts", len(feed))
+				}
+
+				for _, entry := range feed {
+					if entry.Author.ID != alice.ID || entry.Workout == nil ||
+						entry.Workout.CompletedAt == nil {
+						t.Errorf("entry = %+v; want a completed workout by Alice", entry)
+					}
+				}
+			}),
+		},
+		{
+			name:       "feed with a zero limit",
+			method:     http.MethodGet,
+			path:       "/v1/feed?limit=0",
+			token:      bobToken,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "feed with a large limit",
+			method:     http.MethodGet,
+			path:       "/v1/feed?limit=101",
+			token:      bobToken,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "feed with a malformed cursor",
+			method:     http.MethodGet,
+			path:       "/v1/feed?cursor=abc",
+			token:      bobToken,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "profile",
+			method:     http.MethodGet,
+			path:       profilePath,
+			token:      carolToken,
+			wantStatus: http.StatusOK,
+			// Carol doesn't follow Alice, whose workouts are for followers only.
+			check: checkEnvelope("workouts", func(t *testing.T, workouts []data.FeedEntry) {
+				if len(workouts) != 0 {
+					t.Errorf("got %d workouts; want none", len(workouts))
+				}
+			}),
+		},
+		{
+			name:       "profile with an invalid ID",
+			method:     http.MethodGet,
+			path:       "/v1/profiles/abc/workouts",
+			token:      carolToken,
+			wantStatus: http.StatusNotFound,
+		},
+	})
+
+	type page struct {
+		entries int
+		next    *string
+	}
+
+	get := func(path, token string) page {
+		t.Helper()
+
+		res := app.request(t, http.MethodGet, path, token, nil)
+
+		var body struct {
+			Feed       []data.FeedEntry `json:"feed"`
+			Workouts   []data.FeedEntry `json:"workouts"`
+			NextCursor *string          `json:"next_cursor"`
+		}
+
+		res.decode(t, &body)
+
+		return page{len(body.Feed) + len(body.Workouts), body.NextCursor}
+	}
+
+	first := get("/v1/feed?limit=2", bobToken)
+	if first.entries != 2 || first.next == nil {
+		t.Fatalf("first page = %d entries, cursor %v; want 2 and a cursor", first.entries, first.next)
+	}
+
+	second := get("/v1/feed?limit=2&cursor="+url.QueryEscape(*first.next), bobToken)
+	if second.entries != 1 || second.next != nil {
+		t.Errorf("second page = %d entries, cursor %v; want 1 and no cursor", second.entries, second.next)
+	}
+
+	for _, view := range []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"owner", aliceToken, 3},
+		{"follower", bobToken, 3},
+		{"stranger", carolToken, 0},
+	} {
+		if got := get(profilePath, view.token); got.entries != view.want {
+			t.Errorf("%s sees %d workouts; want %d", view.name, got.entries, view.want)
+		}
+	}
+
+	if got := get("/v1/feed", carolToken); got.entries != 0 {
+		t.Errorf("Carol's feed has %d entries; want 0", got.entries)
+	}
+}
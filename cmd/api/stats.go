@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"sulemankhann/workout-tracker/internal/validator"
+	"time"
+)
+
+func (app *application) showStatsHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	v := validator.New()
+
+	windowDays := app.readInt(r.URL.Query(), "window", 30, v)
+
+	v.Check(windowDays > 0, "window", "must be greater than zero")
+	v.Check(windowDays <= 365, "window", "must be a maximum of 365 days")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	activity, err := app.models.Workouts.GetActivityForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	stats := data.CalculateTrainingStats(
+		activity,
+		user.Location(),
+		time.Now(),
+		windowDays,
+	)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"stats": stats}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
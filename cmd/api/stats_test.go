@@ -0,0 +1,113 @@
+package main
+
+import (
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"testing"
+	"time"
+)
+
+func TestShowStatsHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, token := createTestUser(t, app, "Alice")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+
+	bob, _ := createTestUser(t, app, "Bob")
+
+	for i := range 3 {
+		completedAt := time.Now().AddDate(0, 0, -i)
+		createTestWorkout(t, app, alice, squat, &completedAt)
+	}
+
+	// Bob's training doesn't count towards Alice's stats.
+	for i := range 10 {
+		completedAt := time.Now().AddDate(0, 0, -i)
+		createTestWorkout(t, app, bob, squat, &completedAt)
+	}
+
+	// checkStats expects Alice's three sessions on consecutive days up to
+	// today, with nothing scheduled in the window since her workouts were
+	// all scheduled for tomorrow.
+	checkStats := func(windowDays int, average float64) func(*testing.T, testResponse) {
+		return func(t *testing.T, res testResponse) {
+			var body struct {
+				Stats data.TrainingStats `json:"stats"`
+			}
+
+			res.decode(t, &body)
+
+			stats := body.Stats
+
+			if stats.Timezone != "UTC" || stats.CurrentStreak != 3 || stats.LongestStreak != 3 {
+				t.Errorf(
+					"stats = %s, streaks %d and %d; want UTC, 3 and 3",
+					stats.Timezone,
+					stats.CurrentStreak,
+					stats.LongestStreak,
+				)
+			}
+
+			wantAdherence := data.Adherence{WindowDays: windowDays}
+			if stats.Adherence != wantAdherence {
+				t.Errorf("adherence = %+v; want %+v", stats.Adherence, wantAdherence)
+			}
+
+			if stats.AverageSessionsPerWeek != average {
+				t.Errorf("sessions per week = %v; want %v", stats.AverageSessionsPerWeek, average)
+			}
+
+			if len(stats.Heatmap) != 365 {
+				t.Fatalf("heatmap has %d days; want 365", len(stats.Heatmap))
+			}
+
+			total := 0
+			for _, day := range stats.Heatmap {
+				total += day.Count
+			}
+
+			if today := stats.Heatmap[364]; total != 3 || today.Count != 1 {
+				t.Errorf("heatmap has %d sessions, %d today; want 3, 1 today", total, today.Count)
+			}
+		}
+	}
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "default window",
+			method:     http.MethodGet,
+			path:       "/v1/stats",
+			token:      token,
+			wantStatus: http.StatusOK,
+			check:      checkStats(30, 0.7),
+		},
+		{
+			name:       "one week",
+			method:     http.MethodGet,
+			path:       "/v1/stats?window=7",
+			token:      token,
+			wantStatus: http.StatusOK,
+			check:      checkStats(7, 3),
+		},
+		{
+			name:       "zero window",
+			method:     http.MethodGet,
+			path:       "/v1/stats?window=0",
+			token:      token,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "window over a year",
+			method:     http.MethodGet,
+			path:       "/v1/stats?window=366",
+			token:      token,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "anonymous",
+			method:     http.MethodGet,
+			path:       "/v1/stats",
+			wantStatus: http.StatusUnauthorized,
+		},
+	})
+}
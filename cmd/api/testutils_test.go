@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sulemankhann/workout-tracker/internal/data"
+	"sulemankhann/workout-tracker/internal/events"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestApplication returns an application backed by the in-memory models,
+// with logging discarded.
+func newTestApplication(t *testing.T) *application {
+	t.Helper()
+
+	stopping, stop := context.WithCancel(context.Background())
+
+	app := &application{
+		logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		models:      data.NewMockModels(),
+		sessions:    newSessionBroker(nil),
+		subscribers: events.NewSubscribers(),
+		stopping:    stopping,
+		stop:        stop,
+	}
+
+	app.config.env = "development"
+	app.config.tokens.authenticationTTL = time.Hour
+	app.reminderChannels = app.newReminderChannels()
+
+	// Wait for background work such as imports so that it doesn't outlive
+	// the test.
+	t.Cleanup(func() {
+		stop()
+		app.wg.Wait()
+	})
+
+	return app
+}
+
+type testResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// decode unmarshals the response body into dst, failing the test if it isn't
+// valid JSON.
+func (res testResponse) decode(t *testing.T, dst any) {
+	t.Helper()
+
+	err := json.Unmarshal(res.body, dst)
+	if err != nil {
+		t.Fatalf("decoding %q: %v", res.body, err)
+	}
+}
+
+// multipartForm is a request body sent as multipart/form-data. The "file"
+// field is sent as an uploaded file and the others as plain values.
+type multipartForm map[string]string
+
+// request sends a request through the application's routes. A body that
+// isn't a string, []byte or multipartForm is encoded as JSON, and a non-empty
+// token is sent as a bearer token.
+func (app *application) request(
+	t *testing.T,
+	method, path, token string,
+	body any,
+) testResponse {
+	t.Helper()
+
+	var reader io.Reader
+	var contentType string
+
+	switch body := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(body)
+	case []byte:
+		reader = bytes.NewReader(body)
+	case multipartForm:
+		var buf bytes.Buffer
+
+		mw := multipart.NewWriter(&buf)
+
+		for name, value := range body {
+			var err error
+
+			if name == "file" {
+				var part io.Writer
+
+				part, err = mw.CreateFormFile(name, "upload")
+				if err == nil {
+					_, err = io.WriteString(part, value)
+				}
+			} else {
+				err = mw.WriteField(name, value)
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err := mw.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		reader = &buf
+		contentType = mw.FormDataContentType()
+	default:
+		js, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		reader = bytes.NewReader(js)
+	}
+
+	r := httptest.NewRequest(method, path, reader)
+
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, r)
+
+	return testResponse{
+		status: rr.Code,
+		header: rr.Header(),
+		body:   rr.Body.Bytes(),
+	}
+}
+
+const testPassword = "pa55word1234"
+
+// hashedTestPassword holds testPassword hashed once for every test user, as
+// bcrypt is deliberately slow.
+var hashedTestPassword = sync.OnceValue(func() *data.User {
+	var user data.User
+
+	err := user.Password.Set(testPassword)
+	if err != nil {
+		panic(err)
+	}
+
+	return &user
+})
+
+// createTestUser adds a user with the password testPassword and returns them
+// along with an authentication token for them.
+func createTestUser(t *testing.T, app *application, name string) (*data.User, string) {
+	t.Helper()
+
+	user := &data.User{
+		Name:     name,
+		Email:    strings.ToLower(name) + "@example.com",
+		Password: hashedTestPassword().Password,
+	}
+
+	err := app.models.Users.Insert(context.Background(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := app.models.Tokens.New(
+		context.Background(),
+		user.ID,
+		time.Hour,
+		data.ScopeAuthentication,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return user, token.Plaintext
+}
+
+// createTestExercise adds an exercise measured by measurementType.
+func createTestExercise(
+	t *testing.T,
+	app *application,
+	name, measurementType string,
+) *data.Exercise {
+	t.Helper()
+
+	exercise := &data.Exercise{
+		Name:            name,
+		Category:        "strength",
+		MuscleGroup:     "legs",
+		MeasurementType: measurementType,
+	}
+
+	err := app.models.Exercises.Insert(context.Background(), exercise)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return exercise
+}
+
+// createTestWorkout adds a workout for the user with one set of the
+// exercise, completed at completedAt unless it is nil.
+func createTestWorkout(
+	t *testing.T,
+	app *application,
+	user *data.User,
+	exercise *data.Exercise,
+	completedAt *time.Time,
+) *data.Workout {
+	t.Helper()
+
+	workout := &data.Workout{
+		UserID:      user.ID,
+		Title:       "Leg day",
+		ScheduledAt: time.Now().Add(24 * time.Hour).Truncate(time.Second),
+		CompletedAt: completedAt,
+		Exercises: []data.WorkoutExercise{
+			{
+				ExerciseID:  exercise.ID,
+				Sets:        3,
+				Repetitions: 5,
+				Weight:      100,
+			},
+		},
+	}
+
+	err := app.models.Workouts.CreateWorkoutWithExercises(
+		context.Background(),
+		workout,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return workout
+}
+
+// routeTest is one request of a table-driven handler test and the status
+// expected in response.
+type routeTest struct {
+	name       string
+	method     string
+	path       string
+	token      string
+	body       any
+	wantStatus int
+	// check, if set, inspects a response that has the wanted status.
+	check func(t *testing.T, res testResponse)
+}
+
+// runRouteTests sends each request in order, so later ones see the effects
+// of earlier ones, and checks the status of each response, then its body if
+// the test has a check.
+func runRouteTests(t *testing.T, app *application, tests []routeTest) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := app.request(t, tt.method, tt.path, tt.token, tt.body)
+
+			if res.status != tt.wantStatus {
+				t.Fatalf(
+					"%s %s: status = %d; want %d\n%s",
+					tt.method,
+					tt.path,
+					res.status,
+					tt.wantStatus,
+					res.body,
+				)
+			}
+
+			if tt.check != nil {
+				tt.check(t, res)
+			}
+		})
+	}
+}
+
+// checkEnvelope returns a routeTest check that decodes the value under key
+// in the response envelope and hands it to fn.
+func checkEnvelope[T any](
+	key string,
+	fn func(t *testing.T, value T),
+) func(*testing.T, testResponse) {
+	return func(t *testing.T, res testResponse) {
+		t.Helper()
+
+		var env map[string]json.RawMessage
+
+		res.decode(t, &env)
+
+		raw, ok := env[key]
+		if !ok {
+			t.Fatalf("response has no %q\n%s", key, res.body)
+		}
+
+		var value T
+
+		err := json.Unmarshal(raw, &value)
+		if err != nil {
+			t.Fatalf("decoding %q: %v\n%s", key, err, raw)
+		}
+
+		fn(t, value)
+	}
+}
+
+// checkMessage returns a routeTest check for a response with just a
+// message.
+func checkMessage(want string) func(*testing.T, testResponse) {
+	return checkEnvelope("message", func(t *testing.T, message string) {
+		if message != want {
+			t.Errorf("message = %q; want %q", message, want)
+		}
+	})
+}
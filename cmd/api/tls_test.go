@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	oldCert, oldKey := generateTestCert(t, "old.example.com")
+	newCert, newKey := generateTestCert(t, "new.example.com")
+
+	// Each write is dated a second after the last so that the change shows
+	// on file systems with coarse modification times.
+	modTime := time.Now().Add(-time.Hour)
+
+	write := func(name string, contents []byte) {
+		t.Helper()
+
+		err := os.WriteFile(name, contents, 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		modTime = modTime.Add(time.Second)
+
+		err = os.Chtimes(name, modTime, modTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(certFile, oldCert)
+	write(keyFile, oldKey)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	certs, err := newCertReloader(certFile, keyFile, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	srv.TLS = &tls.Config{GetCertificate: certs.GetCertificate}
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+
+	go func() {
+		certs.watch(ctx, 10*time.Millisecond)
+		close(stopped)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+
+	if name := servedCertName(t, srv); name != "old.example.com" {
+		t.Fatalf("served %s; want old.example.com", name)
+	}
+
+	// Until the key is replaced too the new certificate doesn't match it,
+	// so the old pair stays in use.
+	write(certFile, newCert)
+	time.Sleep(50 * time.Millisecond)
+
+	if name := servedCertName(t, srv); name != "old.example.com" {
+		t.Fatalf("served %s with only the certificate replaced; want old.example.com", name)
+	}
+
+	write(keyFile, newKey)
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for servedCertName(t, srv) != "new.example.com" {
+		if time.Now().After(deadline) {
+			t.Fatal("the new certificate was never served")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNewCertReloaderMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	_, err := newCertReloader(
+		filepath.Join(dir, "cert.pem"),
+		filepath.Join(dir, "key.pem"),
+		logger,
+	)
+	if err == nil {
+		t.Error("newCertReloader succeeded without any files")
+	}
+}
+
+// servedCertName connects to the server and returns the common name of the
+// certificate it presents.
+func servedCertName(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+
+	// httptest gives the server a certificate of its own, which is served
+	// to clients that don't send a server name.
+	conn, err := tls.Dial("tcp", srv.Listener.Addr().String(), &tls.Config{
+		ServerName:         "example.com",
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+// generateTestCert returns a PEM-encoded self-signed certificate for name
+// and its key.
+func generateTestCert(t *testing.T, name string) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return certPEM, keyPEM
+}
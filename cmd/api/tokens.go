@@ -5,7 +5,6 @@ import (
 	"net/http"
 	"sulemankhann/workout-tracker/internal/data"
 	"sulemankhann/workout-tracker/internal/validator"
-	"time"
 )
 
 func (app *application) createAuthenticationTokenHandler(
@@ -33,7 +32,7 @@ func (app *application) createAuthenticationTokenHandler(
 		return
 	}
 
-	user, err := app.models.Users.GetByEmail(input.Email)
+	user, err := app.models.Users.GetByEmail(r.Context(), input.Email)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -57,8 +56,9 @@ func (app *application) createAuthenticationTokenHandler(
 	}
 
 	token, err := app.models.Tokens.New(
+		r.Context(),
 		user.ID,
-		24*time.Hour,
+		app.config.tokens.authenticationTTL,
 		data.ScopeAuthentication,
 	)
 	if err != nil {
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"testing"
+	"time"
+)
+
+func TestCreateAuthenticationTokenHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	createTestUser(t, app, "Alice")
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:   "valid credentials",
+			method: http.MethodPost,
+			path:   "/v1/tokens/authentication",
+			body: map[string]string{
+				"email":    "alice@example.com",
+				"password": testPassword,
+			},
+			wantStatus: http.StatusCreated,
+			check: checkEnvelope("authentication_token", func(t *testing.T, token data.Token) {
+				if len(token.Plaintext) != 26 || !token.Expiry.After(time.Now()) {
+					t.Errorf("token = %+v; want a 26-character token that hasn't expired", token)
+				}
+			}),
+		},
+		{
+			name:   "wrong password",
+			method: http.MethodPost,
+			path:   "/v1/tokens/authentication",
+			body: map[string]string{
+				"email":    "alice@example.com",
+				"password": "wrong-password",
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "unknown email",
+			method: http.MethodPost,
+			path:   "/v1/tokens/authentication",
+			body: map[string]string{
+				"email":    "bob@example.com",
+				"password": testPassword,
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "invalid email",
+			method:     http.MethodPost,
+			path:       "/v1/tokens/authentication",
+			body:       map[string]string{"email": "alice", "password": testPassword},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	})
+
+	res := app.request(
+		t,
+		http.MethodPost,
+		"/v1/tokens/authentication",
+		"",
+		map[string]string{"email": "alice@example.com", "password": testPassword},
+	)
+
+	var body struct {
+		Token struct {
+			Token string `json:"token"`
+		} `json:"authentication_token"`
+	}
+
+	res.decode(t, &body)
+
+	res = app.request(t, http.MethodGet, "/v1/users/me", body.Token.Token, nil)
+	if res.status != http.StatusOK {
+		t.Errorf("the new token is not accepted: status = %d", res.status)
+	}
+}
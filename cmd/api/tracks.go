@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sulemankhann/workout-tracker/internal/data"
+	"sulemankhann/workout-tracker/internal/track"
+	"sulemankhann/workout-tracker/internal/validator"
+	"time"
+)
+
+const maxTrackFileBytes = 20 << 20
+
+func (app *application) createWorkoutTrackHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	workout, err := app.models.Workouts.GetByUser(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	// Uploads can be slow, so allow longer than the server's read timeout
+	// for the body to arrive.
+	_ = http.NewResponseController(w).SetReadDeadline(
+		time.Now().Add(uploadReadTimeout),
+	)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxTrackFileBytes)
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &maxBytesError):
+			app.badRequestResponse(
+				w,
+				r,
+				fmt.Errorf(
+					"file must not be larger than %d bytes",
+					maxBytesError.Limit,
+				),
+			)
+		default:
+			app.badRequestResponse(
+				w,
+				r,
+				errors.New("request must include a GPX or TCX file in the \"file\" form field"),
+			)
+		}
+
+		return
+	}
+
+	defer file.Close()
+
+	v := validator.New()
+
+	exerciseID, err := strconv.ParseInt(r.FormValue("exercise_id"), 10, 64)
+	if err != nil || exerciseID < 1 {
+		v.AddError("exercise_id", "must be a positive integer")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	exercise, err := app.models.Exercises.Get(r.Context(), exerciseID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError(
+				"exercise_id",
+				fmt.Sprintf("exercise %d could not be found", exerciseID),
+			)
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	v.Check(
+		exercise.MeasurementType == data.MeasurementDistanceTime,
+		"exercise_id",
+		"must be a distance and time exercise",
+	)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	format, points, err := track.Parse(file)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// Splits follow the user's distance preference: every kilometre or
+	// every mile.
+	splitLength := data.ToMeters(1, user.Units.Distance)
+
+	summary, err := track.Summarize(format, points, splitLength)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	workoutTrack := &data.WorkoutTrack{
+		WorkoutID:       workout.ID,
+		ExerciseID:      exercise.ID,
+		Format:          summary.Format,
+		StartedAt:       summary.StartedAt,
+		Distance:        summary.DistanceMeters,
+		DurationSeconds: summary.DurationSeconds,
+		ElevationGain:   summary.ElevationGain,
+		Splits:          make([]data.TrackSplit, len(summary.Splits)),
+	}
+
+	for i, split := range summary.Splits {
+		workoutTrack.Splits[i] = data.TrackSplit{
+			Number:          split.Number,
+			Distance:        split.DistanceMeters,
+			DurationSeconds: split.DurationSeconds,
+		}
+	}
+
+	workoutExercise := data.WorkoutExercise{
+		ExerciseID:      exercise.ID,
+		Exercise:        *exercise,
+		Sets:            1,
+		DurationSeconds: summary.DurationSeconds,
+		Distance:        summary.DistanceMeters,
+	}
+
+	if data.ValidateWorkoutEXercise(v, &workoutExercise); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// The cardio entry is added by saving the workout, as an edit would, and
+	// the track is recorded against it.
+	workout.Exercises = append(workout.Exercises, workoutExercise)
+
+	err = app.models.Tracks.InsertWithWorkout(app.auditContext(r), workoutTrack, workout)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(
+		w,
+		http.StatusCreated,
+		envelope{
+			"track":   workoutTrack.InUnits(user.Units),
+			"workout": workout.InUnits(user.Units),
+		},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listWorkoutTracksHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	workout, err := app.models.Workouts.GetByUser(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	tracks, err := app.models.Tracks.GetAllForWorkout(r.Context(), workout.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	converted := make([]*data.WorkoutTrack, len(tracks))
+	for i, workoutTrack := range tracks {
+		converted[i] = workoutTrack.InUnits(user.Units)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"tracks": converted}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
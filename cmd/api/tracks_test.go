@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sulemankhann/workout-tracker/internal/data"
+	"testing"
+	"time"
+)
+
+const testGPX = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="Test" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk>
+    <trkseg>
+      <trkpt lat="51.500" lon="-0.1000"><ele>100</ele><time>2024-05-04T07:00:00Z</time></trkpt>
+      <trkpt lat="51.505" lon="-0.1000"><ele>102</ele><time>2024-05-04T07:02:30Z</time></trkpt>
+      <trkpt lat="51.510" lon="-0.1000"><ele>104</ele><time>2024-05-04T07:05:00Z</time></trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+
+func TestWorkoutTrackHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, aliceToken := createTestUser(t, app, "Alice")
+	_, bobToken := createTestUser(t, app, "Bob")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+	run := createTestExercise(t, app, "Run", data.MeasurementDistanceTime)
+
+	workout := createTestWorkout(t, app, alice, squat, nil)
+	path := fmt.Sprintf("/v1/workouts/%d/tracks", workout.ID)
+
+	runID := strconv.FormatInt(run.ID, 10)
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "upload",
+			method:     http.MethodPost,
+			path:       path,
+			token:      aliceToken,
+			body:       multipartForm{"file": testGPX, "exercise_id": runID},
+			wantStatus: http.StatusCreated,
+			check: func(t *testing.T, res testResponse) {
+				var body struct {
+					Track   data.WorkoutTrack `json:"track"`
+					Workout data.Workout      `json:"workout"`
+				}
+
+				res.decode(t, &body)
+
+				// Three points 0.005° of latitude apart, run in five minutes.
+				track := body.Track
+				if track.WorkoutID != workout.ID || track.ExerciseID != run.ID ||
+					track.DistanceUnit != data.UnitKilometers || track.Distance < 1.1 ||
+					track.Distance > 1.12 || track.DurationSeconds != 300 ||
+					track.ElevationGain != 4 {
+					t.Errorf("track = %+v; want 1.11 km in 300 seconds, climbing 4 m", track)
+				}
+
+				// The run is added to the workout.
+				if len(body.Workout.Exercises) != 2 ||
+					body.Workout.Exercises[1].Exercise.ID != run.ID {
+					t.Errorf("workout = %+v; want the squat and the run", body.Workout)
+				}
+			},
+		},
+		{
+			name:       "upload without a file",
+			method:     http.MethodPost,
+			path:       path,
+			token:      aliceToken,
+			body:       multipartForm{"exercise_id": runID},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "upload an unrecognised file",
+			method:     http.MethodPost,
+			path:       path,
+			token:      aliceToken,
+			body:       multipartForm{"file": "not a track", "exercise_id": runID},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "upload without an exercise",
+			method:     http.MethodPost,
+			path:       path,
+			token:      aliceToken,
+			body:       multipartForm{"file": testGPX},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "upload for a strength exercise",
+			method: http.MethodPost,
+			path:   path,
+			token:  aliceToken,
+			body: multipartForm{
+				"file":        testGPX,
+				"exercise_id": strconv.FormatInt(squat.ID, 10),
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "upload for an unknown exercise",
+			method:     http.MethodPost,
+			path:       path,
+			token:      aliceToken,
+			body:       multipartForm{"file": testGPX, "exercise_id": "999"},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "upload to another user's workout",
+			method:     http.MethodPost,
+			path:       path,
+			token:      bobToken,
+			body:       multipartForm{"file": testGPX, "exercise_id": runID},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "list",
+			method:     http.MethodGet,
+			path:       path,
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("tracks", func(t *testing.T, tracks []data.WorkoutTrack) {
+				if len(tracks) != 1 || tracks[0].WorkoutID != workout.ID ||
+					tracks[0].Distance <= 0 {
+					t.Errorf("tracks = %+v; want the uploaded track", tracks)
+				}
+			}),
+		},
+		{
+			name:       "list another user's tracks",
+			method:     http.MethodGet,
+			path:       path,
+			token:      bobToken,
+			wantStatus: http.StatusNotFound,
+		},
+	})
+}
+
+func TestWorkoutTrackFollowsItsExercise(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, token := createTestUser(t, app, "Alice")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+	run := createTestExercise(t, app, "Run", data.MeasurementDistanceTime)
+
+	workout := createTestWorkout(t, app, alice, squat, nil)
+	workoutPath := fmt.Sprintf("/v1/workouts/%d", workout.ID)
+
+	res := app.request(
+		t,
+		http.MethodPost,
+		workoutPath+"/tracks",
+		token,
+		multipartForm{"file": testGPX, "exercise_id": strconv.FormatInt(run.ID, 10)},
+	)
+	if res.status != http.StatusCreated {
+		t.Fatalf("status = %d; want 201\n%s", res.status, res.body)
+	}
+
+	// Adding the run is an edit of the workout, so subscribers hear of it.
+	events, err := app.models.Outbox.Claim(context.Background(), 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(events); n != 2 || events[1].Type != data.EventWorkoutUpdated {
+		t.Errorf("got %d events; want workout.created then workout.updated", n)
+	}
+
+	countTracks := func() int {
+		t.Helper()
+
+		tracks, err := app.models.Tracks.GetAllForWorkout(context.Background(), workout.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return len(tracks)
+	}
+
+	edit := func(exercises ...map[string]any) {
+		t.Helper()
+
+		res := app.request(t, http.MethodPut, workoutPath, token, map[string]any{
+			"title":        "Leg day",
+			"scheduled_at": workout.ScheduledAt,
+			"exercises":    exercises,
+		})
+		if res.status != http.StatusOK {
+			t.Fatalf("status = %d; want 200\n%s", res.status, res.body)
+		}
+	}
+
+	squatInput := map[string]any{
+		"exercise_id": squat.ID,
+		"sets":        3,
+		"repetitions": 5,
+		"weight":      100,
+	}
+
+	edit(squatInput, map[string]any{
+		"exercise_id":      run.ID,
+		"sets":             1,
+		"distance":         1.1,
+		"duration_seconds": 300,
+	})
+
+	if n := countTracks(); n != 1 {
+		t.Errorf("got %d tracks after keeping the run; want 1", n)
+	}
+
+	edit(squatInput)
+
+	if n := countTracks(); n != 0 {
+		t.Errorf("got %d tracks after removing the run; want 0", n)
+	}
+}
@@ -15,6 +15,7 @@ func (app *application) registerUserHandler(
 		Name     string `json:"name"`
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Timezone string `json:"timezone"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -24,8 +25,9 @@ func (app *application) registerUserHandler(
 	}
 
 	user := &data.User{
-		Name:  input.Name,
-		Email: input.Email,
+		Name:     input.Name,
+		Email:    input.Email,
+		Timezone: input.Timezone,
 	}
 
 	err = user.Password.Set(input.Password)
@@ -41,7 +43,7 @@ func (app *application) registerUserHandler(
 		return
 	}
 
-	err = app.models.Users.Insert(user)
+	err = app.models.Users.Insert(r.Context(), user)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrDuplicateEmail):
@@ -60,3 +62,101 @@ func (app *application) registerUserHandler(
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+func (app *application) showCurrentUserHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	user := app.contextGetUser(r)
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateCurrentUserHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Name     *string `json:"name"`
+		Timezone *string `json:"timezone"`
+		Privacy  *string `json:"privacy"`
+		Units    *struct {
+			Weight        *string `json:"weight_unit"`
+			Distance      *string `json:"distance_unit"`
+			PlateRounding *bool   `json:"plate_rounding"`
+		} `json:"units"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		user.Name = *input.Name
+	}
+
+	v := validator.New()
+
+	// ValidateUser allows empty values so registration can fall back to the
+	// defaults, but an update must not clear them.
+	if input.Timezone != nil {
+		v.Check(*input.Timezone != "", "timezone", "must be provided")
+		user.Timezone = *input.Timezone
+	}
+
+	if input.Privacy != nil {
+		v.Check(*input.Privacy != "", "privacy", "must be provided")
+		user.Privacy = *input.Privacy
+	}
+
+	if input.Units != nil {
+		if input.Units.Weight != nil {
+			v.Check(*input.Units.Weight != "", "units.weight_unit", "must be provided")
+			user.Units.Weight = *input.Units.Weight
+		}
+
+		if input.Units.Distance != nil {
+			v.Check(
+				*input.Units.Distance != "",
+				"units.distance_unit",
+				"must be provided",
+			)
+			user.Units.Distance = *input.Units.Distance
+		}
+
+		if input.Units.PlateRounding != nil {
+			user.Units.PlateRounding = *input.Units.PlateRounding
+		}
+	}
+
+	if data.ValidateUser(v, user); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Users.Update(r.Context(), user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateEmail):
+			v.AddError("email", "a user with this email address already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"testing"
+)
+
+func TestRegisterUserHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	createTestUser(t, app, "Alice")
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:   "valid",
+			method: http.MethodPost,
+			path:   "/v1/users",
+			body: map[string]string{
+				"name":     "Bob",
+				"email":    "bob@example.com",
+				"password": testPassword,
+				"timezone": "Europe/London",
+			},
+			wantStatus: http.StatusCreated,
+			check: checkEnvelope("user", func(t *testing.T, user data.User) {
+				if user.ID == 0 || user.Name != "Bob" || user.Email != "bob@example.com" ||
+					user.Timezone != "Europe/London" {
+					t.Errorf("user = %+v; want Bob in Europe/London", user)
+				}
+
+				if user.Units.Weight != data.UnitKilograms ||
+					user.Units.Distance != data.UnitKilometers {
+					t.Errorf("units = %+v; want the metric defaults", user.Units)
+				}
+			}),
+		},
+		{
+			name:   "duplicate email",
+			method: http.MethodPost,
+			path:   "/v1/users",
+			body: map[string]string{
+				"name":     "Alice",
+				"email":    "ALICE@example.com",
+				"password": testPassword,
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "short password",
+			method: http.MethodPost,
+			path:   "/v1/users",
+			body: map[string]string{
+				"name":     "Carol",
+				"email":    "carol@example.com",
+				"password": "short",
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "unknown time zone",
+			method: http.MethodPost,
+			path:   "/v1/users",
+			body: map[string]string{
+				"name":     "Carol",
+				"email":    "carol@example.com",
+				"password": testPassword,
+				"timezone": "Mars/Olympus_Mons",
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "unknown field",
+			method:     http.MethodPost,
+			path:       "/v1/users",
+			body:       map[string]string{"nickname": "carol"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "malformed JSON",
+			method:     http.MethodPost,
+			path:       "/v1/users",
+			body:       `{"name": `,
+			wantStatus: http.StatusBadRequest,
+		},
+	})
+}
+
+func TestShowCurrentUserHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	user, token := createTestUser(t, app, "Alice")
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "authenticated",
+			method:     http.MethodGet,
+			path:       "/v1/users/me",
+			token:      token,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("user", func(t *testing.T, got data.User) {
+				if got.ID != user.ID || got.Email != user.Email || got.Name != "Alice" {
+					t.Errorf("user = %+v; want %d %s", got, user.ID, user.Email)
+				}
+			}),
+		},
+		{
+			name:       "anonymous",
+			method:     http.MethodGet,
+			path:       "/v1/users/me",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "unknown token",
+			method:     http.MethodGet,
+			path:       "/v1/users/me",
+			token:      "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "malformed token",
+			method:     http.MethodGet,
+			path:       "/v1/users/me",
+			token:      "abc",
+			wantStatus: http.StatusUnauthorized,
+		},
+	})
+}
+
+func TestUpdateCurrentUserHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, token := createTestUser(t, app, "Alice")
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:   "valid",
+			method: http.MethodPatch,
+			path:   "/v1/users/me",
+			token:  token,
+			body: map[string]any{
+				"name":    "Alice Smith",
+				"privacy": "public",
+				"units":   map[string]any{"weight_unit": "lb", "plate_rounding": true},
+			},
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("user", func(t *testing.T, user data.User) {
+				want := data.Units{
+					Weight:        data.UnitPounds,
+					Distance:      data.UnitKilometers,
+					PlateRounding: true,
+				}
+
+				if user.Name != "Alice Smith" || user.Privacy != data.PrivacyPublic ||
+					user.Units != want {
+					t.Errorf("user = %+v; want the updated values with distance unchanged", user)
+				}
+			}),
+		},
+		{
+			name:       "unknown unit",
+			method:     http.MethodPatch,
+			path:       "/v1/users/me",
+			token:      token,
+			body:       map[string]any{"units": map[string]string{"distance_unit": "league"}},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "empty weight unit",
+			method:     http.MethodPatch,
+			path:       "/v1/users/me",
+			token:      token,
+			body:       map[string]any{"units": map[string]string{"weight_unit": ""}},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "empty distance unit",
+			method:     http.MethodPatch,
+			path:       "/v1/users/me",
+			token:      token,
+			body:       map[string]any{"units": map[string]string{"distance_unit": ""}},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "empty timezone",
+			method:     http.MethodPatch,
+			path:       "/v1/users/me",
+			token:      token,
+			body:       map[string]string{"timezone": ""},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "empty privacy",
+			method:     http.MethodPatch,
+			path:       "/v1/users/me",
+			token:      token,
+			body:       map[string]string{"privacy": ""},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "anonymous",
+			method:     http.MethodPatch,
+			path:       "/v1/users/me",
+			body:       map[string]string{"name": "Mallory"},
+			wantStatus: http.StatusUnauthorized,
+		},
+	})
+
+	res := app.request(t, http.MethodGet, "/v1/users/me", token, nil)
+
+	var body struct {
+		User struct {
+			Name    string     `json:"name"`
+			Privacy string     `json:"privacy"`
+			Units   data.Units `json:"units"`
+		} `json:"user"`
+	}
+
+	res.decode(t, &body)
+
+	if body.User.Name != "Alice Smith" ||
+		body.User.Privacy != data.PrivacyPublic ||
+		body.User.Units.Weight != data.UnitPounds {
+		t.Errorf("user = %+v; want the updated values", body.User)
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"sulemankhann/workout-tracker/internal/events"
+	"sulemankhann/workout-tracker/internal/validator"
+	"sulemankhann/workout-tracker/internal/webhook"
+	"time"
+)
+
+const (
+	webhookPollInterval = 5 * time.Second
+	webhookBatchSize    = 20
+	webhookTimeout      = 10 * time.Second
+	// webhookLease must comfortably exceed the time a whole batch can take
+	// to send, so a claimed delivery is never claimed twice.
+	webhookLease = 5 * time.Minute
+)
+
+// createWebhookHandler registers an endpoint for the authenticated user. The
+// response is the only time the signing secret is shown.
+func (app *application) createWebhookHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	var input struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	hook := &data.Webhook{
+		UserID: user.ID,
+		URL:    input.URL,
+		Events: input.Events,
+	}
+
+	v := validator.New()
+
+	if data.ValidateWebhook(v, hook, app.config.env == "development"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Webhooks.New(r.Context(), hook)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/webhooks/%d", hook.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"webhook": hook}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listWebhooksHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	user := app.contextGetUser(r)
+
+	hooks, err := app.models.Webhooks.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"webhooks": hooks}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteWebhookHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Webhooks.DeleteByUser(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{"message": "webhook successfully deleted"},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listWebhookDeliveriesHandler shows the delivery log of one of the
+// authenticated user's webhooks, newest first.
+func (app *application) listWebhookDeliveriesHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	qs := r.URL.Query()
+
+	v := validator.New()
+
+	filters := data.Filters{
+		Page:     app.readInt(qs, "page", 1, v),
+		PageSize: app.readInt(qs, "page_size", 20, v),
+	}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	_, err = app.models.Webhooks.GetByUser(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	deliveries, metadata, err := app.models.Webhooks.GetDeliveriesForWebhook(
+		r.Context(),
+		id,
+		user.ID,
+		filters,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{"deliveries": deliveries, "metadata": metadata},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// webhookSink is the events.Sink that queues every workout event for the
+// owner's webhooks subscribed to it. Deliveries are keyed on the event's
+// idempotency key, so an event offered twice is only delivered once.
+type webhookSink struct {
+	models data.Models
+}
+
+func (s webhookSink) Name() string { return "webhooks" }
+
+func (s webhookSink) Handle(ctx context.Context, event *events.Event) error {
+	return s.models.Webhooks.Enqueue(
+		ctx,
+		event.UserID,
+		event.Type,
+		event.Key,
+		event.Payload,
+	)
+}
+
+// runWebhookWorker sends due webhook deliveries until ctx is cancelled. A
+// batch already claimed is sent in full before it returns.
+func (app *application) runWebhookWorker(ctx context.Context) {
+	client := webhook.NewClient(webhookTimeout, app.config.env == "development")
+
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for ctx.Err() == nil {
+			deliveries, err := app.models.Webhooks.ClaimDue(
+				ctx,
+				webhookBatchSize,
+				webhookLease,
+			)
+			if err != nil {
+				app.logger.Error(err.Error())
+				break
+			}
+
+			for _, delivery := range deliveries {
+				app.sendWebhookDelivery(client, delivery)
+			}
+
+			if len(deliveries) < webhookBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// sendWebhookDelivery makes one attempt at a delivery and records the
+// outcome, scheduling a retry with exponential backoff on failure until
+// webhook.MaxAttempts is reached.
+func (app *application) sendWebhookDelivery(
+	client *webhook.Client,
+	delivery *data.WebhookDelivery,
+) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+
+	status, err := client.Send(ctx, webhook.Delivery{
+		ID:      delivery.ID,
+		URL:     delivery.URL,
+		Secret:  delivery.Secret,
+		Event:   delivery.Event,
+		Payload: delivery.Payload,
+	})
+
+	now := time.Now()
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = nil
+	delivery.LastError = ""
+
+	if status != 0 {
+		delivery.ResponseStatus = &status
+	}
+
+	switch {
+	case err == nil:
+		delivery.Status = data.DeliveryStatusSucceeded
+
+	case delivery.Attempts >= webhook.MaxAttempts:
+		delivery.Status = data.DeliveryStatusFailed
+		delivery.LastError = err.Error()
+
+	default:
+		next := now.Add(webhook.Backoff(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+		delivery.LastError = err.Error()
+	}
+
+	err = app.models.Webhooks.RecordAttempt(context.Background(), delivery)
+	if err != nil {
+		app.logger.Error(err.Error(), "delivery_id", delivery.ID)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"sulemankhann/workout-tracker/internal/events"
+	"testing"
+)
+
+func TestWebhookHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, aliceToken := createTestUser(t, app, "Alice")
+	_, bobToken := createTestUser(t, app, "Bob")
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:   "create without an absolute URL",
+			method: http.MethodPost,
+			path:   "/v1/webhooks",
+			token:  aliceToken,
+			body: map[string]any{
+				"url":    "/hooks/workouts",
+				"events": []string{data.EventWorkoutCompleted},
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "create with an unknown event",
+			method: http.MethodPost,
+			path:   "/v1/webhooks",
+			token:  aliceToken,
+			body: map[string]any{
+				"url":    "https://example.com/hooks",
+				"events": []string{"workout.liked"},
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "create without events",
+			method:     http.MethodPost,
+			path:       "/v1/webhooks",
+			token:      aliceToken,
+			body:       map[string]any{"url": "https://example.com/hooks"},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	})
+
+	res := app.request(t, http.MethodPost, "/v1/webhooks", aliceToken, map[string]any{
+		"url":    "https://example.com/hooks",
+		"events": []string{data.EventWorkoutCompleted},
+	})
+	if res.status != http.StatusCreated {
+		t.Fatalf("status = %d; want 201\n%s", res.status, res.body)
+	}
+
+	var body struct {
+		Webhook data.Webhook `json:"webhook"`
+	}
+
+	res.decode(t, &body)
+
+	if body.Webhook.Secret == "" {
+		t.Error("the signing secret wasn't returned on creation")
+	}
+
+	path := res.header.Get("Location")
+
+	res = app.request(t, http.MethodPost, "/v1/webhooks", bobToken, map[string]any{
+		"url":    "https://example.org/hooks",
+		"events": []string{data.EventWorkoutCompleted},
+	})
+	if res.status != http.StatusCreated {
+		t.Fatalf("status = %d; want 201\n%s", res.status, res.body)
+	}
+
+	// Offering the same event twice must only queue one delivery.
+	event := &events.Event{
+		Key:     "workout.completed:1",
+		UserID:  alice.ID,
+		Type:    data.EventWorkoutCompleted,
+		Payload: []byte(`{}`),
+	}
+
+	for range 2 {
+		err := webhookSink{app.models}.Handle(context.Background(), event)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "list",
+			method:     http.MethodGet,
+			path:       "/v1/webhooks",
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("webhooks", func(t *testing.T, hooks []data.Webhook) {
+				if len(hooks) != 1 || hooks[0].ID != body.Webhook.ID || hooks[0].Secret != "" {
+					t.Errorf("webhooks = %+v; want Alice's one without its secret", hooks)
+				}
+			}),
+		},
+		{
+			name:       "list deliveries",
+			method:     http.MethodGet,
+			path:       path + "/deliveries",
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("deliveries", func(t *testing.T, got []data.WebhookDelivery) {
+				if len(got) != 1 || got[0].WebhookID != body.Webhook.ID ||
+					got[0].Event != data.EventWorkoutCompleted {
+					t.Errorf("deliveries = %+v; want one workout.completed", got)
+				}
+			}),
+		},
+		{
+			name:       "list deliveries with an invalid page size",
+			method:     http.MethodGet,
+			path:       path + "/deliveries?page_size=1000",
+			token:      aliceToken,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "list another user's deliveries",
+			method:     http.MethodGet,
+			path:       path + "/deliveries",
+			token:      bobToken,
+			wantStatus: http.StatusNotFound,
+		},
+	})
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "delete another user's webhook",
+			method:     http.MethodDelete,
+			path:       path,
+			token:      bobToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "delete",
+			method:     http.MethodDelete,
+			path:       path,
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check:      checkMessage("webhook successfully deleted"),
+		},
+		{
+			name:       "delete twice",
+			method:     http.MethodDelete,
+			path:       path,
+			token:      aliceToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "list deliveries after deleting",
+			method:     http.MethodGet,
+			path:       fmt.Sprintf("/v1/webhooks/%d/deliveries", body.Webhook.ID),
+			token:      aliceToken,
+			wantStatus: http.StatusNotFound,
+		},
+	})
+}
+
+func TestCreateWebhookOutsideDevelopment(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.env = "production"
+
+	_, token := createTestUser(t, app, "Alice")
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+	}{
+		{"http", "http://example.com/hooks", http.StatusUnprocessableEntity},
+		{"localhost", "https://localhost:8080/hooks", http.StatusUnprocessableEntity},
+		{"loopback address", "https://127.0.0.1/hooks", http.StatusUnprocessableEntity},
+		{"private address", "https://10.0.0.5/hooks", http.StatusUnprocessableEntity},
+		{"metadata address", "https://169.254.169.254/latest", http.StatusUnprocessableEntity},
+		{"IPv6 loopback", "https://[::1]/hooks", http.StatusUnprocessableEntity},
+		{"public https", "https://example.com/hooks", http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := app.request(t, http.MethodPost, "/v1/webhooks", token, map[string]any{
+				"url":    tt.url,
+				"events": []string{data.EventWorkoutCompleted},
+			})
+			if res.status != tt.wantStatus {
+				t.Errorf("status = %d; want %d\n%s", res.status, tt.wantStatus, res.body)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -18,11 +19,15 @@ func (app *application) createWorkoutHandler(
 		Description string    `json:"description"`
 		ScheduledAt time.Time `json:"scheduled_at"`
 		Exercises   []struct {
-			ExerciseID   int64   `json:"exercise_id"`
-			Sets         int     `json:"sets"`
-			Repetitions  int     `json:"repetitions"`
-			Weight       float64 `json:"weight"`
-			RestInterval int     `json:"rest_interval"`
+			ExerciseID      int64   `json:"exercise_id"`
+			Sets            int     `json:"sets"`
+			Repetitions     int     `json:"repetitions"`
+			Weight          float64 `json:"weight"`
+			WeightUnit      string  `json:"weight_unit"`
+			DurationSeconds int     `json:"duration_seconds"`
+			Distance        float64 `json:"distance"`
+			DistanceUnit    string  `json:"distance_unit"`
+			RestInterval    int     `json:"rest_interval"`
 		} `json:"exercises"`
 	}
 
@@ -33,9 +38,10 @@ func (app *application) createWorkoutHandler(
 	}
 
 	user := app.contextGetUser(r)
+	owner := app.contextGetWorkoutOwner(r)
 
 	workout := &data.Workout{
-		UserID:      user.ID,
+		UserID:      owner.ID,
 		Title:       input.Title,
 		Description: input.Description,
 		ScheduledAt: input.ScheduledAt,
@@ -51,7 +57,23 @@ func (app *application) createWorkoutHandler(
 	workoutExercises := []data.WorkoutExercise{}
 
 	for _, exerciseInput := range input.Exercises {
-		exercise, err := app.models.Exercises.Get(exerciseInput.ExerciseID)
+		// Weights and distances are accepted in the units given with each
+		// exercise, falling back to the user's preferences, and stored in
+		// kilograms and metres.
+		weightUnit := exerciseInput.WeightUnit
+		if weightUnit == "" {
+			weightUnit = user.Units.Weight
+		}
+
+		distanceUnit := exerciseInput.DistanceUnit
+		if distanceUnit == "" {
+			distanceUnit = user.Units.Distance
+		}
+
+		data.ValidateUnit(v, "weight_unit", weightUnit, data.WeightUnits)
+		data.ValidateUnit(v, "distance_unit", distanceUnit, data.DistanceUnits)
+
+		exercise, err := app.models.Exercises.Get(r.Context(), exerciseInput.ExerciseID)
 		if err != nil {
 			switch {
 			case errors.Is(err, data.ErrRecordNotFound):
@@ -71,12 +93,14 @@ func (app *application) createWorkoutHandler(
 		}
 
 		workoutExercise := data.WorkoutExercise{
-			ExerciseID:   exerciseInput.ExerciseID,
-			Exercise:     *exercise,
-			Sets:         exerciseInput.Sets,
-			Repetitions:  exerciseInput.Repetitions,
-			Weight:       exerciseInput.Weight,
-			RestInterval: exerciseInput.RestInterval,
+			ExerciseID:      exerciseInput.ExerciseID,
+			Exercise:        *exercise,
+			Sets:            exerciseInput.Sets,
+			Repetitions:     exerciseInput.Repetitions,
+			Weight:          data.ToKilograms(exerciseInput.Weight, weightUnit),
+			RestInterval:    exerciseInput.RestInterval,
+			DurationSeconds: exerciseInput.DurationSeconds,
+			Distance:        data.ToMeters(exerciseInput.Distance, distanceUnit),
 		}
 
 		workoutExercises = append(workoutExercises, workoutExercise)
@@ -90,7 +114,7 @@ func (app *application) createWorkoutHandler(
 
 	workout.Exercises = workoutExercises
 
-	err = app.models.Workouts.CreateWorkoutWithExercises(workout)
+	err = app.models.Workouts.CreateWorkoutWithExercises(app.auditContext(r), workout)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -99,7 +123,7 @@ func (app *application) createWorkoutHandler(
 	err = app.writeJSON(
 		w,
 		http.StatusCreated,
-		envelope{"workout": workout},
+		envelope{"workout": workout.InUnits(user.Units)},
 		nil,
 	)
 	if err != nil {
@@ -118,8 +142,9 @@ func (app *application) updateWorkoutHandler(
 	}
 
 	user := app.contextGetUser(r)
+	owner := app.contextGetWorkoutOwner(r)
 
-	workout, err := app.models.Workouts.GetByUser(id, user.ID)
+	workout, err := app.models.Workouts.GetByUser(r.Context(), id, owner.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -138,11 +163,15 @@ func (app *application) updateWorkoutHandler(
 		Description string    `json:"description"`
 		ScheduledAt time.Time `json:"scheduled_at"`
 		Exercises   []struct {
-			ExerciseID   int64   `json:"exercise_id"`
-			Sets         int     `json:"sets"`
-			Repetitions  int     `json:"repetitions"`
-			Weight       float64 `json:"weight"`
-			RestInterval int     `json:"rest_interval"`
+			ExerciseID      int64   `json:"exercise_id"`
+			Sets            int     `json:"sets"`
+			Repetitions     int     `json:"repetitions"`
+			Weight          float64 `json:"weight"`
+			WeightUnit      string  `json:"weight_unit"`
+			DurationSeconds int     `json:"duration_seconds"`
+			Distance        float64 `json:"distance"`
+			DistanceUnit    string  `json:"distance_unit"`
+			RestInterval    int     `json:"rest_interval"`
 		} `json:"exercises"`
 	}
 
@@ -166,7 +195,23 @@ func (app *application) updateWorkoutHandler(
 	workoutExercises := []data.WorkoutExercise{}
 
 	for _, exerciseInput := range input.Exercises {
-		exercise, err := app.models.Exercises.Get(exerciseInput.ExerciseID)
+		// Weights and distances are accepted in the units given with each
+		// exercise, falling back to the user's preferences, and stored in
+		// kilograms and metres.
+		weightUnit := exerciseInput.WeightUnit
+		if weightUnit == "" {
+			weightUnit = user.Units.Weight
+		}
+
+		distanceUnit := exerciseInput.DistanceUnit
+		if distanceUnit == "" {
+			distanceUnit = user.Units.Distance
+		}
+
+		data.ValidateUnit(v, "weight_unit", weightUnit, data.WeightUnits)
+		data.ValidateUnit(v, "distance_unit", distanceUnit, data.DistanceUnits)
+
+		exercise, err := app.models.Exercises.Get(r.Context(), exerciseInput.ExerciseID)
 		if err != nil {
 			switch {
 			case errors.Is(err, data.ErrRecordNotFound):
@@ -186,12 +231,14 @@ func (app *application) updateWorkoutHandler(
 		}
 
 		workoutExercise := data.WorkoutExercise{
-			ExerciseID:   exerciseInput.ExerciseID,
-			Exercise:     *exercise,
-			Sets:         exerciseInput.Sets,
-			Repetitions:  exerciseInput.Repetitions,
-			Weight:       exerciseInput.Weight,
-			RestInterval: exerciseInput.RestInterval,
+			ExerciseID:      exerciseInput.ExerciseID,
+			Exercise:        *exercise,
+			Sets:            exerciseInput.Sets,
+			Repetitions:     exerciseInput.Repetitions,
+			Weight:          data.ToKilograms(exerciseInput.Weight, weightUnit),
+			RestInterval:    exerciseInput.RestInterval,
+			DurationSeconds: exerciseInput.DurationSeconds,
+			Distance:        data.ToMeters(exerciseInput.Distance, distanceUnit),
 		}
 
 		workoutExercises = append(workoutExercises, workoutExercise)
@@ -205,7 +252,7 @@ func (app *application) updateWorkoutHandler(
 
 	workout.Exercises = workoutExercises
 
-	err = app.models.Workouts.UpdateWorkoutWithExercises(workout)
+	err = app.models.Workouts.UpdateWorkoutWithExercises(app.auditContext(r), workout)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -214,7 +261,7 @@ func (app *application) updateWorkoutHandler(
 	err = app.writeJSON(
 		w,
 		http.StatusOK,
-		envelope{"workout": workout},
+		envelope{"workout": workout.InUnits(user.Units)},
 		nil,
 	)
 	if err != nil {
@@ -227,8 +274,9 @@ func (app *application) listWorkoutsHandler(
 	r *http.Request,
 ) {
 	user := app.contextGetUser(r)
+	owner := app.contextGetWorkoutOwner(r)
 
-	workouts, err := app.models.Workouts.GetAllForUser(user.ID)
+	workouts, err := app.models.Workouts.GetAllForUser(r.Context(), owner.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -237,7 +285,7 @@ func (app *application) listWorkoutsHandler(
 	err = app.writeJSON(
 		w,
 		http.StatusOK,
-		envelope{"workouts": workouts},
+		envelope{"workouts": data.WorkoutsInUnits(workouts, user.Units)},
 		nil,
 	)
 	if err != nil {
@@ -256,8 +304,9 @@ func (app *application) showWorkoutHandler(
 	}
 
 	user := app.contextGetUser(r)
+	owner := app.contextGetWorkoutOwner(r)
 
-	workout, err := app.models.Workouts.GetByUser(id, user.ID)
+	workout, err := app.models.Workouts.GetByUser(r.Context(), id, owner.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -274,7 +323,7 @@ func (app *application) showWorkoutHandler(
 	err = app.writeJSON(
 		w,
 		http.StatusOK,
-		envelope{"workout": workout},
+		envelope{"workout": workout.InUnits(user.Units)},
 		nil,
 	)
 	if err != nil {
@@ -292,9 +341,9 @@ func (app *application) deleteWorkoutHandler(
 		return
 	}
 
-	user := app.contextGetUser(r)
+	owner := app.contextGetWorkoutOwner(r)
 
-	err = app.models.Workouts.DeleteByUser(id, user.ID)
+	err = app.models.Workouts.DeleteByUser(app.auditContext(r), id, owner.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -329,8 +378,9 @@ func (app *application) scheduleWorkoutHandler(
 	}
 
 	user := app.contextGetUser(r)
+	owner := app.contextGetWorkoutOwner(r)
 
-	workout, err := app.models.Workouts.GetByUser(id, user.ID)
+	workout, err := app.models.Workouts.GetByUser(r.Context(), id, owner.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -371,19 +421,112 @@ func (app *application) scheduleWorkoutHandler(
 
 	workout.ScheduledAt = input.ScheduledAt
 
-	err = app.models.Workouts.ScheduleWorkout(workout)
+	err = app.models.Workouts.ScheduleWorkout(app.auditContext(r), workout)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{"workout": workout.InUnits(user.Units)},
+		nil,
+	)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) completeWorkoutHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	owner := app.contextGetWorkoutOwner(r)
+
+	workout, err := app.models.Workouts.GetByUser(r.Context(), id, owner.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+
+	}
+
+	var input struct {
+		CompletedAt *time.Time `json:"completed_at"`
+	}
+
+	// The body is optional; an empty request marks the workout as completed
+	// right now.
+	if r.ContentLength != 0 {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	completedAt := time.Now()
+	if input.CompletedAt != nil {
+		completedAt = *input.CompletedAt
+	}
+
+	v := validator.New()
+
+	v.Check(
+		!completedAt.After(time.Now()),
+		"completed_at",
+		"must not be in the future",
+	)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	workout.CompletedAt = &completedAt
+
+	err = app.models.Workouts.Complete(app.auditContext(r), workout)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+
 		return
 	}
 
 	err = app.writeJSON(
 		w,
 		http.StatusOK,
-		envelope{"workout": workout},
+		envelope{"workout": workout.InUnits(user.Units)},
 		nil,
 	)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// auditContext returns the request's context with the authenticated user as
+// the actor, so that the workout models record the change they make in the
+// audit trail as part of it. The actor is either the workout's owner or one
+// of their coaches.
+func (app *application) auditContext(r *http.Request) context.Context {
+	return data.WithAuditActor(r.Context(), app.contextGetUser(r).ID)
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sulemankhann/workout-tracker/internal/data"
+	"testing"
+	"time"
+)
+
+func TestCreateWorkoutHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	_, token := createTestUser(t, app, "Alice")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+	run := createTestExercise(t, app, "Run", data.MeasurementDistanceTime)
+
+	tomorrow := time.Now().Add(24 * time.Hour)
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:   "valid",
+			method: http.MethodPost,
+			path:   "/v1/workouts",
+			token:  token,
+			body: map[string]any{
+				"title":        "Leg day",
+				"scheduled_at": tomorrow,
+				"exercises": []map[string]any{
+					{"exercise_id": squat.ID, "sets": 3, "repetitions": 5, "weight": 100},
+					{"exercise_id": run.ID, "distance": 5, "duration_seconds": 1500},
+				},
+			},
+			wantStatus: http.StatusCreated,
+			check: checkEnvelope("workout", func(t *testing.T, workout data.Workout) {
+				if workout.ID == 0 || workout.Title != "Leg day" || len(workout.Exercises) != 2 {
+					t.Fatalf("workout = %+v; want Leg day with two exercises", workout)
+				}
+
+				squat, run := workout.Exercises[0], workout.Exercises[1]
+
+				if squat.Exercise.Name != "Squat" || squat.Weight != 100 ||
+					squat.WeightUnit != data.UnitKilograms {
+					t.Errorf("squat = %+v; want 100 kg", squat)
+				}
+
+				// 5 km in 25 minutes.
+				if run.Distance != 5 || run.DistanceUnit != data.UnitKilometers ||
+					run.Speed == nil || *run.Speed != 12 || run.Pace == nil || *run.Pace != 300 {
+					t.Errorf("run = %+v; want 5 km at 12 km/h", run)
+				}
+			}),
+		},
+		{
+			name:       "missing title",
+			method:     http.MethodPost,
+			path:       "/v1/workouts",
+			token:      token,
+			body:       map[string]any{"scheduled_at": tomorrow},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "scheduled in the past",
+			method: http.MethodPost,
+			path:   "/v1/workouts",
+			token:  token,
+			body: map[string]any{
+				"title":        "Leg day",
+				"scheduled_at": time.Now().Add(-time.Hour),
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "unknown exercise",
+			method: http.MethodPost,
+			path:   "/v1/workouts",
+			token:  token,
+			body: map[string]any{
+				"title": "Leg day",
+				"exercises": []map[string]any{
+					{"exercise_id": 999, "sets": 3, "repetitions": 5},
+				},
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "no sets",
+			method: http.MethodPost,
+			path:   "/v1/workouts",
+			token:  token,
+			body: map[string]any{
+				"title": "Leg day",
+				"exercises": []map[string]any{
+					{"exercise_id": squat.ID, "repetitions": 5},
+				},
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "unknown weight unit",
+			method: http.MethodPost,
+			path:   "/v1/workouts",
+			token:  token,
+			body: map[string]any{
+				"title": "Leg day",
+				"exercises": []map[string]any{
+					{"exercise_id": squat.ID, "sets": 3, "repetitions": 5, "weight_unit": "stone"},
+				},
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "malformed JSON",
+			method:     http.MethodPost,
+			path:       "/v1/workouts",
+			token:      token,
+			body:       `{"title": 1}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "anonymous",
+			method:     http.MethodPost,
+			path:       "/v1/workouts",
+			body:       map[string]any{"title": "Leg day"},
+			wantStatus: http.StatusUnauthorized,
+		},
+	})
+}
+
+func TestCreateWorkoutHandlerConvertsUnits(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, token := createTestUser(t, app, "Alice")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+
+	res := app.request(t, http.MethodPost, "/v1/workouts", token, map[string]any{
+		"title": "Leg day",
+		"exercises": []map[string]any{
+			{
+				"exercise_id": squat.ID,
+				"sets":        3,
+				"repetitions": 5,
+				"weight":      225,
+				"weight_unit": data.UnitPounds,
+			},
+		},
+	})
+
+	var body struct {
+		Workout data.Workout `json:"workout"`
+	}
+
+	res.decode(t, &body)
+
+	stored, err := app.models.Workouts.GetByUser(
+		context.Background(),
+		body.Workout.ID,
+		alice.ID,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := stored.Exercises[0].Weight
+	if want := data.ToKilograms(225, data.UnitPounds); got != want {
+		t.Errorf("stored weight = %v; want %v kg", got, want)
+	}
+}
+
+func TestListWorkoutsHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, aliceToken := createTestUser(t, app, "Alice")
+	bob, bobToken := createTestUser(t, app, "Bob")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+
+	createTestWorkout(t, app, alice, squat, nil)
+	createTestWorkout(t, app, alice, squat, nil)
+	createTestWorkout(t, app, bob, squat, nil)
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "authenticated",
+			method:     http.MethodGet,
+			path:       "/v1/workouts",
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("workouts", func(t *testing.T, workouts []data.Workout) {
+				for _, workout := range workouts {
+					if workout.Title != "Leg day" || len(workout.Exercises) != 1 ||
+						workout.Exercises[0].WeightUnit != data.UnitKilograms {
+						t.Errorf("workout = %+v; want Leg day in kilograms", workout)
+					}
+				}
+			}),
+		},
+		{
+			name:       "anonymous",
+			method:     http.MethodGet,
+			path:       "/v1/workouts",
+			wantStatus: http.StatusUnauthorized,
+		},
+	})
+
+	for token, want := range map[string]int{aliceToken: 2, bobToken: 1} {
+		res := app.request(t, http.MethodGet, "/v1/workouts", token, nil)
+
+		var body struct {
+			Workouts []data.Workout `json:"workouts"`
+		}
+
+		res.decode(t, &body)
+
+		if len(body.Workouts) != want {
+			t.Errorf("got %d workouts; want %d", len(body.Workouts), want)
+		}
+	}
+}
+
+func TestWorkoutHandlers(t *testing.T) {
+	app := newTestApplication(t)
+
+	alice, aliceToken := createTestUser(t, app, "Alice")
+	_, bobToken := createTestUser(t, app, "Bob")
+	squat := createTestExercise(t, app, "Squat", data.MeasurementRepsWeight)
+
+	workout := createTestWorkout(t, app, alice, squat, nil)
+	path := fmt.Sprintf("/v1/workouts/%d", workout.ID)
+
+	scheduledAt := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+
+	update := map[string]any{
+		"title": "Heavy leg day",
+		"exercises": []map[string]any{
+			{"exercise_id": squat.ID, "sets": 5, "repetitions": 5, "weight": 120},
+		},
+	}
+
+	runRouteTests(t, app, []routeTest{
+		{
+			name:       "show",
+			method:     http.MethodGet,
+			path:       path,
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("workout", func(t *testing.T, got data.Workout) {
+				if got.ID != workout.ID || got.Title != "Leg day" ||
+					!got.ScheduledAt.Equal(workout.ScheduledAt) || got.CompletedAt != nil ||
+					len(got.Exercises) != 1 || got.Exercises[0].Weight != 100 {
+					t.Errorf("workout = %+v; want %+v", got, workout)
+				}
+			}),
+		},
+		{
+			name:       "show another user's workout",
+			method:     http.MethodGet,
+			path:       path,
+			token:      bobToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "show with an invalid ID",
+			method:     http.MethodGet,
+			path:       "/v1/workouts/abc",
+			token:      aliceToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "update",
+			method:     http.MethodPut,
+			path:       path,
+			token:      aliceToken,
+			body:       update,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("workout", func(t *testing.T, got data.Workout) {
+				if got.ID != workout.ID || got.Title != "Heavy leg day" ||
+					len(got.Exercises) != 1 || got.Exercises[0].Sets != 5 ||
+					got.Exercises[0].Weight != 120 {
+					t.Errorf("workout = %+v; want Heavy leg day with 5 sets at 120 kg", got)
+				}
+			}),
+		},
+		{
+			name:       "update without a title",
+			method:     http.MethodPut,
+			path:       path,
+			token:      aliceToken,
+			body:       map[string]any{"description": "Squats"},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "update another user's workout",
+			method:     http.MethodPut,
+			path:       path,
+			token:      bobToken,
+			body:       update,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "schedule",
+			method:     http.MethodPost,
+			path:       path + "/schedule",
+			token:      aliceToken,
+			body:       map[string]any{"scheduled_at": scheduledAt},
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("workout", func(t *testing.T, got data.Workout) {
+				if !got.ScheduledAt.Equal(scheduledAt) {
+					t.Errorf("scheduled at %v; want %v", got.ScheduledAt, scheduledAt)
+				}
+			}),
+		},
+		{
+			name:       "schedule in the past",
+			method:     http.MethodPost,
+			path:       path + "/schedule",
+			token:      aliceToken,
+			body:       map[string]any{"scheduled_at": time.Now().Add(-time.Hour)},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "complete in the future",
+			method:     http.MethodPost,
+			path:       path + "/complete",
+			token:      aliceToken,
+			body:       map[string]any{"completed_at": time.Now().Add(time.Hour)},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "complete",
+			method:     http.MethodPost,
+			path:       path + "/complete",
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("workout", func(t *testing.T, got data.Workout) {
+				if got.CompletedAt == nil || time.Since(*got.CompletedAt) > time.Minute {
+					t.Errorf("completed at %v; want now", got.CompletedAt)
+				}
+			}),
+		},
+		{
+			name:       "complete another user's workout",
+			method:     http.MethodPost,
+			path:       path + "/complete",
+			token:      bobToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "audit",
+			method:     http.MethodGet,
+			path:       path + "/audit",
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check: checkEnvelope("audit", func(t *testing.T, entries []data.WorkoutAuditEntry) {
+				want := []string{
+					data.AuditActionUpdated,
+					data.AuditActionScheduled,
+					data.AuditActionCompleted,
+				}
+
+				if len(entries) != len(want) {
+					t.Fatalf("got %d audit entries; want %d", len(entries), len(want))
+				}
+
+				for i, entry := range entries {
+					if entry.WorkoutID != workout.ID || entry.Action != want[i] ||
+						entry.ActorID == nil || *entry.ActorID != alice.ID {
+						t.Errorf("audit entry %d = %+v; want %s by Alice", i, entry, want[i])
+					}
+				}
+			}),
+		},
+		{
+			name:       "delete another user's workout",
+			method:     http.MethodDelete,
+			path:       path,
+			token:      bobToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "delete",
+			method:     http.MethodDelete,
+			path:       path,
+			token:      aliceToken,
+			wantStatus: http.StatusOK,
+			check:      checkMessage("workout successfully deleted"),
+		},
+		{
+			name:       "show after deleting",
+			method:     http.MethodGet,
+			path:       path,
+			token:      aliceToken,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "delete twice",
+			method:     http.MethodDelete,
+			path:       path,
+			token:      aliceToken,
+			wantStatus: http.StatusNotFound,
+		},
+	})
+}
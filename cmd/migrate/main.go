@@ -0,0 +1,218 @@
+// Command migrate applies the database migrations embedded in it.
+//
+// Usage:
+//
+//	migrate [-db-dsn DSN] up|down N|goto V|status|force V
+//
+// The DSN defaults to DB_DSN, which may be set in a .env file.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strconv"
+	"sulemankhann/workout-tracker/internal/migrate"
+	"sulemankhann/workout-tracker/migrations"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+)
+
+const usage = `Usage: migrate [flags] command
+
+Commands:
+  up        apply all pending migrations
+  down N    revert the N most recently applied migrations
+  goto V    migrate up or down to version V, 0 to revert everything
+  status    list the migrations and whether each is applied
+  force V   record version V and clear the dirty flag without migrating
+
+Flags:
+`
+
+// errUsage marks errors caused by how the command was invoked.
+var errUsage = errors.New("usage")
+
+func main() {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	err := run(os.Args[1:], logger)
+
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(0)
+
+	case errors.Is(err, errUsage):
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+
+	case err != nil:
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+}
+
+func run(args []string, logger *slog.Logger) error {
+	// A .env file is optional; DB_DSN may come from the environment.
+	err := godotenv.Load()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+
+	dsn := flags.String("db-dsn", os.Getenv("DB_DSN"), "PostgreSQL DSN (env DB_DSN)")
+	timeout := flags.Duration(
+		"timeout",
+		15*time.Minute,
+		"time allowed for the whole command, including waiting for the lock",
+	)
+
+	err = flags.Parse(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	command, arg, err := parseCommand(flags.Args())
+	if err != nil {
+		flags.Usage()
+		return err
+	}
+
+	if *dsn == "" {
+		return fmt.Errorf("%w: -db-dsn or DB_DSN must be set", errUsage)
+	}
+
+	db, err := sql.Open("postgres", *dsn)
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	migrator, err := migrate.New(db, migrations.FS, logger)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	switch command {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx, int(arg))
+	case "goto":
+		err = migrator.Goto(ctx, arg)
+	case "force":
+		err = migrator.Force(ctx, arg)
+	case "status":
+		return printStatus(ctx, migrator)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	version, _, err := migrator.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("database migrated", "version", version)
+
+	return nil
+}
+
+// parseCommand checks the command and its argument, if it takes one.
+func parseCommand(args []string) (string, int64, error) {
+	if len(args) == 0 {
+		return "", 0, fmt.Errorf("%w: no command given", errUsage)
+	}
+
+	command, rest := args[0], args[1:]
+
+	switch command {
+	case "up", "status":
+		if len(rest) != 0 {
+			return "", 0, fmt.Errorf("%w: %s takes no arguments", errUsage, command)
+		}
+
+		return command, 0, nil
+
+	case "down", "goto", "force":
+		if len(rest) != 1 {
+			return "", 0, fmt.Errorf("%w: %s takes one argument", errUsage, command)
+		}
+
+		n, err := strconv.ParseInt(rest[0], 10, 64)
+		if err != nil || n < 0 || (command == "down" && n == 0) {
+			return "", 0, fmt.Errorf(
+				"%w: invalid argument %q for %s",
+				errUsage,
+				rest[0],
+				command,
+			)
+		}
+
+		return command, n, nil
+	}
+
+	return "", 0, fmt.Errorf("%w: unknown command %q", errUsage, command)
+}
+
+func printStatus(ctx context.Context, migrator *migrate.Migrator) error {
+	version, dirty, err := migrator.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+
+	for _, migration := range migrator.Migrations {
+		status := "pending"
+
+		switch {
+		case migration.Version == version && dirty:
+			status = "dirty"
+		case migration.Version <= version:
+			status = "applied"
+		}
+
+		fmt.Fprintf(w, "%06d\t%s\t%s\n", migration.Version, migration.Name, status)
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nCurrent version: %d", version)
+
+	if dirty {
+		fmt.Print(" (dirty)")
+	}
+
+	fmt.Println()
+
+	return nil
+}
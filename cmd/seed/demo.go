@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sulemankhann/workout-tracker/internal/data"
+	"time"
+)
+
+// demoOptions controls the demo data created for local development.
+type demoOptions struct {
+	users    int
+	workouts int
+	password string
+}
+
+// demoSet is one exercise in a demo workout. Weights grow by 2.5 kg each
+// time the workout is repeated so the progress charts have something to
+// show.
+type demoSet struct {
+	exercise        string
+	sets            int
+	repetitions     int
+	weight          float64
+	durationSeconds int
+	distance        float64
+}
+
+// demoTemplates are the workouts demo users rotate through.
+var demoTemplates = []struct {
+	title string
+	sets  []demoSet
+}{
+	{
+		title: "Lower body",
+		sets: []demoSet{
+			{exercise: "Squat", sets: 5, repetitions: 5, weight: 60},
+			{exercise: "Lunges", sets: 3, repetitions: 10, weight: 20},
+			{exercise: "Calf Raises", sets: 3, repetitions: 15, weight: 40},
+		},
+	},
+	{
+		title: "Upper body",
+		sets: []demoSet{
+			{exercise: "Bench Press", sets: 5, repetitions: 5, weight: 50},
+			{exercise: "Pull Up", sets: 3, repetitions: 8},
+			{exercise: "Shoulder Press", sets: 3, repetitions: 8, weight: 30},
+		},
+	},
+	{
+		title: "Conditioning",
+		sets: []demoSet{
+			{exercise: "Running", durationSeconds: 1800, distance: 5000},
+			{exercise: "Plank", sets: 3, durationSeconds: 60},
+			{exercise: "Burpees", sets: 3, repetitions: 15},
+		},
+	},
+}
+
+// SeedDemo creates demo users, demo1@example.com and so on, each with a
+// history of completed workouts every other day and one still to come.
+// Users that already exist are kept, and are only given workouts if they
+// have none, so seeding again changes nothing.
+func (s Seeder) SeedDemo(ctx context.Context, opts demoOptions) error {
+	catalogue, err := s.Models.Exercises.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	exercisesByName := make(map[string]*data.Exercise)
+	for _, exercise := range catalogue {
+		exercisesByName[exercise.Name] = exercise
+	}
+
+	// Check the catalogue before creating any users.
+	_, err = demoWorkouts(0, opts.workouts, exercisesByName)
+	if err != nil {
+		return err
+	}
+
+	for i := range opts.users {
+		user, created, err := s.demoUser(ctx, i+1, opts.password)
+		if err != nil {
+			return err
+		}
+
+		activity, err := s.Models.Workouts.GetActivityForUser(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		if len(activity) > 0 {
+			fmt.Fprintf(s.Out, "Demo user %s already has workouts.\n", user.Email)
+			continue
+		}
+
+		workouts, err := demoWorkouts(user.ID, opts.workouts, exercisesByName)
+		if err != nil {
+			return err
+		}
+
+		err = s.Models.Workouts.CreateWorkoutWithExercises(ctx, workouts...)
+		if err != nil {
+			return fmt.Errorf("failed to create workouts for %s: %w", user.Email, err)
+		}
+
+		verb := "given"
+		if created {
+			verb = "created with"
+		}
+
+		fmt.Fprintf(s.Out, "Demo user %s %s %d workouts.\n", user.Email, verb, len(workouts))
+	}
+
+	return nil
+}
+
+// demoUser returns the nth demo user, creating it if it doesn't exist.
+func (s Seeder) demoUser(
+	ctx context.Context,
+	n int,
+	password string,
+) (*data.User, bool, error) {
+	email := fmt.Sprintf("demo%d@example.com", n)
+
+	user, err := s.Models.Users.GetByEmail(ctx, email)
+	if err == nil {
+		return user, false, nil
+	}
+
+	if !errors.Is(err, data.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	user = &data.User{
+		Name:  fmt.Sprintf("Demo User %d", n),
+		Email: email,
+	}
+
+	err = user.Password.Set(password)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = s.Models.Users.Insert(ctx, user)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create %s: %w", email, err)
+	}
+
+	return user, true, nil
+}
+
+// demoWorkouts returns count workouts for the user, completed every other
+// day up to yesterday, with the last of them scheduled for tomorrow.
+// Exercises missing from the catalogue are left out.
+func demoWorkouts(
+	userID int64,
+	count int,
+	exercisesByName map[string]*data.Exercise,
+) ([]*data.Workout, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	first := today.AddDate(0, 0, 1-2*(count-1)).Add(18 * time.Hour)
+
+	workouts := []*data.Workout{}
+
+	for i := range count {
+		template := demoTemplates[i%len(demoTemplates)]
+		round := float64(i / len(demoTemplates))
+
+		workout := &data.Workout{
+			UserID:      userID,
+			Title:       template.title,
+			Description: "Sample workout",
+			ScheduledAt: first.AddDate(0, 0, 2*i),
+		}
+
+		if i < count-1 {
+			completedAt := workout.ScheduledAt.Add(time.Hour)
+			workout.CompletedAt = &completedAt
+		}
+
+		for _, set := range template.sets {
+			exercise, ok := exercisesByName[set.exercise]
+			if !ok {
+				continue
+			}
+
+			workoutExercise := data.WorkoutExercise{
+				ExerciseID:      exercise.ID,
+				Exercise:        *exercise,
+				Sets:            set.sets,
+				Repetitions:     set.repetitions,
+				DurationSeconds: set.durationSeconds,
+				Distance:        set.distance,
+				RestInterval:    90,
+			}
+
+			if set.weight > 0 {
+				workoutExercise.Weight = set.weight + 2.5*round
+			}
+
+			workout.Exercises = append(workout.Exercises, workoutExercise)
+		}
+
+		if len(workout.Exercises) > 0 {
+			workouts = append(workouts, workout)
+		}
+	}
+
+	if count > 0 && len(workouts) == 0 {
+		return nil, errors.New(
+			"the catalogue has none of the demo workouts' exercises; seed the default exercises first",
+		)
+	}
+
+	return workouts, nil
+}
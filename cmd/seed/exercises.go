@@ -1,176 +1,213 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"sulemankhann/workout-tracker/internal/data"
 )
 
-func (s Seeder) SeedExercises() {
-	em := data.ExerciseModel{DB: s.DB}
-
-	exercises := getAllExercises()
+// SeedExercises adds the exercises to the catalogue and updates the ones
+// whose details have changed, all in one transaction. Exercises are matched
+// by name, so seeding the same catalogue again changes nothing.
+func (s Seeder) SeedExercises(ctx context.Context, exercises []data.Exercise) error {
+	batch := make([]*data.Exercise, len(exercises))
+	for i := range exercises {
+		batch[i] = &exercises[i]
+	}
 
-	for _, exercise := range exercises {
-		if err := em.Insert(&exercise); err != nil {
-			log.Fatalf("Failed to seed exercise '%s': %v", exercise.Name, err)
-		}
+	inserted, updated, err := s.Models.Exercises.Upsert(ctx, batch...)
+	if err != nil {
+		return fmt.Errorf("failed to seed exercises: %w", err)
 	}
 
-	fmt.Println("Exercises data successfully seeded.")
+	fmt.Fprintf(
+		s.Out,
+		"Exercises seeded: %d added, %d updated, %d unchanged.\n",
+		inserted,
+		updated,
+		len(exercises)-inserted-updated,
+	)
+
+	return nil
 }
 
+// getAllExercises returns the default catalogue, used when no file is given.
 func getAllExercises() []data.Exercise {
 	return []data.Exercise{
 		{
-			Name:        "Push Up",
-			Description: "A basic bodyweight exercise that works the chest, shoulders, and triceps.",
-			Category:    "Strength",
-			MuscleGroup: "Chest",
+			Name:            "Push Up",
+			Description:     "A basic bodyweight exercise that works the chest, shoulders, and triceps.",
+			Category:        "Strength",
+			MuscleGroup:     "Chest",
+			MeasurementType: data.MeasurementRepsWeight,
 		},
 		{
-			Name:        "Squat",
-			Description: "A lower body exercise targeting the quads, hamstrings, and glutes.",
-			Category:    "Strength",
-			MuscleGroup: "Legs",
+			Name:            "Squat",
+			Description:     "A lower body exercise targeting the quads, hamstrings, and glutes.",
+			Category:        "Strength",
+			MuscleGroup:     "Legs",
+			MeasurementType: data.MeasurementRepsWeight,
 		},
 		{
-			Name:        "Plank",
-			Description: "An isometric core strength exercise that targets the abs and back.",
-			Category:    "Flexibility",
-			MuscleGroup: "Core",
+			Name:            "Plank",
+			Description:     "An isometric core strength exercise that targets the abs and back.",
+			Category:        "Flexibility",
+			MuscleGroup:     "Core",
+			MeasurementType: data.MeasurementTime,
 		},
 		{
-			Name:        "Running",
-			Description: "A cardiovascular exercise that improves endurance and burns calories.",
-			Category:    "Cardio",
-			MuscleGroup: "",
+			Name:            "Running",
+			Description:     "A cardiovascular exercise that improves endurance and burns calories.",
+			Category:        "Cardio",
+			MuscleGroup:     "",
+			MeasurementType: data.MeasurementDistanceTime,
 		},
 		{
-			Name:        "Deadlift",
-			Description: "A strength exercise that targets the back, glutes, and hamstrings.",
-			Category:    "Strength",
-			MuscleGroup: "Back",
+			Name:            "Deadlift",
+			Description:     "A strength exercise that targets the back, glutes, and hamstrings.",
+			Category:        "Strength",
+			MuscleGroup:     "Back",
+			MeasurementType: data.MeasurementRepsWeight,
 		},
 		{
-			Name:        "Bench Press",
-			Description: "A strength exercise that works the chest, shoulders, and triceps.",
-			Category:    "Strength",
-			MuscleGroup: "Chest",
+			Name:            "Bench Press",
+			Description:     "A strength exercise that works the chest, shoulders, and triceps.",
+			Category:        "Strength",
+			MuscleGroup:     "Chest",
+			MeasurementType: data.MeasurementRepsWeight,
 		},
 		{
-			Name:        "Pull Up",
-			Description: "A bodyweight exercise targeting the back and biceps.",
-			Category:    "Strength",
-			MuscleGroup: "Back",
+			Name:            "Pull Up",
+			Description:     "A bodyweight exercise targeting the back and biceps.",
+			Category:        "Strength",
+			MuscleGroup:     "Back",
+			MeasurementType: data.MeasurementRepsWeight,
 		},
 		{
-			Name:        "Lunges",
-			Description: "A lower body exercise targeting the quads, hamstrings, and glutes.",
-			Category:    "Strength",
-			MuscleGroup: "Legs",
+			Name:            "Lunges",
+			Description:     "A lower body exercise targeting the quads, hamstrings, and glutes.",
+			Category:        "Strength",
+			MuscleGroup:     "Legs",
+			MeasurementType: data.MeasurementRepsWeight,
 		},
 		{
-			Name:        "Bicep Curl",
-			Description: "A strength exercise that isolates the biceps.",
-			Category:    "Strength",
-			MuscleGroup: "Arms",
+			Name:            "Bicep Curl",
+			Description:     "A strength exercise that isolates the biceps.",
+			Category:        "Strength",
+			MuscleGroup:     "Arms",
+			MeasurementType: data.MeasurementRepsWeight,
 		},
 		{
-			Name:        "Tricep Dips",
-			Description: "A bodyweight exercise focusing on the triceps.",
-			Category:    "Strength",
-			MuscleGroup: "Arms",
+			Name:            "Tricep Dips",
+			Description:     "A bodyweight exercise focusing on the triceps.",
+			Category:        "Strength",
+			MuscleGroup:     "Arms",
+			MeasurementType: data.MeasurementRepsWeight,
 		},
 		{
-			Name:        "Burpees",
-			Description: "A full-body exercise combining strength and cardio.",
-			Category:    "Cardio",
-			MuscleGroup: "",
+			Name:            "Burpees",
+			Description:     "A full-body exercise combining strength and cardio.",
+			Category:        "Cardio",
+			MuscleGroup:     "",
+			MeasurementType: data.MeasurementRepsOnly,
 		},
 		{
-			Name:        "Mountain Climbers",
-			Description: "A cardio exercise that also engages the core and legs.",
-			Category:    "Cardio",
-			MuscleGroup: "Core",
+			Name:            "Mountain Climbers",
+			Description:     "A cardio exercise that also engages the core and legs.",
+			Category:        "Cardio",
+			MuscleGroup:     "Core",
+			MeasurementType: data.MeasurementRepsOnly,
 		},
 		{
-			Name:        "Shoulder Press",
-			Description: "A strength exercise for the shoulders and arms.",
-			Category:    "Strength",
-			MuscleGroup: "Shoulders",
+			Name:            "Shoulder Press",
+			Description:     "A strength exercise for the shoulders and arms.",
+			Category:        "Strength",
+			MuscleGroup:     "Shoulders",
+			MeasurementType: data.MeasurementRepsWeight,
 		},
 		{
-			Name:        "Bicycle Crunches",
-			Description: "A core exercise targeting the abs and obliques.",
-			Category:    "Flexibility",
-			MuscleGroup: "Core",
+			Name:            "Bicycle Crunches",
+			Description:     "A core exercise targeting the abs and obliques.",
+			Category:        "Flexibility",
+			MuscleGroup:     "Core",
+			MeasurementType: data.MeasurementRepsOnly,
 		},
 		{
-			Name:        "Jumping Jacks",
-			Description: "A full-body cardio exercise.",
-			Category:    "Cardio",
-			MuscleGroup: "",
+			Name:            "Jumping Jacks",
+			Description:     "A full-body cardio exercise.",
+			Category:        "Cardio",
+			MuscleGroup:     "",
+			MeasurementType: data.MeasurementRepsOnly,
 		},
 		{
-			Name:        "Calf Raises",
-			Description: "A lower-body exercise targeting the calves.",
-			Category:    "Strength",
-			MuscleGroup: "Legs",
+			Name:            "Calf Raises",
+			Description:     "A lower-body exercise targeting the calves.",
+			Category:        "Strength",
+			MuscleGroup:     "Legs",
+			MeasurementType: data.MeasurementRepsWeight,
 		},
 		{
-			Name:        "Chest Fly",
-			Description: "An exercise focusing on the chest muscles.",
-			Category:    "Strength",
-			MuscleGroup: "Chest",
+			Name:            "Chest Fly",
+			Description:     "An exercise focusing on the chest muscles.",
+			Category:        "Strength",
+			MuscleGroup:     "Chest",
+			MeasurementType: data.MeasurementRepsWeight,
 		},
 		{
-			Name:        "Leg Press",
-			Description: "A lower-body exercise targeting the quads and glutes.",
-			Category:    "Strength",
-			MuscleGroup: "Legs",
+			Name:            "Leg Press",
+			Description:     "A lower-body exercise targeting the quads and glutes.",
+			Category:        "Strength",
+			MuscleGroup:     "Legs",
+			MeasurementType: data.MeasurementRepsWeight,
 		},
 		{
-			Name:        "Russian Twists",
-			Description: "A core exercise targeting the obliques.",
-			Category:    "Flexibility",
-			MuscleGroup: "Core",
+			Name:            "Russian Twists",
+			Description:     "A core exercise targeting the obliques.",
+			Category:        "Flexibility",
+			MuscleGroup:     "Core",
+			MeasurementType: data.MeasurementRepsWeight,
 		},
 		{
-			Name:        "High Knees",
-			Description: "A cardio exercise to elevate heart rate and engage the legs.",
-			Category:    "Cardio",
-			MuscleGroup: "Legs",
+			Name:            "High Knees",
+			Description:     "A cardio exercise to elevate heart rate and engage the legs.",
+			Category:        "Cardio",
+			MuscleGroup:     "Legs",
+			MeasurementType: data.MeasurementRepsOnly,
 		},
 		{
-			Name:        "Rowing Machine",
-			Description: "A cardio exercise that engages the back, legs, and arms.",
-			Category:    "Cardio",
-			MuscleGroup: "Back",
+			Name:            "Rowing Machine",
+			Description:     "A cardio exercise that engages the back, legs, and arms.",
+			Category:        "Cardio",
+			MuscleGroup:     "Back",
+			MeasurementType: data.MeasurementDistanceTime,
 		},
 		{
-			Name:        "Side Plank",
-			Description: "A core stability exercise targeting the obliques.",
-			Category:    "Flexibility",
-			MuscleGroup: "Core",
+			Name:            "Side Plank",
+			Description:     "A core stability exercise targeting the obliques.",
+			Category:        "Flexibility",
+			MuscleGroup:     "Core",
+			MeasurementType: data.MeasurementTime,
 		},
 		{
-			Name:        "Hip Thrust",
-			Description: "A lower-body exercise focusing on the glutes.",
-			Category:    "Strength",
-			MuscleGroup: "Glutes",
+			Name:            "Hip Thrust",
+			Description:     "A lower-body exercise focusing on the glutes.",
+			Category:        "Strength",
+			MuscleGroup:     "Glutes",
+			MeasurementType: data.MeasurementRepsWeight,
 		},
 		{
-			Name:        "Farmer's Walk",
-			Description: "A strength exercise for the grip, shoulders, and core.",
-			Category:    "Strength",
-			MuscleGroup: "Full Body",
+			Name:            "Farmer's Walk",
+			Description:     "A strength exercise for the grip, shoulders, and core.",
+			Category:        "Strength",
+			MuscleGroup:     "Full Body",
+			MeasurementType: data.MeasurementRepsWeight,
 		},
 		{
-			Name:        "Jump Squats",
-			Description: "A plyometric leg exercise combining strength and cardio.",
-			Category:    "Cardio",
-			MuscleGroup: "Legs",
+			Name:            "Jump Squats",
+			Description:     "A plyometric leg exercise combining strength and cardio.",
+			Category:        "Cardio",
+			MuscleGroup:     "Legs",
+			MeasurementType: data.MeasurementRepsOnly,
 		},
 	}
 }
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sulemankhann/workout-tracker/internal/data"
+	"sulemankhann/workout-tracker/internal/validator"
+
+	"gopkg.in/yaml.v3"
+)
+
+// exerciseRecord is an exercise as written in a catalogue file. The field
+// names match the API's JSON.
+type exerciseRecord struct {
+	Name            string `json:"name" yaml:"name"`
+	Description     string `json:"description" yaml:"description"`
+	Category        string `json:"category" yaml:"category"`
+	MuscleGroup     string `json:"muscle_group" yaml:"muscle_group"`
+	MeasurementType string `json:"measurement_type" yaml:"measurement_type"`
+}
+
+// loadExercises reads a catalogue from a JSON or YAML list of exercises, or
+// from a CSV file with a header row naming its columns. The format is chosen
+// by the file's extension.
+func loadExercises(path string) ([]data.Exercise, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	var records []exerciseRecord
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		dec := json.NewDecoder(f)
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&records)
+
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(f)
+		dec.KnownFields(true)
+		err = dec.Decode(&records)
+
+	case ".csv":
+		records, err = readExerciseCSV(f)
+
+	default:
+		return nil, fmt.Errorf("%s: unsupported format, use .json, .yaml or .csv", path)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	exercises := make([]data.Exercise, len(records))
+
+	for i, record := range records {
+		exercises[i] = data.Exercise{
+			Name:            strings.TrimSpace(record.Name),
+			Description:     strings.TrimSpace(record.Description),
+			Category:        strings.TrimSpace(record.Category),
+			MuscleGroup:     strings.TrimSpace(record.MuscleGroup),
+			MeasurementType: strings.TrimSpace(record.MeasurementType),
+		}
+	}
+
+	err = validateExercises(exercises)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return exercises, nil
+}
+
+func readExerciseCSV(r io.Reader) ([]exerciseRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("missing header row")
+		}
+
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		switch name {
+		case "name", "description", "category", "muscle_group", "measurement_type":
+		default:
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+
+		columns[name] = i
+	}
+
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New(`missing "name" column`)
+	}
+
+	field := func(row []string, name string) string {
+		i, ok := columns[name]
+		if !ok {
+			return ""
+		}
+
+		return row[i]
+	}
+
+	records := []exerciseRecord{}
+
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, exerciseRecord{
+			Name:            field(row, "name"),
+			Description:     field(row, "description"),
+			Category:        field(row, "category"),
+			MuscleGroup:     field(row, "muscle_group"),
+			MeasurementType: field(row, "measurement_type"),
+		})
+	}
+
+	return records, nil
+}
+
+// validateExercises checks every exercise, so that a bad catalogue is
+// rejected before anything is written, and that no name is used twice.
+func validateExercises(exercises []data.Exercise) error {
+	if len(exercises) == 0 {
+		return errors.New("no exercises")
+	}
+
+	seen := make(map[string]int)
+
+	for i, exercise := range exercises {
+		v := validator.New()
+
+		data.ValidateExercise(v, &exercise)
+
+		if !v.Valid() {
+			problems := []string{}
+
+			for _, key := range slices.Sorted(maps.Keys(v.Errors)) {
+				problems = append(problems, key+" "+v.Errors[key])
+			}
+
+			return fmt.Errorf(
+				"exercise %d (%q): %s",
+				i+1,
+				exercise.Name,
+				strings.Join(problems, ", "),
+			)
+		}
+
+		if previous, ok := seen[exercise.Name]; ok {
+			return fmt.Errorf(
+				"exercise %d (%q): name already used by exercise %d",
+				i+1,
+				exercise.Name,
+				previous,
+			)
+		}
+
+		seen[exercise.Name] = i + 1
+	}
+
+	return nil
+}
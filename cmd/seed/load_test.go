@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sulemankhann/workout-tracker/internal/data"
+	"testing"
+)
+
+func TestLoadExercises(t *testing.T) {
+	want := []data.Exercise{
+		{
+			Name:            "Goblet Squat",
+			Description:     "A squat holding a kettlebell at the chest.",
+			Category:        "Strength",
+			MuscleGroup:     "Legs",
+			MeasurementType: data.MeasurementRepsWeight,
+		},
+		{
+			Name:            "Dead Hang",
+			Category:        "Strength",
+			MuscleGroup:     "Back",
+			MeasurementType: data.MeasurementTime,
+		},
+	}
+
+	for _, file := range []string{"exercises.json", "exercises.yaml", "exercises.csv"} {
+		t.Run(file, func(t *testing.T) {
+			got, err := loadExercises(filepath.Join("testdata", file))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(want) {
+				t.Fatalf("got %d exercises; want %d", len(got), len(want))
+			}
+
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("exercise %d = %+v; want %+v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadExercisesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "unsupported format",
+			file:    "exercises.txt",
+			content: "Squat",
+			wantErr: "unsupported format",
+		},
+		{
+			name:    "unknown JSON field",
+			file:    "exercises.json",
+			content: `[{"name": "Squat", "category": "Strength", "muscles": "Legs"}]`,
+			wantErr: "unknown field",
+		},
+		{
+			name:    "unknown CSV column",
+			file:    "exercises.csv",
+			content: "name,category,muscles\nSquat,Strength,Legs\n",
+			wantErr: `unknown column "muscles"`,
+		},
+		{
+			name:    "missing category",
+			file:    "exercises.yaml",
+			content: "- name: Squat\n",
+			wantErr: "category must be provided",
+		},
+		{
+			name:    "invalid measurement type",
+			file:    "exercises.csv",
+			content: "name,category,measurement_type\nSquat,Strength,laps\n",
+			wantErr: "measurement_type must be one of",
+		},
+		{
+			name:    "duplicate name",
+			file:    "exercises.csv",
+			content: "name,category\nSquat,Strength\nSquat,Legs\n",
+			wantErr: "name already used by exercise 1",
+		},
+		{
+			name:    "empty",
+			file:    "exercises.json",
+			content: "[]",
+			wantErr: "no exercises",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+
+			err := os.WriteFile(path, []byte(tt.content), 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = loadExercises(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v; want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefaultExercisesAreValid(t *testing.T) {
+	err := validateExercises(getAllExercises())
+	if err != nil {
+		t.Error(err)
+	}
+}
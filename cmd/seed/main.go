@@ -1,42 +1,237 @@
+// Command seed fills the database with the exercise catalogue and, for local
+// development, demo users with sample workouts. Seeding is idempotent, so it
+// can be run again after the catalogue changes.
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
 	"log"
 	"os"
+	"strings"
+	"sulemankhann/workout-tracker/internal/data"
+	"sulemankhann/workout-tracker/internal/validator"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
+const usage = `Usage: seed [-db-dsn DSN] [command] [flags]
+
+Commands:
+  exercises  add or update the exercise catalogue (the default)
+  demo       create demo users with sample workouts
+  all        seed the exercises, then the demo data
+
+Run seed COMMAND -h to list the command's flags.
+
+Flags:
+`
+
+// errUsage marks errors caused by how the command was invoked.
+var errUsage = errors.New("usage")
+
 type Seeder struct {
-	DB *sql.DB
+	Models data.Models
+	Out    io.Writer
 }
 
 func main() {
+	err := run(os.Args[1:])
+
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(0)
+
+	case errors.Is(err, errUsage):
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+
+	case err != nil:
+		log.Print(err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	// A .env file is optional; DB_DSN may come from the environment.
 	err := godotenv.Load()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+
+	dsn := flags.String("db-dsn", os.Getenv("DB_DSN"), "PostgreSQL DSN (env DB_DSN)")
+
+	err = parseFlags(flags, args)
 	if err != nil {
-		log.Fatal(err.Error())
+		return err
 	}
 
-	dbDSN, exist := os.LookupEnv("DB_DSN")
-	if !exist {
-		log.Fatal("DB DSN not set")
+	command, args := "exercises", flags.Args()
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
 	}
 
-	db, err := sql.Open("postgres", dbDSN)
+	var exercisesFile string
+	var demo demoOptions
+
+	commandFlags := flag.NewFlagSet("seed "+command, flag.ContinueOnError)
+
+	switch command {
+	case "exercises", "demo", "all":
+	default:
+		flags.Usage()
+		return fmt.Errorf("%w: unknown command %q", errUsage, command)
+	}
+
+	if command != "demo" {
+		commandFlags.StringVar(
+			&exercisesFile,
+			"file",
+			"",
+			"JSON, YAML or CSV catalogue to seed instead of the default one",
+		)
+	}
+
+	if command != "exercises" {
+		commandFlags.IntVar(&demo.users, "users", 3, "number of demo users")
+		commandFlags.IntVar(
+			&demo.workouts,
+			"workouts",
+			12,
+			"number of workouts for each demo user",
+		)
+		commandFlags.StringVar(
+			&demo.password,
+			"password",
+			"pa55word1234",
+			"password for the demo users",
+		)
+	}
+
+	err = parseFlags(commandFlags, args)
 	if err != nil {
-		log.Fatal(err.Error())
+		return err
+	}
+
+	if commandFlags.NArg() > 0 {
+		return fmt.Errorf(
+			"%w: unexpected arguments: %s",
+			errUsage,
+			strings.Join(commandFlags.Args(), " "),
+		)
+	}
+
+	// Check the input before connecting, so mistakes are reported early and
+	// nothing is written.
+	exercises := getAllExercises()
+
+	if exercisesFile != "" {
+		exercises, err = loadExercises(exercisesFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if command != "exercises" {
+		err = validateDemoOptions(demo)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *dsn == "" {
+		return fmt.Errorf("%w: -db-dsn or DB_DSN must be set", errUsage)
 	}
+
+	db, err := openDB(*dsn)
+	if err != nil {
+		return err
+	}
+
 	defer db.Close()
 
-	err = db.Ping()
+	seeder := Seeder{
+		Models: data.NewModels(db, data.DefaultQueryTimeout),
+		Out:    os.Stdout,
+	}
+
+	ctx := context.Background()
+
+	if command != "demo" {
+		err = seeder.SeedExercises(ctx, exercises)
+		if err != nil {
+			return err
+		}
+	}
+
+	if command != "exercises" {
+		err = seeder.SeedDemo(ctx, demo)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseFlags parses args, marking anything but a request for help as a
+// usage error. The flag package has already printed the problem and usage.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	err := flags.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	return err
+}
+
+func validateDemoOptions(opts demoOptions) error {
+	if opts.users < 1 {
+		return fmt.Errorf("%w: -users must be greater than zero", errUsage)
+	}
+
+	if opts.workouts < 0 {
+		return fmt.Errorf("%w: -workouts must not be negative", errUsage)
+	}
+
+	v := validator.New()
+
+	data.ValidatePasswordPlaintext(v, opts.password)
+
+	if !v.Valid() {
+		return fmt.Errorf("%w: -password %s", errUsage, v.Errors["password"])
+	}
+
+	return nil
+}
+
+func openDB(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
 	if err != nil {
-		db.Close()
-		log.Fatal(err.Error())
+		return nil, err
 	}
 
-	seeder := Seeder{DB: db}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
 
-	seeder.SeedExercises()
+	return db, nil
 }
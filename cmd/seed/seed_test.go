@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sulemankhann/workout-tracker/internal/data"
+	"testing"
+)
+
+func newTestSeeder() (Seeder, *bytes.Buffer) {
+	var out bytes.Buffer
+
+	return Seeder{Models: data.NewMockModels(), Out: &out}, &out
+}
+
+func TestSeedExercisesIsIdempotent(t *testing.T) {
+	seeder, out := newTestSeeder()
+	ctx := context.Background()
+
+	for range 2 {
+		err := seeder.SeedExercises(ctx, getAllExercises())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exercises, err := seeder.Models.Exercises.GetAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(exercises) != len(getAllExercises()) {
+		t.Errorf("%d exercises after seeding twice; want %d", len(exercises), len(getAllExercises()))
+	}
+
+	changed := getAllExercises()
+	changed[0].MuscleGroup = "Triceps"
+
+	err = seeder.SeedExercises(ctx, changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := []string{
+		"added, 0 updated, 0 unchanged.",
+		"0 added, 0 updated,",
+		"0 added, 1 updated,",
+	}
+
+	for i, line := range lines {
+		if !strings.Contains(line, want[i]) {
+			t.Errorf("run %d reported %q; want it to contain %q", i+1, line, want[i])
+		}
+	}
+}
+
+func TestSeedDemo(t *testing.T) {
+	seeder, out := newTestSeeder()
+	ctx := context.Background()
+
+	err := seeder.SeedExercises(ctx, getAllExercises())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := demoOptions{users: 2, workouts: 7, password: "pa55word1234"}
+
+	for range 2 {
+		err = seeder.SeedDemo(ctx, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	user, err := seeder.Models.Users.GetByEmail(ctx, "demo2@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := user.Password.Matches(opts.password)
+	if err != nil || !matches {
+		t.Errorf("the demo user's password doesn't match: %v", err)
+	}
+
+	workouts, err := seeder.Models.Workouts.GetAllForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(workouts) != opts.workouts {
+		t.Fatalf("demo user has %d workouts after seeding twice; want %d", len(workouts), opts.workouts)
+	}
+
+	upcoming := 0
+
+	for _, workout := range workouts {
+		if len(workout.Exercises) == 0 {
+			t.Errorf("workout %q has no exercises", workout.Title)
+		}
+
+		if workout.CompletedAt == nil {
+			upcoming++
+		}
+	}
+
+	if upcoming != 1 {
+		t.Errorf("%d upcoming workouts; want 1", upcoming)
+	}
+
+	if !strings.Contains(out.String(), "demo1@example.com already has workouts") {
+		t.Errorf("the second run didn't skip the existing users:\n%s", out.String())
+	}
+}
+
+func TestSeedDemoNeedsExercises(t *testing.T) {
+	seeder, _ := newTestSeeder()
+	ctx := context.Background()
+
+	err := seeder.SeedDemo(ctx, demoOptions{users: 1, workouts: 3, password: "pa55word1234"})
+	if err == nil || !strings.Contains(err.Error(), "seed the default exercises first") {
+		t.Errorf("err = %v", err)
+	}
+
+	_, err = seeder.Models.Users.GetByEmail(ctx, "demo1@example.com")
+	if !errors.Is(err, data.ErrRecordNotFound) {
+		t.Errorf("a demo user was created without workouts: err = %v", err)
+	}
+}
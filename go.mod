@@ -9,3 +9,5 @@ require (
 )
 
 require golang.org/x/crypto v0.32.0
+
+require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,147 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	AuditActionCreated   = "created"
+	AuditActionUpdated   = "updated"
+	AuditActionScheduled = "scheduled"
+	AuditActionCompleted = "completed"
+	AuditActionDeleted   = "deleted"
+)
+
+// WorkoutAuditEntry records a change made to a workout and who made it,
+// which is either the athlete who owns it or one of their coaches.
+type WorkoutAuditEntry struct {
+	ID        int64     `json:"id"`
+	WorkoutID int64     `json:"workout_id"`
+	UserID    int64     `json:"-"`
+	ActorID   *int64    `json:"actor_id"`
+	ActorName *string   `json:"actor_name"`
+	Action    string    `json:"action"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type auditActorContextKey struct{}
+
+// WithAuditActor returns a copy of ctx under which the workout models record
+// the changes they make in the audit trail, as made by actorID, in the same
+// transaction as the change. Without it nothing is recorded, as for imports.
+func WithAuditActor(ctx context.Context, actorID int64) context.Context {
+	return context.WithValue(ctx, auditActorContextKey{}, actorID)
+}
+
+func auditActor(ctx context.Context) (int64, bool) {
+	actorID, ok := ctx.Value(auditActorContextKey{}).(int64)
+	return actorID, ok
+}
+
+// writeWorkoutAudit records the change to workout in tx if ctx carries an
+// actor.
+func writeWorkoutAudit(
+	ctx context.Context,
+	tx *sql.Tx,
+	workout *Workout,
+	action string,
+) error {
+	actorID, ok := auditActor(ctx)
+	if !ok {
+		return nil
+	}
+
+	query := `
+        INSERT INTO workout_audit (workout_id, user_id, actor_id, action)
+        VALUES ($1, $2, $3, $4)`
+
+	_, err := tx.ExecContext(ctx, query, workout.ID, workout.UserID, actorID, action)
+
+	return err
+}
+
+type AuditModel struct {
+	DB      *sql.DB
+	Timeout time.Duration
+}
+
+func (m AuditModel) Insert(
+	ctx context.Context,
+	entry *WorkoutAuditEntry,
+) error {
+	query := `
+        INSERT INTO workout_audit (workout_id, user_id, actor_id, action)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at`
+
+	args := []any{entry.WorkoutID, entry.UserID, entry.ActorID, entry.Action}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&entry.ID,
+		&entry.CreatedAt,
+	)
+}
+
+// GetAllForWorkout returns the history of a workout owned by userID, oldest
+// first. The actor is null if their account has since been deleted.
+func (m AuditModel) GetAllForWorkout(
+	ctx context.Context,
+	workoutID, userID int64,
+) ([]*WorkoutAuditEntry, error) {
+	query := `
+        SELECT a.id, a.workout_id, a.user_id, a.actor_id, u.name, a.action, a.created_at
+        FROM workout_audit a
+        LEFT JOIN users u ON a.actor_id = u.id
+        WHERE a.workout_id = $1 AND a.user_id = $2
+        ORDER BY a.id`
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, workoutID, userID)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to execute query to fetch audit entries for workout %d: %w",
+			workoutID,
+			err,
+		)
+	}
+
+	defer rows.Close()
+
+	entries := []*WorkoutAuditEntry{}
+
+	for rows.Next() {
+		var entry WorkoutAuditEntry
+
+		err := rows.Scan(
+			&entry.ID,
+			&entry.WorkoutID,
+			&entry.UserID,
+			&entry.ActorID,
+			&entry.ActorName,
+			&entry.Action,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry row: %w", err)
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"error occurred while iterating over audit entry rows: %w",
+			err,
+		)
+	}
+
+	return entries, nil
+}
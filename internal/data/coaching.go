@@ -0,0 +1,242 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	CoachingStatusPending  = "pending"
+	CoachingStatusAccepted = "accepted"
+	CoachingStatusRevoked  = "revoked"
+)
+
+var ErrDuplicateCoachingLink = errors.New("duplicate coaching link")
+
+// CoachingLink lets a coach manage an athlete's workouts once the athlete
+// has accepted it.
+type CoachingLink struct {
+	ID          int64      `json:"id"`
+	CoachID     int64      `json:"coach_id"`
+	CoachName   string     `json:"coach_name"`
+	AthleteID   int64      `json:"athlete_id"`
+	AthleteName string     `json:"athlete_name"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	AcceptedAt  *time.Time `json:"accepted_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+}
+
+type CoachingModel struct {
+	DB      *sql.DB
+	Timeout time.Duration
+}
+
+// Invite creates a pending link from the coach to the athlete. Inviting an
+// athlete who already has an open link with the coach returns
+// ErrDuplicateCoachingLink.
+func (m CoachingModel) Invite(ctx context.Context, link *CoachingLink) error {
+	query := `
+        INSERT INTO coach_athletes (coach_id, athlete_id, status)
+        VALUES ($1, $2, $3)
+        RETURNING id, created_at`
+
+	link.Status = CoachingStatusPending
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(
+		ctx,
+		query,
+		link.CoachID,
+		link.AthleteID,
+		link.Status,
+	).Scan(&link.ID, &link.CreatedAt)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "idx_coach_athletes_open"):
+			return ErrDuplicateCoachingLink
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetAllForCoach returns the coach's open links, both pending and accepted.
+func (m CoachingModel) GetAllForCoach(
+	ctx context.Context,
+	coachID int64,
+) ([]*CoachingLink, error) {
+	return m.getAll(ctx, "ca.coach_id = $1", coachID)
+}
+
+// GetAllForAthlete returns the athlete's open links, including invitations
+// waiting for them to accept.
+func (m CoachingModel) GetAllForAthlete(
+	ctx context.Context,
+	athleteID int64,
+) ([]*CoachingLink, error) {
+	return m.getAll(ctx, "ca.athlete_id = $1", athleteID)
+}
+
+func (m CoachingModel) getAll(
+	ctx context.Context,
+	condition string,
+	userID int64,
+) ([]*CoachingLink, error) {
+	query := fmt.Sprintf(`
+        SELECT ca.id, ca.coach_id, c.name, ca.athlete_id, a.name, ca.status,
+            ca.created_at, ca.accepted_at, ca.revoked_at
+        FROM coach_athletes ca
+        JOIN users c ON ca.coach_id = c.id
+        JOIN users a ON ca.athlete_id = a.id
+        WHERE %s AND ca.status IN ('pending', 'accepted')
+        ORDER BY ca.id`, condition)
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to execute query to fetch coaching links for user %d: %w",
+			userID,
+			err,
+		)
+	}
+
+	defer rows.Close()
+
+	links := []*CoachingLink{}
+
+	for rows.Next() {
+		var link CoachingLink
+
+		err := rows.Scan(
+			&link.ID,
+			&link.CoachID,
+			&link.CoachName,
+			&link.AthleteID,
+			&link.AthleteName,
+			&link.Status,
+			&link.CreatedAt,
+			&link.AcceptedAt,
+			&link.RevokedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan coaching link row: %w", err)
+		}
+
+		links = append(links, &link)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"error occurred while iterating over coaching link rows: %w",
+			err,
+		)
+	}
+
+	return links, nil
+}
+
+// Accept grants the coach access to the athlete's workouts.
+func (m CoachingModel) Accept(
+	ctx context.Context,
+	coachID, athleteID int64,
+) error {
+	query := `
+        UPDATE coach_athletes
+        SET status = 'accepted', accepted_at = NOW()
+        WHERE coach_id = $1 AND athlete_id = $2 AND status = 'pending'`
+
+	return m.exec(ctx, query, coachID, athleteID)
+}
+
+// Revoke ends a link, whether it is still pending or has been accepted. It
+// takes effect on the coach's next request.
+func (m CoachingModel) Revoke(
+	ctx context.Context,
+	coachID, athleteID int64,
+) error {
+	query := `
+        UPDATE coach_athletes
+        SET status = 'revoked', revoked_at = NOW()
+        WHERE coach_id = $1 AND athlete_id = $2 AND status IN ('pending', 'accepted')`
+
+	return m.exec(ctx, query, coachID, athleteID)
+}
+
+func (m CoachingModel) exec(
+	ctx context.Context,
+	query string,
+	coachID, athleteID int64,
+) error {
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, coachID, athleteID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetAthlete returns the athlete if the coach has an accepted link with
+// them, and ErrRecordNotFound otherwise.
+func (m CoachingModel) GetAthlete(
+	ctx context.Context,
+	coachID, athleteID int64,
+) (*User, error) {
+	query := `
+        SELECT u.id, u.created_at, u.name, u.email, u.timezone,
+            u.weight_unit, u.distance_unit, u.plate_rounding
+        FROM users u
+        JOIN coach_athletes ca ON ca.athlete_id = u.id
+        WHERE ca.coach_id = $1
+        AND ca.athlete_id = $2
+        AND ca.status = 'accepted'`
+
+	var user User
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, coachID, athleteID).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Timezone,
+		&user.Units.Weight,
+		&user.Units.Distance,
+		&user.Units.PlateRounding,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,234 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sulemankhann/workout-tracker/internal/validator"
+	"time"
+)
+
+// Comment is a message about a workout, or about one exercise in it, from
+// the athlete or someone they have given access to. Replies point at their
+// parent comment; threads are assembled by the client.
+type Comment struct {
+	ID         int64      `json:"id"`
+	WorkoutID  int64      `json:"workout_id"`
+	ExerciseID *int64     `json:"exercise_id"`
+	ParentID   *int64     `json:"parent_id"`
+	AuthorID   int64      `json:"author_id"`
+	AuthorName string     `json:"author_name"`
+	Body       string     `json:"body"`
+	CreatedAt  time.Time  `json:"created_at"`
+	EditedAt   *time.Time `json:"edited_at"`
+	DeletedAt  *time.Time `json:"deleted_at"`
+}
+
+func ValidateComment(v *validator.Validator, comment *Comment) {
+	v.Check(comment.Body != "", "body", "must be provided")
+	v.Check(
+		len(comment.Body) <= 5000,
+		"body",
+		"must not be more than 5000 bytes long",
+	)
+}
+
+type CommentModel struct {
+	DB      *sql.DB
+	Timeout time.Duration
+}
+
+func (m CommentModel) Insert(ctx context.Context, comment *Comment) error {
+	query := `
+        INSERT INTO comments (workout_id, exercise_id, parent_id, user_id, body)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at`
+
+	args := []any{
+		comment.WorkoutID,
+		comment.ExerciseID,
+		comment.ParentID,
+		comment.AuthorID,
+		comment.Body,
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&comment.ID,
+		&comment.CreatedAt,
+	)
+}
+
+// Get returns a comment by id. Callers are responsible for checking that
+// the user may see it.
+func (m CommentModel) Get(ctx context.Context, id int64) (*Comment, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT c.id, c.workout_id, c.exercise_id, c.parent_id, c.user_id, u.name,
+            c.body, c.created_at, c.edited_at, c.deleted_at
+        FROM comments c
+        JOIN users u ON c.user_id = u.id
+        WHERE c.id = $1`
+
+	var comment Comment
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(commentDest(&comment)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &comment, nil
+}
+
+// GetAllForWorkout returns a page of the workout's comments in the order
+// they were written. If exerciseID is non-zero only comments about that
+// exercise are returned.
+func (m CommentModel) GetAllForWorkout(
+	ctx context.Context,
+	workoutID, exerciseID int64,
+	filters Filters,
+) ([]*Comment, Metadata, error) {
+	query := `
+        SELECT count(*) OVER(), c.id, c.workout_id, c.exercise_id, c.parent_id, c.user_id,
+            u.name, c.body, c.created_at, c.edited_at, c.deleted_at
+        FROM comments c
+        JOIN users u ON c.user_id = u.id
+        WHERE c.workout_id = $1
+        AND ($2::bigint = 0 OR c.exercise_id = $2)
+        ORDER BY c.created_at, c.id
+        LIMIT $3 OFFSET $4`
+
+	args := []any{workoutID, exerciseID, filters.limit(), filters.offset()}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, fmt.Errorf(
+			"failed to execute query to fetch comments for workout %d: %w",
+			workoutID,
+			err,
+		)
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	comments := []*Comment{}
+
+	for rows.Next() {
+		var comment Comment
+
+		dest := append([]any{&totalRecords}, commentDest(&comment)...)
+
+		err := rows.Scan(dest...)
+		if err != nil {
+			return nil, Metadata{}, fmt.Errorf("failed to scan comment row: %w", err)
+		}
+
+		comments = append(comments, &comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, fmt.Errorf(
+			"error occurred while iterating over comment rows: %w",
+			err,
+		)
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return comments, metadata, nil
+}
+
+// UpdateByAuthor changes the body of a comment written by userID.
+func (m CommentModel) UpdateByAuthor(
+	ctx context.Context,
+	comment *Comment,
+	userID int64,
+) error {
+	query := `
+        UPDATE comments
+        SET body = $3, edited_at = NOW()
+        WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+        RETURNING edited_at`
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, comment.ID, userID, comment.Body).
+		Scan(&comment.EditedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteByAuthor removes the text of a comment written by userID but keeps
+// the comment itself, so that replies to it stay in their thread.
+func (m CommentModel) DeleteByAuthor(
+	ctx context.Context,
+	id, userID int64,
+) error {
+	query := `
+        UPDATE comments
+        SET body = '', deleted_at = NOW()
+        WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// commentDest returns the scan destinations for the comment columns, in the
+// order they are selected.
+func commentDest(comment *Comment) []any {
+	return []any{
+		&comment.ID,
+		&comment.WorkoutID,
+		&comment.ExerciseID,
+		&comment.ParentID,
+		&comment.AuthorID,
+		&comment.AuthorName,
+		&comment.Body,
+		&comment.CreatedAt,
+		&comment.EditedAt,
+		&comment.DeletedAt,
+	}
+}
@@ -4,47 +4,186 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"slices"
+	"strings"
+	"sulemankhann/workout-tracker/internal/validator"
 	"time"
 )
 
+var ErrDuplicateExercise = errors.New("duplicate exercise")
+
+// Measurement types describe what is recorded when an exercise is performed.
+const (
+	MeasurementRepsWeight   = "reps_weight"
+	MeasurementRepsOnly     = "reps_only"
+	MeasurementTime         = "time"
+	MeasurementDistanceTime = "distance_time"
+)
+
+var MeasurementTypes = []string{
+	MeasurementRepsWeight,
+	MeasurementRepsOnly,
+	MeasurementTime,
+	MeasurementDistanceTime,
+}
+
 type Exercise struct {
-	ID          int64     `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Category    string    `json:"category"`
-	MuscleGroup string    `json:"muscle_group"`
-	CreatedAt   time.Time `json:"-"`
-	UpdatedAt   time.Time `json:"-"`
+	ID              int64     `json:"id"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description"`
+	Category        string    `json:"category"`
+	MuscleGroup     string    `json:"muscle_group"`
+	MeasurementType string    `json:"measurement_type"`
+	CreatedAt       time.Time `json:"-"`
+	UpdatedAt       time.Time `json:"-"`
+}
+
+func ValidateExercise(v *validator.Validator, exercise *Exercise) {
+	v.Check(exercise.Name != "", "name", "must be provided")
+	v.Check(
+		len(exercise.Name) <= 500,
+		"name",
+		"must not be more than 500 bytes long",
+	)
+	v.Check(exercise.Category != "", "category", "must be provided")
+
+	if exercise.MeasurementType != "" {
+		v.Check(
+			slices.Contains(MeasurementTypes, exercise.MeasurementType),
+			"measurement_type",
+			"must be one of reps_weight, reps_only, time or distance_time",
+		)
+	}
 }
 
 type ExerciseModel struct {
-	DB *sql.DB
+	DB      *sql.DB
+	Timeout time.Duration
 }
 
-func (m ExerciseModel) Insert(exercise *Exercise) error {
+// Insert adds an exercise to the catalogue. Names are unique, so inserting
+// one that is already taken returns ErrDuplicateExercise.
+func (m ExerciseModel) Insert(ctx context.Context, exercise *Exercise) error {
+	if exercise.MeasurementType == "" {
+		exercise.MeasurementType = MeasurementRepsWeight
+	}
+
 	query := `
-        INSERT INTO exercises (name, description, category, muscle_group)
-        VALUES ($1,$2,$3,$4)`
+        INSERT INTO exercises (name, description, category, muscle_group, measurement_type)
+        VALUES ($1,$2,$3,$4,$5)
+        RETURNING id, created_at, updated_at`
 
 	args := []any{
 		exercise.Name,
 		exercise.Description,
 		exercise.Category,
 		exercise.MuscleGroup,
+		exercise.MeasurementType,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&exercise.ID,
+		&exercise.CreatedAt,
+		&exercise.UpdatedAt,
+	)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "exercises_name_key"):
+			return ErrDuplicateExercise
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Upsert adds the exercises to the catalogue, or updates the existing
+// exercises with the same names, in a single transaction. Exercises that
+// are already up to date are left alone. ID, CreatedAt and UpdatedAt are set
+// on the exercises inserted or updated.
+func (m ExerciseModel) Upsert(
+	ctx context.Context,
+	exercises ...*Exercise,
+) (inserted, updated int, err error) {
+	query := `
+        INSERT INTO exercises (name, description, category, muscle_group, measurement_type)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (name) DO UPDATE
+        SET description = EXCLUDED.description,
+            category = EXCLUDED.category,
+            muscle_group = EXCLUDED.muscle_group,
+            measurement_type = EXCLUDED.measurement_type,
+            updated_at = NOW()
+        WHERE (exercises.description, exercises.category, exercises.muscle_group,
+                exercises.measurement_type)
+            IS DISTINCT FROM (EXCLUDED.description, EXCLUDED.category,
+                EXCLUDED.muscle_group, EXCLUDED.measurement_type)
+        RETURNING id, created_at, updated_at, xmax = 0`
+
+	// Allow extra time for large catalogues, as imports do for workouts.
+	ctx, cancel := withQueryTimeout(
+		ctx,
+		m.Timeout+time.Duration(len(exercises))*10*time.Millisecond,
+	)
 	defer cancel()
 
-	_, err := m.DB.ExecContext(ctx, query, args...)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	defer tx.Rollback()
+
+	for _, exercise := range exercises {
+		if exercise.MeasurementType == "" {
+			exercise.MeasurementType = MeasurementRepsWeight
+		}
+
+		args := []any{
+			exercise.Name,
+			exercise.Description,
+			exercise.Category,
+			exercise.MuscleGroup,
+			exercise.MeasurementType,
+		}
+
+		var isInsert bool
+
+		err := tx.QueryRowContext(ctx, query, args...).Scan(
+			&exercise.ID,
+			&exercise.CreatedAt,
+			&exercise.UpdatedAt,
+			&isInsert,
+		)
+
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			// Unchanged, so the WHERE clause skipped the update.
+		case err != nil:
+			return 0, 0, err
+		case isInsert:
+			inserted++
+		default:
+			updated++
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, 0, err
+	}
 
-	return err
+	return inserted, updated, nil
 }
 
-func (m ExerciseModel) GetAll() ([]*Exercise, error) {
-	query := `SELECT id, name, description, category, muscle_group, created_at, updated_at from exercises`
+func (m ExerciseModel) GetAll(ctx context.Context) ([]*Exercise, error) {
+	query := `SELECT id, name, description, category, muscle_group, measurement_type, created_at, updated_at from exercises`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	rows, err := m.DB.QueryContext(ctx, query)
@@ -65,6 +204,7 @@ func (m ExerciseModel) GetAll() ([]*Exercise, error) {
 			&exercise.Description,
 			&exercise.Category,
 			&exercise.MuscleGroup,
+			&exercise.MeasurementType,
 			&exercise.CreatedAt,
 			&exercise.UpdatedAt,
 		)
@@ -83,19 +223,19 @@ func (m ExerciseModel) GetAll() ([]*Exercise, error) {
 	return exercises, nil
 }
 
-func (m ExerciseModel) Get(id int64) (*Exercise, error) {
+func (m ExerciseModel) Get(ctx context.Context, id int64) (*Exercise, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
 	query := `
-        SELECT id, name, description, category, muscle_group, created_at, updated_at
+        SELECT id, name, description, category, muscle_group, measurement_type, created_at, updated_at
         FROM exercises
         WHERE id = $1`
 
 	var exercise Exercise
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, id).Scan(
@@ -104,6 +244,7 @@ func (m ExerciseModel) Get(id int64) (*Exercise, error) {
 		&exercise.Description,
 		&exercise.Category,
 		&exercise.MuscleGroup,
+		&exercise.MeasurementType,
 		&exercise.CreatedAt,
 		&exercise.UpdatedAt,
 	)
@@ -0,0 +1,207 @@
+package data
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// workoutVisibleToViewer is the SQL condition deciding whether the workout w,
+// owned by the user u, may be seen by the viewer whose id is $1. Every query
+// returning other users' workouts must include it.
+const workoutVisibleToViewer = `
+        w.completed_at IS NOT NULL
+        AND (
+            w.user_id = $1
+            OR u.privacy = 'public'
+            OR (u.privacy = 'followers' AND EXISTS (
+                SELECT 1 FROM follows f
+                WHERE f.follower_id = $1
+                AND f.followee_id = w.user_id
+                AND f.status = 'accepted'
+            ))
+        )`
+
+// FeedCursor marks a position in a list of completed workouts ordered newest
+// first. It is handed to clients as an opaque string.
+type FeedCursor struct {
+	CompletedAt time.Time
+	ID          int64
+}
+
+func (c FeedCursor) String() string {
+	raw := c.CompletedAt.UTC().Format(time.RFC3339Nano) + "|" +
+		strconv.FormatInt(c.ID, 10)
+
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func ParseFeedCursor(s string) (*FeedCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	completedAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor FeedCursor
+
+	cursor.CompletedAt, err = time.Parse(time.RFC3339Nano, completedAt)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor.ID, err = strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+type FeedAuthor struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type FeedEntry struct {
+	Author  FeedAuthor `json:"author"`
+	Workout *Workout   `json:"workout"`
+}
+
+// GetFeedForViewer returns up to limit of the most recently completed
+// workouts by users the viewer follows, starting after the cursor if one is
+// given. The returned cursor is nil when there are no more entries.
+func (m WorkoutModel) GetFeedForViewer(
+	ctx context.Context,
+	viewerID int64,
+	after *FeedCursor,
+	limit int,
+) ([]*FeedEntry, *FeedCursor, error) {
+	return m.getVisiblePage(ctx, viewerID, 0, true, after, limit)
+}
+
+// GetVisibleForViewer returns a page of the owner's completed workouts that
+// the viewer is allowed to see, newest first.
+func (m WorkoutModel) GetVisibleForViewer(
+	ctx context.Context,
+	ownerID, viewerID int64,
+	after *FeedCursor,
+	limit int,
+) ([]*FeedEntry, *FeedCursor, error) {
+	return m.getVisiblePage(ctx, viewerID, ownerID, false, after, limit)
+}
+
+// getVisiblePage runs the paged query shared by the viewer-aware methods,
+// optionally restricted to a single owner or to users the viewer follows.
+func (m WorkoutModel) getVisiblePage(
+	ctx context.Context,
+	viewerID, ownerID int64,
+	followedOnly bool,
+	after *FeedCursor,
+	limit int,
+) ([]*FeedEntry, *FeedCursor, error) {
+	query := `
+        SELECT w.id, w.user_id, u.name, w.title, w.description, w.scheduled_at,
+            w.completed_at, w.created_at, w.updated_at
+        FROM workouts w
+        JOIN users u ON w.user_id = u.id
+        WHERE ` + workoutVisibleToViewer + `
+        AND ($2::bigint = 0 OR w.user_id = $2)
+        AND (NOT $6 OR EXISTS (
+            SELECT 1 FROM follows f
+            WHERE f.follower_id = $1
+            AND f.followee_id = w.user_id
+            AND f.status = 'accepted'
+        ))
+        AND ($3::timestamptz IS NULL OR (w.completed_at, w.id) < ($3, $4))
+        ORDER BY w.completed_at DESC, w.id DESC
+        LIMIT $5`
+
+	var afterTime *time.Time
+	var afterID int64
+
+	if after != nil {
+		afterTime = &after.CompletedAt
+		afterID = after.ID
+	}
+
+	// Fetch one extra row to find out whether there is another page.
+	args := []any{viewerID, ownerID, afterTime, afterID, limit + 1, followedOnly}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf(
+			"failed to execute query to fetch workouts visible to user %d: %w",
+			viewerID,
+			err,
+		)
+	}
+
+	defer rows.Close()
+
+	entries := []*FeedEntry{}
+	workouts := []*Workout{}
+
+	for rows.Next() {
+		var workout Workout
+		var entry FeedEntry
+
+		err := rows.Scan(
+			&workout.ID,
+			&workout.UserID,
+			&entry.Author.Name,
+			&workout.Title,
+			&workout.Description,
+			&workout.ScheduledAt,
+			&workout.CompletedAt,
+			&workout.CreatedAt,
+			&workout.UpdatedAt,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan workout row: %w", err)
+		}
+
+		entry.Author.ID = workout.UserID
+		entry.Workout = &workout
+
+		entries = append(entries, &entry)
+		workouts = append(workouts, &workout)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf(
+			"error occurred while iterating over workout rows: %w",
+			err,
+		)
+	}
+
+	var next *FeedCursor
+
+	if len(entries) > limit {
+		entries = entries[:limit]
+		workouts = workouts[:limit]
+
+		last := workouts[limit-1]
+		next = &FeedCursor{CompletedAt: *last.CompletedAt, ID: last.ID}
+	}
+
+	err = m.attachExercises(ctx, workouts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return entries, next, nil
+}
@@ -0,0 +1,215 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Privacy settings control who can see a user's completed workouts: anyone,
+// only followers they have approved, or nobody but themselves.
+const (
+	PrivacyPublic    = "public"
+	PrivacyFollowers = "followers"
+	PrivacyPrivate   = "private"
+)
+
+var Privacies = []string{PrivacyPublic, PrivacyFollowers, PrivacyPrivate}
+
+const (
+	FollowStatusPending  = "pending"
+	FollowStatusAccepted = "accepted"
+)
+
+// Follow describes one side of a follow relationship: the other user and
+// whether the follow has been accepted yet.
+type Follow struct {
+	UserID     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+}
+
+type FollowModel struct {
+	DB      *sql.DB
+	Timeout time.Duration
+}
+
+// Follow makes followerID follow followeeID. The follow is accepted at once
+// if the followee is public and otherwise waits for their approval.
+// Following someone already followed returns the existing follow unchanged.
+func (m FollowModel) Follow(
+	ctx context.Context,
+	followerID, followeeID int64,
+) (*Follow, error) {
+	query := `
+        WITH follow AS (
+            INSERT INTO follows (follower_id, followee_id, status, accepted_at)
+            SELECT $1, u.id,
+                CASE WHEN u.privacy = 'public' THEN 'accepted' ELSE 'pending' END,
+                CASE WHEN u.privacy = 'public' THEN NOW() END
+            FROM users u
+            WHERE u.id = $2
+            ON CONFLICT (follower_id, followee_id) DO UPDATE SET status = follows.status
+            RETURNING followee_id, status, created_at, accepted_at
+        )
+        SELECT u.id, u.name, f.status, f.created_at, f.accepted_at
+        FROM follow f
+        JOIN users u ON f.followee_id = u.id`
+
+	follow := Follow{}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, followerID, followeeID).Scan(
+		&follow.UserID,
+		&follow.Name,
+		&follow.Status,
+		&follow.CreatedAt,
+		&follow.AcceptedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &follow, nil
+}
+
+// Unfollow removes a follow or a pending follow request. It is used both by
+// followers leaving and by followees removing or declining a follower.
+func (m FollowModel) Unfollow(
+	ctx context.Context,
+	followerID, followeeID int64,
+) error {
+	query := `
+        DELETE FROM follows
+        WHERE follower_id = $1 AND followee_id = $2`
+
+	return m.exec(ctx, query, followerID, followeeID)
+}
+
+// Approve accepts a pending follow request made to followeeID.
+func (m FollowModel) Approve(
+	ctx context.Context,
+	followerID, followeeID int64,
+) error {
+	query := `
+        UPDATE follows
+        SET status = 'accepted', accepted_at = NOW()
+        WHERE follower_id = $1 AND followee_id = $2 AND status = 'pending'`
+
+	return m.exec(ctx, query, followerID, followeeID)
+}
+
+func (m FollowModel) exec(
+	ctx context.Context,
+	query string,
+	followerID, followeeID int64,
+) error {
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, followerID, followeeID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetFollowing returns the users userID follows or has asked to follow.
+func (m FollowModel) GetFollowing(
+	ctx context.Context,
+	userID int64,
+) ([]*Follow, error) {
+	query := `
+        SELECT u.id, u.name, f.status, f.created_at, f.accepted_at
+        FROM follows f
+        JOIN users u ON f.followee_id = u.id
+        WHERE f.follower_id = $1
+        ORDER BY f.created_at, u.id`
+
+	return m.list(ctx, query, userID)
+}
+
+// GetFollowers returns the users following userID, including requests
+// waiting for their approval.
+func (m FollowModel) GetFollowers(
+	ctx context.Context,
+	userID int64,
+) ([]*Follow, error) {
+	query := `
+        SELECT u.id, u.name, f.status, f.created_at, f.accepted_at
+        FROM follows f
+        JOIN users u ON f.follower_id = u.id
+        WHERE f.followee_id = $1
+        ORDER BY f.created_at, u.id`
+
+	return m.list(ctx, query, userID)
+}
+
+func (m FollowModel) list(
+	ctx context.Context,
+	query string,
+	userID int64,
+) ([]*Follow, error) {
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to execute query to fetch follows for user %d: %w",
+			userID,
+			err,
+		)
+	}
+
+	defer rows.Close()
+
+	follows := []*Follow{}
+
+	for rows.Next() {
+		var follow Follow
+
+		err := rows.Scan(
+			&follow.UserID,
+			&follow.Name,
+			&follow.Status,
+			&follow.CreatedAt,
+			&follow.AcceptedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan follow row: %w", err)
+		}
+
+		follows = append(follows, &follow)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"error occurred while iterating over follow rows: %w",
+			err,
+		)
+	}
+
+	return follows, nil
+}
@@ -0,0 +1,278 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sulemankhann/workout-tracker/internal/events"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+type ImportJob struct {
+	ID                int64    `json:"id"`
+	UserID            int64    `json:"-"`
+	Source            string   `json:"source"`
+	Status            string   `json:"status"`
+	WorkoutsFound     int      `json:"workouts_found"`
+	WorkoutsImported  int      `json:"workouts_imported"`
+	UnmappedExercises []string `json:"unmapped_exercises"`
+	// SkippedWorkouts describes each workout in the file that was left out
+	// because it failed validation, and why.
+	SkippedWorkouts []string   `json:"skipped_workouts"`
+	Error           string     `json:"error,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	FinishedAt      *time.Time `json:"finished_at"`
+}
+
+type ImportJobModel struct {
+	DB      *sql.DB
+	Timeout time.Duration
+}
+
+func (m ImportJobModel) Insert(ctx context.Context, job *ImportJob) error {
+	query := `
+        INSERT INTO import_jobs (user_id, source, status, workouts_found)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at, updated_at`
+
+	args := []any{job.UserID, job.Source, job.Status, job.WorkoutsFound}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&job.ID,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+	)
+}
+
+func (m ImportJobModel) GetByUser(
+	ctx context.Context,
+	id, userID int64,
+) (*ImportJob, error) {
+	if id < 1 || userID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT id, user_id, source, status, workouts_found, workouts_imported,
+            unmapped_exercises, skipped_workouts, error, created_at, updated_at,
+            finished_at
+        FROM import_jobs
+        WHERE id = $1 AND user_id = $2`
+
+	var job ImportJob
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(
+		&job.ID,
+		&job.UserID,
+		&job.Source,
+		&job.Status,
+		&job.WorkoutsFound,
+		&job.WorkoutsImported,
+		pq.Array(&job.UnmappedExercises),
+		pq.Array(&job.SkippedWorkouts),
+		&job.Error,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.FinishedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &job, nil
+}
+
+func (m ImportJobModel) Update(ctx context.Context, job *ImportJob) error {
+	if job.UnmappedExercises == nil {
+		job.UnmappedExercises = []string{}
+	}
+
+	if job.SkippedWorkouts == nil {
+		job.SkippedWorkouts = []string{}
+	}
+
+	query := `
+        UPDATE import_jobs
+        SET status = $2, workouts_imported = $3, unmapped_exercises = $4,
+            skipped_workouts = $5, error = $6, finished_at = $7, updated_at = NOW()
+        WHERE id = $1
+        RETURNING updated_at`
+
+	args := []any{
+		job.ID,
+		job.Status,
+		job.WorkoutsImported,
+		pq.Array(job.UnmappedExercises),
+		pq.Array(job.SkippedWorkouts),
+		job.Error,
+		job.FinishedAt,
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&job.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Complete creates the job's workouts and marks it completed in a single
+// transaction, so that either every valid workout in the file is imported or
+// none of them are.
+// One import.completed event describes the import; the workouts don't get
+// workout.created events of their own, since an import can hold years of
+// history that subscribers would otherwise receive a workout at a time.
+func (m ImportJobModel) Complete(
+	ctx context.Context,
+	job *ImportJob,
+	workouts []*Workout,
+) error {
+	if job.UnmappedExercises == nil {
+		job.UnmappedExercises = []string{}
+	}
+
+	if job.SkippedWorkouts == nil {
+		job.SkippedWorkouts = []string{}
+	}
+
+	// Allow extra time for large imports, as CreateWorkoutWithExercises does.
+	ctx, cancel := withQueryTimeout(
+		ctx,
+		m.Timeout+time.Duration(len(workouts))*10*time.Millisecond,
+	)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	for _, workout := range workouts {
+		err = insertWorkoutWithExercises(ctx, tx, workout)
+		if err != nil {
+			return err
+		}
+	}
+
+	query := `
+        UPDATE import_jobs
+        SET status = $2, workouts_imported = $3, unmapped_exercises = $4,
+            skipped_workouts = $5, error = '', finished_at = NOW(), updated_at = NOW()
+        WHERE id = $1
+        RETURNING finished_at, updated_at`
+
+	args := []any{
+		job.ID,
+		ImportStatusCompleted,
+		len(workouts),
+		pq.Array(job.UnmappedExercises),
+		pq.Array(job.SkippedWorkouts),
+	}
+
+	var finishedAt, updatedAt time.Time
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&finishedAt, &updatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	completed := *job
+	completed.Status = ImportStatusCompleted
+	completed.WorkoutsImported = len(workouts)
+	completed.Error = ""
+	completed.FinishedAt = &finishedAt
+	completed.UpdatedAt = updatedAt
+
+	event, err := newImportEvent(&completed)
+	if err != nil {
+		return err
+	}
+
+	err = insertOutboxEvent(ctx, tx, event)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	*job = completed
+
+	return nil
+}
+
+// newImportEvent builds the import.completed event for a finished job.
+func newImportEvent(job *ImportJob) (*events.Event, error) {
+	return newEvent(EventImportCompleted, job.UserID, map[string]any{"import": job})
+}
+
+// FailStale marks the jobs that have been pending or running for longer than
+// olderThan as failed. Jobs run in the background of the instance that
+// accepted them, so a crash or a shutdown that times out leaves them behind.
+// It returns the number of jobs marked.
+func (m ImportJobModel) FailStale(
+	ctx context.Context,
+	olderThan time.Duration,
+	reason string,
+) (int64, error) {
+	query := `
+        UPDATE import_jobs
+        SET status = $1, error = $2, finished_at = NOW(), updated_at = NOW()
+        WHERE status IN ($3, $4) AND updated_at < NOW() - $5 * interval '1 second'`
+
+	args := []any{
+		ImportStatusFailed,
+		reason,
+		ImportStatusPending,
+		ImportStatusRunning,
+		olderThan.Seconds(),
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,203 @@
+//go:build integration
+
+package data
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestImportJobModel(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	bob := insertTestUser(t, models, "Bob")
+
+	job := &ImportJob{
+		UserID:        alice.ID,
+		Source:        "strong",
+		Status:        ImportStatusPending,
+		WorkoutsFound: 12,
+	}
+
+	err := models.Imports.Insert(ctx, job)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := models.Imports.GetByUser(ctx, job.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Status != ImportStatusPending || got.WorkoutsFound != 12 ||
+		len(got.UnmappedExercises) != 0 || len(got.SkippedWorkouts) != 0 ||
+		got.FinishedAt != nil {
+		t.Errorf("new job = %+v", got)
+	}
+
+	_, err = models.Imports.GetByUser(ctx, job.ID, bob.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("GetByUser of someone else's job: err = %v", err)
+	}
+
+	job.Status = ImportStatusCompleted
+	job.WorkoutsImported = 11
+	job.UnmappedExercises = []string{"Jefferson curl", "Zercher squat"}
+	job.SkippedWorkouts = []string{`"Legs" on 2024-05-01: title must be provided`}
+	job.FinishedAt = ago(0)
+
+	err = models.Imports.Update(ctx, job)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = models.Imports.GetByUser(ctx, job.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Status != ImportStatusCompleted || got.WorkoutsImported != 11 ||
+		!slices.Equal(got.UnmappedExercises, job.UnmappedExercises) ||
+		!slices.Equal(got.SkippedWorkouts, job.SkippedWorkouts) ||
+		got.FinishedAt == nil || !got.FinishedAt.Equal(*job.FinishedAt) {
+		t.Errorf("updated job = %+v; want %+v", got, job)
+	}
+
+	err = models.Imports.Update(ctx, &ImportJob{ID: job.ID + 100})
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Update of a missing job: err = %v", err)
+	}
+
+	stale := &ImportJob{UserID: alice.ID, Source: "hevy", Status: ImportStatusRunning}
+	recent := &ImportJob{UserID: bob.ID, Source: "hevy", Status: ImportStatusPending}
+
+	for _, job := range []*ImportJob{stale, recent} {
+		err = models.Imports.Insert(ctx, job)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = testDB.ExecContext(
+		ctx,
+		"UPDATE import_jobs SET updated_at = NOW() - interval '1 hour' WHERE id IN ($1, $2)",
+		job.ID,
+		stale.ID,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed, err := models.Imports.FailStale(ctx, 10*time.Minute, "interrupted")
+	if err != nil || failed != 1 {
+		t.Fatalf("FailStale = %d, %v; want 1", failed, err)
+	}
+
+	got, err = models.Imports.GetByUser(ctx, stale.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Status != ImportStatusFailed || got.Error != "interrupted" || got.FinishedAt == nil {
+		t.Errorf("stale job = %+v; want failed", got)
+	}
+
+	// Finished jobs and those still within the timeout are left alone.
+	for _, want := range []*ImportJob{job, recent} {
+		got, err = models.Imports.GetByUser(ctx, want.ID, want.UserID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Status != want.Status {
+			t.Errorf("job %d status = %q; want %q", want.ID, got.Status, want.Status)
+		}
+	}
+}
+
+func TestImportJobModelComplete(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	squat := insertTestExercise(t, models, "Squat", MeasurementRepsWeight)
+
+	job := &ImportJob{UserID: alice.ID, Source: "strong", Status: ImportStatusRunning}
+
+	err := models.Imports.Insert(ctx, job)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newWorkouts := func(exerciseID int64) []*Workout {
+		completedAt := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+
+		return []*Workout{
+			{
+				UserID:      alice.ID,
+				Title:       "Legs",
+				ScheduledAt: completedAt,
+				CompletedAt: &completedAt,
+				Exercises: []WorkoutExercise{
+					{ExerciseID: exerciseID, Sets: 3, Repetitions: 5, Weight: 100},
+				},
+			},
+		}
+	}
+
+	// Nothing is written if one of the workouts can't be created.
+	err = models.Imports.Complete(ctx, job, newWorkouts(squat.ID+100))
+	if err == nil {
+		t.Fatal("completing an import with a missing exercise succeeded")
+	}
+
+	if job.Status != ImportStatusRunning {
+		t.Errorf("status after a failed Complete = %q; want it unchanged", job.Status)
+	}
+
+	if n := countRows(t, "workouts", "user_id = $1", alice.ID); n != 0 {
+		t.Errorf("got %d workouts after a failed Complete; want 0", n)
+	}
+
+	job.UnmappedExercises = []string{"Hip Thrust"}
+	job.SkippedWorkouts = []string{
+		`"Legs" on 2024-05-02, Squat: repetitions must be greater than zero`,
+	}
+
+	err = models.Imports.Complete(ctx, job, newWorkouts(squat.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := models.Imports.GetByUser(ctx, job.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Status != ImportStatusCompleted || got.WorkoutsImported != 1 ||
+		!slices.Equal(got.UnmappedExercises, job.UnmappedExercises) ||
+		!slices.Equal(got.SkippedWorkouts, job.SkippedWorkouts) || got.FinishedAt == nil {
+		t.Errorf("completed job = %+v", got)
+	}
+
+	if n := countRows(t, "workouts", "user_id = $1", alice.ID); n != 1 {
+		t.Errorf("got %d workouts; want 1", n)
+	}
+
+	countEvents := func(event string) int {
+		return countRows(t, "outbox", "user_id = $1 AND event = $2", alice.ID, event)
+	}
+
+	if n := countEvents(EventImportCompleted); n != 1 {
+		t.Errorf("got %d import.completed events; want 1", n)
+	}
+
+	if n := countEvents(EventWorkoutCreated); n != 0 {
+		t.Errorf("got %d workout.created events for an import; want 0", n)
+	}
+}
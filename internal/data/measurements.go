@@ -0,0 +1,479 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sulemankhann/workout-tracker/internal/validator"
+	"time"
+)
+
+// MeasurementMetrics lists the values that can be charted with
+// CalculateTrend.
+var MeasurementMetrics = []string{
+	"bodyweight",
+	"body_fat_percentage",
+	"neck",
+	"chest",
+	"waist",
+	"hips",
+	"arm",
+	"thigh",
+	"calf",
+}
+
+// Circumferences are stored in centimetres.
+type Circumferences struct {
+	Neck  *float64 `json:"neck"`
+	Chest *float64 `json:"chest"`
+	Waist *float64 `json:"waist"`
+	Hips  *float64 `json:"hips"`
+	Arm   *float64 `json:"arm"`
+	Thigh *float64 `json:"thigh"`
+	Calf  *float64 `json:"calf"`
+}
+
+type Measurement struct {
+	ID                int64          `json:"id"`
+	UserID            int64          `json:"-"`
+	MeasuredAt        time.Time      `json:"measured_at"`
+	Bodyweight        *float64       `json:"bodyweight"` // kilograms
+	BodyFatPercentage *float64       `json:"body_fat_percentage"`
+	Circumferences    Circumferences `json:"circumferences"`
+	Notes             string         `json:"notes"`
+	WeightUnit        string         `json:"weight_unit"`
+	LengthUnit        string         `json:"length_unit"`
+	CreatedAt         time.Time      `json:"-"`
+	UpdatedAt         time.Time      `json:"-"`
+}
+
+// InUnits returns a copy of the measurement with bodyweight and
+// circumferences converted from kilograms and centimetres into the given
+// units for presentation.
+func (m *Measurement) InUnits(units Units) *Measurement {
+	converted := *m
+
+	converted.Bodyweight = units.DisplayBodyweight(m.Bodyweight)
+	converted.WeightUnit = units.Weight
+	converted.LengthUnit = units.Length()
+
+	c := &converted.Circumferences
+	c.Neck = units.DisplayLength(m.Circumferences.Neck)
+	c.Chest = units.DisplayLength(m.Circumferences.Chest)
+	c.Waist = units.DisplayLength(m.Circumferences.Waist)
+	c.Hips = units.DisplayLength(m.Circumferences.Hips)
+	c.Arm = units.DisplayLength(m.Circumferences.Arm)
+	c.Thigh = units.DisplayLength(m.Circumferences.Thigh)
+	c.Calf = units.DisplayLength(m.Circumferences.Calf)
+
+	return &converted
+}
+
+// MeasurementsInUnits applies InUnits to every measurement in the slice.
+func MeasurementsInUnits(measurements []*Measurement, units Units) []*Measurement {
+	converted := make([]*Measurement, len(measurements))
+
+	for i, measurement := range measurements {
+		converted[i] = measurement.InUnits(units)
+	}
+
+	return converted
+}
+
+// Metric returns the value of the named metric, or nil if it was not
+// recorded in this entry.
+func (m *Measurement) Metric(name string) *float64 {
+	switch name {
+	case "bodyweight":
+		return m.Bodyweight
+	case "body_fat_percentage":
+		return m.BodyFatPercentage
+	case "neck":
+		return m.Circumferences.Neck
+	case "chest":
+		return m.Circumferences.Chest
+	case "waist":
+		return m.Circumferences.Waist
+	case "hips":
+		return m.Circumferences.Hips
+	case "arm":
+		return m.Circumferences.Arm
+	case "thigh":
+		return m.Circumferences.Thigh
+	case "calf":
+		return m.Circumferences.Calf
+	default:
+		return nil
+	}
+}
+
+type MeasurementModel struct {
+	DB      *sql.DB
+	Timeout time.Duration
+}
+
+func (m MeasurementModel) Insert(
+	ctx context.Context,
+	measurement *Measurement,
+) error {
+	query := `
+        INSERT INTO measurements (user_id, measured_at, bodyweight, body_fat_percentage,
+            neck, chest, waist, hips, arm, thigh, calf, notes)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+        RETURNING id, created_at, updated_at`
+
+	args := []any{
+		measurement.UserID,
+		measurement.MeasuredAt,
+		measurement.Bodyweight,
+		measurement.BodyFatPercentage,
+		measurement.Circumferences.Neck,
+		measurement.Circumferences.Chest,
+		measurement.Circumferences.Waist,
+		measurement.Circumferences.Hips,
+		measurement.Circumferences.Arm,
+		measurement.Circumferences.Thigh,
+		measurement.Circumferences.Calf,
+		measurement.Notes,
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&measurement.ID,
+		&measurement.CreatedAt,
+		&measurement.UpdatedAt,
+	)
+}
+
+func (m MeasurementModel) GetByUser(
+	ctx context.Context,
+	id, userID int64,
+) (*Measurement, error) {
+	if id < 1 || userID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT id, user_id, measured_at, bodyweight, body_fat_percentage,
+            neck, chest, waist, hips, arm, thigh, calf, notes, created_at, updated_at
+        FROM measurements
+        WHERE id = $1 AND user_id = $2`
+
+	var measurement Measurement
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(
+		measurementDest(&measurement)...,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &measurement, nil
+}
+
+// GetAllForUser returns the user's measurements taken between from and to
+// (inclusive) in chronological order. A zero from or to leaves that end of
+// the range open.
+func (m MeasurementModel) GetAllForUser(
+	ctx context.Context,
+	userID int64,
+	from, to time.Time,
+) ([]*Measurement, error) {
+	query := `
+        SELECT id, user_id, measured_at, bodyweight, body_fat_percentage,
+            neck, chest, waist, hips, arm, thigh, calf, notes, created_at, updated_at
+        FROM measurements
+        WHERE user_id = $1
+        AND ($2::timestamptz IS NULL OR measured_at >= $2)
+        AND ($3::timestamptz IS NULL OR measured_at <= $3)
+        ORDER BY measured_at, id`
+
+	args := []any{userID, nullTime(from), nullTime(to)}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to execute query to fetch measurements for user %d: %w",
+			userID,
+			err,
+		)
+	}
+
+	defer rows.Close()
+
+	measurements := []*Measurement{}
+
+	for rows.Next() {
+		var measurement Measurement
+
+		err := rows.Scan(measurementDest(&measurement)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan measurement row: %w", err)
+		}
+
+		measurements = append(measurements, &measurement)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"error occurred while iterating over measurement rows: %w",
+			err,
+		)
+	}
+
+	return measurements, nil
+}
+
+func (m MeasurementModel) Update(
+	ctx context.Context,
+	measurement *Measurement,
+) error {
+	query := `
+        UPDATE measurements
+        SET measured_at = $3, bodyweight = $4, body_fat_percentage = $5,
+            neck = $6, chest = $7, waist = $8, hips = $9, arm = $10,
+            thigh = $11, calf = $12, notes = $13, updated_at = NOW()
+        WHERE id = $1 AND user_id = $2
+        RETURNING updated_at`
+
+	args := []any{
+		measurement.ID,
+		measurement.UserID,
+		measurement.MeasuredAt,
+		measurement.Bodyweight,
+		measurement.BodyFatPercentage,
+		measurement.Circumferences.Neck,
+		measurement.Circumferences.Chest,
+		measurement.Circumferences.Waist,
+		measurement.Circumferences.Hips,
+		measurement.Circumferences.Arm,
+		measurement.Circumferences.Thigh,
+		measurement.Circumferences.Calf,
+		measurement.Notes,
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&measurement.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m MeasurementModel) DeleteByUser(
+	ctx context.Context,
+	id, userID int64,
+) error {
+	if id < 1 || userID < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+        DELETE FROM measurements
+        WHERE id = $1
+        AND user_id = $2`
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func measurementDest(measurement *Measurement) []any {
+	return []any{
+		&measurement.ID,
+		&measurement.UserID,
+		&measurement.MeasuredAt,
+		&measurement.Bodyweight,
+		&measurement.BodyFatPercentage,
+		&measurement.Circumferences.Neck,
+		&measurement.Circumferences.Chest,
+		&measurement.Circumferences.Waist,
+		&measurement.Circumferences.Hips,
+		&measurement.Circumferences.Arm,
+		&measurement.Circumferences.Thigh,
+		&measurement.Circumferences.Calf,
+		&measurement.Notes,
+		&measurement.CreatedAt,
+		&measurement.UpdatedAt,
+	}
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+type TrendPoint struct {
+	MeasuredAt    time.Time `json:"measured_at"`
+	Value         float64   `json:"value"`
+	MovingAverage float64   `json:"moving_average"`
+}
+
+// CalculateTrend extracts the named metric from a chronologically ordered
+// slice of measurements and pairs each value with the mean of all values
+// recorded in the preceding windowDays days (including itself). Entries that
+// did not record the metric are skipped.
+func CalculateTrend(
+	measurements []*Measurement,
+	metric string,
+	windowDays int,
+) []TrendPoint {
+	points := []TrendPoint{}
+	window := time.Duration(windowDays) * 24 * time.Hour
+
+	start := 0
+	sum := 0.0
+
+	for _, measurement := range measurements {
+		value := measurement.Metric(metric)
+		if value == nil {
+			continue
+		}
+
+		points = append(points, TrendPoint{
+			MeasuredAt: measurement.MeasuredAt,
+			Value:      *value,
+		})
+		sum += *value
+
+		current := len(points) - 1
+		for points[current].MeasuredAt.Sub(points[start].MeasuredAt) >= window {
+			sum -= points[start].Value
+			start++
+		}
+
+		points[current].MovingAverage = round(sum/float64(current-start+1), 2)
+	}
+
+	return points
+}
+
+// NearestBodyweight returns the bodyweight recorded closest in time to at,
+// looking both backwards and forwards. measurements must be in chronological
+// order. It returns nil if no entry recorded a bodyweight.
+func NearestBodyweight(measurements []*Measurement, at time.Time) *float64 {
+	i, _ := slices.BinarySearchFunc(
+		measurements,
+		at,
+		func(m *Measurement, t time.Time) int {
+			return m.MeasuredAt.Compare(t)
+		},
+	)
+
+	var before, after *Measurement
+
+	for j := i - 1; j >= 0; j-- {
+		if measurements[j].Bodyweight != nil {
+			before = measurements[j]
+			break
+		}
+	}
+
+	for j := i; j < len(measurements); j++ {
+		if measurements[j].Bodyweight != nil {
+			after = measurements[j]
+			break
+		}
+	}
+
+	switch {
+	case before == nil && after == nil:
+		return nil
+	case before == nil:
+		return after.Bodyweight
+	case after == nil:
+		return before.Bodyweight
+	case at.Sub(before.MeasuredAt) <= after.MeasuredAt.Sub(at):
+		return before.Bodyweight
+	default:
+		return after.Bodyweight
+	}
+}
+
+func ValidateMeasurement(v *validator.Validator, measurement *Measurement) {
+	v.Check(!measurement.MeasuredAt.IsZero(), "measured_at", "must be provided")
+	v.Check(
+		!measurement.MeasuredAt.After(time.Now()),
+		"measured_at",
+		"must not be in the future",
+	)
+
+	recorded := false
+	for _, metric := range MeasurementMetrics {
+		if measurement.Metric(metric) != nil {
+			recorded = true
+		}
+	}
+
+	v.Check(recorded, "measurement", "must record at least one value")
+
+	if measurement.Bodyweight != nil {
+		v.Check(
+			*measurement.Bodyweight > 0 && *measurement.Bodyweight <= 1000,
+			"bodyweight",
+			"must be between 0 and 1000",
+		)
+	}
+
+	if measurement.BodyFatPercentage != nil {
+		v.Check(
+			*measurement.BodyFatPercentage > 0 &&
+				*measurement.BodyFatPercentage < 100,
+			"body_fat_percentage",
+			"must be between 0 and 100",
+		)
+	}
+
+	for _, metric := range MeasurementMetrics[2:] {
+		if value := measurement.Metric(metric); value != nil {
+			v.Check(
+				*value > 0 && *value <= 500,
+				"circumferences."+metric,
+				"must be between 0 and 500",
+			)
+		}
+	}
+
+	v.Check(
+		len(measurement.Notes) <= 1000,
+		"notes",
+		"must not be more than 1000 bytes long",
+	)
+}
@@ -0,0 +1,122 @@
+//go:build integration
+
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMeasurementModel(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	bob := insertTestUser(t, models, "Bob")
+
+	ptr := func(v float64) *float64 { return &v }
+
+	measurements := []*Measurement{}
+
+	for i, bodyweight := range []float64{80, 79.5, 79} {
+		measurement := &Measurement{
+			UserID:     alice.ID,
+			MeasuredAt: *ago(time.Duration(3-i) * 24 * time.Hour),
+			Bodyweight: ptr(bodyweight),
+		}
+
+		err := models.Measurements.Insert(ctx, measurement)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		measurements = append(measurements, measurement)
+	}
+
+	first := measurements[0]
+
+	got, err := models.Measurements.GetByUser(ctx, first.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !got.MeasuredAt.Equal(first.MeasuredAt) || got.Bodyweight == nil ||
+		*got.Bodyweight != 80 || got.BodyFatPercentage != nil ||
+		got.Circumferences.Waist != nil {
+		t.Errorf("GetByUser returned %+v", got)
+	}
+
+	_, err = models.Measurements.GetByUser(ctx, first.ID, bob.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("GetByUser of someone else's measurement: err = %v", err)
+	}
+
+	all, err := models.Measurements.GetAllForUser(ctx, alice.ID, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(all) != 3 || all[0].ID != first.ID {
+		t.Errorf("GetAllForUser returned %d measurements; want 3 oldest first", len(all))
+	}
+
+	// The range is inclusive at both ends.
+	inRange, err := models.Measurements.GetAllForUser(
+		ctx,
+		alice.ID,
+		measurements[1].MeasuredAt,
+		measurements[2].MeasuredAt,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(inRange) != 2 || inRange[0].ID != measurements[1].ID {
+		t.Errorf("GetAllForUser in range returned %+v", inRange)
+	}
+
+	got.BodyFatPercentage = ptr(18.5)
+	got.Circumferences.Waist = ptr(84)
+	got.Notes = "Morning"
+
+	err = models.Measurements.Update(ctx, got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := models.Measurements.GetByUser(ctx, first.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if updated.BodyFatPercentage == nil || *updated.BodyFatPercentage != 18.5 ||
+		updated.Circumferences.Waist == nil || *updated.Circumferences.Waist != 84 ||
+		updated.Notes != "Morning" {
+		t.Errorf("after Update got %+v", updated)
+	}
+
+	stolen := *updated
+	stolen.UserID = bob.ID
+
+	err = models.Measurements.Update(ctx, &stolen)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Update of someone else's measurement: err = %v", err)
+	}
+
+	err = models.Measurements.DeleteByUser(ctx, first.ID, bob.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("DeleteByUser by someone else: err = %v", err)
+	}
+
+	err = models.Measurements.DeleteByUser(ctx, first.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = models.Measurements.DeleteByUser(ctx, first.ID, alice.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("deleting twice: err = %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+//go:build integration
+
+package data
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMigrationsRoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	migrator, err := newTestMigrator(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Whatever happens, leave the schema as the other tests expect it.
+	t.Cleanup(func() {
+		err := migrator.Up(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	err = migrator.Goto(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables := countRows(
+		t,
+		"pg_tables",
+		"schemaname = 'public' AND tablename <> 'schema_migrations'",
+	)
+	if tables != 0 {
+		t.Errorf("%d tables left after reverting every migration", tables)
+	}
+
+	version, dirty, err := migrator.Version(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if version != 0 || dirty {
+		t.Errorf("after Goto(0) version = %d, dirty = %t", version, dirty)
+	}
+
+	err = migrator.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version, _, err = migrator.Version(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if version != migrator.Latest() {
+		t.Errorf("after Up version = %d; want %d", version, migrator.Latest())
+	}
+
+	// Reverting and reapplying the newest migration must work too, since
+	// that is what a rollback of a release does.
+	err = migrator.Down(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version, _, err = migrator.Version(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := migrator.Migrations[len(migrator.Migrations)-2].Version
+	if version != previous {
+		t.Errorf("after Down(1) version = %d; want %d", version, previous)
+	}
+
+	err = migrator.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,279 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mockStore holds the rows of every table for the in-memory models. The
+// models share one store, so a workout created through one is visible to the
+// others just as it would be in PostgreSQL, and they hand out copies so that
+// callers can't change stored rows without going through a model.
+type mockStore struct {
+	mu  sync.Mutex
+	ids map[string]int64
+
+	users         []*User
+	tokens        []*Token
+	exercises     []*Exercise
+	workouts      []*Workout
+	imports       []*ImportJob
+	measurements  []*Measurement
+	tracks        []*WorkoutTrack
+	sessions      []*WorkoutSession
+	shares        []*WorkoutShare
+	coaching      []*CoachingLink
+	audit         []*WorkoutAuditEntry
+	comments      []*Comment
+	follows       []*mockFollow
+	webhooks      []*Webhook
+	deliveries    []*mockDelivery
+	outbox        []*mockOutboxEvent
+	preferences   map[int64]*NotificationPreferences
+	notifications []*Notification
+	remindersSent map[mockReminderKey]*mockReminderSent
+}
+
+// NewMockModels returns models that keep their data in memory, for tests
+// that exercise the handlers without a database.
+func NewMockModels() Models {
+	store := &mockStore{
+		ids:           make(map[string]int64),
+		preferences:   make(map[int64]*NotificationPreferences),
+		remindersSent: make(map[mockReminderKey]*mockReminderSent),
+	}
+
+	return Models{
+		Users:         mockUserModel{store},
+		Tokens:        mockTokenModel{store},
+		Exercises:     mockExerciseModel{store},
+		Workouts:      mockWorkoutModel{store},
+		Imports:       mockImportJobModel{store},
+		Measurements:  mockMeasurementModel{store},
+		Tracks:        mockTrackModel{store},
+		Sessions:      mockSessionModel{store},
+		Shares:        mockShareModel{store},
+		Coaching:      mockCoachingModel{store},
+		Audit:         mockAuditModel{store},
+		Comments:      mockCommentModel{store},
+		Follows:       mockFollowModel{store},
+		Webhooks:      mockWebhookModel{store},
+		Outbox:        mockOutboxModel{store},
+		Notifications: mockNotificationModel{store},
+		Reminders:     mockReminderModel{store},
+	}
+}
+
+// nextID returns the next value of the table's id sequence.
+func (s *mockStore) nextID(table string) int64 {
+	s.ids[table]++
+	return s.ids[table]
+}
+
+func (s *mockStore) user(id int64) *User {
+	for _, user := range s.users {
+		if user.ID == id {
+			return user
+		}
+	}
+
+	return nil
+}
+
+func (s *mockStore) exercise(id int64) *Exercise {
+	for _, exercise := range s.exercises {
+		if exercise.ID == id {
+			return exercise
+		}
+	}
+
+	return nil
+}
+
+func (s *mockStore) exerciseNamed(name string) *Exercise {
+	for _, exercise := range s.exercises {
+		if exercise.Name == name {
+			return exercise
+		}
+	}
+
+	return nil
+}
+
+func (s *mockStore) workout(id int64) *Workout {
+	for _, workout := range s.workouts {
+		if workout.ID == id {
+			return workout
+		}
+	}
+
+	return nil
+}
+
+// mockPage returns the bounds of the requested page within n rows and the
+// total to report for it, which like count(*) OVER() is zero when the page
+// is empty.
+func mockPage(n int, filters Filters) (start, end, total int) {
+	start = min(filters.offset(), n)
+	end = min(start+filters.limit(), n)
+
+	if start < end {
+		total = n
+	}
+
+	return start, end, total
+}
+
+// clonePtr copies the value p points to, so that a row handed out by a model
+// doesn't share optional fields with the stored one.
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+
+	v := *p
+	return &v
+}
+
+type mockUserModel struct {
+	store *mockStore
+}
+
+func (m mockUserModel) Insert(_ context.Context, user *User) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	if user.Timezone == "" {
+		user.Timezone = "UTC"
+	}
+
+	if user.Units.Weight == "" {
+		user.Units.Weight = UnitKilograms
+	}
+
+	if user.Units.Distance == "" {
+		user.Units.Distance = UnitKilometers
+	}
+
+	if user.Privacy == "" {
+		user.Privacy = PrivacyPrivate
+	}
+
+	for _, existing := range m.store.users {
+		if sameEmail(existing.Email, user.Email) {
+			return ErrDuplicateEmail
+		}
+	}
+
+	user.ID = m.store.nextID("users")
+	user.CreatedAt = time.Now()
+
+	stored := *user
+	m.store.users = append(m.store.users, &stored)
+
+	return nil
+}
+
+func (m mockUserModel) GetByEmail(_ context.Context, email string) (*User, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	for _, user := range m.store.users {
+		if sameEmail(user.Email, email) {
+			found := *user
+			return &found, nil
+		}
+	}
+
+	return nil, ErrRecordNotFound
+}
+
+func (m mockUserModel) GetForToken(
+	_ context.Context,
+	tokenScope, tokenPlaintext string,
+) (*User, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	hash := hashToken(tokenPlaintext)
+	now := time.Now()
+
+	for _, token := range m.store.tokens {
+		if !bytes.Equal(token.Hash, hash) ||
+			token.Scope != tokenScope ||
+			!token.Expiry.After(now) {
+			continue
+		}
+
+		if user := m.store.user(token.UserID); user != nil {
+			found := *user
+			return &found, nil
+		}
+	}
+
+	return nil, ErrRecordNotFound
+}
+
+func (m mockUserModel) Update(_ context.Context, user *User) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	for _, existing := range m.store.users {
+		if existing.ID != user.ID && sameEmail(existing.Email, user.Email) {
+			return ErrDuplicateEmail
+		}
+	}
+
+	stored := m.store.user(user.ID)
+	if stored == nil {
+		return ErrRecordNotFound
+	}
+
+	createdAt := stored.CreatedAt
+	*stored = *user
+	stored.CreatedAt = createdAt
+
+	return nil
+}
+
+// sameEmail compares addresses case-insensitively, like the citext column.
+func sameEmail(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
+
+type mockTokenModel struct {
+	store *mockStore
+}
+
+func (m mockTokenModel) New(
+	ctx context.Context,
+	userID int64,
+	ttl time.Duration,
+	scope string,
+) (*Token, error) {
+	token, err := generateToken(userID, ttl, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.Insert(ctx, token)
+
+	return token, err
+}
+
+func (m mockTokenModel) Insert(_ context.Context, token *Token) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	stored := *token
+	stored.Plaintext = ""
+	stored.Hash = slices.Clone(token.Hash)
+
+	m.store.tokens = append(m.store.tokens, &stored)
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package data
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+type mockImportJobModel struct {
+	store *mockStore
+}
+
+func copyImportJob(job *ImportJob) *ImportJob {
+	found := *job
+	found.UnmappedExercises = slices.Clone(job.UnmappedExercises)
+	found.SkippedWorkouts = slices.Clone(job.SkippedWorkouts)
+	found.FinishedAt = clonePtr(job.FinishedAt)
+
+	return &found
+}
+
+func (m mockImportJobModel) Insert(_ context.Context, job *ImportJob) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	now := time.Now()
+
+	job.ID = m.store.nextID("import_jobs")
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	m.store.imports = append(m.store.imports, &ImportJob{
+		ID:                job.ID,
+		UserID:            job.UserID,
+		Source:            job.Source,
+		Status:            job.Status,
+		WorkoutsFound:     job.WorkoutsFound,
+		UnmappedExercises: []string{},
+		SkippedWorkouts:   []string{},
+		CreatedAt:         job.CreatedAt,
+		UpdatedAt:         job.UpdatedAt,
+	})
+
+	return nil
+}
+
+func (m mockImportJobModel) GetByUser(
+	_ context.Context,
+	id, userID int64,
+) (*ImportJob, error) {
+	if id < 1 || userID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	for _, job := range m.store.imports {
+		if job.ID == id && job.UserID == userID {
+			return copyImportJob(job), nil
+		}
+	}
+
+	return nil, ErrRecordNotFound
+}
+
+func (m mockImportJobModel) Update(_ context.Context, job *ImportJob) error {
+	if job.UnmappedExercises == nil {
+		job.UnmappedExercises = []string{}
+	}
+
+	if job.SkippedWorkouts == nil {
+		job.SkippedWorkouts = []string{}
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	for _, stored := range m.store.imports {
+		if stored.ID != job.ID {
+			continue
+		}
+
+		job.UpdatedAt = time.Now()
+
+		stored.Status = job.Status
+		stored.WorkoutsImported = job.WorkoutsImported
+		stored.UnmappedExercises = slices.Clone(job.UnmappedExercises)
+		stored.SkippedWorkouts = slices.Clone(job.SkippedWorkouts)
+		stored.Error = job.Error
+		stored.FinishedAt = clonePtr(job.FinishedAt)
+		stored.UpdatedAt = job.UpdatedAt
+
+		return nil
+	}
+
+	return ErrRecordNotFound
+}
+
+func (m mockImportJobModel) Complete(
+	ctx context.Context,
+	job *ImportJob,
+	workouts []*Workout,
+) error {
+	if job.UnmappedExercises == nil {
+		job.UnmappedExercises = []string{}
+	}
+
+	if job.SkippedWorkouts == nil {
+		job.SkippedWorkouts = []string{}
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	var stored *ImportJob
+
+	for _, candidate := range m.store.imports {
+		if candidate.ID == job.ID {
+			stored = candidate
+			break
+		}
+	}
+
+	if stored == nil {
+		return ErrRecordNotFound
+	}
+
+	completed := *job
+	now := time.Now()
+
+	completed.Status = ImportStatusCompleted
+	completed.WorkoutsImported = len(workouts)
+	completed.Error = ""
+	completed.FinishedAt = &now
+	completed.UpdatedAt = now
+
+	event, err := newImportEvent(&completed)
+	if err != nil {
+		return err
+	}
+
+	err = m.store.createWorkouts(ctx, workouts, false)
+	if err != nil {
+		return err
+	}
+
+	m.store.writeEvent(event)
+
+	stored.Status = completed.Status
+	stored.WorkoutsImported = completed.WorkoutsImported
+	stored.UnmappedExercises = slices.Clone(completed.UnmappedExercises)
+	stored.SkippedWorkouts = slices.Clone(completed.SkippedWorkouts)
+	stored.Error = completed.Error
+	stored.FinishedAt = clonePtr(completed.FinishedAt)
+	stored.UpdatedAt = completed.UpdatedAt
+
+	*job = completed
+
+	return nil
+}
+
+func (m mockImportJobModel) FailStale(
+	_ context.Context,
+	olderThan time.Duration,
+	reason string,
+) (int64, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-olderThan)
+
+	var failed int64
+
+	for _, job := range m.store.imports {
+		if job.Status != ImportStatusPending && job.Status != ImportStatusRunning ||
+			!job.UpdatedAt.Before(cutoff) {
+			continue
+		}
+
+		job.Status = ImportStatusFailed
+		job.Error = reason
+		job.FinishedAt = &now
+		job.UpdatedAt = now
+		failed++
+	}
+
+	return failed, nil
+}
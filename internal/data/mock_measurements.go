@@ -0,0 +1,151 @@
+package data
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+type mockMeasurementModel struct {
+	store *mockStore
+}
+
+// copyMeasurement copies the stored columns of a measurement. The units are
+// not stored; InUnits fills them in.
+func copyMeasurement(measurement *Measurement) *Measurement {
+	return &Measurement{
+		ID:                measurement.ID,
+		UserID:            measurement.UserID,
+		MeasuredAt:        measurement.MeasuredAt,
+		Bodyweight:        clonePtr(measurement.Bodyweight),
+		BodyFatPercentage: clonePtr(measurement.BodyFatPercentage),
+		Circumferences: Circumferences{
+			Neck:  clonePtr(measurement.Circumferences.Neck),
+			Chest: clonePtr(measurement.Circumferences.Chest),
+			Waist: clonePtr(measurement.Circumferences.Waist),
+			Hips:  clonePtr(measurement.Circumferences.Hips),
+			Arm:   clonePtr(measurement.Circumferences.Arm),
+			Thigh: clonePtr(measurement.Circumferences.Thigh),
+			Calf:  clonePtr(measurement.Circumferences.Calf),
+		},
+		Notes:     measurement.Notes,
+		CreatedAt: measurement.CreatedAt,
+		UpdatedAt: measurement.UpdatedAt,
+	}
+}
+
+func (m mockMeasurementModel) Insert(
+	_ context.Context,
+	measurement *Measurement,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	now := time.Now()
+
+	measurement.ID = m.store.nextID("measurements")
+	measurement.CreatedAt = now
+	measurement.UpdatedAt = now
+
+	m.store.measurements = append(
+		m.store.measurements,
+		copyMeasurement(measurement),
+	)
+
+	return nil
+}
+
+func (m mockMeasurementModel) GetByUser(
+	_ context.Context,
+	id, userID int64,
+) (*Measurement, error) {
+	if id < 1 || userID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	for _, measurement := range m.store.measurements {
+		if measurement.ID == id && measurement.UserID == userID {
+			return copyMeasurement(measurement), nil
+		}
+	}
+
+	return nil, ErrRecordNotFound
+}
+
+func (m mockMeasurementModel) GetAllForUser(
+	_ context.Context,
+	userID int64,
+	from, to time.Time,
+) ([]*Measurement, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	measurements := []*Measurement{}
+
+	for _, measurement := range m.store.measurements {
+		switch {
+		case measurement.UserID != userID:
+		case !from.IsZero() && measurement.MeasuredAt.Before(from):
+		case !to.IsZero() && measurement.MeasuredAt.After(to):
+		default:
+			measurements = append(measurements, copyMeasurement(measurement))
+		}
+	}
+
+	slices.SortStableFunc(measurements, func(a, b *Measurement) int {
+		return a.MeasuredAt.Compare(b.MeasuredAt)
+	})
+
+	return measurements, nil
+}
+
+func (m mockMeasurementModel) Update(
+	_ context.Context,
+	measurement *Measurement,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	for i, stored := range m.store.measurements {
+		if stored.ID != measurement.ID || stored.UserID != measurement.UserID {
+			continue
+		}
+
+		measurement.UpdatedAt = time.Now()
+
+		updated := copyMeasurement(measurement)
+		updated.CreatedAt = stored.CreatedAt
+
+		m.store.measurements[i] = updated
+
+		return nil
+	}
+
+	return ErrRecordNotFound
+}
+
+func (m mockMeasurementModel) DeleteByUser(
+	_ context.Context,
+	id, userID int64,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	n := len(m.store.measurements)
+
+	m.store.measurements = slices.DeleteFunc(
+		m.store.measurements,
+		func(measurement *Measurement) bool {
+			return measurement.ID == id && measurement.UserID == userID
+		},
+	)
+
+	if len(m.store.measurements) == n {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,687 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sulemankhann/workout-tracker/internal/events"
+	"time"
+)
+
+type mockNotificationModel struct {
+	store *mockStore
+}
+
+func copyNotification(notification *Notification) *Notification {
+	found := *notification
+	found.Data = slices.Clone(notification.Data)
+	found.ReadAt = clonePtr(notification.ReadAt)
+
+	return &found
+}
+
+func (m mockNotificationModel) GetPreferences(
+	_ context.Context,
+	userID int64,
+) (*NotificationPreferences, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	prefs, ok := m.store.preferences[userID]
+	if !ok {
+		return DefaultNotificationPreferences(), nil
+	}
+
+	return &NotificationPreferences{
+		LeadMinutes: slices.Clone(prefs.LeadMinutes),
+		Channels:    slices.Clone(prefs.Channels),
+		UpdatedAt:   clonePtr(prefs.UpdatedAt),
+	}, nil
+}
+
+func (m mockNotificationModel) SetPreferences(
+	_ context.Context,
+	userID int64,
+	prefs *NotificationPreferences,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	now := time.Now()
+	prefs.UpdatedAt = &now
+
+	m.store.preferences[userID] = &NotificationPreferences{
+		LeadMinutes: slices.Clone(prefs.LeadMinutes),
+		Channels:    slices.Clone(prefs.Channels),
+		UpdatedAt:   clonePtr(prefs.UpdatedAt),
+	}
+
+	return nil
+}
+
+func (m mockNotificationModel) Insert(
+	_ context.Context,
+	notification *Notification,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	notification.ID = m.store.nextID("notifications")
+	notification.CreatedAt = time.Now()
+
+	stored := copyNotification(notification)
+	if stored.Data == nil {
+		stored.Data = json.RawMessage("{}")
+	}
+
+	m.store.notifications = append(m.store.notifications, stored)
+
+	return nil
+}
+
+func (m mockNotificationModel) GetAllForUser(
+	_ context.Context,
+	userID int64,
+	unreadOnly bool,
+	filters Filters,
+) ([]*Notification, Metadata, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	var matching []*Notification
+
+	for _, notification := range slices.Backward(m.store.notifications) {
+		if notification.UserID != userID ||
+			unreadOnly && notification.ReadAt != nil {
+			continue
+		}
+
+		matching = append(matching, notification)
+	}
+
+	start, end, total := mockPage(len(matching), filters)
+
+	notifications := []*Notification{}
+	for _, notification := range matching[start:end] {
+		notifications = append(notifications, copyNotification(notification))
+	}
+
+	return notifications, calculateMetadata(total, filters.Page, filters.PageSize), nil
+}
+
+func (m mockNotificationModel) CountUnread(
+	_ context.Context,
+	userID int64,
+) (int, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	count := 0
+
+	for _, notification := range m.store.notifications {
+		if notification.UserID == userID && notification.ReadAt == nil {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (m mockNotificationModel) SetRead(
+	_ context.Context,
+	notification *Notification,
+	read bool,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	for _, stored := range m.store.notifications {
+		if stored.ID != notification.ID || stored.UserID != notification.UserID {
+			continue
+		}
+
+		switch {
+		case !read:
+			stored.ReadAt = nil
+		case stored.ReadAt == nil:
+			now := time.Now()
+			stored.ReadAt = &now
+		}
+
+		*notification = *copyNotification(stored)
+
+		return nil
+	}
+
+	return ErrRecordNotFound
+}
+
+func (m mockNotificationModel) MarkAllRead(
+	_ context.Context,
+	userID int64,
+) (int64, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	var count int64
+
+	now := time.Now()
+
+	for _, notification := range m.store.notifications {
+		if notification.UserID == userID && notification.ReadAt == nil {
+			notification.ReadAt = clonePtr(&now)
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// mockReminderKey is a row of the reminders_sent table.
+type mockReminderKey struct {
+	WorkoutID   int64
+	ScheduledAt int64 // Unix nanoseconds
+	LeadMinutes int64
+	Channel     string
+}
+
+// mockReminderSent is the state kept in a reminders_sent row.
+type mockReminderSent struct {
+	Attempts  int
+	RetryAt   *time.Time
+	LastError string
+}
+
+func reminderKey(reminder *Reminder) mockReminderKey {
+	return mockReminderKey{
+		WorkoutID:   reminder.WorkoutID,
+		ScheduledAt: reminder.ScheduledAt.UnixNano(),
+		LeadMinutes: reminder.LeadMinutes,
+		Channel:     reminder.Channel,
+	}
+}
+
+type mockReminderModel struct {
+	store *mockStore
+}
+
+func (m mockReminderModel) GetDue(
+	_ context.Context,
+	limit int,
+) ([]*Reminder, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	now := time.Now()
+
+	workouts := slices.Clone(m.store.workouts)
+
+	slices.SortStableFunc(workouts, func(a, b *Workout) int {
+		return a.ScheduledAt.Compare(b.ScheduledAt)
+	})
+
+	reminders := []*Reminder{}
+
+	for _, workout := range workouts {
+		if workout.CompletedAt != nil || !workout.ScheduledAt.After(now) {
+			continue
+		}
+
+		user := m.store.user(workout.UserID)
+
+		prefs, ok := m.store.preferences[user.ID]
+		if !ok {
+			prefs = DefaultNotificationPreferences()
+		}
+
+		for _, lead := range prefs.LeadMinutes {
+			sendAt := workout.ScheduledAt.Add(-time.Duration(lead) * time.Minute)
+			if sendAt.After(now) {
+				continue
+			}
+
+			for _, channel := range prefs.Channels {
+				reminder := &Reminder{
+					WorkoutID:    workout.ID,
+					WorkoutTitle: workout.Title,
+					ScheduledAt:  workout.ScheduledAt,
+					LeadMinutes:  lead,
+					Channel:      channel,
+					User: User{
+						ID:       user.ID,
+						Name:     user.Name,
+						Email:    user.Email,
+						Timezone: user.Timezone,
+					},
+				}
+
+				sent := m.store.remindersSent[reminderKey(reminder)]
+				if sent != nil && (sent.RetryAt == nil || sent.RetryAt.After(now)) {
+					continue
+				}
+
+				if len(reminders) == limit {
+					return reminders, nil
+				}
+
+				reminders = append(reminders, reminder)
+			}
+		}
+	}
+
+	return reminders, nil
+}
+
+func (m mockReminderModel) Claim(
+	_ context.Context,
+	reminder *Reminder,
+) (bool, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	key := reminderKey(reminder)
+
+	sent := m.store.remindersSent[key]
+
+	switch {
+	case sent == nil:
+		sent = &mockReminderSent{Attempts: 1}
+		m.store.remindersSent[key] = sent
+
+	case sent.RetryAt != nil && !sent.RetryAt.After(time.Now()):
+		sent.Attempts++
+		sent.RetryAt = nil
+
+	default:
+		return false, nil
+	}
+
+	reminder.Attempts = sent.Attempts
+	reminder.RetryAt = nil
+
+	return true, nil
+}
+
+func (m mockReminderModel) RecordFailure(_ context.Context, reminder *Reminder) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	sent := m.store.remindersSent[reminderKey(reminder)]
+	if sent == nil {
+		return nil
+	}
+
+	sent.RetryAt = clonePtr(reminder.RetryAt)
+	sent.LastError = reminder.LastError
+
+	return nil
+}
+
+// mockDelivery is a row of the webhook_deliveries table.
+type mockDelivery struct {
+	WebhookDelivery
+	IdempotencyKey string
+}
+
+type mockWebhookModel struct {
+	store *mockStore
+}
+
+func (s *mockStore) webhook(id int64) *Webhook {
+	for _, webhook := range s.webhooks {
+		if webhook.ID == id {
+			return webhook
+		}
+	}
+
+	return nil
+}
+
+// withoutSecret copies a stored webhook the way it is listed.
+func withoutSecret(webhook *Webhook) *Webhook {
+	found := *webhook
+	found.Secret = ""
+	found.Events = slices.Clone(webhook.Events)
+
+	return &found
+}
+
+func (m mockWebhookModel) New(_ context.Context, webhook *Webhook) error {
+	secret, _, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	webhook.Secret = secret
+	webhook.ID = m.store.nextID("webhooks")
+	webhook.CreatedAt = time.Now()
+
+	stored := *webhook
+	stored.Events = slices.Clone(webhook.Events)
+
+	m.store.webhooks = append(m.store.webhooks, &stored)
+
+	return nil
+}
+
+func (m mockWebhookModel) GetAllForUser(
+	_ context.Context,
+	userID int64,
+) ([]*Webhook, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	webhooks := []*Webhook{}
+
+	for _, webhook := range m.store.webhooks {
+		if webhook.UserID == userID {
+			webhooks = append(webhooks, withoutSecret(webhook))
+		}
+	}
+
+	return webhooks, nil
+}
+
+func (m mockWebhookModel) GetByUser(
+	_ context.Context,
+	id, userID int64,
+) (*Webhook, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	webhook := m.store.webhook(id)
+	if webhook == nil || webhook.UserID != userID {
+		return nil, ErrRecordNotFound
+	}
+
+	return withoutSecret(webhook), nil
+}
+
+func (m mockWebhookModel) DeleteByUser(
+	_ context.Context,
+	id, userID int64,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	webhook := m.store.webhook(id)
+	if webhook == nil || webhook.UserID != userID {
+		return ErrRecordNotFound
+	}
+
+	m.store.webhooks = slices.DeleteFunc(m.store.webhooks, func(w *Webhook) bool {
+		return w.ID == id
+	})
+
+	m.store.deliveries = slices.DeleteFunc(
+		m.store.deliveries,
+		func(d *mockDelivery) bool {
+			return d.WebhookID == id
+		},
+	)
+
+	return nil
+}
+
+func (m mockWebhookModel) Enqueue(
+	_ context.Context,
+	userID int64,
+	event, idempotencyKey string,
+	payload []byte,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	now := time.Now()
+
+	for _, webhook := range m.store.webhooks {
+		if webhook.UserID != userID || !slices.Contains(webhook.Events, event) {
+			continue
+		}
+
+		queued := slices.ContainsFunc(m.store.deliveries, func(d *mockDelivery) bool {
+			return d.WebhookID == webhook.ID && d.IdempotencyKey == idempotencyKey
+		})
+		if queued {
+			continue
+		}
+
+		m.store.deliveries = append(m.store.deliveries, &mockDelivery{
+			WebhookDelivery: WebhookDelivery{
+				ID:            m.store.nextID("webhook_deliveries"),
+				WebhookID:     webhook.ID,
+				Event:         event,
+				Payload:       slices.Clone(payload),
+				Status:        DeliveryStatusPending,
+				NextAttemptAt: clonePtr(&now),
+				CreatedAt:     now,
+			},
+			IdempotencyKey: idempotencyKey,
+		})
+	}
+
+	return nil
+}
+
+func (m mockWebhookModel) ClaimDue(
+	_ context.Context,
+	limit int,
+	lease time.Duration,
+) ([]*WebhookDelivery, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	now := time.Now()
+
+	var due []*mockDelivery
+
+	for _, delivery := range m.store.deliveries {
+		if delivery.Status == DeliveryStatusPending &&
+			!delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+
+	slices.SortStableFunc(due, func(a, b *mockDelivery) int {
+		return a.NextAttemptAt.Compare(*b.NextAttemptAt)
+	})
+
+	deliveries := []*WebhookDelivery{}
+
+	for _, delivery := range due[:min(limit, len(due))] {
+		next := now.Add(lease)
+		delivery.NextAttemptAt = &next
+
+		webhook := m.store.webhook(delivery.WebhookID)
+
+		deliveries = append(deliveries, &WebhookDelivery{
+			ID:        delivery.ID,
+			WebhookID: delivery.WebhookID,
+			Event:     delivery.Event,
+			Payload:   slices.Clone(delivery.Payload),
+			Status:    DeliveryStatusPending,
+			Attempts:  delivery.Attempts,
+			CreatedAt: delivery.CreatedAt,
+			URL:       webhook.URL,
+			Secret:    webhook.Secret,
+		})
+	}
+
+	return deliveries, nil
+}
+
+func (m mockWebhookModel) RecordAttempt(
+	_ context.Context,
+	delivery *WebhookDelivery,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	for _, stored := range m.store.deliveries {
+		if stored.ID != delivery.ID {
+			continue
+		}
+
+		stored.Status = delivery.Status
+		stored.Attempts = delivery.Attempts
+		stored.LastAttemptAt = clonePtr(delivery.LastAttemptAt)
+		stored.ResponseStatus = clonePtr(delivery.ResponseStatus)
+		stored.LastError = delivery.LastError
+
+		if delivery.NextAttemptAt != nil {
+			stored.NextAttemptAt = clonePtr(delivery.NextAttemptAt)
+		}
+	}
+
+	return nil
+}
+
+func (m mockWebhookModel) GetDeliveriesForWebhook(
+	_ context.Context,
+	webhookID, userID int64,
+	filters Filters,
+) ([]*WebhookDelivery, Metadata, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	var matching []*mockDelivery
+
+	webhook := m.store.webhook(webhookID)
+
+	if webhook != nil && webhook.UserID == userID {
+		for _, delivery := range slices.Backward(m.store.deliveries) {
+			if delivery.WebhookID == webhookID {
+				matching = append(matching, delivery)
+			}
+		}
+	}
+
+	start, end, total := mockPage(len(matching), filters)
+
+	deliveries := []*WebhookDelivery{}
+
+	for _, delivery := range matching[start:end] {
+		found := &WebhookDelivery{
+			ID:             delivery.ID,
+			WebhookID:      delivery.WebhookID,
+			Event:          delivery.Event,
+			Status:         delivery.Status,
+			Attempts:       delivery.Attempts,
+			LastAttemptAt:  clonePtr(delivery.LastAttemptAt),
+			ResponseStatus: clonePtr(delivery.ResponseStatus),
+			LastError:      delivery.LastError,
+			CreatedAt:      delivery.CreatedAt,
+		}
+
+		if delivery.Status == DeliveryStatusPending {
+			found.NextAttemptAt = clonePtr(delivery.NextAttemptAt)
+		}
+
+		deliveries = append(deliveries, found)
+	}
+
+	return deliveries, calculateMetadata(total, filters.Page, filters.PageSize), nil
+}
+
+// mockOutboxEvent is a row of the outbox table.
+type mockOutboxEvent struct {
+	events.Event
+	NextAttemptAt time.Time
+	DispatchedAt  *time.Time
+	LastError     string
+}
+
+type mockOutboxModel struct {
+	store *mockStore
+}
+
+func (m mockOutboxModel) Claim(
+	_ context.Context,
+	limit int,
+	lease time.Duration,
+) ([]*events.Event, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	now := time.Now()
+
+	batch := []*events.Event{}
+
+	for _, event := range m.store.outbox {
+		if len(batch) == limit {
+			break
+		}
+
+		if event.DispatchedAt != nil || event.NextAttemptAt.After(now) {
+			continue
+		}
+
+		event.NextAttemptAt = now.Add(lease)
+
+		claimed := event.Event
+		claimed.Payload = slices.Clone(event.Payload)
+		claimed.Delivered = slices.Clone(event.Delivered)
+
+		batch = append(batch, &claimed)
+	}
+
+	return batch, nil
+}
+
+func (m mockOutboxModel) MarkDispatched(_ context.Context, id int64) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	now := time.Now()
+
+	for _, event := range m.store.outbox {
+		if event.ID == id {
+			event.DispatchedAt = &now
+			event.LastError = ""
+		}
+	}
+
+	return nil
+}
+
+func (m mockOutboxModel) MarkFailed(
+	_ context.Context,
+	id int64,
+	attempts int,
+	next time.Time,
+	reason string,
+	delivered []string,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	for _, event := range m.store.outbox {
+		if event.ID == id {
+			event.Attempts = attempts
+			event.NextAttemptAt = next
+			event.LastError = reason
+			event.Delivered = slices.Clone(delivered)
+		}
+	}
+
+	return nil
+}
+
+func (m mockOutboxModel) DeleteDispatched(
+	_ context.Context,
+	before time.Time,
+) (int64, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	n := len(m.store.outbox)
+
+	m.store.outbox = slices.DeleteFunc(m.store.outbox, func(event *mockOutboxEvent) bool {
+		return event.DispatchedAt != nil && event.DispatchedAt.Before(before)
+	})
+
+	return int64(n - len(m.store.outbox)), nil
+}
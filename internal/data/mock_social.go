@@ -0,0 +1,347 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// mockFollow is a row of the follows table.
+type mockFollow struct {
+	FollowerID int64
+	FolloweeID int64
+	Status     string
+	CreatedAt  time.Time
+	AcceptedAt *time.Time
+}
+
+// following reports whether followerID has an accepted follow of followeeID.
+func (s *mockStore) following(followerID, followeeID int64) bool {
+	for _, follow := range s.follows {
+		if follow.FollowerID == followerID &&
+			follow.FolloweeID == followeeID &&
+			follow.Status == FollowStatusAccepted {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *mockStore) follow(followerID, followeeID int64) *mockFollow {
+	for _, follow := range s.follows {
+		if follow.FollowerID == followerID && follow.FolloweeID == followeeID {
+			return follow
+		}
+	}
+
+	return nil
+}
+
+type mockFollowModel struct {
+	store *mockStore
+}
+
+func (m mockFollowModel) Follow(
+	_ context.Context,
+	followerID, followeeID int64,
+) (*Follow, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	followee := m.store.user(followeeID)
+	if followee == nil {
+		return nil, ErrRecordNotFound
+	}
+
+	follow := m.store.follow(followerID, followeeID)
+
+	if follow == nil {
+		follow = &mockFollow{
+			FollowerID: followerID,
+			FolloweeID: followeeID,
+			Status:     FollowStatusPending,
+			CreatedAt:  time.Now(),
+		}
+
+		if followee.Privacy == PrivacyPublic {
+			follow.Status = FollowStatusAccepted
+			follow.AcceptedAt = clonePtr(&follow.CreatedAt)
+		}
+
+		m.store.follows = append(m.store.follows, follow)
+	}
+
+	return &Follow{
+		UserID:     followee.ID,
+		Name:       followee.Name,
+		Status:     follow.Status,
+		CreatedAt:  follow.CreatedAt,
+		AcceptedAt: clonePtr(follow.AcceptedAt),
+	}, nil
+}
+
+func (m mockFollowModel) Unfollow(
+	_ context.Context,
+	followerID, followeeID int64,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	if m.store.follow(followerID, followeeID) == nil {
+		return ErrRecordNotFound
+	}
+
+	m.store.follows = slices.DeleteFunc(m.store.follows, func(f *mockFollow) bool {
+		return f.FollowerID == followerID && f.FolloweeID == followeeID
+	})
+
+	return nil
+}
+
+func (m mockFollowModel) Approve(
+	_ context.Context,
+	followerID, followeeID int64,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	follow := m.store.follow(followerID, followeeID)
+	if follow == nil || follow.Status != FollowStatusPending {
+		return ErrRecordNotFound
+	}
+
+	now := time.Now()
+
+	follow.Status = FollowStatusAccepted
+	follow.AcceptedAt = &now
+
+	return nil
+}
+
+func (m mockFollowModel) GetFollowing(
+	_ context.Context,
+	userID int64,
+) ([]*Follow, error) {
+	return m.list(func(follow *mockFollow) (int64, bool) {
+		return follow.FolloweeID, follow.FollowerID == userID
+	}), nil
+}
+
+func (m mockFollowModel) GetFollowers(
+	_ context.Context,
+	userID int64,
+) ([]*Follow, error) {
+	return m.list(func(follow *mockFollow) (int64, bool) {
+		return follow.FollowerID, follow.FolloweeID == userID
+	}), nil
+}
+
+// list returns the follows for which match reports true, described from the
+// point of view of the other user it returns.
+func (m mockFollowModel) list(
+	match func(*mockFollow) (otherID int64, ok bool),
+) []*Follow {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	follows := []*Follow{}
+
+	for _, follow := range m.store.follows {
+		otherID, ok := match(follow)
+		if !ok {
+			continue
+		}
+
+		follows = append(follows, &Follow{
+			UserID:     otherID,
+			Name:       m.store.user(otherID).Name,
+			Status:     follow.Status,
+			CreatedAt:  follow.CreatedAt,
+			AcceptedAt: clonePtr(follow.AcceptedAt),
+		})
+	}
+
+	slices.SortFunc(follows, func(a, b *Follow) int {
+		return cmp.Or(
+			a.CreatedAt.Compare(b.CreatedAt),
+			cmp.Compare(a.UserID, b.UserID),
+		)
+	})
+
+	return follows
+}
+
+type mockCoachingModel struct {
+	store *mockStore
+}
+
+func isOpenCoachingLink(link *CoachingLink) bool {
+	return link.Status == CoachingStatusPending ||
+		link.Status == CoachingStatusAccepted
+}
+
+func (m mockCoachingModel) Invite(_ context.Context, link *CoachingLink) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	for _, userID := range []int64{link.CoachID, link.AthleteID} {
+		if m.store.user(userID) == nil {
+			return fmt.Errorf("user %d does not exist", userID)
+		}
+	}
+
+	for _, existing := range m.store.coaching {
+		if existing.CoachID == link.CoachID &&
+			existing.AthleteID == link.AthleteID &&
+			isOpenCoachingLink(existing) {
+			return ErrDuplicateCoachingLink
+		}
+	}
+
+	link.Status = CoachingStatusPending
+	link.ID = m.store.nextID("coach_athletes")
+	link.CreatedAt = time.Now()
+
+	m.store.coaching = append(m.store.coaching, &CoachingLink{
+		ID:        link.ID,
+		CoachID:   link.CoachID,
+		AthleteID: link.AthleteID,
+		Status:    link.Status,
+		CreatedAt: link.CreatedAt,
+	})
+
+	return nil
+}
+
+func (m mockCoachingModel) GetAllForCoach(
+	_ context.Context,
+	coachID int64,
+) ([]*CoachingLink, error) {
+	return m.getAll(func(link *CoachingLink) bool {
+		return link.CoachID == coachID
+	}), nil
+}
+
+func (m mockCoachingModel) GetAllForAthlete(
+	_ context.Context,
+	athleteID int64,
+) ([]*CoachingLink, error) {
+	return m.getAll(func(link *CoachingLink) bool {
+		return link.AthleteID == athleteID
+	}), nil
+}
+
+func (m mockCoachingModel) getAll(match func(*CoachingLink) bool) []*CoachingLink {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	links := []*CoachingLink{}
+
+	for _, link := range m.store.coaching {
+		if !match(link) || !isOpenCoachingLink(link) {
+			continue
+		}
+
+		found := *link
+		found.CoachName = m.store.user(link.CoachID).Name
+		found.AthleteName = m.store.user(link.AthleteID).Name
+		found.AcceptedAt = clonePtr(link.AcceptedAt)
+		found.RevokedAt = clonePtr(link.RevokedAt)
+
+		links = append(links, &found)
+	}
+
+	return links
+}
+
+func (m mockCoachingModel) Accept(
+	_ context.Context,
+	coachID, athleteID int64,
+) error {
+	return m.update(coachID, athleteID, func(link *CoachingLink) bool {
+		if link.Status != CoachingStatusPending {
+			return false
+		}
+
+		now := time.Now()
+
+		link.Status = CoachingStatusAccepted
+		link.AcceptedAt = &now
+
+		return true
+	})
+}
+
+func (m mockCoachingModel) Revoke(
+	_ context.Context,
+	coachID, athleteID int64,
+) error {
+	return m.update(coachID, athleteID, func(link *CoachingLink) bool {
+		if !isOpenCoachingLink(link) {
+			return false
+		}
+
+		now := time.Now()
+
+		link.Status = CoachingStatusRevoked
+		link.RevokedAt = &now
+
+		return true
+	})
+}
+
+// update applies change to the links between the coach and the athlete,
+// returning ErrRecordNotFound if it changed none of them.
+func (m mockCoachingModel) update(
+	coachID, athleteID int64,
+	change func(*CoachingLink) bool,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	changed := false
+
+	for _, link := range m.store.coaching {
+		if link.CoachID == coachID && link.AthleteID == athleteID {
+			changed = change(link) || changed
+		}
+	}
+
+	if !changed {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m mockCoachingModel) GetAthlete(
+	_ context.Context,
+	coachID, athleteID int64,
+) (*User, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	for _, link := range m.store.coaching {
+		if link.CoachID != coachID ||
+			link.AthleteID != athleteID ||
+			link.Status != CoachingStatusAccepted {
+			continue
+		}
+
+		athlete := m.store.user(athleteID)
+
+		return &User{
+			ID:        athlete.ID,
+			CreatedAt: athlete.CreatedAt,
+			Name:      athlete.Name,
+			Email:     athlete.Email,
+			Timezone:  athlete.Timezone,
+			Units:     athlete.Units,
+		}, nil
+	}
+
+	return nil, ErrRecordNotFound
+}
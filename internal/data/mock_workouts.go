@@ -0,0 +1,1215 @@
+package data
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sulemankhann/workout-tracker/internal/events"
+	"time"
+)
+
+type mockExerciseModel struct {
+	store *mockStore
+}
+
+func (m mockExerciseModel) Insert(_ context.Context, exercise *Exercise) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	if exercise.MeasurementType == "" {
+		exercise.MeasurementType = MeasurementRepsWeight
+	}
+
+	if m.store.exerciseNamed(exercise.Name) != nil {
+		return ErrDuplicateExercise
+	}
+
+	now := time.Now()
+
+	exercise.ID = m.store.nextID("exercises")
+	exercise.CreatedAt = now
+	exercise.UpdatedAt = now
+
+	stored := *exercise
+	m.store.exercises = append(m.store.exercises, &stored)
+
+	return nil
+}
+
+func (m mockExerciseModel) Upsert(
+	_ context.Context,
+	exercises ...*Exercise,
+) (inserted, updated int, err error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	now := time.Now()
+
+	for _, exercise := range exercises {
+		if exercise.MeasurementType == "" {
+			exercise.MeasurementType = MeasurementRepsWeight
+		}
+
+		existing := m.store.exerciseNamed(exercise.Name)
+
+		switch {
+		case existing == nil:
+			exercise.ID = m.store.nextID("exercises")
+			exercise.CreatedAt = now
+			exercise.UpdatedAt = now
+
+			stored := *exercise
+			m.store.exercises = append(m.store.exercises, &stored)
+			inserted++
+
+		case existing.Description != exercise.Description ||
+			existing.Category != exercise.Category ||
+			existing.MuscleGroup != exercise.MuscleGroup ||
+			existing.MeasurementType != exercise.MeasurementType:
+			existing.Description = exercise.Description
+			existing.Category = exercise.Category
+			existing.MuscleGroup = exercise.MuscleGroup
+			existing.MeasurementType = exercise.MeasurementType
+			existing.UpdatedAt = now
+
+			exercise.ID = existing.ID
+			exercise.CreatedAt = existing.CreatedAt
+			exercise.UpdatedAt = now
+			updated++
+		}
+	}
+
+	return inserted, updated, nil
+}
+
+func (m mockExerciseModel) GetAll(_ context.Context) ([]*Exercise, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	exercises := []*Exercise{}
+
+	for _, exercise := range m.store.exercises {
+		found := *exercise
+		exercises = append(exercises, &found)
+	}
+
+	return exercises, nil
+}
+
+func (m mockExerciseModel) Get(_ context.Context, id int64) (*Exercise, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	exercise := m.store.exercise(id)
+	if exercise == nil {
+		return nil, ErrRecordNotFound
+	}
+
+	found := *exercise
+	return &found, nil
+}
+
+type mockWorkoutModel struct {
+	store *mockStore
+}
+
+// copyWorkout returns a copy of a stored workout that shares nothing the
+// caller could modify with it.
+func copyWorkout(workout *Workout) *Workout {
+	found := *workout
+	found.Exercises = slices.Clone(workout.Exercises)
+	found.CompletedAt = clonePtr(workout.CompletedAt)
+
+	return &found
+}
+
+// workoutExercises returns the rows to store for the workout's exercises,
+// failing like the foreign key would if an exercise doesn't exist.
+func (s *mockStore) workoutExercises(workout *Workout) ([]WorkoutExercise, error) {
+	var rows []WorkoutExercise
+
+	now := time.Now()
+
+	for _, workoutExercise := range workout.Exercises {
+		exercise := s.exercise(workoutExercise.ExerciseID)
+		if exercise == nil {
+			return nil, fmt.Errorf(
+				"exercise %d does not exist",
+				workoutExercise.ExerciseID,
+			)
+		}
+
+		rows = append(rows, WorkoutExercise{
+			WorkoutID:  workout.ID,
+			ExerciseID: exercise.ID,
+			Exercise: Exercise{
+				ID:              exercise.ID,
+				Name:            exercise.Name,
+				Description:     exercise.Description,
+				Category:        exercise.Category,
+				MuscleGroup:     exercise.MuscleGroup,
+				MeasurementType: exercise.MeasurementType,
+			},
+			Sets:            workoutExercise.Sets,
+			Repetitions:     workoutExercise.Repetitions,
+			Weight:          workoutExercise.Weight,
+			DurationSeconds: workoutExercise.DurationSeconds,
+			Distance:        workoutExercise.Distance,
+			RestInterval:    workoutExercise.RestInterval,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		})
+	}
+
+	return rows, nil
+}
+
+// saveWorkoutExercises stores rows as the exercises of the stored workout,
+// reusing its rows as the database does, and copies the ids assigned back to
+// the exercises of workout. Tracks recorded against rows that go are removed.
+func (s *mockStore) saveWorkoutExercises(
+	rows []WorkoutExercise,
+	stored, workout *Workout,
+) {
+	reuse, remove := matchWorkoutExercises(stored.Exercises, workout.Exercises)
+
+	stored.Exercises = nil
+
+	for i, row := range rows {
+		row.ID = reuse[i]
+		if row.ID == 0 {
+			row.ID = s.nextID("workout_exercises")
+		}
+
+		row.WorkoutID = stored.ID
+		stored.Exercises = append(stored.Exercises, row)
+
+		workout.Exercises[i].ID = row.ID
+		workout.Exercises[i].WorkoutID = stored.ID
+	}
+
+	s.tracks = slices.DeleteFunc(s.tracks, func(t *WorkoutTrack) bool {
+		return slices.Contains(remove, t.WorkoutExerciseID)
+	})
+}
+
+// writeWorkoutEvent adds the event for a change to workout to the outbox.
+func (s *mockStore) writeWorkoutEvent(eventType string, workout *Workout) error {
+	user := s.user(workout.UserID)
+	if user == nil {
+		return fmt.Errorf("user %d does not exist", workout.UserID)
+	}
+
+	event, err := newWorkoutEvent(eventType, workout, user.Units)
+	if err != nil {
+		return err
+	}
+
+	s.writeEvent(event)
+
+	return nil
+}
+
+// writeEvent adds event to the outbox.
+func (s *mockStore) writeEvent(event *events.Event) {
+	event.ID = s.nextID("outbox")
+
+	s.outbox = append(s.outbox, &mockOutboxEvent{
+		Event:         *event,
+		NextAttemptAt: event.CreatedAt,
+	})
+}
+
+// writeWorkoutAudit records the change to workout if ctx carries an actor.
+func (s *mockStore) writeWorkoutAudit(
+	ctx context.Context,
+	workout *Workout,
+	action string,
+) {
+	actorID, ok := auditActor(ctx)
+	if !ok {
+		return
+	}
+
+	s.audit = append(s.audit, &WorkoutAuditEntry{
+		ID:        s.nextID("workout_audit"),
+		WorkoutID: workout.ID,
+		UserID:    workout.UserID,
+		ActorID:   &actorID,
+		Action:    action,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (m mockWorkoutModel) CreateWorkoutWithExercises(
+	ctx context.Context,
+	workouts ...*Workout,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	return m.store.createWorkouts(ctx, workouts, true)
+}
+
+// createWorkouts stores the workouts, writing a workout.created event for
+// each if withEvents is set. The caller must hold the lock.
+func (s *mockStore) createWorkouts(
+	ctx context.Context,
+	workouts []*Workout,
+	withEvents bool,
+) error {
+	// Check everything first so that, as with the transaction, either every
+	// workout is created or none are.
+	exercises := make([][]WorkoutExercise, len(workouts))
+
+	for i, workout := range workouts {
+		if s.user(workout.UserID) == nil {
+			return fmt.Errorf("user %d does not exist", workout.UserID)
+		}
+
+		rows, err := s.workoutExercises(workout)
+		if err != nil {
+			return err
+		}
+
+		exercises[i] = rows
+	}
+
+	for i, workout := range workouts {
+		now := time.Now()
+
+		workout.ID = s.nextID("workouts")
+		workout.CreatedAt = now
+		workout.UpdatedAt = now
+
+		stored := copyWorkout(workout)
+		stored.Exercises = nil
+		s.saveWorkoutExercises(exercises[i], stored, workout)
+
+		s.workouts = append(s.workouts, stored)
+
+		if withEvents {
+			err := s.writeWorkoutEvent(EventWorkoutCreated, workout)
+			if err != nil {
+				return err
+			}
+		}
+
+		s.writeWorkoutAudit(ctx, workout, AuditActionCreated)
+	}
+
+	return nil
+}
+
+func (m mockWorkoutModel) UpdateWorkoutWithExercises(
+	ctx context.Context,
+	workout *Workout,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	stored := m.store.workout(workout.ID)
+	if stored == nil {
+		return ErrRecordNotFound
+	}
+
+	rows, err := m.store.workoutExercises(workout)
+	if err != nil {
+		return err
+	}
+
+	return m.store.updateWorkout(ctx, rows, stored, workout)
+}
+
+func (s *mockStore) updateWorkout(
+	ctx context.Context,
+	rows []WorkoutExercise,
+	stored, workout *Workout,
+) error {
+	stored.Title = workout.Title
+	stored.Description = workout.Description
+	stored.ScheduledAt = workout.ScheduledAt
+	s.saveWorkoutExercises(rows, stored, workout)
+
+	err := s.writeWorkoutEvent(EventWorkoutUpdated, workout)
+	if err != nil {
+		return err
+	}
+
+	s.writeWorkoutAudit(ctx, workout, AuditActionUpdated)
+
+	return nil
+}
+
+func (m mockWorkoutModel) GetAllForUser(
+	_ context.Context,
+	userID int64,
+) ([]*Workout, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	workouts := []*Workout{}
+
+	for _, workout := range m.store.workouts {
+		if workout.UserID == userID {
+			workouts = append(workouts, copyWorkout(workout))
+		}
+	}
+
+	return workouts, nil
+}
+
+func (m mockWorkoutModel) ForEachForUser(
+	_ context.Context,
+	userID int64,
+	batchSize int,
+	fn func(*Workout) error,
+) error {
+	var cursor int64
+
+	for {
+		workouts := m.getPageForUser(userID, cursor, batchSize)
+
+		for _, workout := range workouts {
+			err := fn(workout)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(workouts) < batchSize {
+			return nil
+		}
+
+		cursor = workouts[len(workouts)-1].ID
+	}
+}
+
+// getPageForUser takes the lock for one batch at a time, so fn may use the
+// other models while ForEachForUser runs.
+func (m mockWorkoutModel) getPageForUser(userID, afterID int64, limit int) []*Workout {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	workouts := []*Workout{}
+
+	for _, workout := range m.store.workouts {
+		if len(workouts) == limit {
+			break
+		}
+
+		if workout.UserID == userID && workout.ID > afterID {
+			workouts = append(workouts, copyWorkout(workout))
+		}
+	}
+
+	return workouts
+}
+
+func (m mockWorkoutModel) GetByUser(
+	_ context.Context,
+	id, userID int64,
+) (*Workout, error) {
+	if id < 1 || userID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	workout := m.store.workout(id)
+	if workout == nil || workout.UserID != userID {
+		return nil, ErrRecordNotFound
+	}
+
+	return copyWorkout(workout), nil
+}
+
+func (m mockWorkoutModel) DeleteByUser(
+	ctx context.Context,
+	id, userID int64,
+) error {
+	if id < 1 || userID < 1 {
+		return ErrRecordNotFound
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	workout := m.store.workout(id)
+	if workout == nil || workout.UserID != userID {
+		return ErrRecordNotFound
+	}
+
+	s := m.store
+
+	s.workouts = slices.DeleteFunc(s.workouts, func(w *Workout) bool {
+		return w.ID == id
+	})
+
+	// Cascade to the rows that reference the workout. The audit trail has
+	// no foreign key and outlives it.
+	s.tracks = slices.DeleteFunc(s.tracks, func(t *WorkoutTrack) bool {
+		return t.WorkoutID == id
+	})
+
+	s.sessions = slices.DeleteFunc(s.sessions, func(ws *WorkoutSession) bool {
+		return ws.WorkoutID == id
+	})
+
+	s.shares = slices.DeleteFunc(s.shares, func(ws *WorkoutShare) bool {
+		return ws.WorkoutID == id
+	})
+
+	s.comments = slices.DeleteFunc(s.comments, func(c *Comment) bool {
+		return c.WorkoutID == id
+	})
+
+	for reminder := range s.remindersSent {
+		if reminder.WorkoutID == id {
+			delete(s.remindersSent, reminder)
+		}
+	}
+
+	deleted := &Workout{ID: id, UserID: userID}
+
+	err := s.writeWorkoutEvent(EventWorkoutDeleted, deleted)
+	if err != nil {
+		return err
+	}
+
+	s.writeWorkoutAudit(ctx, deleted, AuditActionDeleted)
+
+	return nil
+}
+
+func (m mockWorkoutModel) ScheduleWorkout(
+	ctx context.Context,
+	workout *Workout,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	if stored := m.store.workout(workout.ID); stored != nil {
+		stored.ScheduledAt = workout.ScheduledAt
+	}
+
+	err := m.store.writeWorkoutEvent(EventWorkoutUpdated, workout)
+	if err != nil {
+		return err
+	}
+
+	m.store.writeWorkoutAudit(ctx, workout, AuditActionScheduled)
+
+	return nil
+}
+
+func (m mockWorkoutModel) Complete(ctx context.Context, workout *Workout) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	stored := m.store.workout(workout.ID)
+	if stored == nil {
+		return ErrRecordNotFound
+	}
+
+	stored.CompletedAt = clonePtr(workout.CompletedAt)
+	stored.UpdatedAt = time.Now()
+	workout.UpdatedAt = stored.UpdatedAt
+
+	err := m.store.writeWorkoutEvent(EventWorkoutCompleted, workout)
+	if err != nil {
+		return err
+	}
+
+	m.store.writeWorkoutAudit(ctx, workout, AuditActionCompleted)
+
+	return nil
+}
+
+func (m mockWorkoutModel) GetActivityForUser(
+	_ context.Context,
+	userID int64,
+) ([]WorkoutActivity, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	activity := []WorkoutActivity{}
+
+	for _, workout := range m.store.workouts {
+		if workout.UserID == userID {
+			found := copyWorkout(workout)
+
+			activity = append(activity, WorkoutActivity{
+				ScheduledAt: found.ScheduledAt,
+				CompletedAt: found.CompletedAt,
+			})
+		}
+	}
+
+	return activity, nil
+}
+
+func (m mockWorkoutModel) GetExerciseHistoryForUser(
+	_ context.Context,
+	userID, exerciseID int64,
+) ([]*ExerciseHistoryEntry, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	type row struct {
+		entry *ExerciseHistoryEntry
+		id    int64
+	}
+
+	var rows []row
+
+	for _, workout := range m.store.workouts {
+		if workout.UserID != userID || workout.CompletedAt == nil {
+			continue
+		}
+
+		for _, workoutExercise := range workout.Exercises {
+			if exerciseID != 0 && workoutExercise.ExerciseID != exerciseID {
+				continue
+			}
+
+			rows = append(rows, row{
+				entry: &ExerciseHistoryEntry{
+					ExerciseID:   workoutExercise.ExerciseID,
+					ExerciseName: workoutExercise.Exercise.Name,
+					WorkoutID:    workout.ID,
+					CompletedAt:  *workout.CompletedAt,
+					Sets:         workoutExercise.Sets,
+					Repetitions:  workoutExercise.Repetitions,
+					Weight:       workoutExercise.Weight,
+				},
+				id: workoutExercise.ID,
+			})
+		}
+	}
+
+	slices.SortFunc(rows, func(a, b row) int {
+		return cmp.Or(
+			a.entry.CompletedAt.Compare(b.entry.CompletedAt),
+			cmp.Compare(a.id, b.id),
+		)
+	})
+
+	history := []*ExerciseHistoryEntry{}
+	for _, row := range rows {
+		history = append(history, row.entry)
+	}
+
+	return history, nil
+}
+
+func (m mockWorkoutModel) GetFeedForViewer(
+	_ context.Context,
+	viewerID int64,
+	after *FeedCursor,
+	limit int,
+) ([]*FeedEntry, *FeedCursor, error) {
+	entries, next := m.getVisiblePage(viewerID, 0, true, after, limit)
+	return entries, next, nil
+}
+
+func (m mockWorkoutModel) GetVisibleForViewer(
+	_ context.Context,
+	ownerID, viewerID int64,
+	after *FeedCursor,
+	limit int,
+) ([]*FeedEntry, *FeedCursor, error) {
+	entries, next := m.getVisiblePage(viewerID, ownerID, false, after, limit)
+	return entries, next, nil
+}
+
+// getVisiblePage applies the same rules as workoutVisibleToViewer and the
+// feed query.
+func (m mockWorkoutModel) getVisiblePage(
+	viewerID, ownerID int64,
+	followedOnly bool,
+	after *FeedCursor,
+	limit int,
+) ([]*FeedEntry, *FeedCursor) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	var visible []*Workout
+
+	for _, workout := range m.store.workouts {
+		owner := m.store.user(workout.UserID)
+
+		switch {
+		case workout.CompletedAt == nil:
+			continue
+		case ownerID != 0 && workout.UserID != ownerID:
+			continue
+		case followedOnly && !m.store.following(viewerID, workout.UserID):
+			continue
+		case workout.UserID == viewerID,
+			owner.Privacy == PrivacyPublic,
+			owner.Privacy == PrivacyFollowers &&
+				m.store.following(viewerID, workout.UserID):
+		default:
+			continue
+		}
+
+		if after != nil && compareFeedPosition(workout, after) >= 0 {
+			continue
+		}
+
+		visible = append(visible, workout)
+	}
+
+	slices.SortFunc(visible, func(a, b *Workout) int {
+		return -compareFeedPosition(
+			a,
+			&FeedCursor{CompletedAt: *b.CompletedAt, ID: b.ID},
+		)
+	})
+
+	entries := []*FeedEntry{}
+
+	for _, workout := range visible {
+		if len(entries) == limit+1 {
+			break
+		}
+
+		entries = append(entries, &FeedEntry{
+			Author: FeedAuthor{
+				ID:   workout.UserID,
+				Name: m.store.user(workout.UserID).Name,
+			},
+			Workout: copyWorkout(workout),
+		})
+	}
+
+	var next *FeedCursor
+
+	if len(entries) > limit {
+		entries = entries[:limit]
+
+		last := entries[limit-1].Workout
+		next = &FeedCursor{CompletedAt: *last.CompletedAt, ID: last.ID}
+	}
+
+	return entries, next
+}
+
+func compareFeedPosition(workout *Workout, cursor *FeedCursor) int {
+	return cmp.Or(
+		workout.CompletedAt.Compare(cursor.CompletedAt),
+		cmp.Compare(workout.ID, cursor.ID),
+	)
+}
+
+type mockTrackModel struct {
+	store *mockStore
+}
+
+func (m mockTrackModel) InsertWithWorkout(
+	ctx context.Context,
+	track *WorkoutTrack,
+	workout *Workout,
+) error {
+	if len(workout.Exercises) == 0 {
+		return errors.New("the workout has no exercise for the track")
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	stored := m.store.workout(workout.ID)
+	if stored == nil {
+		return fmt.Errorf("workout %d does not exist", workout.ID)
+	}
+
+	rows, err := m.store.workoutExercises(workout)
+	if err != nil {
+		return err
+	}
+
+	err = m.store.updateWorkout(ctx, rows, stored, workout)
+	if err != nil {
+		return err
+	}
+
+	workoutExercise := workout.Exercises[len(workout.Exercises)-1]
+
+	track.ID = m.store.nextID("workout_tracks")
+	track.WorkoutID = workout.ID
+	track.WorkoutExerciseID = workoutExercise.ID
+	track.ExerciseID = workoutExercise.ExerciseID
+	track.CreatedAt = time.Now()
+
+	found := *track
+	found.Splits = slices.Clone(track.Splits)
+
+	m.store.tracks = append(m.store.tracks, &found)
+
+	return nil
+}
+
+func (m mockTrackModel) GetAllForWorkout(
+	_ context.Context,
+	workoutID int64,
+) ([]*WorkoutTrack, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	tracks := []*WorkoutTrack{}
+
+	for _, track := range m.store.tracks {
+		if track.WorkoutID == workoutID {
+			found := *track
+			found.Splits = slices.Clone(track.Splits)
+			tracks = append(tracks, &found)
+		}
+	}
+
+	slices.SortStableFunc(tracks, func(a, b *WorkoutTrack) int {
+		return a.StartedAt.Compare(b.StartedAt)
+	})
+
+	return tracks, nil
+}
+
+type mockSessionModel struct {
+	store *mockStore
+}
+
+func (m mockSessionModel) Insert(
+	_ context.Context,
+	session *WorkoutSession,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	if m.store.workout(session.WorkoutID) == nil {
+		return fmt.Errorf("workout %d does not exist", session.WorkoutID)
+	}
+
+	if session.Status == SessionStatusActive {
+		for _, existing := range m.store.sessions {
+			if existing.WorkoutID == session.WorkoutID && existing.IsActive() {
+				return ErrActiveSession
+			}
+		}
+	}
+
+	now := time.Now()
+
+	session.ID = m.store.nextID("workout_sessions")
+	session.StartedAt = now
+	session.UpdatedAt = now
+	session.Version = 1
+
+	m.store.sessions = append(m.store.sessions, &WorkoutSession{
+		ID:            session.ID,
+		WorkoutID:     session.WorkoutID,
+		UserID:        session.UserID,
+		Status:        session.Status,
+		ExerciseIndex: session.ExerciseIndex,
+		SetNumber:     session.SetNumber,
+		StartedAt:     session.StartedAt,
+		UpdatedAt:     session.UpdatedAt,
+		Version:       session.Version,
+	})
+
+	return nil
+}
+
+func (m mockSessionModel) GetByUser(
+	_ context.Context,
+	id, userID int64,
+) (*WorkoutSession, error) {
+	if id < 1 || userID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	for _, session := range m.store.sessions {
+		if session.ID == id && session.UserID == userID {
+			found := *session
+			return &found, nil
+		}
+	}
+
+	return nil, ErrRecordNotFound
+}
+
+func (m mockSessionModel) Update(
+	_ context.Context,
+	session *WorkoutSession,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	return m.store.updateSession(session)
+}
+
+func (m mockSessionModel) Finish(
+	ctx context.Context,
+	session *WorkoutSession,
+	workout *Workout,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	stored := m.store.workout(workout.ID)
+	if stored == nil {
+		return ErrRecordNotFound
+	}
+
+	err := m.store.updateSession(session)
+	if err != nil {
+		return err
+	}
+
+	if workout.CompletedAt == nil && session.FinishedAt != nil {
+		completedAt := *session.FinishedAt
+		workout.CompletedAt = &completedAt
+
+		stored.CompletedAt = clonePtr(workout.CompletedAt)
+		stored.UpdatedAt = time.Now()
+		workout.UpdatedAt = stored.UpdatedAt
+
+		err = m.store.writeWorkoutEvent(EventWorkoutCompleted, workout)
+		if err != nil {
+			return err
+		}
+
+		m.store.writeWorkoutAudit(ctx, workout, AuditActionCompleted)
+	}
+
+	return nil
+}
+
+// updateSession saves the session if its version still matches. The caller
+// must hold the lock.
+func (s *mockStore) updateSession(session *WorkoutSession) error {
+	for _, stored := range s.sessions {
+		if stored.ID != session.ID || stored.Version != session.Version {
+			continue
+		}
+
+		session.UpdatedAt = time.Now()
+		session.Version++
+
+		startedAt := stored.StartedAt
+		*stored = *session
+		stored.StartedAt = startedAt
+
+		return nil
+	}
+
+	return ErrEditConflict
+}
+
+// Notify does nothing: the mock has no other instances to tell.
+func (m mockSessionModel) Notify(_ context.Context, _ SessionNotice) error {
+	return nil
+}
+
+type mockShareModel struct {
+	store *mockStore
+}
+
+func (m mockShareModel) New(
+	_ context.Context,
+	workoutID, userID int64,
+	expiresAt *time.Time,
+) (*WorkoutShare, error) {
+	plaintext, hash, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	if m.store.workout(workoutID) == nil {
+		return nil, fmt.Errorf("workout %d does not exist", workoutID)
+	}
+
+	share := &WorkoutShare{
+		ID:        m.store.nextID("workout_shares"),
+		WorkoutID: workoutID,
+		UserID:    userID,
+		Plaintext: plaintext,
+		Hash:      hash,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	stored := *share
+	stored.Plaintext = ""
+
+	m.store.shares = append(m.store.shares, &stored)
+
+	return share, nil
+}
+
+func (m mockShareModel) GetAllForWorkout(
+	_ context.Context,
+	workoutID, userID int64,
+) ([]*WorkoutShare, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	shares := []*WorkoutShare{}
+
+	for _, share := range m.store.shares {
+		if share.WorkoutID == workoutID && share.UserID == userID {
+			found := *share
+			found.Hash = nil
+			shares = append(shares, &found)
+		}
+	}
+
+	return shares, nil
+}
+
+func (m mockShareModel) GetActiveByToken(
+	_ context.Context,
+	plaintext string,
+) (*WorkoutShare, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	hash := hashToken(plaintext)
+	now := time.Now()
+
+	for _, share := range m.store.shares {
+		if !bytes.Equal(share.Hash, hash) ||
+			share.RevokedAt != nil ||
+			share.ExpiresAt != nil && !share.ExpiresAt.After(now) {
+			continue
+		}
+
+		found := *share
+		found.Hash = nil
+		found.OwnerUnits = m.store.user(share.UserID).Units
+
+		return &found, nil
+	}
+
+	return nil, ErrRecordNotFound
+}
+
+func (m mockShareModel) Revoke(
+	_ context.Context,
+	id, workoutID, userID int64,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	for _, share := range m.store.shares {
+		if share.ID == id &&
+			share.WorkoutID == workoutID &&
+			share.UserID == userID &&
+			share.RevokedAt == nil {
+			now := time.Now()
+			share.RevokedAt = &now
+
+			return nil
+		}
+	}
+
+	return ErrRecordNotFound
+}
+
+type mockAuditModel struct {
+	store *mockStore
+}
+
+func (m mockAuditModel) Insert(
+	_ context.Context,
+	entry *WorkoutAuditEntry,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	entry.ID = m.store.nextID("workout_audit")
+	entry.CreatedAt = time.Now()
+
+	stored := *entry
+	stored.ActorName = nil
+
+	m.store.audit = append(m.store.audit, &stored)
+
+	return nil
+}
+
+func (m mockAuditModel) GetAllForWorkout(
+	_ context.Context,
+	workoutID, userID int64,
+) ([]*WorkoutAuditEntry, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	entries := []*WorkoutAuditEntry{}
+
+	for _, entry := range m.store.audit {
+		if entry.WorkoutID != workoutID || entry.UserID != userID {
+			continue
+		}
+
+		found := *entry
+
+		if entry.ActorID != nil {
+			if actor := m.store.user(*entry.ActorID); actor != nil {
+				name := actor.Name
+				found.ActorName = &name
+			}
+		}
+
+		entries = append(entries, &found)
+	}
+
+	return entries, nil
+}
+
+type mockCommentModel struct {
+	store *mockStore
+}
+
+func (m mockCommentModel) Insert(_ context.Context, comment *Comment) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	if m.store.workout(comment.WorkoutID) == nil {
+		return fmt.Errorf("workout %d does not exist", comment.WorkoutID)
+	}
+
+	comment.ID = m.store.nextID("comments")
+	comment.CreatedAt = time.Now()
+
+	stored := *comment
+	m.store.comments = append(m.store.comments, &stored)
+
+	return nil
+}
+
+// withAuthor returns a copy of a stored comment with its author's name.
+func (s *mockStore) withAuthor(comment *Comment) *Comment {
+	found := *comment
+	found.AuthorName = s.user(comment.AuthorID).Name
+
+	return &found
+}
+
+func (m mockCommentModel) Get(_ context.Context, id int64) (*Comment, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	for _, comment := range m.store.comments {
+		if comment.ID == id {
+			return m.store.withAuthor(comment), nil
+		}
+	}
+
+	return nil, ErrRecordNotFound
+}
+
+func (m mockCommentModel) GetAllForWorkout(
+	_ context.Context,
+	workoutID, exerciseID int64,
+	filters Filters,
+) ([]*Comment, Metadata, error) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	var matching []*Comment
+
+	for _, comment := range m.store.comments {
+		if comment.WorkoutID != workoutID {
+			continue
+		}
+
+		if exerciseID != 0 &&
+			(comment.ExerciseID == nil || *comment.ExerciseID != exerciseID) {
+			continue
+		}
+
+		matching = append(matching, comment)
+	}
+
+	slices.SortStableFunc(matching, func(a, b *Comment) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	start, end, total := mockPage(len(matching), filters)
+
+	comments := []*Comment{}
+	for _, comment := range matching[start:end] {
+		comments = append(comments, m.store.withAuthor(comment))
+	}
+
+	return comments, calculateMetadata(total, filters.Page, filters.PageSize), nil
+}
+
+// authoredComment returns the live comment id written by userID.
+func (s *mockStore) authoredComment(id, userID int64) *Comment {
+	for _, comment := range s.comments {
+		if comment.ID == id &&
+			comment.AuthorID == userID &&
+			comment.DeletedAt == nil {
+			return comment
+		}
+	}
+
+	return nil
+}
+
+func (m mockCommentModel) UpdateByAuthor(
+	_ context.Context,
+	comment *Comment,
+	userID int64,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	stored := m.store.authoredComment(comment.ID, userID)
+	if stored == nil {
+		return ErrRecordNotFound
+	}
+
+	now := time.Now()
+
+	stored.Body = comment.Body
+	stored.EditedAt = &now
+	comment.EditedAt = &now
+
+	return nil
+}
+
+func (m mockCommentModel) DeleteByAuthor(
+	_ context.Context,
+	id, userID int64,
+) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	stored := m.store.authoredComment(id, userID)
+	if stored == nil {
+		return ErrRecordNotFound
+	}
+
+	now := time.Now()
+
+	stored.Body = ""
+	stored.DeletedAt = &now
+
+	return nil
+}
@@ -1,28 +1,262 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"sulemankhann/workout-tracker/internal/events"
+	"time"
 )
 
+// DefaultQueryTimeout bounds each query when no timeout is configured.
+const DefaultQueryTimeout = 3 * time.Second
+
 var (
 	ErrRecordNotFound = errors.New("record not found")
 	ErrEditConflict   = errors.New("edit conflict")
 	ErrDuplicateEmail = errors.New("duplicate email")
 )
 
+// Models groups the stores the application reads and writes through.
+// NewModels returns the PostgreSQL implementations and NewMockModels
+// in-memory ones for tests.
 type Models struct {
-	Users     UserModel
-	Tokens    TokenModel
-	Exercises ExerciseModel
-	Workouts  WorkoutModel
+	Users         UserStore
+	Tokens        TokenStore
+	Exercises     ExerciseStore
+	Workouts      WorkoutStore
+	Imports       ImportJobStore
+	Measurements  MeasurementStore
+	Tracks        TrackStore
+	Sessions      SessionStore
+	Shares        ShareStore
+	Coaching      CoachingStore
+	Audit         AuditStore
+	Comments      CommentStore
+	Follows       FollowStore
+	Webhooks      WebhookStore
+	Outbox        events.Store
+	Notifications NotificationStore
+	Reminders     ReminderStore
+}
+
+type UserStore interface {
+	Insert(ctx context.Context, user *User) error
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error)
+	Update(ctx context.Context, user *User) error
+}
+
+type TokenStore interface {
+	New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error)
+	Insert(ctx context.Context, token *Token) error
+}
+
+type ExerciseStore interface {
+	Insert(ctx context.Context, exercise *Exercise) error
+	Upsert(ctx context.Context, exercises ...*Exercise) (inserted, updated int, err error)
+	GetAll(ctx context.Context) ([]*Exercise, error)
+	Get(ctx context.Context, id int64) (*Exercise, error)
+}
+
+type WorkoutStore interface {
+	CreateWorkoutWithExercises(ctx context.Context, workouts ...*Workout) error
+	UpdateWorkoutWithExercises(ctx context.Context, workout *Workout) error
+	GetAllForUser(ctx context.Context, userID int64) ([]*Workout, error)
+	ForEachForUser(
+		ctx context.Context,
+		userID int64,
+		batchSize int,
+		fn func(*Workout) error,
+	) error
+	GetByUser(ctx context.Context, id, userID int64) (*Workout, error)
+	DeleteByUser(ctx context.Context, id, userID int64) error
+	ScheduleWorkout(ctx context.Context, workout *Workout) error
+	Complete(ctx context.Context, workout *Workout) error
+	GetActivityForUser(ctx context.Context, userID int64) ([]WorkoutActivity, error)
+	GetExerciseHistoryForUser(
+		ctx context.Context,
+		userID, exerciseID int64,
+	) ([]*ExerciseHistoryEntry, error)
+	GetFeedForViewer(
+		ctx context.Context,
+		viewerID int64,
+		after *FeedCursor,
+		limit int,
+	) ([]*FeedEntry, *FeedCursor, error)
+	GetVisibleForViewer(
+		ctx context.Context,
+		ownerID, viewerID int64,
+		after *FeedCursor,
+		limit int,
+	) ([]*FeedEntry, *FeedCursor, error)
+}
+
+type ImportJobStore interface {
+	Insert(ctx context.Context, job *ImportJob) error
+	GetByUser(ctx context.Context, id, userID int64) (*ImportJob, error)
+	Update(ctx context.Context, job *ImportJob) error
+	Complete(ctx context.Context, job *ImportJob, workouts []*Workout) error
+	FailStale(ctx context.Context, olderThan time.Duration, reason string) (int64, error)
+}
+
+type MeasurementStore interface {
+	Insert(ctx context.Context, measurement *Measurement) error
+	GetByUser(ctx context.Context, id, userID int64) (*Measurement, error)
+	GetAllForUser(
+		ctx context.Context,
+		userID int64,
+		from, to time.Time,
+	) ([]*Measurement, error)
+	Update(ctx context.Context, measurement *Measurement) error
+	DeleteByUser(ctx context.Context, id, userID int64) error
+}
+
+type TrackStore interface {
+	InsertWithWorkout(ctx context.Context, track *WorkoutTrack, workout *Workout) error
+	GetAllForWorkout(ctx context.Context, workoutID int64) ([]*WorkoutTrack, error)
+}
+
+type SessionStore interface {
+	Insert(ctx context.Context, session *WorkoutSession) error
+	GetByUser(ctx context.Context, id, userID int64) (*WorkoutSession, error)
+	Update(ctx context.Context, session *WorkoutSession) error
+	Finish(ctx context.Context, session *WorkoutSession, workout *Workout) error
+	Notify(ctx context.Context, notice SessionNotice) error
+}
+
+type ShareStore interface {
+	New(
+		ctx context.Context,
+		workoutID, userID int64,
+		expiresAt *time.Time,
+	) (*WorkoutShare, error)
+	GetAllForWorkout(ctx context.Context, workoutID, userID int64) ([]*WorkoutShare, error)
+	GetActiveByToken(ctx context.Context, plaintext string) (*WorkoutShare, error)
+	Revoke(ctx context.Context, id, workoutID, userID int64) error
 }
 
-func NewModels(db *sql.DB) Models {
+type CoachingStore interface {
+	Invite(ctx context.Context, link *CoachingLink) error
+	GetAllForCoach(ctx context.Context, coachID int64) ([]*CoachingLink, error)
+	GetAllForAthlete(ctx context.Context, athleteID int64) ([]*CoachingLink, error)
+	Accept(ctx context.Context, coachID, athleteID int64) error
+	Revoke(ctx context.Context, coachID, athleteID int64) error
+	GetAthlete(ctx context.Context, coachID, athleteID int64) (*User, error)
+}
+
+type AuditStore interface {
+	Insert(ctx context.Context, entry *WorkoutAuditEntry) error
+	GetAllForWorkout(
+		ctx context.Context,
+		workoutID, userID int64,
+	) ([]*WorkoutAuditEntry, error)
+}
+
+type CommentStore interface {
+	Insert(ctx context.Context, comment *Comment) error
+	Get(ctx context.Context, id int64) (*Comment, error)
+	GetAllForWorkout(
+		ctx context.Context,
+		workoutID, exerciseID int64,
+		filters Filters,
+	) ([]*Comment, Metadata, error)
+	UpdateByAuthor(ctx context.Context, comment *Comment, userID int64) error
+	DeleteByAuthor(ctx context.Context, id, userID int64) error
+}
+
+type FollowStore interface {
+	Follow(ctx context.Context, followerID, followeeID int64) (*Follow, error)
+	Unfollow(ctx context.Context, followerID, followeeID int64) error
+	Approve(ctx context.Context, followerID, followeeID int64) error
+	GetFollowing(ctx context.Context, userID int64) ([]*Follow, error)
+	GetFollowers(ctx context.Context, userID int64) ([]*Follow, error)
+}
+
+type WebhookStore interface {
+	New(ctx context.Context, webhook *Webhook) error
+	GetAllForUser(ctx context.Context, userID int64) ([]*Webhook, error)
+	GetByUser(ctx context.Context, id, userID int64) (*Webhook, error)
+	DeleteByUser(ctx context.Context, id, userID int64) error
+	Enqueue(
+		ctx context.Context,
+		userID int64,
+		event, idempotencyKey string,
+		payload []byte,
+	) error
+	ClaimDue(
+		ctx context.Context,
+		limit int,
+		lease time.Duration,
+	) ([]*WebhookDelivery, error)
+	RecordAttempt(ctx context.Context, delivery *WebhookDelivery) error
+	GetDeliveriesForWebhook(
+		ctx context.Context,
+		webhookID, userID int64,
+		filters Filters,
+	) ([]*WebhookDelivery, Metadata, error)
+}
+
+type NotificationStore interface {
+	GetPreferences(ctx context.Context, userID int64) (*NotificationPreferences, error)
+	SetPreferences(
+		ctx context.Context,
+		userID int64,
+		prefs *NotificationPreferences,
+	) error
+	Insert(ctx context.Context, notification *Notification) error
+	GetAllForUser(
+		ctx context.Context,
+		userID int64,
+		unreadOnly bool,
+		filters Filters,
+	) ([]*Notification, Metadata, error)
+	CountUnread(ctx context.Context, userID int64) (int, error)
+	SetRead(ctx context.Context, notification *Notification, read bool) error
+	MarkAllRead(ctx context.Context, userID int64) (int64, error)
+}
+
+type ReminderStore interface {
+	GetDue(ctx context.Context, limit int) ([]*Reminder, error)
+	Claim(ctx context.Context, reminder *Reminder) (bool, error)
+	RecordFailure(ctx context.Context, reminder *Reminder) error
+}
+
+// NewModels returns the models backed by db. Every query is bounded by
+// queryTimeout on top of the caller's context, or by DefaultQueryTimeout if
+// it is zero.
+func NewModels(db *sql.DB, queryTimeout time.Duration) Models {
 	return Models{
-		Users:     UserModel{DB: db},
-		Tokens:    TokenModel{DB: db},
-		Exercises: ExerciseModel{DB: db},
-		Workouts:  WorkoutModel{DB: db},
+		Users:         UserModel{DB: db, Timeout: queryTimeout},
+		Tokens:        TokenModel{DB: db, Timeout: queryTimeout},
+		Exercises:     ExerciseModel{DB: db, Timeout: queryTimeout},
+		Workouts:      WorkoutModel{DB: db, Timeout: queryTimeout},
+		Imports:       ImportJobModel{DB: db, Timeout: queryTimeout},
+		Measurements:  MeasurementModel{DB: db, Timeout: queryTimeout},
+		Tracks:        TrackModel{DB: db, Timeout: queryTimeout},
+		Sessions:      SessionModel{DB: db, Timeout: queryTimeout},
+		Shares:        ShareModel{DB: db, Timeout: queryTimeout},
+		Coaching:      CoachingModel{DB: db, Timeout: queryTimeout},
+		Audit:         AuditModel{DB: db, Timeout: queryTimeout},
+		Comments:      CommentModel{DB: db, Timeout: queryTimeout},
+		Follows:       FollowModel{DB: db, Timeout: queryTimeout},
+		Webhooks:      WebhookModel{DB: db, Timeout: queryTimeout},
+		Outbox:        OutboxModel{DB: db, Timeout: queryTimeout},
+		Notifications: NotificationModel{DB: db, Timeout: queryTimeout},
+		Reminders:     ReminderModel{DB: db, Timeout: queryTimeout},
 	}
 }
+
+// withQueryTimeout derives the context for a query from the caller's, so
+// that the query is cancelled when the caller gives up, for example because
+// the client disconnected, and never runs longer than timeout.
+func withQueryTimeout(
+	ctx context.Context,
+	timeout time.Duration,
+) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = DefaultQueryTimeout
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,338 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sulemankhann/workout-tracker/internal/validator"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+	ChannelInApp   = "in_app"
+)
+
+var NotificationChannels = []string{ChannelEmail, ChannelWebhook, ChannelInApp}
+
+const NotificationKindWorkoutReminder = "workout_reminder"
+
+// maxLeadMinutes is the furthest ahead of a workout a reminder can be sent:
+// one week.
+const maxLeadMinutes = 7 * 24 * 60
+
+// NotificationPreferences controls which reminders a user gets: one per lead
+// time before each scheduled workout, through each of the channels. An empty
+// list of lead times turns reminders off.
+type NotificationPreferences struct {
+	LeadMinutes []int64    `json:"lead_minutes"`
+	Channels    []string   `json:"channels"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+}
+
+// DefaultNotificationPreferences is used for users who never changed theirs.
+func DefaultNotificationPreferences() *NotificationPreferences {
+	return &NotificationPreferences{
+		LeadMinutes: []int64{60},
+		Channels:    []string{ChannelInApp},
+	}
+}
+
+func ValidateNotificationPreferences(
+	v *validator.Validator,
+	prefs *NotificationPreferences,
+) {
+	v.Check(prefs.LeadMinutes != nil, "lead_minutes", "must be provided")
+	v.Check(len(prefs.LeadMinutes) <= 5, "lead_minutes", "must not contain more than 5 values")
+	v.Check(validator.Unique(prefs.LeadMinutes), "lead_minutes", "must not contain duplicate values")
+
+	for _, lead := range prefs.LeadMinutes {
+		v.Check(
+			lead > 0 && lead <= maxLeadMinutes,
+			"lead_minutes",
+			fmt.Sprintf("must only contain values between 1 and %d", maxLeadMinutes),
+		)
+	}
+
+	v.Check(len(prefs.Channels) > 0, "channels", "must contain at least one channel")
+	v.Check(validator.Unique(prefs.Channels), "channels", "must not contain duplicate values")
+
+	for _, channel := range prefs.Channels {
+		v.Check(
+			slices.Contains(NotificationChannels, channel),
+			"channels",
+			"must only contain "+strings.Join(NotificationChannels, ", "),
+		)
+	}
+}
+
+// Notification is an entry in a user's in-app inbox.
+type Notification struct {
+	ID        int64           `json:"id"`
+	UserID    int64           `json:"-"`
+	Kind      string          `json:"kind"`
+	Title     string          `json:"title"`
+	Body      string          `json:"body"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+	ReadAt    *time.Time      `json:"read_at"`
+}
+
+type NotificationModel struct {
+	DB      *sql.DB
+	Timeout time.Duration
+}
+
+// GetPreferences returns the user's notification preferences, or the
+// defaults if they never set any.
+func (m NotificationModel) GetPreferences(
+	ctx context.Context,
+	userID int64,
+) (*NotificationPreferences, error) {
+	query := `
+        SELECT lead_minutes, channels, updated_at
+        FROM notification_preferences
+        WHERE user_id = $1`
+
+	var prefs NotificationPreferences
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(
+		pq.Array(&prefs.LeadMinutes),
+		pq.Array(&prefs.Channels),
+		&prefs.UpdatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return DefaultNotificationPreferences(), nil
+		default:
+			return nil, err
+		}
+	}
+
+	return &prefs, nil
+}
+
+func (m NotificationModel) SetPreferences(
+	ctx context.Context,
+	userID int64,
+	prefs *NotificationPreferences,
+) error {
+	query := `
+        INSERT INTO notification_preferences (user_id, lead_minutes, channels)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (user_id) DO UPDATE
+        SET lead_minutes = EXCLUDED.lead_minutes, channels = EXCLUDED.channels,
+            updated_at = NOW()
+        RETURNING updated_at`
+
+	args := []any{
+		userID,
+		pq.Array(prefs.LeadMinutes),
+		pq.Array(prefs.Channels),
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&prefs.UpdatedAt)
+}
+
+func (m NotificationModel) Insert(
+	ctx context.Context,
+	notification *Notification,
+) error {
+	query := `
+        INSERT INTO notifications (user_id, kind, title, body, data)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at`
+
+	data := notification.Data
+	if data == nil {
+		data = json.RawMessage("{}")
+	}
+
+	args := []any{
+		notification.UserID,
+		notification.Kind,
+		notification.Title,
+		notification.Body,
+		[]byte(data),
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&notification.ID,
+		&notification.CreatedAt,
+	)
+}
+
+// GetAllForUser returns a page of the user's notifications, newest first,
+// optionally only the unread ones.
+func (m NotificationModel) GetAllForUser(
+	ctx context.Context,
+	userID int64,
+	unreadOnly bool,
+	filters Filters,
+) ([]*Notification, Metadata, error) {
+	query := `
+        SELECT count(*) OVER(), id, user_id, kind, title, body, data, created_at, read_at
+        FROM notifications
+        WHERE user_id = $1
+        AND (read_at IS NULL OR NOT $2)
+        ORDER BY id DESC
+        LIMIT $3 OFFSET $4`
+
+	args := []any{userID, unreadOnly, filters.limit(), filters.offset()}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, fmt.Errorf(
+			"failed to execute query to fetch notifications for user %d: %w",
+			userID,
+			err,
+		)
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	notifications := []*Notification{}
+
+	for rows.Next() {
+		var (
+			notification Notification
+			data         []byte
+		)
+
+		err := rows.Scan(
+			&totalRecords,
+			&notification.ID,
+			&notification.UserID,
+			&notification.Kind,
+			&notification.Title,
+			&notification.Body,
+			&data,
+			&notification.CreatedAt,
+			&notification.ReadAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, fmt.Errorf(
+				"failed to scan notification row: %w",
+				err,
+			)
+		}
+
+		notification.Data = data
+
+		notifications = append(notifications, &notification)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, fmt.Errorf(
+			"error occurred while iterating over notification rows: %w",
+			err,
+		)
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return notifications, metadata, nil
+}
+
+func (m NotificationModel) CountUnread(
+	ctx context.Context,
+	userID int64,
+) (int, error) {
+	query := `
+        SELECT count(*)
+        FROM notifications
+        WHERE user_id = $1 AND read_at IS NULL`
+
+	var count int
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(&count)
+
+	return count, err
+}
+
+// SetRead marks one of the user's notifications as read or unread.
+func (m NotificationModel) SetRead(
+	ctx context.Context,
+	notification *Notification,
+	read bool,
+) error {
+	query := `
+        UPDATE notifications
+        SET read_at = CASE WHEN $3 THEN COALESCE(read_at, NOW()) END
+        WHERE id = $1 AND user_id = $2
+        RETURNING kind, title, body, data, created_at, read_at`
+
+	args := []any{notification.ID, notification.UserID, read}
+
+	var data []byte
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&notification.Kind,
+		&notification.Title,
+		&notification.Body,
+		&data,
+		&notification.CreatedAt,
+		&notification.ReadAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	notification.Data = data
+
+	return nil
+}
+
+// MarkAllRead marks every unread notification of the user as read and
+// returns how many there were.
+func (m NotificationModel) MarkAllRead(
+	ctx context.Context,
+	userID int64,
+) (int64, error) {
+	query := `
+        UPDATE notifications
+        SET read_at = NOW()
+        WHERE user_id = $1 AND read_at IS NULL`
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
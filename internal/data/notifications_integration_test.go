@@ -0,0 +1,634 @@
+//go:build integration
+
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNotificationModel(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	bob := insertTestUser(t, models, "Bob")
+
+	prefs, err := models.Notifications.GetPreferences(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(prefs.LeadMinutes, []int64{60}) ||
+		!slices.Equal(prefs.Channels, []string{ChannelInApp}) ||
+		prefs.UpdatedAt != nil {
+		t.Errorf("default preferences = %+v", prefs)
+	}
+
+	for _, leadMinutes := range [][]int64{{30, 1440}, {15}} {
+		err = models.Notifications.SetPreferences(ctx, alice.ID, &NotificationPreferences{
+			LeadMinutes: leadMinutes,
+			Channels:    []string{ChannelEmail, ChannelWebhook},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prefs, err = models.Notifications.GetPreferences(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(prefs.LeadMinutes, []int64{15}) ||
+		!slices.Equal(prefs.Channels, []string{ChannelEmail, ChannelWebhook}) ||
+		prefs.UpdatedAt == nil {
+		t.Errorf("saved preferences = %+v", prefs)
+	}
+
+	notifications := []*Notification{}
+
+	for _, title := range []string{"First", "Second", "Third"} {
+		notification := &Notification{
+			UserID: alice.ID,
+			Kind:   NotificationKindWorkoutReminder,
+			Title:  title,
+			Body:   "Leg day starts soon",
+		}
+
+		if title == "Third" {
+			notification.Data = json.RawMessage(`{"workout_id": 1}`)
+		}
+
+		err = models.Notifications.Insert(ctx, notification)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		notifications = append(notifications, notification)
+	}
+
+	page, metadata, err := models.Notifications.GetAllForUser(
+		ctx,
+		alice.ID,
+		false,
+		Filters{Page: 1, PageSize: 20},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page) != 3 || page[0].Title != "Third" || metadata.TotalRecords != 3 {
+		t.Fatalf("GetAllForUser returned %+v, %+v; want newest first", page, metadata)
+	}
+
+	if string(page[2].Data) != "{}" {
+		t.Errorf("notification without data has data %s; want {}", page[2].Data)
+	}
+
+	var data struct {
+		WorkoutID int64 `json:"workout_id"`
+	}
+
+	err = json.Unmarshal(page[0].Data, &data)
+	if err != nil || data.WorkoutID != 1 {
+		t.Errorf("notification data = %s, %v", page[0].Data, err)
+	}
+
+	first := notifications[0]
+
+	err = models.Notifications.SetRead(ctx, first, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.ReadAt == nil {
+		t.Error("SetRead didn't set read_at")
+	}
+
+	err = models.Notifications.SetRead(ctx, &Notification{ID: first.ID, UserID: bob.ID}, true)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("SetRead of someone else's notification: err = %v", err)
+	}
+
+	unread, _, err := models.Notifications.GetAllForUser(
+		ctx,
+		alice.ID,
+		true,
+		Filters{Page: 1, PageSize: 20},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(unread) != 2 {
+		t.Errorf("%d unread notifications listed; want 2", len(unread))
+	}
+
+	err = models.Notifications.SetRead(ctx, first, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := models.Notifications.CountUnread(ctx, alice.ID)
+	if err != nil || count != 3 {
+		t.Errorf("CountUnread = %d, %v; want 3", count, err)
+	}
+
+	marked, err := models.Notifications.MarkAllRead(ctx, alice.ID)
+	if err != nil || marked != 3 {
+		t.Errorf("MarkAllRead = %d, %v; want 3", marked, err)
+	}
+
+	count, err = models.Notifications.CountUnread(ctx, alice.ID)
+	if err != nil || count != 0 {
+		t.Errorf("CountUnread after MarkAllRead = %d, %v; want 0", count, err)
+	}
+}
+
+// insertScheduledWorkout adds an incomplete workout for the user starting in
+// the given time from now.
+func insertScheduledWorkout(
+	t *testing.T,
+	models Models,
+	user *User,
+	exercise *Exercise,
+	in time.Duration,
+) *Workout {
+	t.Helper()
+
+	workout := insertTestWorkout(t, models, user, exercise, nil)
+	workout.ScheduledAt = time.Now().Add(in).Truncate(time.Second)
+
+	err := models.Workouts.ScheduleWorkout(context.Background(), workout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return workout
+}
+
+func TestReminderModel(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	bob := insertTestUser(t, models, "Bob")
+	carol := insertTestUser(t, models, "Carol")
+	squat := insertTestExercise(t, models, "Squat", MeasurementRepsWeight)
+
+	// Alice has the defaults: an in-app reminder an hour ahead.
+	soon := insertScheduledWorkout(t, models, alice, squat, 30*time.Minute)
+	insertScheduledWorkout(t, models, alice, squat, 3*time.Hour)
+	insertScheduledWorkout(t, models, alice, squat, -time.Hour)
+
+	done := insertScheduledWorkout(t, models, alice, squat, 20*time.Minute)
+	done.CompletedAt = ago(0)
+
+	err := models.Workouts.Complete(ctx, done)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Bob wants reminders two hours and half an hour ahead by email and in
+	// the app, so only the two hour reminders are due yet.
+	err = models.Notifications.SetPreferences(ctx, bob.ID, &NotificationPreferences{
+		LeadMinutes: []int64{120, 30},
+		Channels:    []string{ChannelEmail, ChannelInApp},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bobs := insertScheduledWorkout(t, models, bob, squat, 90*time.Minute)
+
+	// Carol turned reminders off.
+	err = models.Notifications.SetPreferences(ctx, carol.ID, &NotificationPreferences{
+		LeadMinutes: []int64{},
+		Channels:    []string{ChannelInApp},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	insertScheduledWorkout(t, models, carol, squat, 10*time.Minute)
+
+	reminders, err := models.Reminders.GetDue(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reminders) != 3 {
+		t.Fatalf("GetDue returned %d reminders; want 3", len(reminders))
+	}
+
+	if r := reminders[0]; r.WorkoutID != soon.ID || r.LeadMinutes != 60 ||
+		r.Channel != ChannelInApp || r.User.Email != alice.Email {
+		t.Errorf("first reminder = %+v", r)
+	}
+
+	for _, r := range reminders[1:] {
+		if r.WorkoutID != bobs.ID || r.LeadMinutes != 120 {
+			t.Errorf("reminder = %+v; want bob's two hour reminder", r)
+		}
+	}
+
+	claimed, err := models.Reminders.Claim(ctx, reminders[0])
+	if err != nil || !claimed {
+		t.Fatalf("Claim = %t, %v; want true", claimed, err)
+	}
+
+	claimed, err = models.Reminders.Claim(ctx, reminders[0])
+	if err != nil || claimed {
+		t.Errorf("second Claim = %t, %v; want false", claimed, err)
+	}
+
+	reminders, err = models.Reminders.GetDue(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reminders) != 2 {
+		t.Errorf("GetDue after claiming returned %d reminders; want 2", len(reminders))
+	}
+
+	// Rescheduling the workout re-arms its reminder.
+	soon.ScheduledAt = soon.ScheduledAt.Add(10 * time.Minute)
+
+	err = models.Workouts.ScheduleWorkout(ctx, soon)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reminders, err = models.Reminders.GetDue(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reminders) != 1 || reminders[0].WorkoutID != soon.ID ||
+		!reminders[0].ScheduledAt.Equal(soon.ScheduledAt) {
+		t.Fatalf("GetDue after rescheduling returned %+v", reminders)
+	}
+
+	reminder := reminders[0]
+
+	claimed, err = models.Reminders.Claim(ctx, reminder)
+	if err != nil || !claimed || reminder.Attempts != 1 {
+		t.Fatalf(
+			"Claim = %t, %v with %d attempts; want true with 1",
+			claimed,
+			err,
+			reminder.Attempts,
+		)
+	}
+
+	// A failed send waits for its retry time before it is due again.
+	reminder.RetryAt = ago(-time.Hour)
+	reminder.LastError = "mail server unavailable"
+
+	err = models.Reminders.RecordFailure(ctx, reminder)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reminders, err = models.Reminders.GetDue(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reminders) != 2 {
+		t.Errorf("GetDue while backing off returned %d reminders; want 2", len(reminders))
+	}
+
+	reminder.RetryAt = ago(time.Minute)
+
+	err = models.Reminders.RecordFailure(ctx, reminder)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reminders, err = models.Reminders.GetDue(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reminders) != 3 {
+		t.Errorf("GetDue after the retry time returned %d reminders; want 3", len(reminders))
+	}
+
+	claimed, err = models.Reminders.Claim(ctx, reminder)
+	if err != nil || !claimed || reminder.Attempts != 2 {
+		t.Fatalf(
+			"retry Claim = %t, %v with %d attempts; want true with 2",
+			claimed,
+			err,
+			reminder.Attempts,
+		)
+	}
+
+	claimed, err = models.Reminders.Claim(ctx, reminder)
+	if err != nil || claimed {
+		t.Errorf("Claim of a reminder being retried = %t, %v; want false", claimed, err)
+	}
+
+	// Giving up leaves the reminder claimed for good.
+	reminder.RetryAt = nil
+
+	err = models.Reminders.RecordFailure(ctx, reminder)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := countRows(t, "reminders_sent", "retry_at IS NULL AND last_error <> ''"); n != 1 {
+		t.Errorf("got %d given up reminders; want 1", n)
+	}
+
+	reminders, err = models.Reminders.GetDue(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reminders) != 2 {
+		t.Errorf("GetDue after giving up returned %d reminders; want 2", len(reminders))
+	}
+}
+
+func TestWebhookModel(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	bob := insertTestUser(t, models, "Bob")
+
+	created := &Webhook{
+		UserID: alice.ID,
+		URL:    "https://example.com/created",
+		Events: []string{EventWorkoutCreated, EventWorkoutCompleted},
+	}
+	deleted := &Webhook{
+		UserID: alice.ID,
+		URL:    "https://example.com/deleted",
+		Events: []string{EventWorkoutDeleted},
+	}
+	bobs := &Webhook{
+		UserID: bob.ID,
+		URL:    "https://example.com/bob",
+		Events: []string{EventWorkoutCreated},
+	}
+
+	for _, webhook := range []*Webhook{created, deleted, bobs} {
+		err := models.Webhooks.New(ctx, webhook)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if webhook.Secret == "" {
+			t.Error("New didn't generate a secret")
+		}
+	}
+
+	webhooks, err := models.Webhooks.GetAllForUser(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(webhooks) != 2 || webhooks[0].ID != created.ID ||
+		!slices.Equal(webhooks[0].Events, created.Events) {
+		t.Errorf("GetAllForUser returned %+v", webhooks)
+	}
+
+	_, err = models.Webhooks.GetByUser(ctx, created.ID, bob.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("GetByUser of someone else's webhook: err = %v", err)
+	}
+
+	payload := []byte(`{"event": "workout.created"}`)
+
+	// Only the webhook subscribed to the event gets it, and only once.
+	for range 2 {
+		err = models.Webhooks.Enqueue(ctx, alice.ID, EventWorkoutCreated, "key-1", payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = models.Webhooks.Enqueue(ctx, alice.ID, EventWorkoutDeleted, "key-2", payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := countRows(t, "webhook_deliveries", "true"); n != 2 {
+		t.Fatalf("%d deliveries queued; want 2", n)
+	}
+
+	deliveries, err := models.Webhooks.ClaimDue(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(deliveries) != 2 {
+		t.Fatalf("ClaimDue returned %d deliveries; want 2", len(deliveries))
+	}
+
+	for _, delivery := range deliveries {
+		if delivery.URL == "" || delivery.Secret == "" || len(delivery.Payload) == 0 {
+			t.Errorf("claimed delivery is missing its details: %+v", delivery)
+		}
+	}
+
+	// Both deliveries are leased, so nothing else is due.
+	again, err := models.Webhooks.ClaimDue(ctx, 10, time.Minute)
+	if err != nil || len(again) != 0 {
+		t.Errorf("ClaimDue during the lease returned %d, %v; want none", len(again), err)
+	}
+
+	now := time.Now()
+	status := 200
+
+	for _, delivery := range deliveries {
+		delivery.Attempts++
+		delivery.LastAttemptAt = &now
+
+		if delivery.WebhookID == created.ID {
+			delivery.Status = DeliveryStatusSucceeded
+			delivery.ResponseStatus = &status
+		} else {
+			retryAt := now.Add(-time.Second)
+
+			delivery.NextAttemptAt = &retryAt
+			delivery.LastError = "connection refused"
+		}
+
+		err = models.Webhooks.RecordAttempt(ctx, delivery)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	retried, err := models.Webhooks.ClaimDue(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(retried) != 1 || retried[0].WebhookID != deleted.ID || retried[0].Attempts != 1 {
+		t.Errorf("ClaimDue after a failure returned %+v; want the failed delivery", retried)
+	}
+
+	log, metadata, err := models.Webhooks.GetDeliveriesForWebhook(
+		ctx,
+		created.ID,
+		alice.ID,
+		Filters{Page: 1, PageSize: 20},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(log) != 1 || log[0].Status != DeliveryStatusSucceeded ||
+		log[0].NextAttemptAt != nil || log[0].ResponseStatus == nil ||
+		metadata.TotalRecords != 1 {
+		t.Errorf("GetDeliveriesForWebhook returned %+v, %+v", log, metadata)
+	}
+
+	log, _, err = models.Webhooks.GetDeliveriesForWebhook(
+		ctx,
+		created.ID,
+		bob.ID,
+		Filters{Page: 1, PageSize: 20},
+	)
+	if err != nil || len(log) != 0 {
+		t.Errorf("someone else sees %d deliveries, %v; want none", len(log), err)
+	}
+
+	err = models.Webhooks.DeleteByUser(ctx, created.ID, bob.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("DeleteByUser of someone else's webhook: err = %v", err)
+	}
+
+	err = models.Webhooks.DeleteByUser(ctx, created.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := countRows(t, "webhook_deliveries", "webhook_id = $1", created.ID); n != 0 {
+		t.Errorf("%d deliveries left for the deleted webhook", n)
+	}
+}
+
+func TestOutboxModel(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	squat := insertTestExercise(t, models, "Squat", MeasurementRepsWeight)
+	workout := insertTestWorkout(t, models, alice, squat, nil)
+
+	events, err := models.Outbox.Claim(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("Claim returned %d events; want 1", len(events))
+	}
+
+	event := events[0]
+
+	var payload struct {
+		ID   string `json:"id"`
+		Data struct {
+			Workout struct {
+				ID    int64  `json:"id"`
+				Title string `json:"title"`
+			} `json:"workout"`
+		} `json:"data"`
+	}
+
+	err = json.Unmarshal(event.Payload, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if event.Type != EventWorkoutCreated || event.UserID != alice.ID ||
+		payload.ID != event.Key || payload.Data.Workout.ID != workout.ID ||
+		payload.Data.Workout.Title != workout.Title {
+		t.Errorf("claimed event %+v with payload %+v", event, payload)
+	}
+
+	events, err = models.Outbox.Claim(ctx, 10, time.Minute)
+	if err != nil || len(events) != 0 {
+		t.Errorf("Claim during the lease returned %d, %v; want none", len(events), err)
+	}
+
+	if len(event.Delivered) != 0 {
+		t.Errorf("new event delivered to %v; want none", event.Delivered)
+	}
+
+	err = models.Outbox.MarkFailed(
+		ctx,
+		event.ID,
+		1,
+		time.Now().Add(-time.Second),
+		"webhooks: sink down",
+		[]string{"subscribers"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err = models.Outbox.Claim(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 1 || events[0].Attempts != 1 ||
+		!slices.Equal(events[0].Delivered, []string{"subscribers"}) {
+		t.Fatalf("Claim after a failure returned %+v; want the event again", events)
+	}
+
+	err = models.Outbox.MarkDispatched(ctx, event.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = testDB.ExecContext(ctx, "UPDATE outbox SET next_attempt_at = NOW()")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err = models.Outbox.Claim(ctx, 10, time.Minute)
+	if err != nil || len(events) != 0 {
+		t.Errorf("Claim after dispatching returned %d, %v; want none", len(events), err)
+	}
+
+	// Only events dispatched before the cutoff are deleted; pending ones are
+	// kept however old they are.
+	insertTestWorkout(t, models, alice, squat, nil)
+
+	_, err = testDB.ExecContext(ctx, "UPDATE outbox SET created_at = NOW() - interval '30 days'")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := models.Outbox.DeleteDispatched(ctx, time.Now().Add(-time.Hour))
+	if err != nil || deleted != 0 {
+		t.Errorf("DeleteDispatched of recent events = %d, %v; want 0", deleted, err)
+	}
+
+	deleted, err = models.Outbox.DeleteDispatched(ctx, time.Now().Add(time.Minute))
+	if err != nil || deleted != 1 {
+		t.Errorf("DeleteDispatched = %d, %v; want 1", deleted, err)
+	}
+
+	if n := countRows(t, "outbox", "id = $1", event.ID); n != 0 {
+		t.Error("the dispatched event is still in the outbox")
+	}
+
+	if n := countRows(t, "outbox", "dispatched_at IS NULL"); n != 1 {
+		t.Errorf("got %d pending events; want 1", n)
+	}
+}
@@ -0,0 +1,258 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sulemankhann/workout-tracker/internal/events"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// newWorkoutEvent builds the outbox event describing a change to workout.
+// The workout is presented in its owner's units, as the API would show it,
+// except for deletions which only carry the workout's id.
+func newWorkoutEvent(
+	eventType string,
+	workout *Workout,
+	units Units,
+) (*events.Event, error) {
+	var workoutData any = workout.InUnits(units)
+
+	if eventType == EventWorkoutDeleted {
+		workoutData = map[string]int64{"id": workout.ID}
+	}
+
+	return newEvent(
+		eventType,
+		workout.UserID,
+		map[string]any{"workout": workoutData},
+	)
+}
+
+// newEvent builds an outbox event of eventType for the user, wrapping data
+// in the envelope every event payload shares.
+func newEvent(
+	eventType string,
+	userID int64,
+	data map[string]any,
+) (*events.Event, error) {
+	key, _, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+
+	payload, err := json.Marshal(map[string]any{
+		"id":         key,
+		"event":      eventType,
+		"created_at": now,
+		"data":       data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &events.Event{
+		Key:       key,
+		UserID:    userID,
+		Type:      eventType,
+		Payload:   payload,
+		CreatedAt: now,
+	}, nil
+}
+
+// insertOutboxEvent stages an event in the outbox as part of tx, so it is
+// only dispatched if the change it describes is committed.
+func insertOutboxEvent(
+	ctx context.Context,
+	tx *sql.Tx,
+	event *events.Event,
+) error {
+	query := `
+        INSERT INTO outbox (idempotency_key, user_id, event, payload, created_at)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id`
+
+	args := []any{
+		event.Key,
+		event.UserID,
+		event.Type,
+		[]byte(event.Payload),
+		event.CreatedAt,
+	}
+
+	return tx.QueryRowContext(ctx, query, args...).Scan(&event.ID)
+}
+
+// writeWorkoutEvent stages the event for a change to workout in tx.
+func writeWorkoutEvent(
+	ctx context.Context,
+	tx *sql.Tx,
+	eventType string,
+	workout *Workout,
+) error {
+	units, err := unitsForUser(ctx, tx, workout.UserID)
+	if err != nil {
+		return err
+	}
+
+	event, err := newWorkoutEvent(eventType, workout, units)
+	if err != nil {
+		return err
+	}
+
+	return insertOutboxEvent(ctx, tx, event)
+}
+
+func unitsForUser(ctx context.Context, tx *sql.Tx, userID int64) (Units, error) {
+	query := `
+        SELECT weight_unit, distance_unit, plate_rounding
+        FROM users
+        WHERE id = $1`
+
+	var units Units
+
+	err := tx.QueryRowContext(ctx, query, userID).Scan(
+		&units.Weight,
+		&units.Distance,
+		&units.PlateRounding,
+	)
+
+	return units, err
+}
+
+// OutboxModel is the events.Store backed by the outbox table.
+type OutboxModel struct {
+	DB      *sql.DB
+	Timeout time.Duration
+}
+
+func (m OutboxModel) Claim(
+	ctx context.Context,
+	limit int,
+	lease time.Duration,
+) ([]*events.Event, error) {
+	query := `
+        UPDATE outbox
+        SET next_attempt_at = NOW() + $2 * interval '1 second'
+        WHERE id IN (
+            SELECT id FROM outbox
+            WHERE dispatched_at IS NULL AND next_attempt_at <= NOW()
+            ORDER BY id
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING id, idempotency_key, user_id, event, payload, attempts,
+            delivered_sinks, created_at`
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+
+	defer rows.Close()
+
+	batch := []*events.Event{}
+
+	for rows.Next() {
+		var (
+			event   events.Event
+			payload []byte
+		)
+
+		err := rows.Scan(
+			&event.ID,
+			&event.Key,
+			&event.UserID,
+			&event.Type,
+			&payload,
+			&event.Attempts,
+			pq.Array(&event.Delivered),
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox row: %w", err)
+		}
+
+		event.Payload = payload
+
+		batch = append(batch, &event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"error occurred while iterating over outbox rows: %w",
+			err,
+		)
+	}
+
+	return batch, nil
+}
+
+func (m OutboxModel) MarkDispatched(ctx context.Context, id int64) error {
+	query := `
+        UPDATE outbox
+        SET dispatched_at = NOW(), last_error = ''
+        WHERE id = $1`
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+func (m OutboxModel) MarkFailed(
+	ctx context.Context,
+	id int64,
+	attempts int,
+	next time.Time,
+	reason string,
+	delivered []string,
+) error {
+	query := `
+        UPDATE outbox
+        SET attempts = $2, next_attempt_at = $3, last_error = $4,
+            delivered_sinks = $5
+        WHERE id = $1`
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(
+		ctx,
+		query,
+		id,
+		attempts,
+		next,
+		reason,
+		pq.Array(delivered),
+	)
+	return err
+}
+
+func (m OutboxModel) DeleteDispatched(
+	ctx context.Context,
+	before time.Time,
+) (int64, error) {
+	query := `
+        DELETE FROM outbox
+        WHERE dispatched_at < $1`
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete dispatched outbox events: %w", err)
+	}
+
+	return result.RowsAffected()
+}
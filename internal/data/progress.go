@@ -0,0 +1,149 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ExerciseHistoryEntry is a single completed performance of an exercise.
+type ExerciseHistoryEntry struct {
+	ExerciseID       int64     `json:"-"`
+	ExerciseName     string    `json:"-"`
+	WorkoutID        int64     `json:"workout_id"`
+	CompletedAt      time.Time `json:"completed_at"`
+	Sets             int       `json:"sets"`
+	Repetitions      int       `json:"repetitions"`
+	Weight           float64   `json:"weight"`
+	WeightUnit       string    `json:"weight_unit"`
+	Bodyweight       *float64  `json:"bodyweight"`
+	RelativeStrength *float64  `json:"relative_strength"`
+}
+
+type ExerciseProgress struct {
+	ExerciseID   int64                   `json:"exercise_id"`
+	ExerciseName string                  `json:"exercise_name"`
+	Best         *ExerciseHistoryEntry   `json:"best"`
+	Latest       *ExerciseHistoryEntry   `json:"latest"`
+	History      []*ExerciseHistoryEntry `json:"history"`
+}
+
+// GetExerciseHistoryForUser returns every performance of every exercise in
+// the user's completed workouts in chronological order. If exerciseID is
+// non-zero only that exercise is returned.
+func (m WorkoutModel) GetExerciseHistoryForUser(
+	ctx context.Context,
+	userID, exerciseID int64,
+) ([]*ExerciseHistoryEntry, error) {
+	query := `
+        SELECT e.id, e.name, w.id, w.completed_at, we.sets, we.repetitions, we.weight
+        FROM workout_exercises we
+        JOIN workouts w ON we.workout_id = w.id
+        JOIN exercises e ON we.exercise_id = e.id
+        WHERE w.user_id = $1
+        AND w.completed_at IS NOT NULL
+        AND ($2::bigint = 0 OR e.id = $2)
+        ORDER BY w.completed_at, we.id`
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, exerciseID)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to execute query to fetch exercise history for user %d: %w",
+			userID,
+			err,
+		)
+	}
+
+	defer rows.Close()
+
+	history := []*ExerciseHistoryEntry{}
+
+	for rows.Next() {
+		var entry ExerciseHistoryEntry
+
+		err := rows.Scan(
+			&entry.ExerciseID,
+			&entry.ExerciseName,
+			&entry.WorkoutID,
+			&entry.CompletedAt,
+			&entry.Sets,
+			&entry.Repetitions,
+			&entry.Weight,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan exercise history row: %w", err)
+		}
+
+		history = append(history, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"error occurred while iterating over exercise history rows: %w",
+			err,
+		)
+	}
+
+	return history, nil
+}
+
+// BuildProgressReport groups exercise history by exercise and annotates each
+// entry with the bodyweight recorded nearest to it and the resulting
+// relative strength (weight lifted divided by bodyweight). Bodyweight-only
+// entries with no external load have no relative strength. measurements must
+// be in chronological order.
+func BuildProgressReport(
+	history []*ExerciseHistoryEntry,
+	measurements []*Measurement,
+) []*ExerciseProgress {
+	report := []*ExerciseProgress{}
+	index := make(map[int64]*ExerciseProgress)
+
+	for _, entry := range history {
+		entry.Bodyweight = NearestBodyweight(measurements, entry.CompletedAt)
+
+		if entry.Bodyweight != nil && entry.Weight > 0 {
+			ratio := round(entry.Weight/(*entry.Bodyweight), 2)
+			entry.RelativeStrength = &ratio
+		}
+
+		progress, ok := index[entry.ExerciseID]
+		if !ok {
+			progress = &ExerciseProgress{
+				ExerciseID:   entry.ExerciseID,
+				ExerciseName: entry.ExerciseName,
+				History:      []*ExerciseHistoryEntry{},
+			}
+			index[entry.ExerciseID] = progress
+			report = append(report, progress)
+		}
+
+		progress.History = append(progress.History, entry)
+		progress.Latest = entry
+
+		if progress.Best == nil ||
+			entry.Weight > progress.Best.Weight ||
+			(entry.Weight == progress.Best.Weight &&
+				entry.Repetitions > progress.Best.Repetitions) {
+			progress.Best = entry
+		}
+	}
+
+	return report
+}
+
+// ConvertProgressReport converts the weights and bodyweights in a report
+// from kilograms into the given units in place. Relative strength is a ratio
+// and is unaffected.
+func ConvertProgressReport(report []*ExerciseProgress, units Units) {
+	for _, progress := range report {
+		for _, entry := range progress.History {
+			entry.Weight = units.DisplayWeight(entry.Weight)
+			entry.Bodyweight = units.DisplayBodyweight(entry.Bodyweight)
+			entry.WeightUnit = units.Weight
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package data
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNearestBodyweight(t *testing.T) {
+	day := func(n int) time.Time {
+		return time.Date(2024, time.June, n, 8, 0, 0, 0, time.UTC)
+	}
+
+	measurements := []*Measurement{
+		{MeasuredAt: day(1), Bodyweight: floatPtr(80)},
+		// Body fat only, so it is passed over.
+		{MeasuredAt: day(5), BodyFatPercentage: floatPtr(18)},
+		{MeasuredAt: day(10), Bodyweight: floatPtr(82)},
+	}
+
+	tests := []struct {
+		name         string
+		measurements []*Measurement
+		at           time.Time
+		want         *float64
+	}{
+		{
+			name: "no measurements",
+			at:   day(3),
+		},
+		{
+			name:         "no bodyweights",
+			measurements: measurements[1:2],
+			at:           day(3),
+		},
+		{
+			name:         "single measurement",
+			measurements: measurements[:1],
+			at:           day(20),
+			want:         floatPtr(80),
+		},
+		{
+			name:         "before the first",
+			measurements: measurements,
+			at:           day(1).Add(-48 * time.Hour),
+			want:         floatPtr(80),
+		},
+		{
+			name:         "at a measurement",
+			measurements: measurements,
+			at:           day(10),
+			want:         floatPtr(82),
+		},
+		{
+			name:         "closer to the earlier",
+			measurements: measurements,
+			at:           day(3),
+			want:         floatPtr(80),
+		},
+		{
+			name:         "closer to the later",
+			measurements: measurements,
+			at:           day(8),
+			want:         floatPtr(82),
+		},
+		{
+			name:         "skips a measurement without bodyweight",
+			measurements: measurements,
+			at:           day(5),
+			want:         floatPtr(80),
+		},
+		{
+			name:         "halfway takes the earlier",
+			measurements: measurements,
+			at:           day(1).Add(4*24*time.Hour + 12*time.Hour),
+			want:         floatPtr(80),
+		},
+		{
+			name:         "after the last",
+			measurements: measurements,
+			at:           day(30),
+			want:         floatPtr(82),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NearestBodyweight(tt.measurements, tt.at)
+
+			if !equalFloatPtr(got, tt.want) {
+				t.Errorf("NearestBodyweight = %s; want %s", formatFloatPtr(got), formatFloatPtr(tt.want))
+			}
+		})
+	}
+}
+
+func TestBuildProgressReport(t *testing.T) {
+	day := func(n int) time.Time {
+		return time.Date(2024, time.June, n, 18, 0, 0, 0, time.UTC)
+	}
+
+	// entry is a performance in workout n, which was completed on day n.
+	entry := func(
+		exerciseID int64,
+		name string,
+		workoutID int64,
+		weight float64,
+		reps int,
+	) *ExerciseHistoryEntry {
+		return &ExerciseHistoryEntry{
+			ExerciseID:   exerciseID,
+			ExerciseName: name,
+			WorkoutID:    workoutID,
+			CompletedAt:  day(int(workoutID)),
+			Sets:         3,
+			Repetitions:  reps,
+			Weight:       weight,
+		}
+	}
+
+	type wantEntry struct {
+		bodyweight       *float64
+		relativeStrength *float64
+	}
+
+	type wantProgress struct {
+		exerciseID int64
+		history    []int64 // workout IDs, in order
+		best       int64
+		latest     int64
+	}
+
+	tests := []struct {
+		name         string
+		history      []*ExerciseHistoryEntry
+		measurements []*Measurement
+		want         []wantProgress
+		// wantEntries are keyed by workout ID.
+		wantEntries map[int64]wantEntry
+	}{
+		{
+			name: "no history",
+		},
+		{
+			name:    "single data point",
+			history: []*ExerciseHistoryEntry{entry(1, "Squat", 1, 100, 5)},
+			measurements: []*Measurement{
+				{MeasuredAt: day(1), Bodyweight: floatPtr(80)},
+			},
+			want: []wantProgress{
+				{exerciseID: 1, history: []int64{1}, best: 1, latest: 1},
+			},
+			wantEntries: map[int64]wantEntry{
+				1: {bodyweight: floatPtr(80), relativeStrength: floatPtr(1.25)},
+			},
+		},
+		{
+			name: "no bodyweight recorded",
+			history: []*ExerciseHistoryEntry{
+				entry(1, "Squat", 1, 100, 5),
+				entry(1, "Squat", 2, 105, 5),
+			},
+			want: []wantProgress{
+				{exerciseID: 1, history: []int64{1, 2}, best: 2, latest: 2},
+			},
+			wantEntries: map[int64]wantEntry{
+				1: {},
+				2: {},
+			},
+		},
+		{
+			// The lifter skipped weighing in for most of the month, so every
+			// session uses whichever weigh-in was closest.
+			name: "gaps between weigh-ins",
+			history: []*ExerciseHistoryEntry{
+				entry(1, "Squat", 2, 100, 5),
+				entry(2, "Bench Press", 3, 70, 8),
+				entry(1, "Squat", 12, 110, 3),
+				entry(1, "Squat", 20, 110, 5),
+				entry(3, "Pull-up", 21, 0, 10),
+				entry(1, "Squat", 28, 90, 8),
+			},
+			measurements: []*Measurement{
+				{MeasuredAt: day(1), Bodyweight: floatPtr(80)},
+				{MeasuredAt: day(25), Bodyweight: floatPtr(84)},
+			},
+			want: []wantProgress{
+				// The heaviest weight wins, with repetitions breaking ties.
+				{exerciseID: 1, history: []int64{2, 12, 20, 28}, best: 20, latest: 28},
+				{exerciseID: 2, history: []int64{3}, best: 3, latest: 3},
+				{exerciseID: 3, history: []int64{21}, best: 21, latest: 21},
+			},
+			wantEntries: map[int64]wantEntry{
+				2:  {bodyweight: floatPtr(80), relativeStrength: floatPtr(1.25)},
+				3:  {bodyweight: floatPtr(80), relativeStrength: floatPtr(0.88)},
+				12: {bodyweight: floatPtr(80), relativeStrength: floatPtr(1.38)},
+				20: {bodyweight: floatPtr(84), relativeStrength: floatPtr(1.31)},
+				// Bodyweight exercises have no relative strength.
+				21: {bodyweight: floatPtr(84)},
+				28: {bodyweight: floatPtr(84), relativeStrength: floatPtr(1.07)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := BuildProgressReport(tt.history, tt.measurements)
+
+			if report == nil {
+				t.Fatal("report is nil; want an empty list")
+			}
+
+			if len(report) != len(tt.want) {
+				t.Fatalf("report has %d exercises; want %d", len(report), len(tt.want))
+			}
+
+			for i, want := range tt.want {
+				progress := report[i]
+
+				if progress.ExerciseID != want.exerciseID {
+					t.Errorf("exercise %d = %d; want %d", i, progress.ExerciseID, want.exerciseID)
+					continue
+				}
+
+				history := make([]int64, 0, len(progress.History))
+				for _, entry := range progress.History {
+					history = append(history, entry.WorkoutID)
+				}
+
+				if !slices.Equal(history, want.history) {
+					t.Errorf("exercise %d history = %v; want %v", want.exerciseID, history, want.history)
+				}
+
+				if progress.Best.WorkoutID != want.best || progress.Latest.WorkoutID != want.latest {
+					t.Errorf(
+						"exercise %d best, latest = workouts %d, %d; want %d, %d",
+						want.exerciseID,
+						progress.Best.WorkoutID,
+						progress.Latest.WorkoutID,
+						want.best,
+						want.latest,
+					)
+				}
+
+				for _, entry := range progress.History {
+					want := tt.wantEntries[entry.WorkoutID]
+
+					if !equalFloatPtr(entry.Bodyweight, want.bodyweight) ||
+						!equalFloatPtr(entry.RelativeStrength, want.relativeStrength) {
+						t.Errorf(
+							"workout %d bodyweight, relative strength = %s, %s; want %s, %s",
+							entry.WorkoutID,
+							formatFloatPtr(entry.Bodyweight),
+							formatFloatPtr(entry.RelativeStrength),
+							formatFloatPtr(want.bodyweight),
+							formatFloatPtr(want.relativeStrength),
+						)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestConvertProgressReport(t *testing.T) {
+	tests := []struct {
+		name           string
+		units          Units
+		wantWeight     float64
+		wantBodyweight *float64
+		wantUnit       string
+	}{
+		{
+			name:           "kilograms",
+			units:          Units{Weight: UnitKilograms},
+			wantWeight:     102.5,
+			wantBodyweight: floatPtr(81.3),
+			wantUnit:       UnitKilograms,
+		},
+		{
+			name:           "pounds",
+			units:          Units{Weight: UnitPounds},
+			wantWeight:     225.97,
+			wantBodyweight: floatPtr(179.24),
+			wantUnit:       UnitPounds,
+		},
+		{
+			name:           "pounds with plate rounding",
+			units:          Units{Weight: UnitPounds, PlateRounding: true},
+			wantWeight:     225,
+			wantBodyweight: floatPtr(179.24),
+			wantUnit:       UnitPounds,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &ExerciseHistoryEntry{
+				WorkoutID:        1,
+				Weight:           102.5,
+				Bodyweight:       floatPtr(81.3),
+				RelativeStrength: floatPtr(1.26),
+			}
+			bodyweightOnly := &ExerciseHistoryEntry{WorkoutID: 2}
+
+			report := []*ExerciseProgress{{
+				ExerciseID: 1,
+				Best:       entry,
+				Latest:     bodyweightOnly,
+				History:    []*ExerciseHistoryEntry{entry, bodyweightOnly},
+			}}
+
+			ConvertProgressReport(report, tt.units)
+
+			if entry.Weight != tt.wantWeight || !equalFloatPtr(entry.Bodyweight, tt.wantBodyweight) ||
+				entry.WeightUnit != tt.wantUnit {
+				t.Errorf(
+					"entry = %v %s at bodyweight %s; want %v %s at %s",
+					entry.Weight,
+					entry.WeightUnit,
+					formatFloatPtr(entry.Bodyweight),
+					tt.wantWeight,
+					tt.wantUnit,
+					formatFloatPtr(tt.wantBodyweight),
+				)
+			}
+
+			// A ratio is the same in any unit.
+			if !equalFloatPtr(entry.RelativeStrength, floatPtr(1.26)) {
+				t.Errorf("relative strength = %s; want 1.26", formatFloatPtr(entry.RelativeStrength))
+			}
+
+			if bodyweightOnly.Weight != 0 || bodyweightOnly.Bodyweight != nil ||
+				bodyweightOnly.WeightUnit != tt.wantUnit {
+				t.Errorf("bodyweight-only entry = %+v", bodyweightOnly)
+			}
+
+			// Best and Latest point into the history, so they are converted
+			// too rather than twice.
+			if report[0].Best.Weight != tt.wantWeight {
+				t.Errorf("best weight = %v; want %v", report[0].Best.Weight, tt.wantWeight)
+			}
+		})
+	}
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func equalFloatPtr(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+func formatFloatPtr(p *float64) string {
+	if p == nil {
+		return "nil"
+	}
+
+	return fmt.Sprint(*p)
+}
@@ -0,0 +1,168 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Reminder is one reminder due to be sent through one channel for an
+// upcoming workout. Attempts counts the sends tried so far, including the
+// one the reminder was last claimed for.
+type Reminder struct {
+	WorkoutID    int64
+	WorkoutTitle string
+	ScheduledAt  time.Time
+	LeadMinutes  int64
+	Channel      string
+	User         User
+	Attempts     int
+	RetryAt      *time.Time
+	LastError    string
+}
+
+type ReminderModel struct {
+	DB      *sql.DB
+	Timeout time.Duration
+}
+
+// GetDue returns up to limit reminders whose send time has passed for
+// workouts that are still upcoming and incomplete, skipping any already
+// sent or given up on and those waiting to be retried. Users without
+// preferences get the defaults.
+func (m ReminderModel) GetDue(
+	ctx context.Context,
+	limit int,
+) ([]*Reminder, error) {
+	query := `
+        SELECT w.id, w.title, w.scheduled_at, l.lead, c.channel,
+            u.id, u.name, u.email, u.timezone
+        FROM workouts w
+        JOIN users u ON w.user_id = u.id
+        LEFT JOIN notification_preferences p ON p.user_id = u.id
+        CROSS JOIN LATERAL unnest(COALESCE(p.lead_minutes, '{60}')) AS l(lead)
+        CROSS JOIN LATERAL unnest(COALESCE(p.channels, '{in_app}')) AS c(channel)
+        WHERE w.completed_at IS NULL
+        AND w.scheduled_at > NOW()
+        AND w.scheduled_at - l.lead * interval '1 minute' <= NOW()
+        AND NOT EXISTS (
+            SELECT 1 FROM reminders_sent s
+            WHERE s.workout_id = w.id
+            AND s.scheduled_at = w.scheduled_at
+            AND s.lead_minutes = l.lead
+            AND s.channel = c.channel
+            AND (s.retry_at IS NULL OR s.retry_at > NOW())
+        )
+        ORDER BY w.scheduled_at, w.id
+        LIMIT $1`
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query to fetch due reminders: %w", err)
+	}
+
+	defer rows.Close()
+
+	reminders := []*Reminder{}
+
+	for rows.Next() {
+		var reminder Reminder
+
+		err := rows.Scan(
+			&reminder.WorkoutID,
+			&reminder.WorkoutTitle,
+			&reminder.ScheduledAt,
+			&reminder.LeadMinutes,
+			&reminder.Channel,
+			&reminder.User.ID,
+			&reminder.User.Name,
+			&reminder.User.Email,
+			&reminder.User.Timezone,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reminder row: %w", err)
+		}
+
+		reminders = append(reminders, &reminder)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"error occurred while iterating over reminder rows: %w",
+			err,
+		)
+	}
+
+	return reminders, nil
+}
+
+// Claim records that the reminder is being sent and reports whether this
+// caller won it. A reminder can only be claimed once, or again once a
+// failed attempt is due to be retried, so it is never sent twice even
+// across restarts or by concurrent schedulers.
+func (m ReminderModel) Claim(
+	ctx context.Context,
+	reminder *Reminder,
+) (bool, error) {
+	query := `
+        INSERT INTO reminders_sent (workout_id, scheduled_at, lead_minutes, channel)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (workout_id, scheduled_at, lead_minutes, channel) DO UPDATE
+        SET attempts = reminders_sent.attempts + 1, retry_at = NULL, sent_at = NOW()
+        WHERE reminders_sent.retry_at <= NOW()
+        RETURNING attempts`
+
+	args := []any{
+		reminder.WorkoutID,
+		reminder.ScheduledAt,
+		reminder.LeadMinutes,
+		reminder.Channel,
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&reminder.Attempts)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+
+	reminder.RetryAt = nil
+
+	return true, nil
+}
+
+// RecordFailure records that the claimed reminder failed to send. It is
+// tried again once RetryAt has passed, or never if RetryAt is nil.
+func (m ReminderModel) RecordFailure(ctx context.Context, reminder *Reminder) error {
+	query := `
+        UPDATE reminders_sent
+        SET retry_at = $5, last_error = $6
+        WHERE workout_id = $1 AND scheduled_at = $2
+        AND lead_minutes = $3 AND channel = $4`
+
+	args := []any{
+		reminder.WorkoutID,
+		reminder.ScheduledAt,
+		reminder.LeadMinutes,
+		reminder.Channel,
+		reminder.RetryAt,
+		reminder.LastError,
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	return err
+}
@@ -0,0 +1,339 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	SessionStatusActive   = "active"
+	SessionStatusFinished = "finished"
+)
+
+// SessionChannel is the Postgres notification channel that changes to
+// sessions are announced on.
+const SessionChannel = "workout_sessions"
+
+var ErrActiveSession = errors.New("workout already has an active session")
+
+// SessionNotice announces a change to a session to every instance of the
+// API. It carries no state: the session is small and each instance streaming
+// it reads it back, which also keeps notices well within the size Postgres
+// allows.
+type SessionNotice struct {
+	SessionID int64  `json:"session_id"`
+	UserID    int64  `json:"user_id"`
+	Event     string `json:"event"`
+}
+
+// WorkoutSession is the live state of a workout being performed: which
+// exercise and set the athlete is on, and any running rest timer. Timers are
+// stored as absolute times so that every device (and a restarted server)
+// agrees on when they end.
+type WorkoutSession struct {
+	ID            int64      `json:"id"`
+	WorkoutID     int64      `json:"workout_id"`
+	UserID        int64      `json:"-"`
+	Status        string     `json:"status"`
+	ExerciseIndex int        `json:"exercise_index"`
+	SetNumber     int        `json:"set_number"`
+	RestStartedAt *time.Time `json:"rest_started_at"`
+	RestEndsAt    *time.Time `json:"rest_ends_at"`
+	StartedAt     time.Time  `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Version       int32      `json:"version"`
+}
+
+// SessionState is a session together with the details a client needs to
+// render it without loading the workout separately.
+type SessionState struct {
+	*WorkoutSession
+	Exercise             *WorkoutExercise `json:"current_exercise"`
+	TotalSets            int              `json:"total_sets"`
+	TotalExercises       int              `json:"total_exercises"`
+	AllSetsComplete      bool             `json:"all_sets_complete"`
+	RestRemainingSeconds int              `json:"rest_remaining_seconds"`
+}
+
+func (s *WorkoutSession) IsActive() bool {
+	return s.Status == SessionStatusActive
+}
+
+// State describes the session as of now.
+func (s *WorkoutSession) State(workout *Workout, now time.Time) *SessionState {
+	state := &SessionState{
+		WorkoutSession: s,
+		TotalExercises: len(workout.Exercises),
+	}
+
+	if s.ExerciseIndex < len(workout.Exercises) {
+		current := workout.Exercises[s.ExerciseIndex]
+		state.Exercise = &current
+		state.TotalSets = setsFor(current)
+	} else {
+		state.AllSetsComplete = true
+	}
+
+	if s.RestEndsAt != nil && s.RestEndsAt.After(now) {
+		state.RestRemainingSeconds = int(math.Ceil(s.RestEndsAt.Sub(now).Seconds()))
+	}
+
+	return state
+}
+
+// CompleteSet records the current set as done, starts the rest timer
+// configured for the exercise and moves the cursor to the next set, or to
+// the first set of the next exercise. No rest is started after the final set
+// of the workout.
+func (s *WorkoutSession) CompleteSet(workout *Workout, now time.Time) {
+	if s.ExerciseIndex >= len(workout.Exercises) {
+		return
+	}
+
+	current := workout.Exercises[s.ExerciseIndex]
+
+	if s.SetNumber < setsFor(current) {
+		s.SetNumber++
+	} else {
+		s.ExerciseIndex++
+		s.SetNumber = 1
+	}
+
+	s.StopRest()
+
+	if s.ExerciseIndex < len(workout.Exercises) && current.RestInterval > 0 {
+		s.StartRest(now, time.Duration(current.RestInterval)*time.Second)
+	}
+}
+
+func (s *WorkoutSession) StartRest(now time.Time, duration time.Duration) {
+	endsAt := now.Add(duration)
+
+	s.RestStartedAt = &now
+	s.RestEndsAt = &endsAt
+}
+
+func (s *WorkoutSession) StopRest() {
+	s.RestStartedAt = nil
+	s.RestEndsAt = nil
+}
+
+func (s *WorkoutSession) Finish(now time.Time) {
+	s.StopRest()
+	s.Status = SessionStatusFinished
+	s.FinishedAt = &now
+}
+
+// setsFor treats exercises without a set count, such as a single run, as one
+// set.
+func setsFor(workoutExercise WorkoutExercise) int {
+	return max(workoutExercise.Sets, 1)
+}
+
+type SessionModel struct {
+	DB      *sql.DB
+	Timeout time.Duration
+}
+
+func (m SessionModel) Insert(
+	ctx context.Context,
+	session *WorkoutSession,
+) error {
+	query := `
+        INSERT INTO workout_sessions (workout_id, user_id, status, exercise_index, set_number)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, started_at, updated_at, version`
+
+	args := []any{
+		session.WorkoutID,
+		session.UserID,
+		session.Status,
+		session.ExerciseIndex,
+		session.SetNumber,
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&session.ID,
+		&session.StartedAt,
+		&session.UpdatedAt,
+		&session.Version,
+	)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "idx_workout_sessions_active"):
+			return ErrActiveSession
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m SessionModel) GetByUser(
+	ctx context.Context,
+	id, userID int64,
+) (*WorkoutSession, error) {
+	if id < 1 || userID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT id, workout_id, user_id, status, exercise_index, set_number,
+            rest_started_at, rest_ends_at, started_at, finished_at, updated_at, version
+        FROM workout_sessions
+        WHERE id = $1 AND user_id = $2`
+
+	var session WorkoutSession
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(
+		&session.ID,
+		&session.WorkoutID,
+		&session.UserID,
+		&session.Status,
+		&session.ExerciseIndex,
+		&session.SetNumber,
+		&session.RestStartedAt,
+		&session.RestEndsAt,
+		&session.StartedAt,
+		&session.FinishedAt,
+		&session.UpdatedAt,
+		&session.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &session, nil
+}
+
+// Update saves the session using optimistic locking: if another device
+// changed it since it was read, ErrEditConflict is returned and the caller
+// should reload and retry.
+func (m SessionModel) Update(
+	ctx context.Context,
+	session *WorkoutSession,
+) error {
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	return updateSession(ctx, m.DB.QueryRowContext, session)
+}
+
+// Finish saves a session that has just been finished and, if its workout
+// hasn't been completed yet, completes it as of the session's end, in one
+// transaction. Like Update it returns ErrEditConflict if the session changed
+// since it was read, in which case the workout is left alone too.
+func (m SessionModel) Finish(
+	ctx context.Context,
+	session *WorkoutSession,
+	workout *Workout,
+) error {
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = updateSession(ctx, tx.QueryRowContext, session)
+	if err != nil {
+		return err
+	}
+
+	if workout.CompletedAt == nil && session.FinishedAt != nil {
+		completedAt := *session.FinishedAt
+		workout.CompletedAt = &completedAt
+
+		err = completeWorkout(ctx, tx, workout)
+		if err != nil {
+			workout.CompletedAt = nil
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		workout.CompletedAt = nil
+		return err
+	}
+
+	return nil
+}
+
+// updateSession runs the update described on Update through queryRow, which
+// is the QueryRowContext of either the database or a transaction.
+func updateSession(
+	ctx context.Context,
+	queryRow func(ctx context.Context, query string, args ...any) *sql.Row,
+	session *WorkoutSession,
+) error {
+	query := `
+        UPDATE workout_sessions
+        SET status = $3, exercise_index = $4, set_number = $5, rest_started_at = $6,
+            rest_ends_at = $7, finished_at = $8, updated_at = NOW(), version = version + 1
+        WHERE id = $1 AND version = $2
+        RETURNING updated_at, version`
+
+	args := []any{
+		session.ID,
+		session.Version,
+		session.Status,
+		session.ExerciseIndex,
+		session.SetNumber,
+		session.RestStartedAt,
+		session.RestEndsAt,
+		session.FinishedAt,
+	}
+
+	err := queryRow(ctx, query, args...).Scan(
+		&session.UpdatedAt,
+		&session.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Notify sends the notice on SessionChannel. Postgres delivers it to every
+// connection listening on the channel, including this instance's own.
+func (m SessionModel) Notify(ctx context.Context, notice SessionNotice) error {
+	payload, err := json.Marshal(notice)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	_, err = m.DB.ExecContext(ctx, "SELECT pg_notify($1, $2)", SessionChannel, string(payload))
+
+	return err
+}
@@ -0,0 +1,360 @@
+//go:build integration
+
+package data
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSessionModel(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	bob := insertTestUser(t, models, "Bob")
+	squat := insertTestExercise(t, models, "Squat", MeasurementRepsWeight)
+	workout := insertTestWorkout(t, models, alice, squat, nil)
+
+	session := &WorkoutSession{
+		WorkoutID: workout.ID,
+		UserID:    alice.ID,
+		Status:    SessionStatusActive,
+		SetNumber: 1,
+	}
+
+	err := models.Sessions.Insert(ctx, session)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if session.Version != 1 {
+		t.Errorf("new session has version %d; want 1", session.Version)
+	}
+
+	err = models.Sessions.Insert(ctx, &WorkoutSession{
+		WorkoutID: workout.ID,
+		UserID:    alice.ID,
+		Status:    SessionStatusActive,
+		SetNumber: 1,
+	})
+	if !errors.Is(err, ErrActiveSession) {
+		t.Errorf("second active session: err = %v; want ErrActiveSession", err)
+	}
+
+	_, err = models.Sessions.GetByUser(ctx, session.ID, bob.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("GetByUser by another user: err = %v", err)
+	}
+
+	// Two devices read the same version; the second to save loses.
+	first, err := models.Sessions.GetByUser(ctx, session.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second := *first
+
+	now := time.Now()
+
+	first.CompleteSet(workout, now)
+	first.StartRest(now, 90*time.Second)
+
+	err = models.Sessions.Update(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Version != 2 {
+		t.Errorf("version after Update = %d; want 2", first.Version)
+	}
+
+	second.Finish(now)
+
+	err = models.Sessions.Update(ctx, &second)
+	if !errors.Is(err, ErrEditConflict) {
+		t.Errorf("Update of a stale session: err = %v; want ErrEditConflict", err)
+	}
+
+	saved, err := models.Sessions.GetByUser(ctx, session.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if saved.SetNumber != first.SetNumber || saved.RestEndsAt == nil ||
+		saved.Status != SessionStatusActive {
+		t.Errorf("saved session = %+v; want %+v", saved, first)
+	}
+
+	// Once the session is finished the workout can be started again.
+	saved.Finish(now)
+
+	err = models.Sessions.Update(ctx, saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = models.Sessions.Insert(ctx, &WorkoutSession{
+		WorkoutID: workout.ID,
+		UserID:    alice.ID,
+		Status:    SessionStatusActive,
+		SetNumber: 1,
+	})
+	if err != nil {
+		t.Errorf("starting again after finishing: %v", err)
+	}
+}
+
+func TestSessionModelFinish(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	squat := insertTestExercise(t, models, "Squat", MeasurementRepsWeight)
+	workout := insertTestWorkout(t, models, alice, squat, nil)
+
+	session := &WorkoutSession{
+		WorkoutID: workout.ID,
+		UserID:    alice.ID,
+		Status:    SessionStatusActive,
+		SetNumber: 1,
+	}
+
+	err := models.Sessions.Insert(ctx, session)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A stale session leaves both the session and the workout untouched.
+	stale := *session
+	stale.Version--
+	stale.Finish(time.Now())
+
+	err = models.Sessions.Finish(ctx, &stale, workout)
+	if !errors.Is(err, ErrEditConflict) {
+		t.Errorf("Finish of a stale session: err = %v; want ErrEditConflict", err)
+	}
+
+	if workout.CompletedAt != nil {
+		t.Errorf("workout completed at %v after a conflict", workout.CompletedAt)
+	}
+
+	n := countRows(t, "workouts", "id = $1 AND completed_at IS NOT NULL", workout.ID)
+	if n != 0 {
+		t.Errorf("workout stored as completed after a conflict")
+	}
+
+	session.Finish(time.Now().Truncate(time.Microsecond))
+
+	err = models.Sessions.Finish(ctx, session, workout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := models.Workouts.GetByUser(ctx, workout.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if saved.CompletedAt == nil || !saved.CompletedAt.Equal(*session.FinishedAt) {
+		t.Errorf("workout completed at %v; want %v", saved.CompletedAt, session.FinishedAt)
+	}
+
+	if n := countRows(t, "outbox", "event = $1", EventWorkoutCompleted); n != 1 {
+		t.Errorf("%d workout.completed events in the outbox; want 1", n)
+	}
+}
+
+func TestTrackModel(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	squat := insertTestExercise(t, models, "Squat", MeasurementRepsWeight)
+	run := insertTestExercise(t, models, "Run", MeasurementDistanceTime)
+	workout := insertTestWorkout(t, models, alice, squat, nil)
+
+	track := &WorkoutTrack{
+		Format:          "gpx",
+		StartedAt:       ago(time.Hour).Truncate(time.Second),
+		Distance:        2000,
+		DurationSeconds: 600,
+		ElevationGain:   12.5,
+		Splits: []TrackSplit{
+			{Number: 1, Distance: 1000, DurationSeconds: 290},
+			{Number: 2, Distance: 1000, DurationSeconds: 310},
+		},
+	}
+
+	workout.Exercises = append(
+		workout.Exercises,
+		WorkoutExercise{ExerciseID: run.ID, Sets: 1, Distance: 2000, DurationSeconds: 600},
+	)
+
+	err := models.Tracks.InsertWithWorkout(ctx, track, workout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracks, err := models.Tracks.GetAllForWorkout(ctx, workout.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tracks) != 1 {
+		t.Fatalf("GetAllForWorkout returned %d tracks; want 1", len(tracks))
+	}
+
+	got := tracks[0]
+
+	if got.ID != track.ID || !got.StartedAt.Equal(track.StartedAt) ||
+		got.ExerciseID != run.ID || got.WorkoutExerciseID != workout.Exercises[1].ID ||
+		got.Distance != 2000 || got.ElevationGain != 12.5 ||
+		len(got.Splits) != 2 || got.Splits[1].DurationSeconds != 310 {
+		t.Errorf("track = %+v; want %+v", got, track)
+	}
+
+	if n := countRows(t, "workout_exercises", "workout_id = $1", workout.ID); n != 2 {
+		t.Errorf("workout has %d exercises; want the original and the run", n)
+	}
+
+	if n := countRows(t, "outbox", "event = $1", EventWorkoutUpdated); n != 1 {
+		t.Errorf("%d workout.updated events in the outbox; want 1", n)
+	}
+
+	// A workout that fails to save takes the track with it.
+	failed := *workout
+	failed.Exercises = append(
+		slices.Clone(workout.Exercises),
+		WorkoutExercise{ExerciseID: run.ID + 100, Sets: 1},
+	)
+
+	err = models.Tracks.InsertWithWorkout(
+		ctx,
+		&WorkoutTrack{Format: "gpx", StartedAt: time.Now()},
+		&failed,
+	)
+	if err == nil {
+		t.Fatal("inserting a track for a missing exercise succeeded")
+	}
+
+	if n := countRows(t, "workout_tracks", "workout_id = $1", workout.ID); n != 1 {
+		t.Errorf("workout has %d tracks after a failed insert; want 1", n)
+	}
+
+	// Editing the workout keeps the run, and its track, until the run is
+	// removed.
+	edited, err := models.Workouts.GetByUser(ctx, workout.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edited.Exercises = []WorkoutExercise{
+		{ExerciseID: run.ID, Sets: 1, Distance: 2100, DurationSeconds: 610},
+		{ExerciseID: squat.ID, Sets: 5, Repetitions: 5, Weight: 100},
+	}
+
+	err = models.Workouts.UpdateWorkoutWithExercises(ctx, edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if edited.Exercises[0].ID != track.WorkoutExerciseID {
+		t.Errorf(
+			"run was saved as row %d; want row %d kept",
+			edited.Exercises[0].ID,
+			track.WorkoutExerciseID,
+		)
+	}
+
+	if n := countRows(t, "workout_tracks", "workout_id = $1", workout.ID); n != 1 {
+		t.Errorf("workout has %d tracks after keeping the run; want 1", n)
+	}
+
+	edited.Exercises = edited.Exercises[1:]
+
+	err = models.Workouts.UpdateWorkoutWithExercises(ctx, edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := countRows(t, "workout_tracks", "workout_id = $1", workout.ID); n != 0 {
+		t.Errorf("workout has %d tracks after removing the run; want 0", n)
+	}
+}
+
+func TestShareModel(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	bob := insertTestUser(t, models, "Bob")
+	squat := insertTestExercise(t, models, "Squat", MeasurementRepsWeight)
+	workout := insertTestWorkout(t, models, alice, squat, nil)
+
+	share, err := models.Shares.New(ctx, workout.ID, alice.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	active, err := models.Shares.GetActiveByToken(ctx, share.Plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if active.ID != share.ID || active.WorkoutID != workout.ID ||
+		active.OwnerUnits.Weight != UnitKilograms {
+		t.Errorf("GetActiveByToken returned %+v", active)
+	}
+
+	expired, err := models.Shares.New(ctx, workout.ID, alice.ID, ago(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = models.Shares.GetActiveByToken(ctx, expired.Plaintext)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("GetActiveByToken for an expired share: err = %v", err)
+	}
+
+	err = models.Shares.Revoke(ctx, share.ID, workout.ID, bob.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Revoke by another user: err = %v", err)
+	}
+
+	err = models.Shares.Revoke(ctx, share.ID, workout.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = models.Shares.Revoke(ctx, share.ID, workout.ID, alice.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Revoke of a revoked share: err = %v", err)
+	}
+
+	_, err = models.Shares.GetActiveByToken(ctx, share.Plaintext)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("GetActiveByToken for a revoked share: err = %v", err)
+	}
+
+	shares, err := models.Shares.GetAllForWorkout(ctx, workout.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(shares) != 2 || shares[0].RevokedAt == nil || shares[1].ExpiresAt == nil {
+		t.Errorf("GetAllForWorkout returned %+v; want the revoked and expired shares", shares)
+	}
+
+	shares, err = models.Shares.GetAllForWorkout(ctx, workout.ID, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(shares) != 0 {
+		t.Errorf("another user sees %d shares; want 0", len(shares))
+	}
+}
@@ -0,0 +1,229 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sulemankhann/workout-tracker/internal/validator"
+	"time"
+)
+
+// WorkoutShare is a public, read-only link to a workout. The plaintext token
+// is only available when the share is created; afterwards it can be revoked
+// but not shown again.
+type WorkoutShare struct {
+	ID         int64      `json:"id"`
+	WorkoutID  int64      `json:"workout_id"`
+	UserID     int64      `json:"-"`
+	Plaintext  string     `json:"token,omitempty"`
+	Hash       []byte     `json:"-"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	OwnerUnits Units      `json:"-"`
+}
+
+// SharedWorkout is the view of a workout shown through a share link. It
+// deliberately leaves out anything identifying the owner.
+type SharedWorkout struct {
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	ScheduledAt time.Time         `json:"scheduled_at"`
+	CompletedAt *time.Time        `json:"completed_at"`
+	Exercises   []WorkoutExercise `json:"exercises"`
+}
+
+func NewSharedWorkout(workout *Workout) *SharedWorkout {
+	return &SharedWorkout{
+		Title:       workout.Title,
+		Description: workout.Description,
+		ScheduledAt: workout.ScheduledAt,
+		CompletedAt: workout.CompletedAt,
+		Exercises:   workout.Exercises,
+	}
+}
+
+func ValidateShare(v *validator.Validator, share *WorkoutShare) {
+	if share.ExpiresAt != nil {
+		v.Check(
+			share.ExpiresAt.After(time.Now()),
+			"expires_at",
+			"must be in the future",
+		)
+	}
+}
+
+type ShareModel struct {
+	DB      *sql.DB
+	Timeout time.Duration
+}
+
+// New generates a share token for the workout and stores it.
+func (m ShareModel) New(
+	ctx context.Context,
+	workoutID, userID int64,
+	expiresAt *time.Time,
+) (*WorkoutShare, error) {
+	plaintext, hash, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	share := &WorkoutShare{
+		WorkoutID: workoutID,
+		UserID:    userID,
+		Plaintext: plaintext,
+		Hash:      hash,
+		ExpiresAt: expiresAt,
+	}
+
+	query := `
+        INSERT INTO workout_shares (workout_id, user_id, hash, expires_at)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at`
+
+	args := []any{share.WorkoutID, share.UserID, share.Hash, share.ExpiresAt}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err = m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&share.ID,
+		&share.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return share, nil
+}
+
+func (m ShareModel) GetAllForWorkout(
+	ctx context.Context,
+	workoutID, userID int64,
+) ([]*WorkoutShare, error) {
+	query := `
+        SELECT id, workout_id, user_id, expires_at, revoked_at, created_at
+        FROM workout_shares
+        WHERE workout_id = $1 AND user_id = $2
+        ORDER BY id`
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, workoutID, userID)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to execute query to fetch shares for workout %d: %w",
+			workoutID,
+			err,
+		)
+	}
+
+	defer rows.Close()
+
+	shares := []*WorkoutShare{}
+
+	for rows.Next() {
+		var share WorkoutShare
+
+		err := rows.Scan(
+			&share.ID,
+			&share.WorkoutID,
+			&share.UserID,
+			&share.ExpiresAt,
+			&share.RevokedAt,
+			&share.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan share row: %w", err)
+		}
+
+		shares = append(shares, &share)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"error occurred while iterating over share rows: %w",
+			err,
+		)
+	}
+
+	return shares, nil
+}
+
+// GetActiveByToken looks up a share that has been neither revoked nor
+// reached its expiry, along with the owner's unit preferences so the workout
+// can be shown the way they recorded it.
+func (m ShareModel) GetActiveByToken(
+	ctx context.Context,
+	plaintext string,
+) (*WorkoutShare, error) {
+	query := `
+        SELECT s.id, s.workout_id, s.user_id, s.expires_at, s.revoked_at, s.created_at,
+            u.weight_unit, u.distance_unit, u.plate_rounding
+        FROM workout_shares s
+        JOIN users u ON s.user_id = u.id
+        WHERE s.hash = $1
+        AND s.revoked_at IS NULL
+        AND (s.expires_at IS NULL OR s.expires_at > $2)`
+
+	var share WorkoutShare
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, hashToken(plaintext), time.Now()).Scan(
+		&share.ID,
+		&share.WorkoutID,
+		&share.UserID,
+		&share.ExpiresAt,
+		&share.RevokedAt,
+		&share.CreatedAt,
+		&share.OwnerUnits.Weight,
+		&share.OwnerUnits.Distance,
+		&share.OwnerUnits.PlateRounding,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &share, nil
+}
+
+// Revoke disables a share link. Revoked shares are kept so the owner can see
+// which links once existed.
+func (m ShareModel) Revoke(
+	ctx context.Context,
+	id, workoutID, userID int64,
+) error {
+	query := `
+        UPDATE workout_shares
+        SET revoked_at = NOW()
+        WHERE id = $1 AND workout_id = $2 AND user_id = $3 AND revoked_at IS NULL`
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, workoutID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,528 @@
+//go:build integration
+
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// setTestPrivacy changes who may see the user's completed workouts.
+func setTestPrivacy(t *testing.T, models Models, user *User, privacy string) {
+	t.Helper()
+
+	user.Privacy = privacy
+
+	err := models.Users.Update(context.Background(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFollowModel(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	bob := insertTestUser(t, models, "Bob")
+	carol := insertTestUser(t, models, "Carol")
+
+	setTestPrivacy(t, models, alice, PrivacyPublic)
+
+	follow, err := models.Follows.Follow(ctx, carol.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if follow.Status != FollowStatusAccepted || follow.AcceptedAt == nil ||
+		follow.Name != "Alice" {
+		t.Errorf("following a public user returned %+v", follow)
+	}
+
+	follow, err = models.Follows.Follow(ctx, carol.ID, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if follow.Status != FollowStatusPending || follow.AcceptedAt != nil {
+		t.Errorf("following a private user returned %+v", follow)
+	}
+
+	// Following again leaves the request as it was.
+	again, err := models.Follows.Follow(ctx, carol.ID, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if again.Status != FollowStatusPending || !again.CreatedAt.Equal(follow.CreatedAt) {
+		t.Errorf("following again returned %+v; want %+v", again, follow)
+	}
+
+	_, err = models.Follows.Follow(ctx, carol.ID, bob.ID+100)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("following a missing user: err = %v", err)
+	}
+
+	err = models.Follows.Approve(ctx, carol.ID, alice.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("approving an accepted follow: err = %v", err)
+	}
+
+	err = models.Follows.Approve(ctx, carol.ID, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	following, err := models.Follows.GetFollowing(ctx, carol.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(following) != 2 ||
+		following[0].UserID != alice.ID ||
+		following[1].UserID != bob.ID ||
+		following[1].Status != FollowStatusAccepted {
+		t.Errorf("GetFollowing returned %+v", following)
+	}
+
+	followers, err := models.Follows.GetFollowers(ctx, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(followers) != 1 || followers[0].UserID != carol.ID || followers[0].Name != "Carol" {
+		t.Errorf("GetFollowers returned %+v", followers)
+	}
+
+	err = models.Follows.Unfollow(ctx, carol.ID, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = models.Follows.Unfollow(ctx, carol.ID, bob.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("unfollowing twice: err = %v", err)
+	}
+
+	_, err = testDB.ExecContext(
+		ctx,
+		"INSERT INTO follows (follower_id, followee_id) VALUES ($1, $1)",
+		alice.ID,
+	)
+	if err == nil {
+		t.Error("a user was allowed to follow themselves")
+	}
+}
+
+func TestFeed(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	public := insertTestUser(t, models, "Public")
+	followersOnly := insertTestUser(t, models, "Followers")
+	private := insertTestUser(t, models, "Private")
+	viewer := insertTestUser(t, models, "Viewer")
+	stranger := insertTestUser(t, models, "Stranger")
+	squat := insertTestExercise(t, models, "Squat", MeasurementRepsWeight)
+
+	setTestPrivacy(t, models, public, PrivacyPublic)
+	setTestPrivacy(t, models, followersOnly, PrivacyFollowers)
+
+	publicOlder := insertTestWorkout(t, models, public, squat, ago(3*time.Hour))
+	publicNewer := insertTestWorkout(t, models, public, squat, ago(time.Hour))
+	insertTestWorkout(t, models, public, squat, nil)
+	followersWorkout := insertTestWorkout(t, models, followersOnly, squat, ago(2*time.Hour))
+	insertTestWorkout(t, models, private, squat, ago(time.Hour))
+
+	for _, followee := range []*User{public, followersOnly, private} {
+		_, err := models.Follows.Follow(ctx, viewer.ID, followee.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	feedIDs := func(viewerID int64, after *FeedCursor, limit int) ([]int64, *FeedCursor) {
+		t.Helper()
+
+		entries, next, err := models.Workouts.GetFeedForViewer(ctx, viewerID, after, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids := []int64{}
+		for _, entry := range entries {
+			if len(entry.Workout.Exercises) != 1 {
+				t.Errorf("feed workout %d has no exercises", entry.Workout.ID)
+			}
+
+			ids = append(ids, entry.Workout.ID)
+		}
+
+		return ids, next
+	}
+
+	// Only the public user has accepted the viewer so far, and incomplete
+	// workouts are never shown.
+	ids, next := feedIDs(viewer.ID, nil, 10)
+	if !equalIDs(ids, publicNewer.ID, publicOlder.ID) || next != nil {
+		t.Errorf("feed = %v, %v; want %v", ids, next, []int64{publicNewer.ID, publicOlder.ID})
+	}
+
+	err := models.Follows.Approve(ctx, viewer.ID, followersOnly.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = models.Follows.Approve(ctx, viewer.ID, private.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Pages of two, newest first, with the cursor surviving a round trip
+	// through its string form. Private users stay hidden even from approved
+	// followers.
+	ids, next = feedIDs(viewer.ID, nil, 2)
+	if !equalIDs(ids, publicNewer.ID, followersWorkout.ID) || next == nil {
+		t.Fatalf("first page = %v, %v", ids, next)
+	}
+
+	cursor, err := ParseFeedCursor(next.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids, next = feedIDs(viewer.ID, cursor, 2)
+	if !equalIDs(ids, publicOlder.ID) || next != nil {
+		t.Errorf("second page = %v, %v; want %v", ids, next, []int64{publicOlder.ID})
+	}
+
+	// Strangers follow nobody, so their feed is empty, but they can still
+	// browse public profiles.
+	ids, _ = feedIDs(stranger.ID, nil, 10)
+	if len(ids) != 0 {
+		t.Errorf("stranger's feed = %v; want none", ids)
+	}
+
+	for _, tt := range []struct {
+		owner, viewer *User
+		want          int
+	}{
+		{public, stranger, 2},
+		{followersOnly, stranger, 0},
+		{followersOnly, viewer, 1},
+		{private, viewer, 0},
+		{private, private, 1},
+	} {
+		entries, _, err := models.Workouts.GetVisibleForViewer(
+			ctx,
+			tt.owner.ID,
+			tt.viewer.ID,
+			nil,
+			10,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(entries) != tt.want {
+			t.Errorf(
+				"%s sees %d of %s's workouts; want %d",
+				tt.viewer.Name,
+				len(entries),
+				tt.owner.Name,
+				tt.want,
+			)
+		}
+	}
+}
+
+func equalIDs(got []int64, want ...int64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestCoachingModel(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	coach := insertTestUser(t, models, "Coach")
+	athlete := insertTestUser(t, models, "Athlete")
+
+	link := &CoachingLink{CoachID: coach.ID, AthleteID: athlete.ID}
+
+	err := models.Coaching.Invite(ctx, link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = models.Coaching.Invite(ctx, &CoachingLink{CoachID: coach.ID, AthleteID: athlete.ID})
+	if !errors.Is(err, ErrDuplicateCoachingLink) {
+		t.Errorf("second invitation: err = %v; want ErrDuplicateCoachingLink", err)
+	}
+
+	err = models.Coaching.Invite(ctx, &CoachingLink{CoachID: coach.ID, AthleteID: coach.ID})
+	if err == nil {
+		t.Error("a user was allowed to coach themselves")
+	}
+
+	_, err = models.Coaching.GetAthlete(ctx, coach.ID, athlete.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("GetAthlete before accepting: err = %v", err)
+	}
+
+	links, err := models.Coaching.GetAllForAthlete(ctx, athlete.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(links) != 1 || links[0].Status != CoachingStatusPending ||
+		links[0].CoachName != "Coach" || links[0].AthleteName != "Athlete" {
+		t.Errorf("GetAllForAthlete returned %+v", links)
+	}
+
+	err = models.Coaching.Accept(ctx, coach.ID, athlete.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = models.Coaching.Accept(ctx, coach.ID, athlete.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("accepting twice: err = %v", err)
+	}
+
+	found, err := models.Coaching.GetAthlete(ctx, coach.ID, athlete.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if found.ID != athlete.ID || found.Units.Weight != UnitKilograms {
+		t.Errorf("GetAthlete returned %+v", found)
+	}
+
+	err = models.Coaching.Revoke(ctx, coach.ID, athlete.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links, err = models.Coaching.GetAllForCoach(ctx, coach.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(links) != 0 {
+		t.Errorf("GetAllForCoach returned revoked links %+v", links)
+	}
+
+	_, err = models.Coaching.GetAthlete(ctx, coach.ID, athlete.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("GetAthlete after revoking: err = %v", err)
+	}
+
+	// A revoked link no longer counts as open, so the coach can invite the
+	// athlete again.
+	err = models.Coaching.Invite(ctx, &CoachingLink{CoachID: coach.ID, AthleteID: athlete.ID})
+	if err != nil {
+		t.Errorf("inviting again after revoking: %v", err)
+	}
+}
+
+func TestAuditModel(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	coach := insertTestUser(t, models, "Coach")
+	athlete := insertTestUser(t, models, "Athlete")
+	squat := insertTestExercise(t, models, "Squat", MeasurementRepsWeight)
+	workout := insertTestWorkout(t, models, athlete, squat, nil)
+
+	for _, entry := range []*WorkoutAuditEntry{
+		{WorkoutID: workout.ID, UserID: athlete.ID, ActorID: &athlete.ID, Action: AuditActionCreated},
+		{WorkoutID: workout.ID, UserID: athlete.ID, ActorID: &coach.ID, Action: AuditActionScheduled},
+	} {
+		err := models.Audit.Insert(ctx, entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := models.Audit.GetAllForWorkout(ctx, workout.ID, athlete.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 ||
+		entries[0].Action != AuditActionCreated ||
+		entries[1].ActorName == nil || *entries[1].ActorName != "Coach" {
+		t.Errorf("GetAllForWorkout returned %+v", entries)
+	}
+
+	entries, err = models.Audit.GetAllForWorkout(ctx, workout.ID, coach.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 0 {
+		t.Errorf("the coach sees %d entries of a workout they don't own", len(entries))
+	}
+
+	// Changes made with an actor are recorded with them, and not at all if
+	// they fail.
+	actorCtx := WithAuditActor(ctx, coach.ID)
+
+	workout.ScheduledAt = workout.ScheduledAt.Add(time.Hour)
+
+	err = models.Workouts.ScheduleWorkout(actorCtx, workout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed := *workout
+	failed.Exercises = []WorkoutExercise{{ExerciseID: squat.ID + 100, Sets: 1}}
+
+	err = models.Workouts.UpdateWorkoutWithExercises(actorCtx, &failed)
+	if err == nil {
+		t.Fatal("updating a workout with a missing exercise succeeded")
+	}
+
+	entries, err = models.Audit.GetAllForWorkout(ctx, workout.ID, athlete.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 3 || entries[2].Action != AuditActionScheduled ||
+		entries[2].ActorID == nil || *entries[2].ActorID != coach.ID {
+		t.Errorf("after scheduling and a failed update got %+v", entries)
+	}
+}
+
+func TestCommentModel(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	bob := insertTestUser(t, models, "Bob")
+	squat := insertTestExercise(t, models, "Squat", MeasurementRepsWeight)
+	workout := insertTestWorkout(t, models, alice, squat, ago(time.Hour))
+
+	thread := &Comment{WorkoutID: workout.ID, ExerciseID: &squat.ID, AuthorID: bob.ID, Body: "Depth?"}
+
+	err := models.Comments.Insert(ctx, thread)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reply := &Comment{WorkoutID: workout.ID, ParentID: &thread.ID, AuthorID: alice.ID, Body: "Parallel"}
+
+	err = models.Comments.Insert(ctx, reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 3 {
+		err = models.Comments.Insert(ctx, &Comment{WorkoutID: workout.ID, AuthorID: bob.ID, Body: "Nice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	comments, metadata, err := models.Comments.GetAllForWorkout(
+		ctx,
+		workout.ID,
+		0,
+		Filters{Page: 1, PageSize: 2},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(comments) != 2 || comments[0].ID != thread.ID || comments[1].ID != reply.ID ||
+		metadata.TotalRecords != 5 || metadata.LastPage != 3 {
+		t.Errorf("first page = %+v, %+v", comments, metadata)
+	}
+
+	comments, _, err = models.Comments.GetAllForWorkout(
+		ctx,
+		workout.ID,
+		squat.ID,
+		Filters{Page: 1, PageSize: 20},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(comments) != 1 || comments[0].ID != thread.ID || comments[0].AuthorName != "Bob" {
+		t.Errorf("comments about the squat = %+v", comments)
+	}
+
+	thread.Body = "How deep?"
+
+	err = models.Comments.UpdateByAuthor(ctx, thread, alice.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("editing someone else's comment: err = %v", err)
+	}
+
+	err = models.Comments.UpdateByAuthor(ctx, thread, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = models.Comments.DeleteByAuthor(ctx, thread.ID, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = models.Comments.DeleteByAuthor(ctx, thread.ID, bob.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("deleting twice: err = %v", err)
+	}
+
+	// Deleting only blanks the comment, so the reply keeps its parent.
+	deleted, err := models.Comments.Get(ctx, thread.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if deleted.Body != "" || deleted.DeletedAt == nil || deleted.EditedAt == nil {
+		t.Errorf("deleted comment = %+v", deleted)
+	}
+
+	err = models.Comments.UpdateByAuthor(ctx, deleted, bob.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("editing a deleted comment: err = %v", err)
+	}
+
+	found, err := models.Comments.Get(ctx, reply.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if found.ParentID == nil || *found.ParentID != thread.ID {
+		t.Errorf("reply = %+v", found)
+	}
+
+	// Removing the exercise from the catalogue keeps comments about it.
+	_, err = testDB.ExecContext(ctx, "DELETE FROM exercises WHERE id = $1", squat.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found, err = models.Comments.Get(ctx, thread.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if found.ExerciseID != nil {
+		t.Errorf("comment still refers to deleted exercise %d", *found.ExerciseID)
+	}
+}
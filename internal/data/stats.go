@@ -0,0 +1,172 @@
+package data
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+const heatmapDays = 365
+
+type WorkoutActivity struct {
+	ScheduledAt time.Time
+	CompletedAt *time.Time
+}
+
+type DailyCount struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+type Adherence struct {
+	WindowDays int     `json:"window_days"`
+	Scheduled  int     `json:"scheduled"`
+	Completed  int     `json:"completed"`
+	Percentage float64 `json:"percentage"`
+}
+
+type TrainingStats struct {
+	Timezone               string       `json:"timezone"`
+	CurrentStreak          int          `json:"current_streak"`
+	LongestStreak          int          `json:"longest_streak"`
+	Adherence              Adherence    `json:"adherence"`
+	AverageSessionsPerWeek float64      `json:"average_sessions_per_week"`
+	MostTrainedWeekday     string       `json:"most_trained_weekday,omitempty"`
+	Heatmap                []DailyCount `json:"heatmap"`
+}
+
+// CalculateTrainingStats derives streaks, adherence and per-day counts from a
+// user's workout activity. All calendar maths happens in loc so that a session
+// at 23:30 local time counts towards the right day. Streaks are measured in
+// consecutive days with at least one completed workout; the current streak is
+// still alive if the last session was yesterday.
+func CalculateTrainingStats(
+	activity []WorkoutActivity,
+	loc *time.Location,
+	now time.Time,
+	windowDays int,
+) *TrainingStats {
+	now = now.In(loc)
+	today := startOfDay(now)
+	windowStart := today.AddDate(0, 0, -(windowDays - 1))
+
+	stats := &TrainingStats{
+		Timezone:  loc.String(),
+		Adherence: Adherence{WindowDays: windowDays},
+	}
+
+	perDay := make(map[string]int)
+	weekdays := make(map[time.Weekday]int)
+	windowSessions := 0
+
+	for _, entry := range activity {
+		if !entry.ScheduledAt.IsZero() {
+			scheduled := entry.ScheduledAt.In(loc)
+			if !scheduled.Before(windowStart) && !scheduled.After(now) {
+				stats.Adherence.Scheduled++
+				if entry.CompletedAt != nil {
+					stats.Adherence.Completed++
+				}
+			}
+		}
+
+		if entry.CompletedAt == nil {
+			continue
+		}
+
+		completed := entry.CompletedAt.In(loc)
+		perDay[completed.Format(time.DateOnly)]++
+		weekdays[completed.Weekday()]++
+
+		if !completed.Before(windowStart) && !completed.After(now) {
+			windowSessions++
+		}
+	}
+
+	if stats.Adherence.Scheduled > 0 {
+		stats.Adherence.Percentage = round(
+			float64(stats.Adherence.Completed)/
+				float64(stats.Adherence.Scheduled)*100,
+			1,
+		)
+	}
+
+	stats.AverageSessionsPerWeek = round(
+		float64(windowSessions)/(float64(windowDays)/7),
+		2,
+	)
+
+	best := 0
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if weekdays[day] > best {
+			best = weekdays[day]
+			stats.MostTrainedWeekday = day.String()
+		}
+	}
+
+	stats.CurrentStreak, stats.LongestStreak = streaks(perDay, today, loc)
+
+	stats.Heatmap = make([]DailyCount, 0, heatmapDays)
+	for i := heatmapDays - 1; i >= 0; i-- {
+		date := today.AddDate(0, 0, -i).Format(time.DateOnly)
+		stats.Heatmap = append(
+			stats.Heatmap,
+			DailyCount{Date: date, Count: perDay[date]},
+		)
+	}
+
+	return stats
+}
+
+func streaks(
+	perDay map[string]int,
+	today time.Time,
+	loc *time.Location,
+) (current, longest int) {
+	if len(perDay) == 0 {
+		return 0, 0
+	}
+
+	days := make([]time.Time, 0, len(perDay))
+	for date := range perDay {
+		day, err := time.ParseInLocation(time.DateOnly, date, loc)
+		if err != nil {
+			continue
+		}
+		days = append(days, day)
+	}
+
+	// Walk the active days in order, extending the run whenever the next
+	// active day is exactly one calendar day after the previous one.
+	slices.SortFunc(days, func(a, b time.Time) int { return a.Compare(b) })
+
+	run := 0
+	var previous time.Time
+	for _, day := range days {
+		if run > 0 && previous.AddDate(0, 0, 1).Equal(day) {
+			run++
+		} else {
+			run = 1
+		}
+
+		longest = max(longest, run)
+		previous = day
+	}
+
+	last := days[len(days)-1]
+	if last.Equal(today) || last.AddDate(0, 0, 1).Equal(today) {
+		current = run
+	}
+
+	return current, longest
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+func round(value float64, places int) float64 {
+	factor := math.Pow(10, float64(places))
+	return math.Round(value*factor) / factor
+}
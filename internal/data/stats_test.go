@@ -0,0 +1,272 @@
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalculateTrainingStats(t *testing.T) {
+	utc := time.UTC
+	losAngeles := mustLoadLocation(t, "America/Los_Angeles")
+	london := mustLoadLocation(t, "Europe/London")
+	newYork := mustLoadLocation(t, "America/New_York")
+
+	// done is a workout scheduled for and completed at the same time.
+	done := func(loc *time.Location, value string) WorkoutActivity {
+		at := localTime(t, loc, value)
+		return WorkoutActivity{ScheduledAt: at, CompletedAt: &at}
+	}
+
+	// unscheduled is a workout logged without having been scheduled.
+	unscheduled := func(loc *time.Location, value string) WorkoutActivity {
+		at := localTime(t, loc, value)
+		return WorkoutActivity{CompletedAt: &at}
+	}
+
+	tests := []struct {
+		name          string
+		loc           *time.Location
+		now           string
+		activity      []WorkoutActivity
+		wantCurrent   int
+		wantLongest   int
+		wantAdherence Adherence
+		// wantDays are heatmap counts to check, by local date.
+		wantDays map[string]int
+	}{
+		{
+			name: "no activity",
+			loc:  utc,
+			now:  "2024-06-10 18:00",
+			wantAdherence: Adherence{
+				WindowDays: 30,
+			},
+			wantDays: map[string]int{"2024-06-10": 0},
+		},
+		{
+			name: "streak across a week boundary",
+			loc:  utc,
+			now:  "2024-06-10 18:00",
+			activity: []WorkoutActivity{
+				done(utc, "2024-06-01 10:00"),
+				done(utc, "2024-06-02 10:00"),
+				done(utc, "2024-06-08 10:00"), // Saturday
+				done(utc, "2024-06-09 10:00"), // Sunday
+				done(utc, "2024-06-10 07:00"), // Monday
+			},
+			wantCurrent: 3,
+			wantLongest: 3,
+			wantAdherence: Adherence{
+				WindowDays: 30,
+				Scheduled:  5,
+				Completed:  5,
+				Percentage: 100,
+			},
+		},
+		{
+			name: "streak still alive from yesterday",
+			loc:  utc,
+			now:  "2024-06-10 08:00",
+			activity: []WorkoutActivity{
+				done(utc, "2024-06-08 10:00"),
+				done(utc, "2024-06-09 10:00"),
+			},
+			wantCurrent: 2,
+			wantLongest: 2,
+			wantAdherence: Adherence{
+				WindowDays: 30,
+				Scheduled:  2,
+				Completed:  2,
+				Percentage: 100,
+			},
+		},
+		{
+			name: "streak broken two days ago",
+			loc:  utc,
+			now:  "2024-06-10 18:00",
+			activity: []WorkoutActivity{
+				done(utc, "2024-06-06 10:00"),
+				done(utc, "2024-06-07 10:00"),
+				done(utc, "2024-06-08 10:00"),
+			},
+			wantCurrent: 0,
+			wantLongest: 3,
+			wantAdherence: Adherence{
+				WindowDays: 30,
+				Scheduled:  3,
+				Completed:  3,
+				Percentage: 100,
+			},
+		},
+		{
+			// In UTC these are a Saturday and two sessions on Monday, which
+			// would be no streak at all.
+			name: "late sessions count towards the local day",
+			loc:  losAngeles,
+			now:  "2024-06-10 15:00",
+			activity: []WorkoutActivity{
+				done(losAngeles, "2024-06-07 23:30"),
+				done(losAngeles, "2024-06-09 22:30"),
+				done(losAngeles, "2024-06-10 13:00"),
+			},
+			wantCurrent: 2,
+			wantLongest: 2,
+			wantAdherence: Adherence{
+				WindowDays: 30,
+				Scheduled:  3,
+				Completed:  3,
+				Percentage: 100,
+			},
+			wantDays: map[string]int{
+				"2024-06-07": 1,
+				"2024-06-08": 0,
+				"2024-06-09": 1,
+				"2024-06-10": 1,
+			},
+		},
+		{
+			// The clocks went forward at 01:00 on 31 March, making it a
+			// 23-hour day.
+			name: "streak across the start of summer time",
+			loc:  london,
+			now:  "2024-04-01 18:00",
+			activity: []WorkoutActivity{
+				done(london, "2024-03-30 12:00"),
+				done(london, "2024-03-31 23:30"),
+				done(london, "2024-04-01 08:00"),
+			},
+			wantCurrent: 3,
+			wantLongest: 3,
+			wantAdherence: Adherence{
+				WindowDays: 30,
+				Scheduled:  3,
+				Completed:  3,
+				Percentage: 100,
+			},
+			wantDays: map[string]int{"2024-03-31": 1},
+		},
+		{
+			// The clocks went back at 02:00 on 3 November, making it a
+			// 25-hour day.
+			name: "streak across the end of daylight saving time",
+			loc:  newYork,
+			now:  "2024-11-04 20:00",
+			activity: []WorkoutActivity{
+				done(newYork, "2024-11-02 21:00"),
+				done(newYork, "2024-11-03 23:30"),
+				done(newYork, "2024-11-04 19:00"),
+			},
+			wantCurrent: 3,
+			wantLongest: 3,
+			wantAdherence: Adherence{
+				WindowDays: 30,
+				Scheduled:  3,
+				Completed:  3,
+				Percentage: 100,
+			},
+			wantDays: map[string]int{"2024-11-03": 1, "2024-11-04": 1},
+		},
+		{
+			name: "adherence with nothing scheduled",
+			loc:  utc,
+			now:  "2024-06-10 18:00",
+			activity: []WorkoutActivity{
+				unscheduled(utc, "2024-06-10 07:00"),
+			},
+			wantCurrent: 1,
+			wantLongest: 1,
+			wantAdherence: Adherence{
+				WindowDays: 30,
+			},
+		},
+		{
+			name: "adherence only counts the window up to now",
+			loc:  utc,
+			now:  "2024-06-10 18:00",
+			activity: []WorkoutActivity{
+				done(utc, "2024-05-01 10:00"), // before the window
+				done(utc, "2024-05-20 10:00"),
+				done(utc, "2024-06-03 10:00"),
+				{ScheduledAt: localTime(t, utc, "2024-06-05 10:00")}, // missed
+				done(utc, "2024-06-10 10:00"),
+				{ScheduledAt: localTime(t, utc, "2024-06-11 10:00")}, // upcoming
+			},
+			wantCurrent: 1,
+			wantLongest: 1,
+			wantAdherence: Adherence{
+				WindowDays: 30,
+				Scheduled:  4,
+				Completed:  3,
+				Percentage: 75,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := localTime(t, tt.loc, tt.now)
+
+			stats := CalculateTrainingStats(tt.activity, tt.loc, now, 30)
+
+			if stats.Timezone != tt.loc.String() {
+				t.Errorf("Timezone = %q; want %q", stats.Timezone, tt.loc.String())
+			}
+
+			if stats.CurrentStreak != tt.wantCurrent || stats.LongestStreak != tt.wantLongest {
+				t.Errorf(
+					"streaks = %d current, %d longest; want %d, %d",
+					stats.CurrentStreak,
+					stats.LongestStreak,
+					tt.wantCurrent,
+					tt.wantLongest,
+				)
+			}
+
+			if stats.Adherence != tt.wantAdherence {
+				t.Errorf("Adherence = %+v; want %+v", stats.Adherence, tt.wantAdherence)
+			}
+
+			if len(stats.Heatmap) != heatmapDays {
+				t.Fatalf("heatmap has %d days; want %d", len(stats.Heatmap), heatmapDays)
+			}
+
+			if last := stats.Heatmap[heatmapDays-1].Date; last != now.Format(time.DateOnly) {
+				t.Errorf("heatmap ends on %s; want today, %s", last, now.Format(time.DateOnly))
+			}
+
+			counts := make(map[string]int, len(stats.Heatmap))
+			for _, day := range stats.Heatmap {
+				counts[day.Date] = day.Count
+			}
+
+			for date, want := range tt.wantDays {
+				if counts[date] != want {
+					t.Errorf("heatmap count on %s = %d; want %d", date, counts[date], want)
+				}
+			}
+		})
+	}
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s is not available: %v", name, err)
+	}
+
+	return loc
+}
+
+// localTime parses a "2006-01-02 15:04" wall clock time in loc.
+func localTime(t *testing.T, loc *time.Location, value string) time.Time {
+	t.Helper()
+
+	at, err := time.ParseInLocation("2006-01-02 15:04", value, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return at
+}
@@ -34,21 +34,36 @@ func generateToken(
 		Scope:  scope,
 	}
 
+	plaintext, hash, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	token.Plaintext = plaintext
+	token.Hash = hash
+
+	return token, nil
+}
+
+// randomToken returns a random 26 character plaintext token and the SHA-256
+// hash of it that is stored in place of the plaintext.
+func randomToken() (string, []byte, error) {
 	randomBytes := make([]byte, 16)
 
 	_, err := rand.Read(randomBytes)
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 
-	token.Plaintext = base32.StdEncoding.WithPadding(base32.NoPadding).
+	plaintext := base32.StdEncoding.WithPadding(base32.NoPadding).
 		EncodeToString(randomBytes)
 
-	hash := sha256.Sum256([]byte(token.Plaintext))
-
-	token.Hash = hash[:]
+	return plaintext, hashToken(plaintext), nil
+}
 
-	return token, nil
+func hashToken(plaintext string) []byte {
+	hash := sha256.Sum256([]byte(plaintext))
+	return hash[:]
 }
 
 func ValidateTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
@@ -57,10 +72,12 @@ func ValidateTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
 }
 
 type TokenModel struct {
-	DB *sql.DB
+	DB      *sql.DB
+	Timeout time.Duration
 }
 
 func (m TokenModel) New(
+	ctx context.Context,
 	userID int64,
 	ttl time.Duration,
 	scope string,
@@ -70,19 +87,19 @@ func (m TokenModel) New(
 		return nil, err
 	}
 
-	err = m.Insert(token)
+	err = m.Insert(ctx, token)
 
 	return token, err
 }
 
-func (m TokenModel) Insert(token *Token) error {
+func (m TokenModel) Insert(ctx context.Context, token *Token) error {
 	query := `
         INSERT INTO tokens (hash, user_id, expiry, scope)
         VALUES ($1,$2,$3,$4)`
 
 	args := []any{token.Hash, token.UserID, token.Expiry, token.Scope}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, args...)
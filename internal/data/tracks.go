@@ -0,0 +1,222 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const metersPerFoot = 0.3048
+
+type TrackSplit struct {
+	Number          int      `json:"number"`
+	Distance        float64  `json:"distance"` // metres
+	DurationSeconds float64  `json:"duration_seconds"`
+	Pace            *float64 `json:"pace,omitempty"` // seconds per distance unit
+}
+
+// WorkoutTrack is a GPS activity recorded on a device and attached to a
+// cardio exercise in a workout.
+type WorkoutTrack struct {
+	ID                int64        `json:"id"`
+	WorkoutID         int64        `json:"workout_id"`
+	WorkoutExerciseID int64        `json:"-"`
+	ExerciseID        int64        `json:"exercise_id"`
+	Format            string       `json:"format"`
+	StartedAt         time.Time    `json:"started_at"`
+	Distance          float64      `json:"distance"` // metres
+	DistanceUnit      string       `json:"distance_unit"`
+	DurationSeconds   int          `json:"duration_seconds"`
+	Pace              *float64     `json:"pace,omitempty"`
+	ElevationGain     float64      `json:"elevation_gain"` // metres
+	ElevationUnit     string       `json:"elevation_unit"`
+	Splits            []TrackSplit `json:"splits"`
+	CreatedAt         time.Time    `json:"created_at"`
+}
+
+// InUnits returns a copy of the track with distances converted from metres
+// into the given units and pace derived for the whole activity and for each
+// split. Elevation is reported in feet for users who prefer miles.
+func (t *WorkoutTrack) InUnits(units Units) *WorkoutTrack {
+	converted := *t
+
+	converted.Distance = units.DisplayDistance(t.Distance)
+	converted.DistanceUnit = units.Distance
+	converted.Pace = pace(float64(t.DurationSeconds), t.Distance, units)
+
+	converted.ElevationUnit = "m"
+	if units.Distance == UnitMiles {
+		converted.ElevationGain = round(t.ElevationGain/metersPerFoot, 0)
+		converted.ElevationUnit = "ft"
+	}
+
+	converted.Splits = make([]TrackSplit, len(t.Splits))
+	for i, split := range t.Splits {
+		split.Pace = pace(split.DurationSeconds, split.Distance, units)
+		split.Distance = units.DisplayDistance(split.Distance)
+		converted.Splits[i] = split
+	}
+
+	return &converted
+}
+
+func pace(seconds, meters float64, units Units) *float64 {
+	if seconds <= 0 || meters <= 0 {
+		return nil
+	}
+
+	distance := meters / 1000
+	if units.Distance == UnitMiles {
+		distance = meters / metersPerMile
+	}
+
+	value := round(seconds/distance, 0)
+	return &value
+}
+
+type TrackModel struct {
+	DB      *sql.DB
+	Timeout time.Duration
+}
+
+// InsertWithWorkout saves the workout, whose last exercise is the cardio
+// entry the track records, and stores the track against that entry in a
+// single transaction. Saving the workout writes its workout.updated event.
+func (m TrackModel) InsertWithWorkout(
+	ctx context.Context,
+	track *WorkoutTrack,
+	workout *Workout,
+) error {
+	if len(workout.Exercises) == 0 {
+		return errors.New("the workout has no exercise for the track")
+	}
+
+	splits, err := json.Marshal(track.Splits)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = updateWorkoutWithExercises(ctx, tx, workout)
+	if err != nil {
+		return err
+	}
+
+	workoutExercise := workout.Exercises[len(workout.Exercises)-1]
+
+	track.WorkoutID = workout.ID
+	track.WorkoutExerciseID = workoutExercise.ID
+	track.ExerciseID = workoutExercise.ExerciseID
+
+	query := `
+        INSERT INTO workout_tracks (workout_id, workout_exercise_id, exercise_id,
+            format, started_at, distance, duration_seconds, elevation_gain, splits)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        RETURNING id, created_at`
+
+	args := []any{
+		track.WorkoutID,
+		track.WorkoutExerciseID,
+		track.ExerciseID,
+		track.Format,
+		track.StartedAt,
+		track.Distance,
+		track.DurationSeconds,
+		track.ElevationGain,
+		splits,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(
+		&track.ID,
+		&track.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m TrackModel) GetAllForWorkout(
+	ctx context.Context,
+	workoutID int64,
+) ([]*WorkoutTrack, error) {
+	query := `
+        SELECT id, workout_id, workout_exercise_id, exercise_id, format,
+            started_at, distance, duration_seconds, elevation_gain, splits,
+            created_at
+        FROM workout_tracks
+        WHERE workout_id = $1
+        ORDER BY started_at, id`
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, workoutID)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to execute query to fetch tracks for workout %d: %w",
+			workoutID,
+			err,
+		)
+	}
+
+	defer rows.Close()
+
+	tracks := []*WorkoutTrack{}
+
+	for rows.Next() {
+		var track WorkoutTrack
+		var splits []byte
+
+		err := rows.Scan(
+			&track.ID,
+			&track.WorkoutID,
+			&track.WorkoutExerciseID,
+			&track.ExerciseID,
+			&track.Format,
+			&track.StartedAt,
+			&track.Distance,
+			&track.DurationSeconds,
+			&track.ElevationGain,
+			&splits,
+			&track.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan track row: %w", err)
+		}
+
+		err = json.Unmarshal(splits, &track.Splits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode splits for track %d: %w", track.ID, err)
+		}
+
+		tracks = append(tracks, &track)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"error occurred while iterating over track rows: %w",
+			err,
+		)
+	}
+
+	return tracks, nil
+}
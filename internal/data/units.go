@@ -0,0 +1,168 @@
+package data
+
+import (
+	"math"
+	"slices"
+	"sulemankhann/workout-tracker/internal/validator"
+)
+
+// Weights are stored in kilograms, distances in metres and body
+// circumferences in centimetres. Everything else is a presentation concern
+// handled by the helpers in this file.
+const (
+	UnitKilograms   = "kg"
+	UnitPounds      = "lb"
+	UnitKilometers  = "km"
+	UnitMiles       = "mi"
+	UnitCentimeters = "cm"
+	UnitInches      = "in"
+)
+
+const (
+	kilogramsPerPound  = 0.45359237
+	metersPerMile      = 1609.344
+	centimetersPerInch = 2.54
+)
+
+var (
+	WeightUnits   = []string{UnitKilograms, UnitPounds}
+	DistanceUnits = []string{UnitKilometers, UnitMiles}
+	LengthUnits   = []string{UnitCentimeters, UnitInches}
+)
+
+// Units describes how a user wants values presented. PlateRounding rounds
+// lifted weights to the nearest loadable increment: 2.5 kg or 5 lb.
+type Units struct {
+	Weight        string `json:"weight_unit"`
+	Distance      string `json:"distance_unit"`
+	PlateRounding bool   `json:"plate_rounding"`
+}
+
+// Length returns the unit used for body measurements, which follows the
+// distance preference: centimetres for metric users and inches otherwise.
+func (u Units) Length() string {
+	if u.Distance == UnitMiles {
+		return UnitInches
+	}
+
+	return UnitCentimeters
+}
+
+// DisplayWeight converts a lifted weight from kilograms into the preferred
+// unit, applying plate rounding if it is enabled.
+func (u Units) DisplayWeight(kilograms float64) float64 {
+	value := FromKilograms(kilograms, u.Weight)
+
+	if u.PlateRounding {
+		increment := 2.5
+		if u.Weight == UnitPounds {
+			increment = 5
+		}
+
+		return math.Round(value/increment) * increment
+	}
+
+	return round(value, 2)
+}
+
+// DisplayBodyweight converts a bodyweight from kilograms into the preferred
+// unit. Plate rounding never applies to bodyweight.
+func (u Units) DisplayBodyweight(kilograms *float64) *float64 {
+	if kilograms == nil {
+		return nil
+	}
+
+	value := round(FromKilograms(*kilograms, u.Weight), 2)
+	return &value
+}
+
+func (u Units) DisplayLength(centimeters *float64) *float64 {
+	if centimeters == nil {
+		return nil
+	}
+
+	value := *centimeters
+	if u.Length() == UnitInches {
+		value /= centimetersPerInch
+	}
+
+	value = round(value, 1)
+	return &value
+}
+
+func (u Units) DisplayDistance(meters float64) float64 {
+	if u.Distance == UnitMiles {
+		return round(meters/metersPerMile, 2)
+	}
+
+	return round(meters/1000, 2)
+}
+
+func ToKilograms(value float64, unit string) float64 {
+	if unit == UnitPounds {
+		return value * kilogramsPerPound
+	}
+
+	return value
+}
+
+func FromKilograms(kilograms float64, unit string) float64 {
+	if unit == UnitPounds {
+		return kilograms / kilogramsPerPound
+	}
+
+	return kilograms
+}
+
+func ToMeters(value float64, unit string) float64 {
+	if unit == UnitMiles {
+		return value * metersPerMile
+	}
+
+	return value * 1000
+}
+
+func FromMeters(meters float64, unit string) float64 {
+	if unit == UnitMiles {
+		return meters / metersPerMile
+	}
+
+	return meters / 1000
+}
+
+func ToCentimeters(value *float64, unit string) *float64 {
+	if value == nil || unit != UnitInches {
+		return value
+	}
+
+	converted := *value * centimetersPerInch
+	return &converted
+}
+
+func ValidateUnit(v *validator.Validator, key, unit string, permitted []string) {
+	if unit == "" {
+		return
+	}
+
+	v.Check(
+		slices.Contains(permitted, unit),
+		key,
+		"must be one of "+joinUnits(permitted),
+	)
+}
+
+func joinUnits(units []string) string {
+	switch len(units) {
+	case 0:
+		return ""
+	case 1:
+		return units[0]
+	}
+
+	s := units[0]
+	for _, unit := range units[1 : len(units)-1] {
+		s += ", " + unit
+	}
+
+	return s + " or " + units[len(units)-1]
+}
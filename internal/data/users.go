@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"errors"
+	"slices"
 	"sulemankhann/workout-tracker/internal/validator"
 	"time"
 
@@ -18,6 +19,9 @@ type User struct {
 	CreatedAt time.Time `json:"created_at"`
 	Name      string    `json:"name"`
 	Email     string    `json:"email"`
+	Timezone  string    `json:"timezone"`
+	Units     Units     `json:"units"`
+	Privacy   string    `json:"privacy"`
 	Password  password  `json:"-"`
 }
 
@@ -25,6 +29,21 @@ func (u *User) IsAnonymous() bool {
 	return u == AnonymousUser
 }
 
+// Location returns the user's configured time zone, falling back to UTC if
+// it is unset or cannot be loaded.
+func (u *User) Location() *time.Location {
+	if u.Timezone == "" {
+		return time.UTC
+	}
+
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
+
 type password struct {
 	plaintext *string
 	hash      []byte
@@ -57,18 +76,44 @@ func (p *password) Matches(plaintextPassword string) (bool, error) {
 }
 
 type UserModel struct {
-	DB *sql.DB
+	DB      *sql.DB
+	Timeout time.Duration
 }
 
-func (m UserModel) Insert(user *User) error {
+func (m UserModel) Insert(ctx context.Context, user *User) error {
+	if user.Timezone == "" {
+		user.Timezone = "UTC"
+	}
+
+	if user.Units.Weight == "" {
+		user.Units.Weight = UnitKilograms
+	}
+
+	if user.Units.Distance == "" {
+		user.Units.Distance = UnitKilometers
+	}
+
+	if user.Privacy == "" {
+		user.Privacy = PrivacyPrivate
+	}
+
 	query := `
-        INSERT INTO users (name, email, password_hash)
-        VALUES ($1, $2, $3)
+        INSERT INTO users (name, email, timezone, weight_unit, distance_unit, plate_rounding, privacy, password_hash)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
         RETURNING id, created_at`
 
-	args := []any{user.Name, user.Email, user.Password.hash}
+	args := []any{
+		user.Name,
+		user.Email,
+		user.Timezone,
+		user.Units.Weight,
+		user.Units.Distance,
+		user.Units.PlateRounding,
+		user.Privacy,
+		user.Password.hash,
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
@@ -87,15 +132,18 @@ func (m UserModel) Insert(user *User) error {
 	return nil
 }
 
-func (m UserModel) GetByEmail(email string) (*User, error) {
+func (m UserModel) GetByEmail(
+	ctx context.Context,
+	email string,
+) (*User, error) {
 	query := `
-        SELECT id, created_at, name, email, password_hash 
+        SELECT id, created_at, name, email, timezone, weight_unit, distance_unit, plate_rounding, privacy, password_hash 
         FROM users
         WHERE email = $1`
 
 	var user User
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, email).Scan(
@@ -103,6 +151,11 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 		&user.CreatedAt,
 		&user.Name,
 		&user.Email,
+		&user.Timezone,
+		&user.Units.Weight,
+		&user.Units.Distance,
+		&user.Units.PlateRounding,
+		&user.Privacy,
 		&user.Password.hash,
 	)
 	if err != nil {
@@ -118,12 +171,15 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 }
 
 func (m UserModel) GetForToken(
+	ctx context.Context,
 	tokenScope, tokenPlaintext string,
 ) (*User, error) {
 	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
 
 	query := `
-        SELECT users.id, users.created_at, users.name, users.email, users.password_hash
+        SELECT users.id, users.created_at, users.name, users.email, users.timezone,
+            users.weight_unit, users.distance_unit, users.plate_rounding, users.privacy,
+            users.password_hash
         FROM users
         INNER JOIN tokens
         ON users.id = tokens.user_id
@@ -135,7 +191,7 @@ func (m UserModel) GetForToken(
 
 	var user User
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
@@ -143,6 +199,11 @@ func (m UserModel) GetForToken(
 		&user.CreatedAt,
 		&user.Name,
 		&user.Email,
+		&user.Timezone,
+		&user.Units.Weight,
+		&user.Units.Distance,
+		&user.Units.PlateRounding,
+		&user.Privacy,
 		&user.Password.hash,
 	)
 	if err != nil {
@@ -158,6 +219,50 @@ func (m UserModel) GetForToken(
 	return &user, nil
 }
 
+func (m UserModel) Update(ctx context.Context, user *User) error {
+	query := `
+        UPDATE users
+        SET name = $2, email = $3, timezone = $4, weight_unit = $5,
+            distance_unit = $6, plate_rounding = $7, privacy = $8, password_hash = $9
+        WHERE id = $1`
+
+	args := []any{
+		user.ID,
+		user.Name,
+		user.Email,
+		user.Timezone,
+		user.Units.Weight,
+		user.Units.Distance,
+		user.Units.PlateRounding,
+		user.Privacy,
+		user.Password.hash,
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
+			return ErrDuplicateEmail
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
 func ValidateEmail(v *validator.Validator, email string) {
 	v.Check(email != "", "email", "must be provided")
 	v.Check(
@@ -177,6 +282,15 @@ func ValidatePasswordPlaintext(v *validator.Validator, password string) {
 	)
 }
 
+func ValidateTimezone(v *validator.Validator, timezone string) {
+	if timezone == "" {
+		return
+	}
+
+	_, err := time.LoadLocation(timezone)
+	v.Check(err == nil, "timezone", "must be a valid IANA time zone name")
+}
+
 func ValidateUser(v *validator.Validator, user *User) {
 	v.Check(user.Name != "", "name", "must be provided")
 	v.Check(
@@ -188,6 +302,19 @@ func ValidateUser(v *validator.Validator, user *User) {
 	// Call the standalone ValidateEmail() helper.
 	ValidateEmail(v, user.Email)
 
+	ValidateTimezone(v, user.Timezone)
+
+	ValidateUnit(v, "units.weight_unit", user.Units.Weight, WeightUnits)
+	ValidateUnit(v, "units.distance_unit", user.Units.Distance, DistanceUnits)
+
+	if user.Privacy != "" {
+		v.Check(
+			slices.Contains(Privacies, user.Privacy),
+			"privacy",
+			"must be one of public, followers or private",
+		)
+	}
+
 	// If the plaintext password is not nil, call the standalone
 	// ValidatePasswordPlaintext() helper.
 	if user.Password.plaintext != nil {
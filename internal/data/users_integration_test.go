@@ -0,0 +1,214 @@
+//go:build integration
+
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUserModel(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	bob := insertTestUser(t, models, "Bob")
+
+	if alice.Timezone != "UTC" || alice.Units.Weight != UnitKilograms ||
+		alice.Privacy != PrivacyPrivate {
+		t.Errorf("defaults = %q, %q, %q", alice.Timezone, alice.Units.Weight, alice.Privacy)
+	}
+
+	// Emails are case insensitive.
+	duplicate := &User{
+		Name:     "Alice again",
+		Email:    "ALICE@example.com",
+		Password: testPasswordHash(),
+	}
+
+	err := models.Users.Insert(ctx, duplicate)
+	if !errors.Is(err, ErrDuplicateEmail) {
+		t.Errorf("Insert with a taken email: err = %v; want ErrDuplicateEmail", err)
+	}
+
+	found, err := models.Users.GetByEmail(ctx, "Alice@Example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if found.ID != alice.ID {
+		t.Errorf("GetByEmail returned user %d; want %d", found.ID, alice.ID)
+	}
+
+	matches, err := found.Password.Matches("pa55word1234")
+	if err != nil || !matches {
+		t.Errorf("stored password matches = %t, %v", matches, err)
+	}
+
+	_, err = models.Users.GetByEmail(ctx, "nobody@example.com")
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("GetByEmail for an unknown email: err = %v", err)
+	}
+
+	found.Name = "Alice Smith"
+	found.Timezone = "Europe/London"
+	found.Units = Units{Weight: UnitPounds, Distance: UnitMiles, PlateRounding: true}
+	found.Privacy = PrivacyPublic
+
+	err = models.Users.Update(ctx, found)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := models.Users.GetByEmail(ctx, alice.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if updated.Name != "Alice Smith" || updated.Timezone != "Europe/London" ||
+		updated.Units != found.Units || updated.Privacy != PrivacyPublic {
+		t.Errorf("after Update got %+v", updated)
+	}
+
+	updated.Email = bob.Email
+
+	err = models.Users.Update(ctx, updated)
+	if !errors.Is(err, ErrDuplicateEmail) {
+		t.Errorf("Update to a taken email: err = %v; want ErrDuplicateEmail", err)
+	}
+
+	err = models.Users.Update(ctx, &User{ID: bob.ID + 100, Password: testPasswordHash()})
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Update of a missing user: err = %v; want ErrRecordNotFound", err)
+	}
+}
+
+func TestTokenModel(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+
+	token, err := models.Tokens.New(ctx, alice.ID, time.Hour, ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := models.Users.GetForToken(ctx, ScopeAuthentication, token.Plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.ID != alice.ID {
+		t.Errorf("GetForToken returned user %d; want %d", user.ID, alice.ID)
+	}
+
+	_, err = models.Users.GetForToken(ctx, ScopeActivation, token.Plaintext)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("GetForToken with the wrong scope: err = %v", err)
+	}
+
+	expired, err := models.Tokens.New(ctx, alice.ID, -time.Minute, ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = models.Users.GetForToken(ctx, ScopeAuthentication, expired.Plaintext)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("GetForToken with an expired token: err = %v", err)
+	}
+
+	err = models.Tokens.Insert(ctx, &Token{
+		Hash:   hashToken("ABCDEFGHIJKLMNOPQRSTUVWXYZ"),
+		UserID: alice.ID + 100,
+		Expiry: time.Now().Add(time.Hour),
+		Scope:  ScopeAuthentication,
+	})
+	if err == nil {
+		t.Error("Insert of a token for a missing user succeeded")
+	}
+}
+
+func TestExerciseModel(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	squat := insertTestExercise(t, models, "Squat", MeasurementRepsWeight)
+	run := insertTestExercise(t, models, "Run", MeasurementDistanceTime)
+
+	if squat.ID == 0 || squat.CreatedAt.IsZero() {
+		t.Errorf("Insert didn't set the id and timestamps: %+v", squat)
+	}
+
+	exercise, err := models.Exercises.Get(ctx, run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exercise.Name != "Run" || exercise.MeasurementType != MeasurementDistanceTime {
+		t.Errorf("Get returned %+v", exercise)
+	}
+
+	_, err = models.Exercises.Get(ctx, run.ID+100)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Get of a missing exercise: err = %v", err)
+	}
+
+	exercises, err := models.Exercises.GetAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(exercises) != 2 {
+		t.Errorf("GetAll returned %d exercises; want 2", len(exercises))
+	}
+
+	err = models.Exercises.Insert(ctx, &Exercise{Name: "Squat", Category: "strength"})
+	if !errors.Is(err, ErrDuplicateExercise) {
+		t.Errorf("inserting a duplicate name: err = %v", err)
+	}
+
+	changedSquat := *squat
+	changedSquat.MuscleGroup = "quads"
+
+	unchangedRun := *run
+	lunge := &Exercise{Name: "Lunge", Category: "strength", MuscleGroup: "legs"}
+
+	inserted, updated, err := models.Exercises.Upsert(ctx, &changedSquat, &unchangedRun, lunge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if inserted != 1 || updated != 1 {
+		t.Errorf("Upsert inserted %d and updated %d; want 1 and 1", inserted, updated)
+	}
+
+	if changedSquat.ID != squat.ID || lunge.ID == 0 {
+		t.Errorf("Upsert set the ids to %d and %d", changedSquat.ID, lunge.ID)
+	}
+
+	exercise, err = models.Exercises.Get(ctx, squat.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exercise.MuscleGroup != "quads" {
+		t.Errorf("after Upsert the squat's muscle group is %q", exercise.MuscleGroup)
+	}
+
+	// Upserting the same catalogue again changes nothing.
+	inserted, updated, err = models.Exercises.Upsert(ctx, &changedSquat, &unchangedRun, lunge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if inserted != 0 || updated != 0 {
+		t.Errorf("repeated Upsert inserted %d and updated %d", inserted, updated)
+	}
+
+	if count := countRows(t, "exercises", "true"); count != 3 {
+		t.Errorf("%d exercises after upserting; want 3", count)
+	}
+}
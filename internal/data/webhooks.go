@@ -0,0 +1,457 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"sulemankhann/workout-tracker/internal/validator"
+	"sulemankhann/workout-tracker/internal/webhook"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	EventWorkoutCreated   = "workout.created"
+	EventWorkoutUpdated   = "workout.updated"
+	EventWorkoutDeleted   = "workout.deleted"
+	EventWorkoutCompleted = "workout.completed"
+	EventWorkoutReminder  = "workout.reminder"
+	EventImportCompleted  = "import.completed"
+)
+
+var WebhookEvents = []string{
+	EventWorkoutCreated,
+	EventWorkoutUpdated,
+	EventWorkoutDeleted,
+	EventWorkoutCompleted,
+	EventWorkoutReminder,
+	EventImportCompleted,
+}
+
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusSucceeded = "succeeded"
+	DeliveryStatusFailed    = "failed"
+)
+
+// Webhook is an endpoint registered by a user to receive the events they
+// subscribed to. The secret is only shown when the webhook is created.
+type Webhook struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"-"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ValidateWebhook checks the webhook's URL and events. Unless allowLocal is
+// set, which is meant for development, the URL must use https and must not
+// name a local or private address.
+func ValidateWebhook(v *validator.Validator, webhook *Webhook, allowLocal bool) {
+	u, err := url.Parse(webhook.URL)
+
+	v.Check(webhook.URL != "", "url", "must be provided")
+	v.Check(len(webhook.URL) <= 2000, "url", "must not be more than 2000 bytes long")
+	v.Check(
+		err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "",
+		"url",
+		"must be an absolute http or https URL",
+	)
+
+	if !allowLocal && err == nil {
+		v.Check(u.Scheme == "https", "url", "must be an https URL")
+		v.Check(!isLocalHost(u.Hostname()), "url", "must not point to a local or private address")
+	}
+
+	v.Check(len(webhook.Events) > 0, "events", "must contain at least one event")
+
+	for _, event := range webhook.Events {
+		v.Check(
+			slices.Contains(WebhookEvents, event),
+			"events",
+			"must only contain "+strings.Join(WebhookEvents, ", "),
+		)
+	}
+}
+
+// isLocalHost reports whether host obviously names the server itself or its
+// network. Hostnames that only resolve to such addresses are refused when
+// the delivery is made.
+func isLocalHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	ip, err := netip.ParseAddr(host)
+
+	return err == nil && !webhook.IsPublicAddr(ip)
+}
+
+// WebhookDelivery is one event sent, or waiting to be sent, to a webhook.
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	WebhookID      int64      `json:"webhook_id"`
+	Event          string     `json:"event"`
+	Payload        []byte     `json:"-"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`
+	ResponseStatus *int       `json:"response_status"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	URL            string     `json:"-"`
+	Secret         string     `json:"-"`
+}
+
+type WebhookModel struct {
+	DB      *sql.DB
+	Timeout time.Duration
+}
+
+// New generates a signing secret for the webhook and stores it.
+func (m WebhookModel) New(ctx context.Context, webhook *Webhook) error {
+	secret, _, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	webhook.Secret = secret
+
+	query := `
+        INSERT INTO webhooks (user_id, url, secret, events)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at`
+
+	args := []any{
+		webhook.UserID,
+		webhook.URL,
+		webhook.Secret,
+		pq.Array(webhook.Events),
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&webhook.ID,
+		&webhook.CreatedAt,
+	)
+}
+
+func (m WebhookModel) GetAllForUser(
+	ctx context.Context,
+	userID int64,
+) ([]*Webhook, error) {
+	query := `
+        SELECT id, user_id, url, events, created_at
+        FROM webhooks
+        WHERE user_id = $1
+        ORDER BY id`
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to execute query to fetch webhooks for user %d: %w",
+			userID,
+			err,
+		)
+	}
+
+	defer rows.Close()
+
+	webhooks := []*Webhook{}
+
+	for rows.Next() {
+		var webhook Webhook
+
+		err := rows.Scan(
+			&webhook.ID,
+			&webhook.UserID,
+			&webhook.URL,
+			pq.Array(&webhook.Events),
+			&webhook.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook row: %w", err)
+		}
+
+		webhooks = append(webhooks, &webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"error occurred while iterating over webhook rows: %w",
+			err,
+		)
+	}
+
+	return webhooks, nil
+}
+
+func (m WebhookModel) DeleteByUser(
+	ctx context.Context,
+	id, userID int64,
+) error {
+	query := `
+        DELETE FROM webhooks
+        WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Enqueue queues payload for delivery to every one of the user's webhooks
+// subscribed to the event. Enqueueing the same idempotency key twice is a
+// no-op, so an event relayed more than once is only delivered once.
+func (m WebhookModel) Enqueue(
+	ctx context.Context,
+	userID int64,
+	event, idempotencyKey string,
+	payload []byte,
+) error {
+	query := `
+        INSERT INTO webhook_deliveries (webhook_id, event, idempotency_key, payload)
+        SELECT id, $2, $3, $4
+        FROM webhooks
+        WHERE user_id = $1 AND $2 = ANY(events)
+        ON CONFLICT (webhook_id, idempotency_key) DO NOTHING`
+
+	args := []any{userID, event, idempotencyKey, payload}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// ClaimDue returns up to limit pending deliveries whose next attempt is due,
+// along with the URL and secret to send them with. Claimed deliveries are
+// pushed back by lease so that other workers, or this one after a crash,
+// don't pick them up while they are being sent; RecordAttempt then sets the
+// real next attempt time.
+func (m WebhookModel) ClaimDue(
+	ctx context.Context,
+	limit int,
+	lease time.Duration,
+) ([]*WebhookDelivery, error) {
+	query := `
+        UPDATE webhook_deliveries d
+        SET next_attempt_at = NOW() + $2 * interval '1 second'
+        FROM webhooks w
+        WHERE w.id = d.webhook_id
+        AND d.id IN (
+            SELECT id FROM webhook_deliveries
+            WHERE status = 'pending' AND next_attempt_at <= NOW()
+            ORDER BY next_attempt_at
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING d.id, d.webhook_id, d.event, d.payload, d.attempts, d.created_at, w.url, w.secret`
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due webhook deliveries: %w", err)
+	}
+
+	defer rows.Close()
+
+	deliveries := []*WebhookDelivery{}
+
+	for rows.Next() {
+		delivery := WebhookDelivery{Status: DeliveryStatusPending}
+
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.Event,
+			&delivery.Payload,
+			&delivery.Attempts,
+			&delivery.CreatedAt,
+			&delivery.URL,
+			&delivery.Secret,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery row: %w", err)
+		}
+
+		deliveries = append(deliveries, &delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"error occurred while iterating over webhook delivery rows: %w",
+			err,
+		)
+	}
+
+	return deliveries, nil
+}
+
+// RecordAttempt saves the outcome of sending a delivery.
+func (m WebhookModel) RecordAttempt(
+	ctx context.Context,
+	delivery *WebhookDelivery,
+) error {
+	query := `
+        UPDATE webhook_deliveries
+        SET status = $2, attempts = $3, next_attempt_at = COALESCE($4, next_attempt_at),
+            last_attempt_at = $5, response_status = $6, last_error = $7
+        WHERE id = $1`
+
+	args := []any{
+		delivery.ID,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.NextAttemptAt,
+		delivery.LastAttemptAt,
+		delivery.ResponseStatus,
+		delivery.LastError,
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// GetDeliveriesForWebhook returns a page of a webhook's deliveries, newest
+// first. Deliveries of webhooks not owned by userID are never returned.
+func (m WebhookModel) GetDeliveriesForWebhook(
+	ctx context.Context,
+	webhookID, userID int64,
+	filters Filters,
+) ([]*WebhookDelivery, Metadata, error) {
+	query := `
+        SELECT count(*) OVER(), d.id, d.webhook_id, d.event, d.status, d.attempts,
+            CASE WHEN d.status = 'pending' THEN d.next_attempt_at END,
+            d.last_attempt_at, d.response_status, d.last_error, d.created_at
+        FROM webhook_deliveries d
+        JOIN webhooks w ON d.webhook_id = w.id
+        WHERE d.webhook_id = $1 AND w.user_id = $2
+        ORDER BY d.id DESC
+        LIMIT $3 OFFSET $4`
+
+	args := []any{webhookID, userID, filters.limit(), filters.offset()}
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, fmt.Errorf(
+			"failed to execute query to fetch deliveries for webhook %d: %w",
+			webhookID,
+			err,
+		)
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	deliveries := []*WebhookDelivery{}
+
+	for rows.Next() {
+		var delivery WebhookDelivery
+
+		err := rows.Scan(
+			&totalRecords,
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.Event,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.NextAttemptAt,
+			&delivery.LastAttemptAt,
+			&delivery.ResponseStatus,
+			&delivery.LastError,
+			&delivery.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, fmt.Errorf(
+				"failed to scan webhook delivery row: %w",
+				err,
+			)
+		}
+
+		deliveries = append(deliveries, &delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, fmt.Errorf(
+			"error occurred while iterating over webhook delivery rows: %w",
+			err,
+		)
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return deliveries, metadata, nil
+}
+
+// GetByUser returns one of the user's webhooks, without its secret.
+func (m WebhookModel) GetByUser(
+	ctx context.Context,
+	id, userID int64,
+) (*Webhook, error) {
+	query := `
+        SELECT id, user_id, url, events, created_at
+        FROM webhooks
+        WHERE id = $1 AND user_id = $2`
+
+	var webhook Webhook
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(
+		&webhook.ID,
+		&webhook.UserID,
+		&webhook.URL,
+		pq.Array(&webhook.Events),
+		&webhook.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &webhook, nil
+}
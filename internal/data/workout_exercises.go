@@ -6,32 +6,120 @@ import (
 )
 
 type WorkoutExercise struct {
-	ID           int64     `json:"-"`
-	WorkoutID    int64     `json:"-"`
-	ExerciseID   int64     `json:"-"`
-	Exercise     Exercise  `json:"exercise"`
-	Sets         int       `json:"set"`
-	Repetitions  int       `json:"repetitions"`
-	Weight       float64   `json:"weight"` // 0 for bodyweight exercises
-	RestInterval int       `json:"rest_interval"`
-	CreatedAt    time.Time `json:"-"`
-	UpdatedAt    time.Time `json:"-"`
+	ID              int64     `json:"-"`
+	WorkoutID       int64     `json:"-"`
+	ExerciseID      int64     `json:"-"`
+	Exercise        Exercise  `json:"exercise"`
+	Sets            int       `json:"set"`
+	Repetitions     int       `json:"repetitions"`
+	Weight          float64   `json:"weight"` // kilograms, 0 for bodyweight exercises
+	WeightUnit      string    `json:"weight_unit"`
+	DurationSeconds int       `json:"duration_seconds"`
+	Distance        float64   `json:"distance"` // metres
+	DistanceUnit    string    `json:"distance_unit"`
+	Pace            *float64  `json:"pace,omitempty"`  // seconds per distance unit
+	Speed           *float64  `json:"speed,omitempty"` // distance units per hour
+	RestInterval    int       `json:"rest_interval"`
+	CreatedAt       time.Time `json:"-"`
+	UpdatedAt       time.Time `json:"-"`
 }
 
+// deriveCardioMetrics converts the stored distance into the given units and,
+// for distance-and-time exercises, fills in pace and speed.
+func (we *WorkoutExercise) deriveCardioMetrics(units Units) {
+	meters := we.Distance
+
+	we.Distance = units.DisplayDistance(meters)
+	we.DistanceUnit = units.Distance
+	we.Pace = nil
+	we.Speed = nil
+
+	if we.Exercise.MeasurementType != MeasurementDistanceTime ||
+		meters <= 0 || we.DurationSeconds <= 0 {
+		return
+	}
+
+	hours := float64(we.DurationSeconds) / 3600
+	speed := round(units.DisplayDistance(meters)/hours, 2)
+
+	we.Pace = pace(float64(we.DurationSeconds), meters, units)
+	we.Speed = &speed
+}
+
+// ValidateWorkoutEXercise checks the fields that matter for the exercise's
+// measurement type, so a run needs a distance and a time while a plank needs
+// only a time. The Exercise field must be populated before calling it.
 func ValidateWorkoutEXercise(
 	v *validator.Validator,
 	workoutExercise *WorkoutExercise,
 ) {
+	switch workoutExercise.Exercise.MeasurementType {
+	case MeasurementRepsOnly:
+		validateSets(v, workoutExercise)
+		validateRepetitions(v, workoutExercise)
+		v.Check(
+			workoutExercise.Weight == 0,
+			"weight",
+			"must be zero for this exercise",
+		)
+
+	case MeasurementTime:
+		validateSets(v, workoutExercise)
+		validateDuration(v, workoutExercise)
+
+	case MeasurementDistanceTime:
+		v.Check(
+			workoutExercise.Distance > 0,
+			"distance",
+			"must be greater than zero",
+		)
+		validateDuration(v, workoutExercise)
+
+	default:
+		validateSets(v, workoutExercise)
+		validateRepetitions(v, workoutExercise)
+	}
+
+	v.Check(workoutExercise.Weight >= 0, "weight", "must be zero or greater")
+	v.Check(
+		workoutExercise.DurationSeconds >= 0,
+		"duration_seconds",
+		"must be zero or greater",
+	)
+	v.Check(
+		workoutExercise.Distance >= 0,
+		"distance",
+		"must be zero or greater",
+	)
+	v.Check(
+		workoutExercise.RestInterval >= 0,
+		"rest_interval",
+		"must be zero or greater",
+	)
+}
+
+func validateSets(v *validator.Validator, workoutExercise *WorkoutExercise) {
 	v.Check(workoutExercise.Sets > 0, "sets", "must be greater than zero")
+}
+
+func validateRepetitions(
+	v *validator.Validator,
+	workoutExercise *WorkoutExercise,
+) {
 	v.Check(
 		workoutExercise.Repetitions > 0,
 		"repetitions",
 		"must be greater than zero",
 	)
-	v.Check(workoutExercise.Weight >= 0, "weight", "must be zero or greater")
+}
+
+func validateDuration(
+	v *validator.Validator,
+	workoutExercise *WorkoutExercise,
+) {
 	v.Check(
-		workoutExercise.RestInterval >= 0,
-		"rest_interval",
-		"must be zero or greater",
+		workoutExercise.DurationSeconds > 0,
+		"duration_seconds",
+		"must be greater than zero",
 	)
 }
@@ -17,17 +17,78 @@ type Workout struct {
 	Title       string            `json:"title"`
 	Description string            `json:"description"`
 	ScheduledAt time.Time         `json:"scheduled_at"`
+	CompletedAt *time.Time        `json:"completed_at"`
 	Exercises   []WorkoutExercise `json:"exercises"`
 	CreatedAt   time.Time         `json:"-"`
 	UpdatedAt   time.Time         `json:"-"`
 }
 
+// InUnits returns a copy of the workout with weights and distances converted
+// from kilograms and metres into the given units for presentation, and with
+// pace and speed derived for cardio exercises.
+func (w *Workout) InUnits(units Units) *Workout {
+	converted := *w
+	converted.Exercises = make([]WorkoutExercise, len(w.Exercises))
+
+	for i, workoutExercise := range w.Exercises {
+		workoutExercise.Weight = units.DisplayWeight(workoutExercise.Weight)
+		workoutExercise.WeightUnit = units.Weight
+		workoutExercise.deriveCardioMetrics(units)
+		converted.Exercises[i] = workoutExercise
+	}
+
+	return &converted
+}
+
+// InExactUnits converts the workout like InUnits but without rounding
+// weights or distances, or applying plate rounding, so that nothing is lost
+// when the values are read back in. Exports use it.
+func (w *Workout) InExactUnits(units Units) *Workout {
+	converted := w.InUnits(units)
+
+	for i, workoutExercise := range w.Exercises {
+		converted.Exercises[i].Weight = FromKilograms(
+			workoutExercise.Weight,
+			units.Weight,
+		)
+		converted.Exercises[i].Distance = FromMeters(
+			workoutExercise.Distance,
+			units.Distance,
+		)
+	}
+
+	return converted
+}
+
+// WorkoutsInUnits applies InUnits to every workout in the slice.
+func WorkoutsInUnits(workouts []*Workout, units Units) []*Workout {
+	converted := make([]*Workout, len(workouts))
+
+	for i, workout := range workouts {
+		converted[i] = workout.InUnits(units)
+	}
+
+	return converted
+}
+
 type WorkoutModel struct {
-	DB *sql.DB
+	DB      *sql.DB
+	Timeout time.Duration
 }
 
-func (m WorkoutModel) CreateWorkoutWithExercises(workout *Workout) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+// CreateWorkoutWithExercises inserts one or more workouts and their exercises
+// in a single transaction, so either all of them are created or none are. A
+// workout.created event is written to the outbox for each of them.
+func (m WorkoutModel) CreateWorkoutWithExercises(
+	ctx context.Context,
+	workouts ...*Workout,
+) error {
+	// Bulk imports can insert thousands of rows, so allow extra time on top
+	// of the usual query timeout for large batches.
+	ctx, cancel := withQueryTimeout(
+		ctx,
+		m.Timeout+time.Duration(len(workouts))*10*time.Millisecond,
+	)
 	defer cancel()
 
 	tx, err := m.DB.BeginTx(ctx, nil)
@@ -37,9 +98,58 @@ func (m WorkoutModel) CreateWorkoutWithExercises(workout *Workout) error {
 
 	defer tx.Rollback()
 
+	// Imports create every workout for the same user, so look their units
+	// up once rather than once per workout.
+	unitsByUser := make(map[int64]Units)
+
+	for _, workout := range workouts {
+		err = insertWorkoutWithExercises(ctx, tx, workout)
+		if err != nil {
+			return err
+		}
+
+		units, ok := unitsByUser[workout.UserID]
+		if !ok {
+			units, err = unitsForUser(ctx, tx, workout.UserID)
+			if err != nil {
+				return err
+			}
+
+			unitsByUser[workout.UserID] = units
+		}
+
+		event, err := newWorkoutEvent(EventWorkoutCreated, workout, units)
+		if err != nil {
+			return err
+		}
+
+		err = insertOutboxEvent(ctx, tx, event)
+		if err != nil {
+			return err
+		}
+
+		err = writeWorkoutAudit(ctx, tx, workout, AuditActionCreated)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func insertWorkoutWithExercises(
+	ctx context.Context,
+	tx *sql.Tx,
+	workout *Workout,
+) error {
 	query := `
-        INSERT INTO workouts (user_id, title, description, scheduled_at)
-        VALUES ($1, $2, $3, $4)
+        INSERT INTO workouts (user_id, title, description, scheduled_at, completed_at)
+        VALUES ($1, $2, $3, $4, $5)
         RETURNING id, created_at, updated_at`
 
 	args := []any{
@@ -47,9 +157,10 @@ func (m WorkoutModel) CreateWorkoutWithExercises(workout *Workout) error {
 		workout.Title,
 		workout.Description,
 		workout.ScheduledAt,
+		workout.CompletedAt,
 	}
 
-	err = tx.QueryRowContext(ctx, query, args...).Scan(
+	err := tx.QueryRowContext(ctx, query, args...).Scan(
 		&workout.ID,
 		&workout.CreatedAt,
 		&workout.UpdatedAt,
@@ -58,36 +169,195 @@ func (m WorkoutModel) CreateWorkoutWithExercises(workout *Workout) error {
 		return err
 	}
 
-	for _, workoutExercise := range workout.Exercises {
-		query = `
-		INSERT INTO workout_exercises (workout_id, exercise_id, sets, repetitions, weight, rest_interval)
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`
-		args = []any{
-			workout.ID,
-			workoutExercise.ExerciseID,
+	return insertWorkoutExercises(ctx, tx, workout)
+}
+
+func insertWorkoutExercises(
+	ctx context.Context,
+	tx *sql.Tx,
+	workout *Workout,
+) error {
+	for i := range workout.Exercises {
+		err := insertWorkoutExercise(ctx, tx, workout.ID, i, &workout.Exercises[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func insertWorkoutExercise(
+	ctx context.Context,
+	tx *sql.Tx,
+	workoutID int64,
+	position int,
+	workoutExercise *WorkoutExercise,
+) error {
+	query := `
+        INSERT INTO workout_exercises (workout_id, exercise_id, position, sets,
+            repetitions, weight, rest_interval, duration_seconds, distance)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        RETURNING id`
+
+	args := []any{
+		workoutID,
+		workoutExercise.ExerciseID,
+		position,
+		workoutExercise.Sets,
+		workoutExercise.Repetitions,
+		workoutExercise.Weight,
+		workoutExercise.RestInterval,
+		workoutExercise.DurationSeconds,
+		workoutExercise.Distance,
+	}
+
+	workoutExercise.WorkoutID = workoutID
+
+	return tx.QueryRowContext(ctx, query, args...).Scan(&workoutExercise.ID)
+}
+
+// replaceWorkoutExercises saves the workout's exercises over the ones it
+// had. Rows that are kept are updated in place rather than replaced, so that
+// the tracks recorded against them survive the edit.
+func replaceWorkoutExercises(
+	ctx context.Context,
+	tx *sql.Tx,
+	workout *Workout,
+) error {
+	query := `
+        SELECT id, exercise_id
+        FROM workout_exercises
+        WHERE workout_id = $1
+        ORDER BY position, id
+        FOR UPDATE`
+
+	rows, err := tx.QueryContext(ctx, query, workout.ID)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	existing := []WorkoutExercise{}
+
+	for rows.Next() {
+		var workoutExercise WorkoutExercise
+
+		err := rows.Scan(&workoutExercise.ID, &workoutExercise.ExerciseID)
+		if err != nil {
+			return err
+		}
+
+		existing = append(existing, workoutExercise)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	reuse, remove := matchWorkoutExercises(existing, workout.Exercises)
+
+	if len(remove) > 0 {
+		query = `DELETE FROM workout_exercises WHERE id = ANY($1)`
+
+		_, err = tx.ExecContext(ctx, query, pq.Array(remove))
+		if err != nil {
+			return err
+		}
+	}
+
+	query = `
+        UPDATE workout_exercises
+        SET position = $2, sets = $3, repetitions = $4, weight = $5,
+            rest_interval = $6, duration_seconds = $7, distance = $8,
+            updated_at = NOW()
+        WHERE id = $1`
+
+	for i := range workout.Exercises {
+		workoutExercise := &workout.Exercises[i]
+
+		if reuse[i] == 0 {
+			err = insertWorkoutExercise(ctx, tx, workout.ID, i, workoutExercise)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		args := []any{
+			reuse[i],
+			i,
 			workoutExercise.Sets,
 			workoutExercise.Repetitions,
 			workoutExercise.Weight,
 			workoutExercise.RestInterval,
+			workoutExercise.DurationSeconds,
+			workoutExercise.Distance,
 		}
 
 		_, err = tx.ExecContext(ctx, query, args...)
 		if err != nil {
 			return err
 		}
-	}
 
-	err = tx.Commit()
-	if err != nil {
-		return err
+		workoutExercise.ID = reuse[i]
+		workoutExercise.WorkoutID = workout.ID
 	}
 
 	return nil
 }
 
-func (m WorkoutModel) UpdateWorkoutWithExercises(workout *Workout) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+// matchWorkoutExercises pairs the exercises a workout is being saved with
+// against the rows it already has. An exercise keeps the row with its ID if
+// it has one, or otherwise takes the first unclaimed row for the same
+// catalogue exercise. It returns the row each exercise reuses, 0 if it needs
+// a new one, and the rows left over.
+func matchWorkoutExercises(
+	existing, exercises []WorkoutExercise,
+) (reuse, remove []int64) {
+	reuse = make([]int64, len(exercises))
+	claimed := make([]bool, len(existing))
+
+	claim := func(i int, match func(WorkoutExercise) bool) {
+		for j, row := range existing {
+			if !claimed[j] && row.ExerciseID == exercises[i].ExerciseID && match(row) {
+				claimed[j] = true
+				reuse[i] = row.ID
+				return
+			}
+		}
+	}
+
+	for i, workoutExercise := range exercises {
+		if workoutExercise.ID != 0 {
+			claim(i, func(row WorkoutExercise) bool {
+				return row.ID == workoutExercise.ID
+			})
+		}
+	}
+
+	for i := range exercises {
+		if reuse[i] == 0 {
+			claim(i, func(WorkoutExercise) bool { return true })
+		}
+	}
+
+	for j, row := range existing {
+		if !claimed[j] {
+			remove = append(remove, row.ID)
+		}
+	}
+
+	return reuse, remove
+}
+
+func (m WorkoutModel) UpdateWorkoutWithExercises(
+	ctx context.Context,
+	workout *Workout,
+) error {
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	tx, err := m.DB.BeginTx(ctx, nil)
@@ -97,6 +367,24 @@ func (m WorkoutModel) UpdateWorkoutWithExercises(workout *Workout) error {
 
 	defer tx.Rollback()
 
+	err = updateWorkoutWithExercises(ctx, tx, workout)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func updateWorkoutWithExercises(
+	ctx context.Context,
+	tx *sql.Tx,
+	workout *Workout,
+) error {
 	query := `
         UPDATE workouts
         SET title = $2, description = $3, scheduled_at = $4
@@ -109,57 +397,134 @@ func (m WorkoutModel) UpdateWorkoutWithExercises(workout *Workout) error {
 		workout.ScheduledAt,
 	}
 
-	_, err = tx.ExecContext(ctx, query, args...)
+	_, err := tx.ExecContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
 
-	// Delete all existing exercises for this workout
-	query = `DELETE FROM workout_exercises WHERE workout_id = $1`
-	_, err = tx.ExecContext(ctx, query, workout.ID)
+	err = replaceWorkoutExercises(ctx, tx, workout)
 	if err != nil {
 		return err
 	}
 
-	// Insert the new exercises
-	for _, workoutExercise := range workout.Exercises {
-		query = `
-		INSERT INTO workout_exercises (workout_id, exercise_id, sets, repetitions, weight, rest_interval)
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`
-		args = []any{
-			workout.ID,
-			workoutExercise.ExerciseID,
-			workoutExercise.Sets,
-			workoutExercise.Repetitions,
-			workoutExercise.Weight,
-			workoutExercise.RestInterval,
-		}
+	err = writeWorkoutEvent(ctx, tx, EventWorkoutUpdated, workout)
+	if err != nil {
+		return err
+	}
 
-		_, err = tx.ExecContext(ctx, query, args...)
+	return writeWorkoutAudit(ctx, tx, workout, AuditActionUpdated)
+}
+
+func (m WorkoutModel) GetAllForUser(
+	ctx context.Context,
+	userID int64,
+) ([]*Workout, error) {
+	query := `
+	       SELECT id, user_id, title, description, scheduled_at, completed_at, created_at, updated_at
+	       FROM workouts
+	       WHERE user_id = $1`
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to execute query to fetch workouts for user %d: %w",
+			userID,
+			err,
+		)
+	}
+
+	defer rows.Close()
+
+	workouts := []*Workout{}
+
+	for rows.Next() {
+		var workout Workout
+
+		err := rows.Scan(
+			&workout.ID,
+			&workout.UserID,
+			&workout.Title,
+			&workout.Description,
+			&workout.ScheduledAt,
+			&workout.CompletedAt,
+			&workout.CreatedAt,
+			&workout.UpdatedAt,
+		)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("failed to scan workout row: %w", err)
 		}
+
+		workouts = append(workouts, &workout)
 	}
 
-	err = tx.Commit()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"error occurred while iterating over workout rows: %w",
+			err,
+		)
+	}
+
+	err = m.attachExercises(ctx, workouts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return workouts, nil
+}
+
+// ForEachForUser streams every workout belonging to the user to fn, oldest
+// first, fetching batchSize workouts (and their exercises) at a time using the
+// workout id as a cursor. Memory use is bounded by the batch size regardless
+// of how long the user's history is. Iteration stops at the first error
+// returned by fn.
+func (m WorkoutModel) ForEachForUser(
+	ctx context.Context,
+	userID int64,
+	batchSize int,
+	fn func(*Workout) error,
+) error {
+	var cursor int64
+
+	for {
+		workouts, err := m.getPageForUser(ctx, userID, cursor, batchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, workout := range workouts {
+			err = fn(workout)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(workouts) < batchSize {
+			return nil
+		}
+
+		cursor = workouts[len(workouts)-1].ID
+	}
 }
 
-func (m WorkoutModel) GetAllForUser(userID int64) ([]*Workout, error) {
+func (m WorkoutModel) getPageForUser(
+	ctx context.Context,
+	userID, afterID int64,
+	limit int,
+) ([]*Workout, error) {
 	query := `
-	       SELECT id, user_id, title, description, scheduled_at, created_at, updated_at
-	       FROM workouts
-	       WHERE user_id = $1`
+        SELECT id, user_id, title, description, scheduled_at, completed_at, created_at, updated_at
+        FROM workouts
+        WHERE user_id = $1 AND id > $2
+        ORDER BY id
+        LIMIT $3`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query, userID)
+	rows, err := m.DB.QueryContext(ctx, query, userID, afterID, limit)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"failed to execute query to fetch workouts for user %d: %w",
@@ -171,7 +536,6 @@ func (m WorkoutModel) GetAllForUser(userID int64) ([]*Workout, error) {
 	defer rows.Close()
 
 	workouts := []*Workout{}
-	workoutMap := make(map[int64]*Workout)
 
 	for rows.Next() {
 		var workout Workout
@@ -182,6 +546,7 @@ func (m WorkoutModel) GetAllForUser(userID int64) ([]*Workout, error) {
 			&workout.Title,
 			&workout.Description,
 			&workout.ScheduledAt,
+			&workout.CompletedAt,
 			&workout.CreatedAt,
 			&workout.UpdatedAt,
 		)
@@ -190,7 +555,6 @@ func (m WorkoutModel) GetAllForUser(userID int64) ([]*Workout, error) {
 		}
 
 		workouts = append(workouts, &workout)
-		workoutMap[workout.ID] = &workout
 	}
 
 	if err = rows.Err(); err != nil {
@@ -200,27 +564,44 @@ func (m WorkoutModel) GetAllForUser(userID int64) ([]*Workout, error) {
 		)
 	}
 
-	// Fetch exercises for all workouts
-	workoutIDs := make([]int64, 0, len(workoutMap))
+	err = m.attachExercises(ctx, workouts)
+	if err != nil {
+		return nil, err
+	}
+
+	return workouts, nil
+}
+
+// attachExercises loads the exercises for all of the given workouts with a
+// single query and appends them to the matching workout.
+func (m WorkoutModel) attachExercises(
+	ctx context.Context,
+	workouts []*Workout,
+) error {
+	workoutMap := make(map[int64]*Workout, len(workouts))
+	workoutIDs := make([]int64, 0, len(workouts))
 	for _, workout := range workouts {
+		workoutMap[workout.ID] = workout
 		workoutIDs = append(workoutIDs, workout.ID)
 	}
 
 	if len(workoutIDs) == 0 {
-		return workouts, nil
+		return nil
 	}
 
-	query = `
+	query := `
         SELECT 
-            we.workout_id, we.sets, we.repetitions, we.weight, we.rest_interval,
-            e.id as exercise_id, e.name, e.description, e.category, e.muscle_group
+            we.id, we.workout_id, we.sets, we.repetitions, we.weight, we.rest_interval,
+            we.duration_seconds, we.distance, e.id as exercise_id, e.name,
+            e.description, e.category, e.muscle_group, e.measurement_type
         FROM workout_exercises we
         JOIN exercises e ON we.exercise_id = e.id
         WHERE we.workout_id = ANY($1)
+        ORDER BY we.position, we.id
     `
 	exerciseRows, err := m.DB.QueryContext(ctx, query, pq.Array(workoutIDs))
 	if err != nil {
-		return nil, fmt.Errorf(
+		return fmt.Errorf(
 			"failed to execute query to fetch exercises for workouts %v: %w",
 			workoutIDs,
 			err,
@@ -233,41 +614,51 @@ func (m WorkoutModel) GetAllForUser(userID int64) ([]*Workout, error) {
 		var workoutExercise WorkoutExercise
 
 		err := exerciseRows.Scan(
+			&workoutExercise.ID,
 			&workoutID,
 			&workoutExercise.Sets,
 			&workoutExercise.Repetitions,
 			&workoutExercise.Weight,
 			&workoutExercise.RestInterval,
+			&workoutExercise.DurationSeconds,
+			&workoutExercise.Distance,
 			&workoutExercise.Exercise.ID,
 			&workoutExercise.Exercise.Name,
 			&workoutExercise.Exercise.Description,
 			&workoutExercise.Exercise.Category,
 			&workoutExercise.Exercise.MuscleGroup,
+			&workoutExercise.Exercise.MeasurementType,
 		)
 		if err != nil {
-			return nil, fmt.Errorf(
+			return fmt.Errorf(
 				"failed to scan exercise row for workout %d: %w",
 				workoutID,
 				err,
 			)
 		}
 
+		workoutExercise.WorkoutID = workoutID
+		workoutExercise.ExerciseID = workoutExercise.Exercise.ID
+
 		if workout, exists := workoutMap[workoutID]; exists {
 			workout.Exercises = append(workout.Exercises, workoutExercise)
 		}
 	}
 
 	if err = exerciseRows.Err(); err != nil {
-		return nil, fmt.Errorf(
+		return fmt.Errorf(
 			"error occurred while iterating over exercise rows: %w",
 			err,
 		)
 	}
 
-	return workouts, nil
+	return nil
 }
 
-func (m WorkoutModel) DeleteByUser(id, userId int64) error {
+func (m WorkoutModel) DeleteByUser(
+	ctx context.Context,
+	id, userId int64,
+) error {
 	if id < 1 || userId < 1 {
 		return ErrRecordNotFound
 	}
@@ -277,10 +668,17 @@ func (m WorkoutModel) DeleteByUser(id, userId int64) error {
         WHERE id = $1
         AND user_id = $2`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id, userId)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, id, userId)
 	if err != nil {
 		return err
 	}
@@ -294,22 +692,37 @@ func (m WorkoutModel) DeleteByUser(id, userId int64) error {
 		return ErrRecordNotFound
 	}
 
-	return nil
+	workout := &Workout{ID: id, UserID: userId}
+
+	err = writeWorkoutEvent(ctx, tx, EventWorkoutDeleted, workout)
+	if err != nil {
+		return err
+	}
+
+	err = writeWorkoutAudit(ctx, tx, workout, AuditActionDeleted)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (m WorkoutModel) GetByUser(id, userId int64) (*Workout, error) {
+func (m WorkoutModel) GetByUser(
+	ctx context.Context,
+	id, userId int64,
+) (*Workout, error) {
 	if id < 1 || userId < 1 {
 		return nil, ErrRecordNotFound
 	}
 
 	query := `
-        SELECT id, user_id, title, description, scheduled_at, created_at, updated_at        
+        SELECT id, user_id, title, description, scheduled_at, completed_at, created_at, updated_at        
         FROM workouts
         WHERE id = $1 AND user_id = $2`
 
 	var workout Workout
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, id, userId).Scan(
@@ -318,6 +731,7 @@ func (m WorkoutModel) GetByUser(id, userId int64) (*Workout, error) {
 		&workout.Title,
 		&workout.Description,
 		&workout.ScheduledAt,
+		&workout.CompletedAt,
 		&workout.CreatedAt,
 		&workout.UpdatedAt,
 	)
@@ -330,61 +744,58 @@ func (m WorkoutModel) GetByUser(id, userId int64) (*Workout, error) {
 		}
 	}
 
-	query = `
-        SELECT 
-            we.sets, we.repetitions, we.weight, we.rest_interval,
-            e.id as exercise_id, e.name, e.description, e.category, e.muscle_group
-        FROM workout_exercises we
-        JOIN exercises e ON we.exercise_id = e.id
-        WHERE we.workout_id = $1
-    `
-	exerciseRows, err := m.DB.QueryContext(ctx, query, workout.ID)
+	err = m.attachExercises(ctx, []*Workout{&workout})
 	if err != nil {
-		return nil, fmt.Errorf(
-			"failed to execute query to fetch exercises for workout %d: %w",
-			workout.ID,
-			err,
-		)
+		return nil, err
 	}
-	defer exerciseRows.Close()
 
-	for exerciseRows.Next() {
-		var workoutExercise WorkoutExercise
+	return &workout, nil
+}
 
-		err := exerciseRows.Scan(
-			&workoutExercise.Sets,
-			&workoutExercise.Repetitions,
-			&workoutExercise.Weight,
-			&workoutExercise.RestInterval,
-			&workoutExercise.Exercise.ID,
-			&workoutExercise.Exercise.Name,
-			&workoutExercise.Exercise.Description,
-			&workoutExercise.Exercise.Category,
-			&workoutExercise.Exercise.MuscleGroup,
-		)
-		if err != nil {
-			return nil, fmt.Errorf(
-				"failed to scan exercise row for workout %d: %w",
-				workout.ID,
-				err,
-			)
-		}
+func (m WorkoutModel) ScheduleWorkout(
+	ctx context.Context,
+	workout *Workout,
+) error {
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
 
-		workout.Exercises = append(workout.Exercises, workoutExercise)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
 	}
 
-	if err = exerciseRows.Err(); err != nil {
-		return nil, fmt.Errorf(
-			"error occurred while iterating over exercise rows: %w",
-			err,
-		)
+	defer tx.Rollback()
+
+	query := `
+        UPDATE workouts
+        SET scheduled_at = $2
+        WHERE id = $1`
+
+	args := []any{
+		workout.ID,
+		workout.ScheduledAt,
 	}
 
-	return &workout, nil
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	err = writeWorkoutEvent(ctx, tx, EventWorkoutUpdated, workout)
+	if err != nil {
+		return err
+	}
+
+	err = writeWorkoutAudit(ctx, tx, workout, AuditActionScheduled)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (m WorkoutModel) ScheduleWorkout(workout *Workout) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+func (m WorkoutModel) Complete(ctx context.Context, workout *Workout) error {
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	tx, err := m.DB.BeginTx(ctx, nil)
@@ -394,22 +805,97 @@ func (m WorkoutModel) ScheduleWorkout(workout *Workout) error {
 
 	defer tx.Rollback()
 
+	err = completeWorkout(ctx, tx, workout)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// completeWorkout saves the workout's completion time along with its
+// workout.completed event and audit entry.
+func completeWorkout(ctx context.Context, tx *sql.Tx, workout *Workout) error {
 	query := `
         UPDATE workouts
-        SET scheduled_at = $2
-        WHERE id = $1`
+        SET completed_at = $2, updated_at = NOW()
+        WHERE id = $1
+        RETURNING updated_at`
 
 	args := []any{
 		workout.ID,
-		workout.ScheduledAt,
+		workout.CompletedAt,
 	}
 
-	_, err = tx.ExecContext(ctx, query, args...)
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&workout.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	err = writeWorkoutEvent(ctx, tx, EventWorkoutCompleted, workout)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return writeWorkoutAudit(ctx, tx, workout, AuditActionCompleted)
+}
+
+// GetActivityForUser returns the scheduled and completed timestamps of every
+// workout belonging to the user, which is all the stats calculations need.
+func (m WorkoutModel) GetActivityForUser(
+	ctx context.Context,
+	userID int64,
+) ([]WorkoutActivity, error) {
+	query := `
+        SELECT scheduled_at, completed_at
+        FROM workouts
+        WHERE user_id = $1`
+
+	ctx, cancel := withQueryTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to execute query to fetch activity for user %d: %w",
+			userID,
+			err,
+		)
+	}
+
+	defer rows.Close()
+
+	activity := []WorkoutActivity{}
+
+	for rows.Next() {
+		var scheduledAt sql.NullTime
+		var entry WorkoutActivity
+
+		err := rows.Scan(&scheduledAt, &entry.CompletedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan activity row: %w", err)
+		}
+
+		if scheduledAt.Valid {
+			entry.ScheduledAt = scheduledAt.Time
+		}
+
+		activity = append(activity, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"error occurred while iterating over activity rows: %w",
+			err,
+		)
+	}
+
+	return activity, nil
 }
 
 func ValidateWorkout(v *validator.Validator, workout *Workout) {
@@ -420,7 +906,9 @@ func ValidateWorkout(v *validator.Validator, workout *Workout) {
 		"must not be more than 500 bytes long",
 	)
 
-	if !workout.ScheduledAt.IsZero() {
+	// A completed workout is a record of what happened, so only workouts
+	// still to come must be scheduled in the future.
+	if !workout.ScheduledAt.IsZero() && workout.CompletedAt == nil {
 		v.Check(
 			workout.ScheduledAt.After(
 				time.Now(),
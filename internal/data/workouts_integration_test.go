@@ -0,0 +1,585 @@
+//go:build integration
+
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCreateWorkoutWithExercises(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	squat := insertTestExercise(t, models, "Squat", MeasurementRepsWeight)
+	run := insertTestExercise(t, models, "Run", MeasurementDistanceTime)
+
+	completedAt := ago(time.Hour)
+
+	workouts := []*Workout{
+		{
+			UserID:      alice.ID,
+			Title:       "Legs",
+			ScheduledAt: time.Now().Truncate(time.Second),
+			Exercises: []WorkoutExercise{
+				{ExerciseID: squat.ID, Sets: 5, Repetitions: 5, Weight: 120.5},
+			},
+		},
+		{
+			UserID:      alice.ID,
+			Title:       "Long run",
+			ScheduledAt: completedAt.Add(-time.Hour).Truncate(time.Second),
+			CompletedAt: completedAt,
+			Exercises: []WorkoutExercise{
+				{ExerciseID: run.ID, Sets: 1, Distance: 10000, DurationSeconds: 3000},
+			},
+		},
+	}
+
+	err := models.Workouts.CreateWorkoutWithExercises(ctx, workouts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range workouts {
+		got, err := models.Workouts.GetByUser(ctx, want.ID, alice.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Title != want.Title || !got.ScheduledAt.Equal(want.ScheduledAt) {
+			t.Errorf("GetByUser returned %+v; want %+v", got, want)
+		}
+
+		if (got.CompletedAt == nil) != (want.CompletedAt == nil) ||
+			(got.CompletedAt != nil && !got.CompletedAt.Equal(*want.CompletedAt)) {
+			t.Errorf("completed_at = %v; want %v", got.CompletedAt, want.CompletedAt)
+		}
+
+		if len(got.Exercises) != 1 {
+			t.Fatalf("workout %d has %d exercises; want 1", got.ID, len(got.Exercises))
+		}
+
+		gotExercise, wantExercise := got.Exercises[0], want.Exercises[0]
+
+		if gotExercise.ExerciseID != wantExercise.ExerciseID ||
+			gotExercise.Sets != wantExercise.Sets ||
+			gotExercise.Repetitions != wantExercise.Repetitions ||
+			gotExercise.Weight != wantExercise.Weight ||
+			gotExercise.Distance != wantExercise.Distance ||
+			gotExercise.DurationSeconds != wantExercise.DurationSeconds {
+			t.Errorf("exercise = %+v; want %+v", gotExercise, wantExercise)
+		}
+	}
+
+	if n := countRows(t, "outbox", "event = $1", EventWorkoutCreated); n != 2 {
+		t.Errorf("%d workout.created events in the outbox; want 2", n)
+	}
+
+	bob := insertTestUser(t, models, "Bob")
+
+	_, err = models.Workouts.GetByUser(ctx, workouts[0].ID, bob.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("GetByUser of another user's workout: err = %v", err)
+	}
+}
+
+func TestCreateWorkoutWithExercisesRollsBack(t *testing.T) {
+	models := newTestModels(t)
+
+	alice := insertTestUser(t, models, "Alice")
+	squat := insertTestExercise(t, models, "Squat", MeasurementRepsWeight)
+
+	valid := &Workout{
+		UserID:      alice.ID,
+		Title:       "Legs",
+		ScheduledAt: time.Now(),
+		Exercises:   []WorkoutExercise{{ExerciseID: squat.ID, Sets: 3}},
+	}
+
+	invalid := &Workout{
+		UserID:      alice.ID,
+		Title:       "Mystery",
+		ScheduledAt: time.Now(),
+		Exercises:   []WorkoutExercise{{ExerciseID: squat.ID + 100, Sets: 3}},
+	}
+
+	err := models.Workouts.CreateWorkoutWithExercises(
+		context.Background(),
+		valid,
+		invalid,
+	)
+	if err == nil {
+		t.Fatal("creating a workout with a missing exercise succeeded")
+	}
+
+	// The first workout was inserted before the second failed, so it must
+	// have been rolled back along with its exercises and event.
+	for _, table := range []string{"workouts", "workout_exercises", "outbox"} {
+		if n := countRows(t, table, "true"); n != 0 {
+			t.Errorf("%d rows left in %s; want 0", n, table)
+		}
+	}
+}
+
+func TestUpdateWorkoutWithExercises(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	squat := insertTestExercise(t, models, "Squat", MeasurementRepsWeight)
+	lunge := insertTestExercise(t, models, "Lunge", MeasurementRepsWeight)
+
+	workout := insertTestWorkout(t, models, alice, squat, nil)
+
+	workout.Title = "Leg day, revised"
+	workout.Exercises = []WorkoutExercise{
+		{ExerciseID: lunge.ID, Sets: 4, Repetitions: 10, Weight: 20},
+		{ExerciseID: squat.ID, Sets: 2, Repetitions: 3, Weight: 140},
+	}
+
+	err := models.Workouts.UpdateWorkoutWithExercises(ctx, workout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := models.Workouts.GetByUser(ctx, workout.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Title != workout.Title {
+		t.Errorf("title = %q; want %q", got.Title, workout.Title)
+	}
+
+	if len(got.Exercises) != 2 ||
+		got.Exercises[0].ExerciseID != lunge.ID ||
+		got.Exercises[1].ExerciseID != squat.ID {
+		t.Errorf("exercises = %+v; want lunge then squat", got.Exercises)
+	}
+
+	if n := countRows(t, "outbox", "event = $1", EventWorkoutUpdated); n != 1 {
+		t.Errorf("%d workout.updated events in the outbox; want 1", n)
+	}
+
+	// A failed update leaves the workout as it was, including the exercises
+	// that were deleted before the new ones failed to insert.
+	failed := *got
+	failed.Title = "Never saved"
+	failed.Exercises = []WorkoutExercise{{ExerciseID: lunge.ID + 100, Sets: 1}}
+
+	err = models.Workouts.UpdateWorkoutWithExercises(ctx, &failed)
+	if err == nil {
+		t.Fatal("updating a workout with a missing exercise succeeded")
+	}
+
+	got, err = models.Workouts.GetByUser(ctx, workout.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Title != workout.Title || len(got.Exercises) != 2 {
+		t.Errorf("after a failed update got %q with %d exercises", got.Title, len(got.Exercises))
+	}
+
+	if n := countRows(t, "outbox", "event = $1", EventWorkoutUpdated); n != 1 {
+		t.Errorf("%d workout.updated events in the outbox; want 1", n)
+	}
+}
+
+func TestScheduleWorkout(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	squat := insertTestExercise(t, models, "Squat", MeasurementRepsWeight)
+
+	workout := insertTestWorkout(t, models, alice, squat, nil)
+	workout.ScheduledAt = workout.ScheduledAt.Add(72 * time.Hour)
+
+	err := models.Workouts.ScheduleWorkout(ctx, workout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Read it back on a fresh query, so a change that was never committed
+	// would show up as the old time.
+	got, err := models.Workouts.GetByUser(ctx, workout.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !got.ScheduledAt.Equal(workout.ScheduledAt) {
+		t.Errorf("scheduled_at = %v; want %v", got.ScheduledAt, workout.ScheduledAt)
+	}
+
+	if n := countRows(t, "outbox", "event = $1", EventWorkoutUpdated); n != 1 {
+		t.Errorf("%d workout.updated events in the outbox; want 1", n)
+	}
+}
+
+func TestCompleteWorkout(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	squat := insertTestExercise(t, models, "Squat", MeasurementRepsWeight)
+
+	workout := insertTestWorkout(t, models, alice, squat, nil)
+	workout.CompletedAt = ago(0)
+
+	err := models.Workouts.Complete(ctx, workout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := models.Workouts.GetByUser(ctx, workout.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.CompletedAt == nil || !got.CompletedAt.Equal(*workout.CompletedAt) {
+		t.Errorf("completed_at = %v; want %v", got.CompletedAt, workout.CompletedAt)
+	}
+
+	missing := &Workout{ID: workout.ID + 100, UserID: alice.ID, CompletedAt: ago(0)}
+
+	err = models.Workouts.Complete(ctx, missing)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Complete of a missing workout: err = %v", err)
+	}
+
+	if n := countRows(t, "outbox", "event = $1", EventWorkoutCompleted); n != 1 {
+		t.Errorf("%d workout.completed events in the outbox; want 1", n)
+	}
+}
+
+func TestDeleteWorkoutCascades(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	bob := insertTestUser(t, models, "Bob")
+	squat := insertTestExercise(t, models, "Squat", MeasurementRepsWeight)
+	run := insertTestExercise(t, models, "Run", MeasurementDistanceTime)
+
+	workout := insertTestWorkout(t, models, alice, squat, nil)
+	kept := insertTestWorkout(t, models, alice, squat, nil)
+
+	workout.Exercises = append(
+		workout.Exercises,
+		WorkoutExercise{ExerciseID: run.ID, Sets: 1, Distance: 5000, DurationSeconds: 1500},
+	)
+
+	err := models.Tracks.InsertWithWorkout(
+		ctx,
+		&WorkoutTrack{
+			Format:          "gpx",
+			StartedAt:       time.Now(),
+			Distance:        5000,
+			DurationSeconds: 1500,
+		},
+		workout,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = models.Sessions.Insert(ctx, &WorkoutSession{
+		WorkoutID: workout.ID,
+		UserID:    alice.ID,
+		Status:    SessionStatusActive,
+		SetNumber: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = models.Shares.New(ctx, workout.ID, alice.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comment := &Comment{WorkoutID: workout.ID, AuthorID: bob.ID, Body: "Nice"}
+
+	err = models.Comments.Insert(ctx, comment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = models.Reminders.Claim(ctx, &Reminder{
+		WorkoutID:   workout.ID,
+		ScheduledAt: workout.ScheduledAt,
+		LeadMinutes: 60,
+		Channel:     ChannelInApp,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = models.Audit.Insert(ctx, &WorkoutAuditEntry{
+		WorkoutID: workout.ID,
+		UserID:    alice.ID,
+		ActorID:   &alice.ID,
+		Action:    AuditActionCreated,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = models.Workouts.DeleteByUser(ctx, workout.ID, bob.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("DeleteByUser by another user: err = %v; want ErrRecordNotFound", err)
+	}
+
+	err = models.Workouts.DeleteByUser(ctx, workout.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = models.Workouts.GetByUser(ctx, workout.ID, alice.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("GetByUser after deleting: err = %v", err)
+	}
+
+	for _, table := range []string{
+		"workout_exercises",
+		"workout_tracks",
+		"workout_sessions",
+		"workout_shares",
+		"comments",
+		"reminders_sent",
+	} {
+		if n := countRows(t, table, "workout_id = $1", workout.ID); n != 0 {
+			t.Errorf("%d rows of the deleted workout left in %s", n, table)
+		}
+	}
+
+	// The audit trail outlives the workout, and other workouts are untouched.
+	if n := countRows(t, "workout_audit", "workout_id = $1", workout.ID); n != 1 {
+		t.Errorf("%d audit entries for the deleted workout; want 1", n)
+	}
+
+	if n := countRows(t, "workout_exercises", "workout_id = $1", kept.ID); n != 1 {
+		t.Errorf("%d exercises left for the other workout; want 1", n)
+	}
+
+	if n := countRows(t, "outbox", "event = $1", EventWorkoutDeleted); n != 1 {
+		t.Errorf("%d workout.deleted events in the outbox; want 1", n)
+	}
+}
+
+func TestDeleteUserCascades(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	bob := insertTestUser(t, models, "Bob")
+	squat := insertTestExercise(t, models, "Squat", MeasurementRepsWeight)
+
+	workout := insertTestWorkout(t, models, alice, squat, nil)
+	insertTestWorkout(t, models, bob, squat, nil)
+
+	_, err := models.Tokens.New(ctx, alice.ID, time.Hour, ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = models.Follows.Follow(ctx, bob.ID, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = models.Coaching.Invite(ctx, &CoachingLink{CoachID: alice.ID, AthleteID: bob.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Alice coaches Bob and left a comment on Bob's workout and an entry in
+	// his audit trail.
+	bobsWorkout := insertTestWorkout(t, models, bob, squat, nil)
+
+	err = models.Comments.Insert(ctx, &Comment{
+		WorkoutID: bobsWorkout.ID,
+		AuthorID:  alice.ID,
+		Body:      "Go deeper",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = models.Audit.Insert(ctx, &WorkoutAuditEntry{
+		WorkoutID: bobsWorkout.ID,
+		UserID:    bob.ID,
+		ActorID:   &alice.ID,
+		Action:    AuditActionScheduled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = testDB.ExecContext(ctx, "DELETE FROM users WHERE id = $1", alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for table, condition := range map[string]string{
+		"workouts":       "user_id = $1",
+		"tokens":         "user_id = $1",
+		"follows":        "follower_id = $1 OR followee_id = $1",
+		"coach_athletes": "coach_id = $1 OR athlete_id = $1",
+		"comments":       "user_id = $1",
+	} {
+		if n := countRows(t, table, condition, alice.ID); n != 0 {
+			t.Errorf("%d of the deleted user's rows left in %s", n, table)
+		}
+	}
+
+	if n := countRows(t, "workout_exercises", "workout_id = $1", workout.ID); n != 0 {
+		t.Errorf("%d exercises left for the deleted user's workout", n)
+	}
+
+	if n := countRows(t, "workouts", "user_id = $1", bob.ID); n != 2 {
+		t.Errorf("%d of the other user's workouts left; want 2", n)
+	}
+
+	// Bob's audit trail keeps the entry but no longer names its actor.
+	entries, err := models.Audit.GetAllForWorkout(ctx, bobsWorkout.ID, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 || entries[0].ActorID != nil || entries[0].ActorName != nil {
+		t.Errorf("audit entries = %+v; want one without an actor", entries)
+	}
+}
+
+func TestGetWorkoutsForUser(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	bob := insertTestUser(t, models, "Bob")
+	squat := insertTestExercise(t, models, "Squat", MeasurementRepsWeight)
+
+	ids := []int64{}
+	for i := range 5 {
+		var completedAt *time.Time
+		if i%2 == 0 {
+			completedAt = ago(time.Duration(i) * time.Hour)
+		}
+
+		ids = append(ids, insertTestWorkout(t, models, alice, squat, completedAt).ID)
+	}
+
+	insertTestWorkout(t, models, bob, squat, nil)
+
+	workouts, err := models.Workouts.GetAllForUser(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(workouts) != 5 {
+		t.Fatalf("GetAllForUser returned %d workouts; want 5", len(workouts))
+	}
+
+	for _, workout := range workouts {
+		if len(workout.Exercises) != 1 || workout.Exercises[0].Exercise.Name != "Squat" {
+			t.Errorf("workout %d has exercises %+v", workout.ID, workout.Exercises)
+		}
+	}
+
+	// Five workouts in batches of two take three queries, the last of them
+	// short.
+	visited := []int64{}
+
+	err = models.Workouts.ForEachForUser(ctx, alice.ID, 2, func(workout *Workout) error {
+		visited = append(visited, workout.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(visited) != len(ids) {
+		t.Fatalf("ForEachForUser visited %v; want %v", visited, ids)
+	}
+
+	for i := range ids {
+		if visited[i] != ids[i] {
+			t.Fatalf("ForEachForUser visited %v; want %v", visited, ids)
+		}
+	}
+
+	errStop := errors.New("stop")
+	calls := 0
+
+	err = models.Workouts.ForEachForUser(ctx, alice.ID, 2, func(*Workout) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || calls != 1 {
+		t.Errorf("ForEachForUser returned %v after %d calls; want errStop after 1", err, calls)
+	}
+
+	activity, err := models.Workouts.GetActivityForUser(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	completed := 0
+	for _, entry := range activity {
+		if entry.CompletedAt != nil {
+			completed++
+		}
+	}
+
+	if len(activity) != 5 || completed != 3 {
+		t.Errorf("activity has %d entries, %d completed; want 5, 3", len(activity), completed)
+	}
+}
+
+func TestGetExerciseHistoryForUser(t *testing.T) {
+	models := newTestModels(t)
+	ctx := context.Background()
+
+	alice := insertTestUser(t, models, "Alice")
+	squat := insertTestExercise(t, models, "Squat", MeasurementRepsWeight)
+	bench := insertTestExercise(t, models, "Bench press", MeasurementRepsWeight)
+
+	older := insertTestWorkout(t, models, alice, squat, ago(48*time.Hour))
+	newer := insertTestWorkout(t, models, alice, squat, ago(24*time.Hour))
+	insertTestWorkout(t, models, alice, bench, ago(time.Hour))
+	insertTestWorkout(t, models, alice, squat, nil)
+
+	history, err := models.Workouts.GetExerciseHistoryForUser(ctx, alice.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history) != 3 {
+		t.Fatalf("history has %d entries; want 3 completed ones", len(history))
+	}
+
+	history, err = models.Workouts.GetExerciseHistoryForUser(ctx, alice.ID, squat.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history) != 2 ||
+		history[0].WorkoutID != older.ID ||
+		history[1].WorkoutID != newer.ID {
+		t.Fatalf("squat history = %+v; want the two completed workouts oldest first", history)
+	}
+
+	entry := history[0]
+
+	if entry.ExerciseName != "Squat" || entry.Sets != 3 ||
+		entry.Repetitions != 5 || entry.Weight != 100 ||
+		!entry.CompletedAt.Equal(*older.CompletedAt) {
+		t.Errorf("history entry = %+v", entry)
+	}
+}
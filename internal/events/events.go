@@ -0,0 +1,302 @@
+// Package events relays domain events from the transactional outbox to the
+// sinks interested in them.
+//
+// Events are written to the outbox in the same database transaction as the
+// change they describe, so an event exists if and only if the change was
+// committed. A Dispatcher then claims pending events and hands each one to
+// every sink, marking it dispatched only once all of them have accepted it.
+// When a sink fails the sinks that succeeded are recorded with the event, and
+// only the others are offered it again. Delivery is still at least once: after
+// a crash part way through an event it is offered to every sink again, so
+// sinks must use Event.Key to discard events they have already handled.
+//
+// Dispatched events are kept for a while, which helps when looking into a
+// problem, and then deleted.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Event is one entry of the outbox. Key is unique per event and stays the
+// same every time the event is offered to a sink. Delivered names the sinks
+// that have handled the event on earlier attempts.
+type Event struct {
+	ID        int64
+	Key       string
+	UserID    int64
+	Type      string
+	Payload   json.RawMessage
+	Attempts  int
+	Delivered []string
+	CreatedAt time.Time
+}
+
+// Store is the outbox the dispatcher reads from.
+type Store interface {
+	// Claim returns up to limit pending events that are due, hiding them
+	// from other claims for lease.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*Event, error)
+	// MarkDispatched records that every sink has handled the event.
+	MarkDispatched(ctx context.Context, id int64) error
+	// MarkFailed records a failed attempt, the sinks that have handled the
+	// event so far and when to try again.
+	MarkFailed(
+		ctx context.Context,
+		id int64,
+		attempts int,
+		next time.Time,
+		reason string,
+		delivered []string,
+	) error
+	// DeleteDispatched deletes the events dispatched before the given time,
+	// returning how many there were.
+	DeleteDispatched(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Sink receives dispatched events. Handle must be idempotent on Event.Key.
+// Name is recorded with the events the sink has handled, so it must stay the
+// same across releases.
+type Sink interface {
+	Name() string
+	Handle(ctx context.Context, event *Event) error
+}
+
+const (
+	baseRetryDelay = 5 * time.Second
+	maxRetryDelay  = 10 * time.Minute
+)
+
+// RetryDelay returns how long to wait before offering an event again after
+// attempts failed tries: 5s, 10s, 20s and so on, capped at ten minutes.
+// Events are never given up on, since dropping one would break the outbox's
+// guarantee.
+func RetryDelay(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+
+	delay := baseRetryDelay
+
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+
+	return delay
+}
+
+// Dispatcher relays events from a Store to its sinks.
+type Dispatcher struct {
+	Store        Store
+	Sinks        []Sink
+	Logger       *slog.Logger
+	PollInterval time.Duration
+	BatchSize    int
+	// Lease must exceed the time a whole batch can take to dispatch, or an
+	// event may be claimed again while it is still being handled.
+	Lease time.Duration
+	// Retention is how long dispatched events are kept, checked every
+	// CleanupInterval. Zero keeps them forever.
+	Retention       time.Duration
+	CleanupInterval time.Duration
+}
+
+func NewDispatcher(store Store, logger *slog.Logger, sinks ...Sink) *Dispatcher {
+	return &Dispatcher{
+		Store:           store,
+		Sinks:           sinks,
+		Logger:          logger,
+		PollInterval:    time.Second,
+		BatchSize:       50,
+		Lease:           2 * time.Minute,
+		Retention:       7 * 24 * time.Hour,
+		CleanupInterval: time.Hour,
+	}
+}
+
+// Run dispatches events, and deletes old ones, until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+
+	cleanup := time.NewTicker(d.CleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-cleanup.C:
+			if d.Retention <= 0 {
+				continue
+			}
+
+			_, err := d.Cleanup(ctx)
+			if err != nil {
+				d.Logger.Error(err.Error())
+			}
+
+		case <-ticker.C:
+			for {
+				n, err := d.DispatchBatch(ctx)
+				if err != nil {
+					d.Logger.Error(err.Error())
+					break
+				}
+
+				if n < d.BatchSize || ctx.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+}
+
+// DispatchBatch claims one batch of due events and offers each to every
+// sink. It returns the number of events claimed.
+func (d *Dispatcher) DispatchBatch(ctx context.Context) (int, error) {
+	batch, err := d.Store.Claim(ctx, d.BatchSize, d.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range batch {
+		d.dispatch(ctx, event)
+	}
+
+	return len(batch), nil
+}
+
+// Cleanup deletes the events dispatched longer ago than the retention
+// period, returning how many there were.
+func (d *Dispatcher) Cleanup(ctx context.Context) (int64, error) {
+	deleted, err := d.Store.DeleteDispatched(ctx, time.Now().Add(-d.Retention))
+	if err != nil {
+		return 0, err
+	}
+
+	if deleted > 0 {
+		d.Logger.Info("deleted dispatched events", "count", deleted)
+	}
+
+	return deleted, nil
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, event *Event) {
+	var errs []error
+
+	// The outcome is recorded even if ctx is cancelled while the sinks run,
+	// so that an event they have all handled is not dispatched again.
+	recordCtx := context.WithoutCancel(ctx)
+
+	delivered := slices.Clone(event.Delivered)
+
+	for _, sink := range d.Sinks {
+		if slices.Contains(event.Delivered, sink.Name()) {
+			continue
+		}
+
+		err := sink.Handle(ctx, event)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+			continue
+		}
+
+		delivered = append(delivered, sink.Name())
+	}
+
+	if len(errs) == 0 {
+		err := d.Store.MarkDispatched(recordCtx, event.ID)
+		if err != nil {
+			d.Logger.Error(err.Error(), "event_id", event.ID)
+		}
+
+		return
+	}
+
+	err := errors.Join(errs...)
+
+	attempts := event.Attempts + 1
+
+	d.Logger.Error(
+		"failed to dispatch event",
+		"event_id", event.ID,
+		"attempts", attempts,
+		"error", err.Error(),
+	)
+
+	err = d.Store.MarkFailed(
+		recordCtx,
+		event.ID,
+		attempts,
+		time.Now().Add(RetryDelay(attempts)),
+		err.Error(),
+		delivered,
+	)
+	if err != nil {
+		d.Logger.Error(err.Error(), "event_id", event.ID)
+	}
+}
+
+// LogSink writes a line for every event, which is handy in development.
+type LogSink struct {
+	Logger *slog.Logger
+}
+
+func (s LogSink) Name() string { return "log" }
+
+func (s LogSink) Handle(_ context.Context, event *Event) error {
+	s.Logger.Info(
+		"event",
+		"type", event.Type,
+		"key", event.Key,
+		"user_id", event.UserID,
+	)
+
+	return nil
+}
+
+// Subscribers fans events out to in-process subscribers. Subscribers are
+// called synchronously by the dispatcher, so they should hand slow work off
+// to a goroutine.
+type Subscribers struct {
+	mu   sync.RWMutex
+	subs map[string][]func(*Event)
+}
+
+func NewSubscribers() *Subscribers {
+	return &Subscribers{subs: make(map[string][]func(*Event))}
+}
+
+// Subscribe registers fn for events of the given type.
+func (s *Subscribers) Subscribe(eventType string, fn func(*Event)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subs[eventType] = append(s.subs[eventType], fn)
+}
+
+func (s *Subscribers) Name() string { return "subscribers" }
+
+func (s *Subscribers) Handle(_ context.Context, event *Event) error {
+	s.mu.RLock()
+	fns := s.subs[event.Type]
+	s.mu.RUnlock()
+
+	for _, fn := range fns {
+		fn(event)
+	}
+
+	return nil
+}
@@ -0,0 +1,214 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+)
+
+type fakeStore struct {
+	pending       []*Event
+	dispatched    []int64
+	failed        map[int64]int
+	delivered     map[int64][]string
+	deletedBefore time.Time
+}
+
+func (s *fakeStore) Claim(
+	_ context.Context,
+	limit int,
+	_ time.Duration,
+) ([]*Event, error) {
+	n := min(limit, len(s.pending))
+	batch := s.pending[:n]
+	s.pending = s.pending[n:]
+
+	return batch, nil
+}
+
+func (s *fakeStore) MarkDispatched(_ context.Context, id int64) error {
+	s.dispatched = append(s.dispatched, id)
+	return nil
+}
+
+func (s *fakeStore) MarkFailed(
+	_ context.Context,
+	id int64,
+	attempts int,
+	_ time.Time,
+	_ string,
+	delivered []string,
+) error {
+	s.failed[id] = attempts
+	s.delivered[id] = delivered
+	return nil
+}
+
+func (s *fakeStore) DeleteDispatched(_ context.Context, before time.Time) (int64, error) {
+	s.deletedBefore = before
+	return 3, nil
+}
+
+type recordingSink struct {
+	name string
+	keys []string
+	fail bool
+}
+
+func (s *recordingSink) Name() string { return s.name }
+
+func (s *recordingSink) Handle(_ context.Context, event *Event) error {
+	s.keys = append(s.keys, event.Key)
+
+	if s.fail {
+		return errors.New("sink unavailable")
+	}
+
+	return nil
+}
+
+func newTestDispatcher(store Store, sinks ...Sink) *Dispatcher {
+	return NewDispatcher(store, slog.New(slog.NewTextHandler(io.Discard, nil)), sinks...)
+}
+
+func TestDispatchBatchMarksDispatched(t *testing.T) {
+	store := &fakeStore{
+		pending:   []*Event{{ID: 1, Key: "a"}, {ID: 2, Key: "b"}},
+		failed:    map[int64]int{},
+		delivered: map[int64][]string{},
+	}
+	sink := &recordingSink{name: "recording"}
+
+	n, err := newTestDispatcher(store, sink).DispatchBatch(context.Background())
+	if err != nil {
+		t.Fatalf("DispatchBatch() error = %v", err)
+	}
+
+	if n != 2 {
+		t.Errorf("DispatchBatch() = %d, want 2", n)
+	}
+
+	if len(sink.keys) != 2 || sink.keys[0] != "a" || sink.keys[1] != "b" {
+		t.Errorf("sink received %v, want [a b]", sink.keys)
+	}
+
+	if len(store.dispatched) != 2 {
+		t.Errorf("dispatched %v, want both events", store.dispatched)
+	}
+}
+
+func TestDispatchBatchRetriesFailedSinks(t *testing.T) {
+	store := &fakeStore{
+		pending:   []*Event{{ID: 7, Key: "a", Attempts: 2}},
+		failed:    map[int64]int{},
+		delivered: map[int64][]string{},
+	}
+	ok := &recordingSink{name: "ok"}
+	failing := &recordingSink{name: "failing", fail: true}
+
+	dispatcher := newTestDispatcher(store, ok, failing)
+
+	_, err := dispatcher.DispatchBatch(context.Background())
+	if err != nil {
+		t.Fatalf("DispatchBatch() error = %v", err)
+	}
+
+	if len(store.dispatched) != 0 {
+		t.Errorf("dispatched %v, want none", store.dispatched)
+	}
+
+	if store.failed[7] != 3 {
+		t.Errorf("attempts = %d, want 3", store.failed[7])
+	}
+
+	if !slices.Equal(store.delivered[7], []string{"ok"}) {
+		t.Errorf("delivered to %v, want [ok]", store.delivered[7])
+	}
+
+	// The retry only goes to the sink that failed.
+	store.pending = []*Event{{ID: 7, Key: "a", Attempts: 3, Delivered: store.delivered[7]}}
+	failing.fail = false
+
+	_, err = dispatcher.DispatchBatch(context.Background())
+	if err != nil {
+		t.Fatalf("DispatchBatch() error = %v", err)
+	}
+
+	if len(ok.keys) != 1 {
+		t.Errorf("healthy sink received %v, want the event once", ok.keys)
+	}
+
+	if len(failing.keys) != 2 {
+		t.Errorf("failing sink received %v, want the event twice", failing.keys)
+	}
+
+	if !slices.Equal(store.dispatched, []int64{7}) {
+		t.Errorf("dispatched %v, want [7]", store.dispatched)
+	}
+}
+
+func TestDispatcherCleanup(t *testing.T) {
+	store := &fakeStore{}
+
+	dispatcher := newTestDispatcher(store)
+	dispatcher.Retention = 48 * time.Hour
+
+	n, err := dispatcher.Cleanup(context.Background())
+	if err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+
+	if n != 3 {
+		t.Errorf("Cleanup() = %d, want 3", n)
+	}
+
+	want := time.Now().Add(-48 * time.Hour)
+	if diff := store.deletedBefore.Sub(want).Abs(); diff > time.Second {
+		t.Errorf("deleted events dispatched before %v, want %v", store.deletedBefore, want)
+	}
+}
+
+func TestSubscribers(t *testing.T) {
+	subs := NewSubscribers()
+
+	var got []string
+
+	subs.Subscribe("workout.created", func(e *Event) { got = append(got, e.Key) })
+
+	for _, event := range []*Event{
+		{Key: "a", Type: "workout.created"},
+		{Key: "b", Type: "workout.deleted"},
+	} {
+		if err := subs.Handle(context.Background(), event); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+	}
+
+	if len(got) != 1 || got[0] != "a" {
+		t.Errorf("subscriber received %v, want [a]", got)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{4, 40 * time.Second},
+		{8, 10 * time.Minute},
+		{100, 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := RetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("RetryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
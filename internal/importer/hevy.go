@@ -0,0 +1,85 @@
+package importer
+
+import (
+	"sulemankhann/workout-tracker/internal/data"
+	"time"
+)
+
+var hevyTimeLayouts = []string{
+	"2 Jan 2006, 15:04",
+	"2 January 2006, 15:04",
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+}
+
+// parseHevyRow handles Hevy's workout export, which names its unit in the
+// column header (weight_kg or weight_lbs, distance_km or distance_miles), so
+// the user's units are never needed.
+func parseHevyRow(
+	cols *columns,
+	record []string,
+	loc *time.Location,
+	_ data.Units,
+) (row, error) {
+	var r row
+	var err error
+
+	r.startedAt, err = parseTime(cols.get(record, "start_time"), loc, hevyTimeLayouts...)
+	if err != nil {
+		return r, err
+	}
+
+	r.completedAt = r.startedAt
+	if end := cols.get(record, "end_time"); end != "" {
+		r.completedAt, err = parseTime(end, loc, hevyTimeLayouts...)
+		if err != nil {
+			return r, err
+		}
+	}
+
+	r.workoutName = cols.get(record, "title")
+	r.workoutNotes = cols.get(record, "description")
+	r.exerciseName = cols.get(record, "exercise_title")
+	r.exerciseNotes = cols.get(record, "exercise_notes")
+	r.set.Warmup = cols.get(record, "set_type") == "warmup"
+
+	r.set.Order, err = cols.int(record, "set_index")
+	if err != nil {
+		return r, err
+	}
+
+	switch {
+	case cols.has("weight_lbs"):
+		r.set.Weight, err = cols.float(record, "weight_lbs")
+		r.set.Weight *= poundsToKilograms
+	default:
+		r.set.Weight, err = cols.float(record, "weight_kg")
+	}
+	if err != nil {
+		return r, err
+	}
+
+	r.set.Repetitions, err = cols.int(record, "reps")
+	if err != nil {
+		return r, err
+	}
+
+	switch {
+	case cols.has("distance_miles"):
+		r.set.DistanceMeters, err = cols.float(record, "distance_miles")
+		r.set.DistanceMeters *= 1609.344
+	default:
+		r.set.DistanceMeters, err = cols.float(record, "distance_km")
+		r.set.DistanceMeters *= 1000
+	}
+	if err != nil {
+		return r, err
+	}
+
+	r.set.DurationSeconds, err = cols.int(record, "duration_seconds")
+	if err != nil {
+		return r, err
+	}
+
+	return r, nil
+}
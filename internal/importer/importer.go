@@ -0,0 +1,233 @@
+// Package importer parses workout history exported from other training apps
+// into a format-neutral representation that can be mapped onto the exercise
+// catalogue.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sulemankhann/workout-tracker/internal/data"
+	"time"
+)
+
+const (
+	SourceStrong = "strong"
+	SourceHevy   = "hevy"
+)
+
+const poundsToKilograms = 0.45359237
+
+var ErrUnknownFormat = errors.New(
+	"unrecognised CSV export: expected a Strong or Hevy export",
+)
+
+// Set is a single logged set as it appears in the source export. Weights are
+// normalised to kilograms and distances to metres. Order is 0 for sets the
+// source doesn't number, such as Strong's warm-up and drop sets. A drop set
+// continues the set before it rather than being a set of its own.
+type Set struct {
+	Order           int
+	Warmup          bool
+	Drop            bool
+	Weight          float64
+	Repetitions     int
+	DistanceMeters  float64
+	DurationSeconds int
+}
+
+type Exercise struct {
+	Name  string
+	Sets  []Set
+	Notes string
+}
+
+type Workout struct {
+	Name        string
+	Notes       string
+	StartedAt   time.Time
+	CompletedAt time.Time
+	Exercises   []*Exercise
+}
+
+// Parse detects whether r holds a Strong or Hevy CSV export and returns the
+// workouts it contains in the order they first appear. Timestamps without an
+// explicit offset are interpreted in loc, and weights and distances the file
+// doesn't give a unit for, as in Strong's classic export, are read in units.
+func Parse(
+	r io.Reader,
+	loc *time.Location,
+	units data.Units,
+) (string, []*Workout, error) {
+	br := bufio.NewReader(r)
+
+	firstLine, err := br.Peek(4096)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return "", nil, err
+	}
+
+	if idx := bytes.IndexByte(firstLine, '\n'); idx >= 0 {
+		firstLine = firstLine[:idx]
+	}
+
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+
+	// Older Strong exports on some locales use semicolons between fields.
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		cr.Comma = ';'
+	}
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return "", nil, errors.New("file is empty")
+		}
+		return "", nil, err
+	}
+
+	cols := newColumns(header)
+
+	var source string
+	var parse func(*columns, []string, *time.Location, data.Units) (row, error)
+
+	switch {
+	case cols.has("exercise_title") && cols.has("start_time"):
+		source, parse = SourceHevy, parseHevyRow
+	case cols.has("exercise name") && cols.has("date"):
+		source, parse = SourceStrong, parseStrongRow
+	default:
+		return "", nil, ErrUnknownFormat
+	}
+
+	var workouts []*Workout
+	workoutIndex := make(map[string]*Workout)
+	exerciseIndex := make(map[string]*Exercise)
+
+	line := 1
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line++
+		if err != nil {
+			return "", nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		row, err := parse(cols, record, loc, units)
+		if err != nil {
+			return "", nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		// Rows are grouped into workouts by start time and name, and into
+		// exercises by name within a workout.
+		workoutKey := row.startedAt.Format(time.RFC3339) + "|" + row.workoutName
+
+		workout, ok := workoutIndex[workoutKey]
+		if !ok {
+			workout = &Workout{
+				Name:        row.workoutName,
+				Notes:       row.workoutNotes,
+				StartedAt:   row.startedAt,
+				CompletedAt: row.completedAt,
+			}
+			workoutIndex[workoutKey] = workout
+			workouts = append(workouts, workout)
+		}
+
+		exerciseKey := workoutKey + "|" + row.exerciseName
+
+		exercise, ok := exerciseIndex[exerciseKey]
+		if !ok {
+			exercise = &Exercise{Name: row.exerciseName, Notes: row.exerciseNotes}
+			exerciseIndex[exerciseKey] = exercise
+			workout.Exercises = append(workout.Exercises, exercise)
+		}
+
+		exercise.Sets = append(exercise.Sets, row.set)
+	}
+
+	return source, workouts, nil
+}
+
+// row is one line of an export after format-specific parsing.
+type row struct {
+	workoutName   string
+	workoutNotes  string
+	startedAt     time.Time
+	completedAt   time.Time
+	exerciseName  string
+	exerciseNotes string
+	set           Set
+}
+
+type columns struct {
+	index map[string]int
+}
+
+func newColumns(header []string) *columns {
+	c := &columns{index: make(map[string]int, len(header))}
+
+	for i, name := range header {
+		name = strings.TrimPrefix(name, "\ufeff")
+		c.index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	return c
+}
+
+func (c *columns) has(name string) bool {
+	_, ok := c.index[name]
+	return ok
+}
+
+func (c *columns) get(record []string, name string) string {
+	i, ok := c.index[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+
+	return strings.TrimSpace(record[i])
+}
+
+func (c *columns) float(record []string, name string) (float64, error) {
+	s := c.get(record, name)
+	if s == "" {
+		return 0, nil
+	}
+
+	// Some locales export decimals with a comma.
+	f, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q in column %q", s, name)
+	}
+
+	return f, nil
+}
+
+func (c *columns) int(record []string, name string) (int, error) {
+	f, err := c.float(record, name)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(f), nil
+}
+
+func parseTime(value string, loc *time.Location, layouts ...string) (time.Time, error) {
+	for _, layout := range layouts {
+		t, err := time.ParseInLocation(layout, value, loc)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
@@ -0,0 +1,347 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"sulemankhann/workout-tracker/internal/data"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name         string
+		csv          string
+		units        data.Units
+		wantSource   string
+		wantWorkouts []Workout
+	}{
+		{
+			name: "Strong export",
+			csv: "\ufeffDate,Workout Name,Duration,Exercise Name,Set Order,Weight,Reps,Distance,Seconds,Notes,Workout Notes,RPE\n" +
+				"2024-05-04 07:00:00,Push,1h 5m,Bench Press (Barbell),W,40,10,0,0,,Felt good,\n" +
+				"2024-05-04 07:00:00,Push,1h 5m,Bench Press (Barbell),1,80,5,0,0,,Felt good,\n" +
+				"2024-05-04 07:00:00,Push,1h 5m,Bench Press (Barbell),2,80,5,0,0,,Felt good,\n" +
+				"2024-05-04 07:00:00,Push,1h 5m,Bench Press (Barbell),D,60,8,0,0,,Felt good,\n" +
+				"2024-05-04 07:00:00,Push,1h 5m,Plank,F,0,0,0,60,,Felt good,\n" +
+				"2024-05-06 18:30:00,Run,30m,Running,1,0,0,5,1800,Easy pace,,\n",
+			wantSource: SourceStrong,
+			wantWorkouts: []Workout{
+				{
+					Name:        "Push",
+					Notes:       "Felt good",
+					StartedAt:   time.Date(2024, 5, 4, 7, 0, 0, 0, time.UTC),
+					CompletedAt: time.Date(2024, 5, 4, 8, 5, 0, 0, time.UTC),
+					Exercises: []*Exercise{
+						{
+							Name: "Bench Press (Barbell)",
+							Sets: []Set{
+								{Warmup: true, Weight: 40, Repetitions: 10},
+								{Order: 1, Weight: 80, Repetitions: 5},
+								{Order: 2, Weight: 80, Repetitions: 5},
+								{Drop: true, Weight: 60, Repetitions: 8},
+							},
+						},
+						{
+							Name: "Plank",
+							Sets: []Set{{DurationSeconds: 60}},
+						},
+					},
+				},
+				{
+					Name:        "Run",
+					StartedAt:   time.Date(2024, 5, 6, 18, 30, 0, 0, time.UTC),
+					CompletedAt: time.Date(2024, 5, 6, 19, 0, 0, 0, time.UTC),
+					Exercises: []*Exercise{
+						{
+							Name:  "Running",
+							Notes: "Easy pace",
+							Sets:  []Set{{Order: 1, DistanceMeters: 5000, DurationSeconds: 1800}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Strong export with semicolons, comma decimals and pounds",
+			csv: "Date;Workout Name;Duration;Exercise Name;Set Order;Weight;Weight Unit;Reps;Distance;Distance Unit;Seconds\n" +
+				"2024-05-04 07:00;Legs;45m;Squat;1;225;lbs;5;0;;0\n" +
+				"2024-05-04 07:00;Legs;45m;Rowing;1;0;;0;1,5;mi;600\n",
+			wantSource: SourceStrong,
+			wantWorkouts: []Workout{
+				{
+					Name:        "Legs",
+					StartedAt:   time.Date(2024, 5, 4, 7, 0, 0, 0, time.UTC),
+					CompletedAt: time.Date(2024, 5, 4, 7, 45, 0, 0, time.UTC),
+					Exercises: []*Exercise{
+						{
+							Name: "Squat",
+							Sets: []Set{{Order: 1, Weight: 225 * poundsToKilograms, Repetitions: 5}},
+						},
+						{
+							Name: "Rowing",
+							Sets: []Set{{Order: 1, DistanceMeters: 1.5 * 1609.344, DurationSeconds: 600}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Strong export without units for a user in pounds and miles",
+			csv: "Date,Workout Name,Duration,Exercise Name,Set Order,Weight,Reps,Distance,Seconds\n" +
+				"2024-05-04 07:00:00,Mixed,30m,Squat,1,225,5,0,0\n" +
+				"2024-05-04 07:00:00,Mixed,30m,Running,1,0,0,2,1200\n",
+			units:      data.Units{Weight: data.UnitPounds, Distance: data.UnitMiles},
+			wantSource: SourceStrong,
+			wantWorkouts: []Workout{
+				{
+					Name:        "Mixed",
+					StartedAt:   time.Date(2024, 5, 4, 7, 0, 0, 0, time.UTC),
+					CompletedAt: time.Date(2024, 5, 4, 7, 30, 0, 0, time.UTC),
+					Exercises: []*Exercise{
+						{
+							Name: "Squat",
+							Sets: []Set{{Order: 1, Weight: 225 * poundsToKilograms, Repetitions: 5}},
+						},
+						{
+							Name: "Running",
+							Sets: []Set{{Order: 1, DistanceMeters: 2 * 1609.344, DurationSeconds: 1200}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Hevy export in kilograms",
+			csv: "title,start_time,end_time,description,exercise_title,superset_id,exercise_notes,set_index,set_type,weight_kg,reps,distance_km,duration_seconds,rpe\n" +
+				`Pull,"4 May 2024, 07:00","4 May 2024, 08:10",,Pull Up,,,0,warmup,,5,,,` + "\n" +
+				`Pull,"4 May 2024, 07:00","4 May 2024, 08:10",,Pull Up,,Strict,1,normal,10,8,,,` + "\n" +
+				`Pull,"4 May 2024, 07:00","4 May 2024, 08:10",,Treadmill,,,0,normal,,,2.5,900,` + "\n",
+			wantSource: SourceHevy,
+			wantWorkouts: []Workout{
+				{
+					Name:        "Pull",
+					StartedAt:   time.Date(2024, 5, 4, 7, 0, 0, 0, time.UTC),
+					CompletedAt: time.Date(2024, 5, 4, 8, 10, 0, 0, time.UTC),
+					Exercises: []*Exercise{
+						{
+							Name: "Pull Up",
+							Sets: []Set{
+								{Warmup: true, Repetitions: 5},
+								{Order: 1, Weight: 10, Repetitions: 8},
+							},
+						},
+						{
+							Name: "Treadmill",
+							Sets: []Set{{DistanceMeters: 2500, DurationSeconds: 900}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Hevy export in pounds and miles",
+			csv: "title,start_time,end_time,exercise_title,set_index,set_type,weight_lbs,reps,distance_miles,duration_seconds\n" +
+				"Mixed,2024-05-04 07:00:00,,Deadlift,0,normal,315,3,,\n" +
+				"Mixed,2024-05-04 07:00:00,,Cycling,0,normal,,,2,1200\n",
+			wantSource: SourceHevy,
+			wantWorkouts: []Workout{
+				{
+					Name:        "Mixed",
+					StartedAt:   time.Date(2024, 5, 4, 7, 0, 0, 0, time.UTC),
+					CompletedAt: time.Date(2024, 5, 4, 7, 0, 0, 0, time.UTC),
+					Exercises: []*Exercise{
+						{
+							Name: "Deadlift",
+							Sets: []Set{{Weight: 315 * poundsToKilograms, Repetitions: 3}},
+						},
+						{
+							Name: "Cycling",
+							Sets: []Set{{DistanceMeters: 2 * 1609.344, DurationSeconds: 1200}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, workouts, err := Parse(strings.NewReader(tt.csv), time.UTC, tt.units)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			if source != tt.wantSource {
+				t.Errorf("source = %q; want %q", source, tt.wantSource)
+			}
+
+			if len(workouts) != len(tt.wantWorkouts) {
+				t.Fatalf("got %d workouts; want %d", len(workouts), len(tt.wantWorkouts))
+			}
+
+			for i, want := range tt.wantWorkouts {
+				checkWorkout(t, workouts[i], &want)
+			}
+		})
+	}
+}
+
+func checkWorkout(t *testing.T, got, want *Workout) {
+	t.Helper()
+
+	if got.Name != want.Name || got.Notes != want.Notes {
+		t.Errorf("workout = %q (%q); want %q (%q)", got.Name, got.Notes, want.Name, want.Notes)
+	}
+
+	if !got.StartedAt.Equal(want.StartedAt) {
+		t.Errorf("%s: StartedAt = %v; want %v", want.Name, got.StartedAt, want.StartedAt)
+	}
+
+	if !got.CompletedAt.Equal(want.CompletedAt) {
+		t.Errorf("%s: CompletedAt = %v; want %v", want.Name, got.CompletedAt, want.CompletedAt)
+	}
+
+	if len(got.Exercises) != len(want.Exercises) {
+		t.Fatalf("%s: got %d exercises; want %d", want.Name, len(got.Exercises), len(want.Exercises))
+	}
+
+	for i, wantExercise := range want.Exercises {
+		gotExercise := got.Exercises[i]
+
+		if gotExercise.Name != wantExercise.Name || gotExercise.Notes != wantExercise.Notes {
+			t.Errorf(
+				"exercise %d = %q (%q); want %q (%q)",
+				i,
+				gotExercise.Name,
+				gotExercise.Notes,
+				wantExercise.Name,
+				wantExercise.Notes,
+			)
+		}
+
+		if len(gotExercise.Sets) != len(wantExercise.Sets) {
+			t.Errorf(
+				"%s: got %d sets; want %d",
+				wantExercise.Name,
+				len(gotExercise.Sets),
+				len(wantExercise.Sets),
+			)
+			continue
+		}
+
+		for j, wantSet := range wantExercise.Sets {
+			if gotExercise.Sets[j] != wantSet {
+				t.Errorf("%s: set %d = %+v; want %+v", wantExercise.Name, j, gotExercise.Sets[j], wantSet)
+			}
+		}
+	}
+}
+
+func TestParseInLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	csv := "Date,Workout Name,Exercise Name,Set Order,Weight,Reps\n" +
+		"2024-05-04 07:00:00,Push,Bench Press,1,80,5\n"
+
+	_, workouts, err := Parse(strings.NewReader(csv), loc, data.Units{})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(workouts) != 1 {
+		t.Fatalf("got %d workouts; want 1", len(workouts))
+	}
+
+	want := time.Date(2024, 5, 4, 5, 0, 0, 0, time.UTC)
+	if !workouts[0].StartedAt.Equal(want) {
+		t.Errorf("StartedAt = %v; want %v", workouts[0].StartedAt, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		wantErr string
+		wantIs  error
+	}{
+		{
+			name:    "empty file",
+			csv:     "",
+			wantErr: "file is empty",
+		},
+		{
+			name:   "unknown format",
+			csv:    "name,sets,reps\nSquat,5,5\n",
+			wantIs: ErrUnknownFormat,
+		},
+		{
+			name: "invalid date",
+			csv: "Date,Workout Name,Exercise Name,Set Order,Weight,Reps\n" +
+				"yesterday,Push,Bench Press,1,80,5\n",
+			wantErr: `line 2: invalid date "yesterday"`,
+		},
+		{
+			name: "invalid set order",
+			csv: "Date,Workout Name,Exercise Name,Set Order,Weight,Reps\n" +
+				"2024-05-04 07:00:00,Push,Bench Press,X,80,5\n",
+			wantErr: `line 2: invalid number "X" in column "set order"`,
+		},
+		{
+			name: "invalid weight",
+			csv: "title,start_time,exercise_title,set_index,weight_kg,reps\n" +
+				"Pull,2024-05-04 07:00:00,Pull Up,0,heavy,8\n",
+			wantErr: `line 2: invalid number "heavy" in column "weight_kg"`,
+		},
+		{
+			name: "invalid duration",
+			csv: "Date,Workout Name,Duration,Exercise Name,Set Order,Weight,Reps\n" +
+				"2024-05-04 07:00:00,Push,an hour,Bench Press,1,80,5\n",
+			wantErr: `line 2: invalid duration "an hour"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Parse(strings.NewReader(tt.csv), time.UTC, data.Units{})
+			if err == nil {
+				t.Fatal("Parse() error = nil; want an error")
+			}
+
+			if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+				t.Errorf("Parse() error = %v; want %v", err, tt.wantIs)
+			}
+
+			if tt.wantErr != "" && err.Error() != tt.wantErr {
+				t.Errorf("Parse() error = %q; want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseStrongDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"45m", 45 * time.Minute},
+		{"1h 5m", time.Hour + 5*time.Minute},
+		{"2h", 2 * time.Hour},
+		{"1h 2m 3s", time.Hour + 2*time.Minute + 3*time.Second},
+		{"30s", 30 * time.Second},
+		{"3900", 3900 * time.Second},
+	}
+
+	for _, tt := range tests {
+		got, err := parseStrongDuration(tt.value)
+		if err != nil {
+			t.Errorf("parseStrongDuration(%q) error = %v", tt.value, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("parseStrongDuration(%q) = %v; want %v", tt.value, got, tt.want)
+		}
+	}
+}
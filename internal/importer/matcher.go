@@ -0,0 +1,188 @@
+package importer
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// minMatchScore is the similarity below which a name is reported as unmapped
+// rather than guessed at.
+const minMatchScore = 0.75
+
+var (
+	parentheticalRX = regexp.MustCompile(`\([^)]*\)`)
+	nonAlnumRX      = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+type candidate struct {
+	id     int64
+	full   string
+	tokens []string
+}
+
+// Matcher maps free-text exercise names from other apps onto catalogue
+// exercise ids.
+type Matcher struct {
+	candidates []candidate
+	cache      map[string]int64
+}
+
+func NewMatcher(names map[int64]string) *Matcher {
+	m := &Matcher{cache: make(map[string]int64)}
+
+	for id, name := range names {
+		normalized := normalize(name)
+		m.candidates = append(m.candidates, candidate{
+			id:     id,
+			full:   normalized,
+			tokens: strings.Fields(normalized),
+		})
+	}
+
+	// Sort so that ties are broken deterministically.
+	slices.SortFunc(m.candidates, func(a, b candidate) int {
+		return cmp.Compare(a.id, b.id)
+	})
+
+	return m
+}
+
+// Match returns the id of the catalogue exercise that best matches name.
+// Names are compared case-insensitively with punctuation, plurals and any
+// parenthesised equipment suffix such as "(Barbell)" ignored. When there is
+// no exact match the candidate with the highest edit-distance or token
+// overlap similarity wins, provided it scores at least minMatchScore.
+func (m *Matcher) Match(name string) (int64, bool) {
+	if id, ok := m.cache[name]; ok {
+		return id, id != 0
+	}
+
+	id := m.match(name)
+	m.cache[name] = id
+
+	return id, id != 0
+}
+
+func (m *Matcher) match(name string) int64 {
+	withEquipment := normalize(name)
+	bare := normalize(parentheticalRX.ReplaceAllString(name, " "))
+
+	for _, c := range m.candidates {
+		if c.full == withEquipment || c.full == bare {
+			return c.id
+		}
+	}
+
+	tokens := strings.Fields(bare)
+
+	var bestID int64
+	var bestScore float64
+
+	for _, c := range m.candidates {
+		score := max(levenshteinRatio(bare, c.full), jaccard(tokens, c.tokens))
+		if score > bestScore {
+			bestID, bestScore = c.id, score
+		}
+	}
+
+	if bestScore < minMatchScore {
+		return 0
+	}
+
+	return bestID
+}
+
+func normalize(name string) string {
+	name = strings.ToLower(strings.ReplaceAll(name, "'", ""))
+	name = strings.TrimSpace(nonAlnumRX.ReplaceAllString(name, " "))
+
+	tokens := strings.Fields(name)
+	for i, token := range tokens {
+		tokens[i] = singular(token)
+	}
+
+	return strings.Join(tokens, " ")
+}
+
+func singular(token string) string {
+	switch {
+	case len(token) > 3 && strings.HasSuffix(token, "ies"):
+		return token[:len(token)-3] + "y"
+	case len(token) > 3 && strings.HasSuffix(token, "s") &&
+		!strings.HasSuffix(token, "ss"):
+		return token[:len(token)-1]
+	default:
+		return token
+	}
+}
+
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	set := make(map[string]bool, len(a))
+	for _, token := range a {
+		set[token] = true
+	}
+
+	intersection := 0
+	union := len(set)
+	seen := make(map[string]bool, len(b))
+
+	for _, token := range b {
+		if seen[token] {
+			continue
+		}
+		seen[token] = true
+
+		if set[token] {
+			intersection++
+		} else {
+			union++
+		}
+	}
+
+	return float64(intersection) / float64(union)
+}
+
+func levenshteinRatio(a, b string) float64 {
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return 1
+	}
+
+	return 1 - float64(levenshtein(a, b))/float64(longest)
+}
+
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(
+				previous[j]+1,
+				current[j-1]+1,
+				previous[j-1]+cost,
+			)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
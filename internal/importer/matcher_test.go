@@ -0,0 +1,67 @@
+package importer
+
+import "testing"
+
+func TestMatcher(t *testing.T) {
+	matcher := NewMatcher(map[int64]string{
+		1: "Bench Press",
+		2: "Squat",
+		3: "Deadlift",
+		4: "Pull Up",
+		5: "Calf Raises",
+		6: "Dumbbell Shoulder Press",
+		7: "Farmer's Walk",
+		8: "Running",
+	})
+
+	tests := []struct {
+		name   string
+		input  string
+		wantID int64
+	}{
+		{name: "exact", input: "Squat", wantID: 2},
+		{name: "case", input: "bench press", wantID: 1},
+		{name: "equipment suffix", input: "Bench Press (Barbell)", wantID: 1},
+		{name: "punctuation", input: "Pull-Up", wantID: 4},
+		{name: "plural", input: "Pull Ups", wantID: 4},
+		{name: "singular", input: "Calf Raise", wantID: 5},
+		{name: "apostrophe", input: "Farmers Walk", wantID: 7},
+		{name: "typo", input: "Deadlfit", wantID: 3},
+		{name: "word order", input: "Shoulder Press Dumbbell", wantID: 6},
+		{name: "unrelated", input: "Bicep Curl", wantID: 0},
+		{name: "empty", input: "", wantID: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Match twice so that the cached answer is checked as well.
+			for range 2 {
+				id, ok := matcher.Match(tt.input)
+
+				if id != tt.wantID || ok != (tt.wantID != 0) {
+					t.Errorf("Match(%q) = %d, %t; want %d", tt.input, id, ok, tt.wantID)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Bench Press", "bench press"},
+		{"  Lat Pull-Downs ", "lat pull down"},
+		{"Farmer's Walks", "farmer walk"},
+		{"Calf Raises", "calf raise"},
+		{"Lateral Flies", "lateral fly"},
+		{"Press", "press"},
+	}
+
+	for _, tt := range tests {
+		if got := normalize(tt.input); got != tt.want {
+			t.Errorf("normalize(%q) = %q; want %q", tt.input, got, tt.want)
+		}
+	}
+}
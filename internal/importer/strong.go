@@ -0,0 +1,138 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sulemankhann/workout-tracker/internal/data"
+	"time"
+)
+
+var strongDurationRX = regexp.MustCompile(`^(?:(\d+)h)?\s*(?:(\d+)m(?:in)?)?\s*(?:(\d+)s)?$`)
+
+var strongTimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	time.RFC3339,
+}
+
+// parseStrongRow handles both the comma-separated export with a "Duration"
+// column and the newer semicolon-separated export that adds explicit weight
+// and distance unit columns. The classic export is written in the units the
+// user picked in the app, which are taken to be their units here.
+func parseStrongRow(
+	cols *columns,
+	record []string,
+	loc *time.Location,
+	units data.Units,
+) (row, error) {
+	var r row
+	var err error
+
+	r.startedAt, err = parseTime(cols.get(record, "date"), loc, strongTimeLayouts...)
+	if err != nil {
+		return r, err
+	}
+
+	duration, err := parseStrongDuration(cols.get(record, "duration"))
+	if err != nil {
+		return r, err
+	}
+
+	r.completedAt = r.startedAt.Add(duration)
+	r.workoutName = cols.get(record, "workout name")
+	r.workoutNotes = cols.get(record, "workout notes")
+	r.exerciseName = cols.get(record, "exercise name")
+	r.exerciseNotes = cols.get(record, "notes")
+
+	// Strong numbers working sets and marks the others with a letter: W for
+	// warm-up, D for drop and F for failure sets.
+	switch strings.ToUpper(cols.get(record, "set order")) {
+	case "W":
+		r.set.Warmup = true
+	case "D":
+		r.set.Drop = true
+	case "F":
+	default:
+		r.set.Order, err = cols.int(record, "set order")
+		if err != nil {
+			return r, err
+		}
+	}
+
+	r.set.Weight, err = cols.float(record, "weight")
+	if err != nil {
+		return r, err
+	}
+
+	weightUnit := units.Weight
+
+	switch unit := strings.ToLower(cols.get(record, "weight unit")); {
+	case strings.HasPrefix(unit, "lb"):
+		weightUnit = data.UnitPounds
+	case strings.HasPrefix(unit, "kg"):
+		weightUnit = data.UnitKilograms
+	}
+
+	r.set.Weight = data.ToKilograms(r.set.Weight, weightUnit)
+
+	r.set.Repetitions, err = cols.int(record, "reps")
+	if err != nil {
+		return r, err
+	}
+
+	r.set.DistanceMeters, err = cols.float(record, "distance")
+	if err != nil {
+		return r, err
+	}
+
+	switch unit := strings.ToLower(cols.get(record, "distance unit")); unit {
+	case "m":
+	case data.UnitMiles, data.UnitKilometers:
+		r.set.DistanceMeters = data.ToMeters(r.set.DistanceMeters, unit)
+	default:
+		r.set.DistanceMeters = data.ToMeters(r.set.DistanceMeters, units.Distance)
+	}
+
+	r.set.DurationSeconds, err = cols.int(record, "seconds")
+	if err != nil {
+		return r, err
+	}
+
+	return r, nil
+}
+
+// parseStrongDuration parses the length of a workout as Strong writes it,
+// such as "1h 5m" or "45m". A plain number is a number of seconds.
+func parseStrongDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	match := strongDurationRX.FindStringSubmatch(strings.ToLower(value))
+	if match == nil || match[0] == "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	var duration time.Duration
+
+	for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+		if match[i+1] == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(match[i+1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+
+		duration += time.Duration(n) * unit
+	}
+
+	return duration, nil
+}
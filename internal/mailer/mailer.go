@@ -0,0 +1,56 @@
+// Package mailer sends plain text email over SMTP.
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+type Mailer struct {
+	addr   string
+	auth   smtp.Auth
+	sender string
+}
+
+// New returns a Mailer that sends through the SMTP server at host:port as
+// sender. Authentication is only used if a username is given.
+func New(host string, port int, username, password, sender string) Mailer {
+	m := Mailer{
+		addr:   net.JoinHostPort(host, strconv.Itoa(port)),
+		sender: sender,
+	}
+
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return m
+}
+
+// Send emails body to recipient with the given subject.
+func (m Mailer) Send(recipient, subject, body string) error {
+	var msg bytes.Buffer
+
+	// Encoding the subject also escapes any line breaks in it, which would
+	// otherwise let it inject extra headers.
+	fmt.Fprintf(&msg, "From: %s\r\n", m.sender)
+	fmt.Fprintf(&msg, "To: %s\r\n", recipient)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(body)
+
+	err := smtp.SendMail(m.addr, m.auth, m.sender, []string{recipient}, msg.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", recipient, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,464 @@
+// Package migrate applies SQL migrations to the database and records which
+// of them have been applied.
+//
+// Migrations are pairs of files named NNNNNN_description.up.sql and
+// NNNNNN_description.down.sql. The version the database is at is kept in the
+// schema_migrations table, in the same layout golang-migrate uses, so that a
+// database migrated with that tool can be taken over as it is.
+//
+// Each migration runs in its own transaction together with the change to the
+// recorded version, so a failing migration leaves the database at the
+// version before it. A PostgreSQL advisory lock is held while migrating, so
+// when several instances start at once the others wait and then find
+// nothing left to do.
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// lockID identifies the advisory lock taken while migrating. Any constant
+// works as long as nothing else in the database uses it.
+const lockID = 7_208_301_946
+
+var (
+	// ErrDirty is returned when a migration was left half applied, for
+	// example by another tool that doesn't run migrations in transactions.
+	// The schema has to be repaired by hand and the version set with Force.
+	ErrDirty = errors.New("database is dirty")
+
+	ErrUnknownVersion = errors.New("unknown migration version")
+)
+
+var filenameRX = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one version of the schema and the scripts to reach it from
+// the previous version and to return from it.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Load reads the migrations from the top level of fsys, in version order.
+// Files that don't look like migrations are ignored.
+func Load(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+
+	for _, entry := range entries {
+		match := filenameRX.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("%s: invalid version", entry.Name())
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf(
+				"%s: version %d is already used by %s",
+				entry.Name(),
+				version,
+				migration.Name,
+			)
+		}
+
+		script, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(script)
+		} else {
+			migration.Down = string(script)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf(
+				"migration %d_%s has no up script",
+				migration.Version,
+				migration.Name,
+			)
+		}
+
+		migrations = append(migrations, migration)
+	}
+
+	slices.SortFunc(migrations, func(a, b *Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return migrations, nil
+}
+
+// Migrator moves a database between the versions of its migrations.
+type Migrator struct {
+	DB         *sql.DB
+	Migrations []*Migration
+	Logger     *slog.Logger
+}
+
+// New returns a Migrator for db using the migrations in fsys.
+func New(db *sql.DB, fsys fs.FS, logger *slog.Logger) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{DB: db, Migrations: migrations, Logger: logger}, nil
+}
+
+// Latest returns the version of the newest migration, or 0 if there are
+// none.
+func (m *Migrator) Latest() int64 {
+	if len(m.Migrations) == 0 {
+		return 0
+	}
+
+	return m.Migrations[len(m.Migrations)-1].Version
+}
+
+// Version returns the version the database is at, 0 if no migrations have
+// been applied, and whether it was left dirty.
+func (m *Migrator) Version(ctx context.Context) (int64, bool, error) {
+	var version int64
+	var dirty bool
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		var err error
+
+		version, dirty, err = readVersion(ctx, conn)
+		return err
+	})
+
+	return version, dirty, err
+}
+
+// Up applies every migration newer than the database's version. A database
+// already at or beyond the newest known version is left alone, so an older
+// release starting against a newer schema doesn't roll it back.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		current, err := m.readCleanVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		if current >= m.Latest() {
+			return nil
+		}
+
+		return m.migrate(ctx, conn, current, m.Latest())
+	})
+}
+
+// Down reverts the n most recently applied migrations.
+func (m *Migrator) Down(ctx context.Context, n int) error {
+	if n < 1 {
+		return errors.New("the number of migrations to revert must be positive")
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		current, err := m.readCleanVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		applied := slices.IndexFunc(m.Migrations, func(migration *Migration) bool {
+			return migration.Version > current
+		})
+		if applied < 0 {
+			applied = len(m.Migrations)
+		}
+
+		if n > applied {
+			return fmt.Errorf(
+				"cannot revert %d migrations when only %d are applied",
+				n,
+				applied,
+			)
+		}
+
+		var target int64
+		if n < applied {
+			target = m.Migrations[applied-n-1].Version
+		}
+
+		return m.migrate(ctx, conn, current, target)
+	})
+}
+
+// Goto applies or reverts migrations until the database is at version,
+// where 0 reverts all of them.
+func (m *Migrator) Goto(ctx context.Context, version int64) error {
+	if !m.known(version) {
+		return fmt.Errorf("%w %d", ErrUnknownVersion, version)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		current, err := m.readCleanVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		return m.migrate(ctx, conn, current, version)
+	})
+}
+
+// Force records version as the database's version and clears the dirty
+// flag without running any migrations. It is for repairing the record after
+// the schema has been fixed by hand.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	if !m.known(version) {
+		return fmt.Errorf("%w %d", ErrUnknownVersion, version)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		return setVersion(ctx, conn, version)
+	})
+}
+
+func (m *Migrator) known(version int64) bool {
+	return version == 0 || slices.ContainsFunc(m.Migrations, func(migration *Migration) bool {
+		return migration.Version == version
+	})
+}
+
+// migrate runs the scripts taking the database from version current to
+// version target, one migration at a time.
+func (m *Migrator) migrate(
+	ctx context.Context,
+	conn *sql.Conn,
+	current, target int64,
+) error {
+	if target > current {
+		for _, migration := range m.Migrations {
+			if migration.Version <= current || migration.Version > target {
+				continue
+			}
+
+			err := m.apply(ctx, conn, migration, "up", migration.Up, migration.Version)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if current != 0 && !m.known(current) {
+		return fmt.Errorf(
+			"%w: the database is at version %d, which this release doesn't know how to revert",
+			ErrUnknownVersion,
+			current,
+		)
+	}
+
+	for i := len(m.Migrations) - 1; i >= 0; i-- {
+		migration := m.Migrations[i]
+
+		if migration.Version > current || migration.Version <= target {
+			continue
+		}
+
+		if migration.Down == "" {
+			return fmt.Errorf(
+				"migration %06d_%s has no down script",
+				migration.Version,
+				migration.Name,
+			)
+		}
+
+		var previous int64
+		if i > 0 {
+			previous = m.Migrations[i-1].Version
+		}
+
+		err := m.apply(ctx, conn, migration, "down", migration.Down, previous)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// apply runs script and records version as the database's version in a
+// single transaction.
+func (m *Migrator) apply(
+	ctx context.Context,
+	conn *sql.Conn,
+	migration *Migration,
+	direction, script string,
+	version int64,
+) error {
+	start := time.Now()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, script)
+	if err != nil {
+		return fmt.Errorf(
+			"migration %06d_%s (%s) failed: %w",
+			migration.Version,
+			migration.Name,
+			direction,
+			err,
+		)
+	}
+
+	err = setVersion(ctx, tx, version)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	m.Logger.Info(
+		"migration applied",
+		"version", migration.Version,
+		"name", migration.Name,
+		"direction", direction,
+		"duration", time.Since(start),
+	)
+
+	return nil
+}
+
+// withLock runs fn on a connection holding the migration lock, creating the
+// schema_migrations table first if needed. Advisory locks belong to the
+// session, so everything has to happen on that one connection.
+func (m *Migrator) withLock(ctx context.Context, fn func(*sql.Conn) error) error {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID)
+	if err != nil {
+		return fmt.Errorf("failed to take the migration lock: %w", err)
+	}
+
+	defer func() {
+		// Unlock even if ctx has been cancelled, or the connection would go
+		// back to the pool still holding the lock.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", lockID)
+		if err != nil {
+			m.Logger.Error("failed to release the migration lock", "error", err)
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version bigint NOT NULL PRIMARY KEY,
+            dirty boolean NOT NULL
+        )`)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+type execQueryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func readVersion(ctx context.Context, db execQueryer) (int64, bool, error) {
+	var version int64
+	var dirty bool
+
+	err := db.QueryRowContext(
+		ctx,
+		"SELECT version, dirty FROM schema_migrations LIMIT 1",
+	).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+
+	return version, dirty, err
+}
+
+// readCleanVersion returns the database's version, failing if it is dirty.
+func (m *Migrator) readCleanVersion(
+	ctx context.Context,
+	conn *sql.Conn,
+) (int64, error) {
+	version, dirty, err := readVersion(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+
+	if dirty {
+		return 0, fmt.Errorf(
+			"%w at version %d: repair the schema, then force the version",
+			ErrDirty,
+			version,
+		)
+	}
+
+	return version, nil
+}
+
+// setVersion records version, which is 0 once every migration is reverted.
+func setVersion(ctx context.Context, db execQueryer, version int64) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM schema_migrations")
+	if err != nil {
+		return err
+	}
+
+	if version == 0 {
+		return nil
+	}
+
+	_, err = db.ExecContext(
+		ctx,
+		"INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)",
+		version,
+	)
+
+	return err
+}
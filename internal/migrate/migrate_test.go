@@ -0,0 +1,95 @@
+package migrate
+
+import (
+	"sulemankhann/workout-tracker/migrations"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000002_add_index.up.sql":      {Data: []byte("CREATE INDEX")},
+		"000002_add_index.down.sql":    {Data: []byte("DROP INDEX")},
+		"000001_create_users.up.sql":   {Data: []byte("CREATE TABLE")},
+		"000001_create_users.down.sql": {Data: []byte("DROP TABLE")},
+		"000010_backfill.up.sql":       {Data: []byte("UPDATE")},
+		"README.md":                    {Data: []byte("notes")},
+		"migrations.go":                {Data: []byte("package migrations")},
+	}
+
+	got, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Migration{
+		{Version: 1, Name: "create_users", Up: "CREATE TABLE", Down: "DROP TABLE"},
+		{Version: 2, Name: "add_index", Up: "CREATE INDEX", Down: "DROP INDEX"},
+		{Version: 10, Name: "backfill", Up: "UPDATE"},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d migrations; want %d", len(got), len(want))
+	}
+
+	for i := range want {
+		if *got[i] != want[i] {
+			t.Errorf("migration %d = %+v; want %+v", i, *got[i], want[i])
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"000001_create_users.up.sql": {Data: []byte("CREATE TABLE")},
+				"000001_create_posts.up.sql": {Data: []byte("CREATE TABLE")},
+			},
+		},
+		{
+			name: "missing up script",
+			fsys: fstest.MapFS{
+				"000001_create_users.down.sql": {Data: []byte("DROP TABLE")},
+			},
+		},
+		{
+			name: "version zero",
+			fsys: fstest.MapFS{
+				"000000_create_users.up.sql": {Data: []byte("CREATE TABLE")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.fsys)
+			if err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+// TestEmbeddedMigrations checks that the migrations shipped in the binaries
+// load and that each of them can be reverted.
+func TestEmbeddedMigrations(t *testing.T) {
+	got, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+
+	for _, migration := range got {
+		if migration.Down == "" {
+			t.Errorf("migration %d_%s has no down script", migration.Version, migration.Name)
+		}
+	}
+}
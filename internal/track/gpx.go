@@ -0,0 +1,57 @@
+package track
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+type gpxFile struct {
+	Tracks []struct {
+		Segments []struct {
+			Points []struct {
+				Lat       float64  `xml:"lat,attr"`
+				Lon       float64  `xml:"lon,attr"`
+				Elevation *float64 `xml:"ele"`
+				Time      string   `xml:"time"`
+			} `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+}
+
+// ParseGPX reads every track point from every track segment of a GPX 1.1
+// file.
+func ParseGPX(r io.Reader) ([]Point, error) {
+	var file gpxFile
+
+	err := xml.NewDecoder(r).Decode(&file)
+	if err != nil {
+		return nil, err
+	}
+
+	points := []Point{}
+
+	for _, trk := range file.Tracks {
+		for _, seg := range trk.Segments {
+			for _, pt := range seg.Points {
+				point := Point{
+					Lat:       pt.Lat,
+					Lon:       pt.Lon,
+					HasCoords: true,
+					Elevation: pt.Elevation,
+				}
+
+				if pt.Time != "" {
+					point.Time, err = time.Parse(time.RFC3339, pt.Time)
+					if err != nil {
+						return nil, err
+					}
+				}
+
+				points = append(points, point)
+			}
+		}
+	}
+
+	return points, nil
+}
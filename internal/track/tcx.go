@@ -0,0 +1,65 @@
+package track
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+type tcxFile struct {
+	Activities []struct {
+		Laps []struct {
+			Trackpoints []struct {
+				Time     string `xml:"Time"`
+				Position *struct {
+					Lat float64 `xml:"LatitudeDegrees"`
+					Lon float64 `xml:"LongitudeDegrees"`
+				} `xml:"Position"`
+				Altitude *float64 `xml:"AltitudeMeters"`
+				Distance *float64 `xml:"DistanceMeters"`
+			} `xml:"Track>Trackpoint"`
+		} `xml:"Lap"`
+	} `xml:"Activities>Activity"`
+}
+
+// ParseTCX reads every trackpoint from every lap of every activity in a
+// Garmin Training Center file. Trackpoints without a position are kept so
+// that indoor activities can fall back to the recorded distance.
+func ParseTCX(r io.Reader) ([]Point, error) {
+	var file tcxFile
+
+	err := xml.NewDecoder(r).Decode(&file)
+	if err != nil {
+		return nil, err
+	}
+
+	points := []Point{}
+
+	for _, activity := range file.Activities {
+		for _, lap := range activity.Laps {
+			for _, tp := range lap.Trackpoints {
+				point := Point{
+					Elevation: tp.Altitude,
+					Distance:  tp.Distance,
+				}
+
+				if tp.Position != nil {
+					point.Lat = tp.Position.Lat
+					point.Lon = tp.Position.Lon
+					point.HasCoords = true
+				}
+
+				if tp.Time != "" {
+					point.Time, err = time.Parse(time.RFC3339, tp.Time)
+					if err != nil {
+						return nil, err
+					}
+				}
+
+				points = append(points, point)
+			}
+		}
+	}
+
+	return points, nil
+}
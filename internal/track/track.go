@@ -0,0 +1,240 @@
+// Package track parses GPS activity files recorded by watches and phones
+// (GPX and Garmin TCX) and summarises them into distance, duration,
+// elevation gain and per-split pace.
+package track
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"math"
+	"time"
+)
+
+const (
+	FormatGPX = "gpx"
+	FormatTCX = "tcx"
+)
+
+const (
+	earthRadiusMeters = 6371008.8
+
+	// elevationThreshold is the minimum climb, in metres, that counts
+	// towards elevation gain. It filters out GPS altitude noise that would
+	// otherwise inflate the total on flat routes.
+	elevationThreshold = 1.0
+)
+
+var (
+	ErrUnknownFormat   = errors.New("file is not a GPX or TCX activity")
+	ErrNotEnoughPoints = errors.New("activity must contain at least two timed track points")
+)
+
+// Point is a single recorded sample. Distance is the cumulative distance in
+// metres reported by the device, which TCX files from indoor activities
+// provide in place of coordinates.
+type Point struct {
+	Time      time.Time
+	Lat       float64
+	Lon       float64
+	HasCoords bool
+	Elevation *float64
+	Distance  *float64
+}
+
+// Split is one fixed-length segment of an activity. The final split is
+// usually shorter than the others.
+type Split struct {
+	Number          int     `json:"number"`
+	DistanceMeters  float64 `json:"distance_meters"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+type Summary struct {
+	Format          string    `json:"format"`
+	StartedAt       time.Time `json:"started_at"`
+	DistanceMeters  float64   `json:"distance_meters"`
+	DurationSeconds int       `json:"duration_seconds"`
+	ElevationGain   float64   `json:"elevation_gain"`
+	Splits          []Split   `json:"splits"`
+}
+
+// Parse detects the format of r from its root element and returns its track
+// points in recorded order.
+func Parse(r io.Reader) (string, []Point, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(content))
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return "", nil, ErrUnknownFormat
+			}
+			return "", nil, err
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "gpx":
+			points, err := ParseGPX(bytes.NewReader(content))
+			return FormatGPX, points, err
+		case "TrainingCenterDatabase":
+			points, err := ParseTCX(bytes.NewReader(content))
+			return FormatTCX, points, err
+		default:
+			return "", nil, ErrUnknownFormat
+		}
+	}
+}
+
+// Summarize computes totals and splits of splitMeters length for a track.
+// Points without a timestamp are ignored. Distance comes from coordinates
+// when every point has them, and from the device's own cumulative distance
+// otherwise.
+func Summarize(format string, points []Point, splitMeters float64) (*Summary, error) {
+	timed := make([]Point, 0, len(points))
+	for _, p := range points {
+		if !p.Time.IsZero() {
+			timed = append(timed, p)
+		}
+	}
+
+	if len(timed) < 2 {
+		return nil, ErrNotEnoughPoints
+	}
+
+	cumulative := cumulativeDistances(timed)
+
+	summary := &Summary{
+		Format:          format,
+		StartedAt:       timed[0].Time,
+		DistanceMeters:  math.Round(cumulative[len(cumulative)-1]*10) / 10,
+		DurationSeconds: int(timed[len(timed)-1].Time.Sub(timed[0].Time).Seconds()),
+		ElevationGain:   math.Round(elevationGain(timed)*10) / 10,
+		Splits:          splits(timed, cumulative, splitMeters),
+	}
+
+	return summary, nil
+}
+
+func cumulativeDistances(points []Point) []float64 {
+	useCoords := true
+	for _, p := range points {
+		if !p.HasCoords {
+			useCoords = false
+			break
+		}
+	}
+
+	cumulative := make([]float64, len(points))
+
+	for i := 1; i < len(points); i++ {
+		switch {
+		case useCoords:
+			cumulative[i] = cumulative[i-1] + haversine(points[i-1], points[i])
+		case points[i].Distance != nil:
+			cumulative[i] = max(cumulative[i-1], *points[i].Distance)
+		default:
+			cumulative[i] = cumulative[i-1]
+		}
+	}
+
+	return cumulative
+}
+
+func haversine(a, b Point) float64 {
+	lat1 := a.Lat * math.Pi / 180
+	lat2 := b.Lat * math.Pi / 180
+	dLat := lat2 - lat1
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(h))
+}
+
+// elevationGain totals climbs of at least elevationThreshold metres. The
+// reference altitude follows every descent but only moves up once a climb
+// clears the threshold, so small oscillations are not counted.
+func elevationGain(points []Point) float64 {
+	var gain float64
+	var reference *float64
+
+	for _, p := range points {
+		if p.Elevation == nil {
+			continue
+		}
+
+		elevation := *p.Elevation
+
+		switch {
+		case reference == nil || elevation < *reference:
+			reference = &elevation
+		case elevation-*reference >= elevationThreshold:
+			gain += elevation - *reference
+			reference = &elevation
+		}
+	}
+
+	return gain
+}
+
+// splits cuts the track into splitMeters segments, interpolating the time at
+// which each boundary was crossed.
+func splits(points []Point, cumulative []float64, splitMeters float64) []Split {
+	result := []Split{}
+
+	if splitMeters <= 0 {
+		return result
+	}
+
+	total := cumulative[len(cumulative)-1]
+	start := points[0].Time
+	boundary := splitMeters
+
+	for i := 1; i < len(points) && boundary <= total; i++ {
+		for boundary <= cumulative[i] {
+			segment := cumulative[i] - cumulative[i-1]
+			fraction := 0.0
+			if segment > 0 {
+				fraction = (boundary - cumulative[i-1]) / segment
+			}
+
+			elapsed := points[i].Time.Sub(points[i-1].Time)
+			crossed := points[i-1].Time.Add(
+				time.Duration(float64(elapsed) * fraction),
+			)
+
+			result = append(result, Split{
+				Number:          len(result) + 1,
+				DistanceMeters:  splitMeters,
+				DurationSeconds: math.Round(crossed.Sub(start).Seconds()*10) / 10,
+			})
+
+			start = crossed
+			boundary += splitMeters
+		}
+	}
+
+	remaining := total - float64(len(result))*splitMeters
+	if remaining >= 1 {
+		result = append(result, Split{
+			Number:          len(result) + 1,
+			DistanceMeters:  math.Round(remaining*10) / 10,
+			DurationSeconds: math.Round(points[len(points)-1].Time.Sub(start).Seconds()*10) / 10,
+		})
+	}
+
+	return result
+}
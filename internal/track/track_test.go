@@ -0,0 +1,152 @@
+package track
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseFixtures(t *testing.T) {
+	tests := []struct {
+		name           string
+		file           string
+		wantFormat     string
+		wantPoints     int
+		wantStartedAt  time.Time
+		wantDistance   float64
+		wantDuration   int
+		wantElevation  float64
+		wantSplits     []Split
+		distanceMargin float64
+	}{
+		{
+			name:          "GPX outdoor run",
+			file:          "morning_run.gpx",
+			wantFormat:    FormatGPX,
+			wantPoints:    21,
+			wantStartedAt: time.Date(2024, 5, 4, 7, 0, 0, 0, time.UTC),
+			wantDistance:  2223.9,
+			wantDuration:  600,
+			wantElevation: 11,
+			wantSplits: []Split{
+				{Number: 1, DistanceMeters: 1000, DurationSeconds: 269.8},
+				{Number: 2, DistanceMeters: 1000, DurationSeconds: 269.8},
+				{Number: 3, DistanceMeters: 223.9, DurationSeconds: 60.4},
+			},
+			distanceMargin: 0.2,
+		},
+		{
+			name:          "TCX treadmill run without coordinates",
+			file:          "treadmill.tcx",
+			wantFormat:    FormatTCX,
+			wantPoints:    11,
+			wantStartedAt: time.Date(2024, 5, 4, 7, 0, 0, 0, time.UTC),
+			wantDistance:  2000,
+			wantDuration:  600,
+			wantElevation: 0,
+			wantSplits: []Split{
+				{Number: 1, DistanceMeters: 1000, DurationSeconds: 300},
+				{Number: 2, DistanceMeters: 1000, DurationSeconds: 300},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			format, points, err := Parse(f)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			if format != tt.wantFormat {
+				t.Errorf("format = %q; want %q", format, tt.wantFormat)
+			}
+
+			if len(points) != tt.wantPoints {
+				t.Errorf("got %d points; want %d", len(points), tt.wantPoints)
+			}
+
+			summary, err := Summarize(format, points, 1000)
+			if err != nil {
+				t.Fatalf("Summarize() error = %v", err)
+			}
+
+			if !summary.StartedAt.Equal(tt.wantStartedAt) {
+				t.Errorf("StartedAt = %v; want %v", summary.StartedAt, tt.wantStartedAt)
+			}
+
+			if math.Abs(summary.DistanceMeters-tt.wantDistance) > tt.distanceMargin {
+				t.Errorf("DistanceMeters = %v; want %v", summary.DistanceMeters, tt.wantDistance)
+			}
+
+			if summary.DurationSeconds != tt.wantDuration {
+				t.Errorf("DurationSeconds = %d; want %d", summary.DurationSeconds, tt.wantDuration)
+			}
+
+			if summary.ElevationGain != tt.wantElevation {
+				t.Errorf("ElevationGain = %v; want %v", summary.ElevationGain, tt.wantElevation)
+			}
+
+			if len(summary.Splits) != len(tt.wantSplits) {
+				t.Fatalf("got %d splits; want %d", len(summary.Splits), len(tt.wantSplits))
+			}
+
+			for i, want := range tt.wantSplits {
+				got := summary.Splits[i]
+
+				if got.Number != want.Number ||
+					math.Abs(got.DistanceMeters-want.DistanceMeters) > tt.distanceMargin ||
+					math.Abs(got.DurationSeconds-want.DurationSeconds) > 0.1 {
+					t.Errorf("split %d = %+v; want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseUnknownFormat(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "not_an_activity.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	_, _, err = Parse(f)
+	if !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Parse() error = %v; want %v", err, ErrUnknownFormat)
+	}
+}
+
+func TestSummarizeNotEnoughPoints(t *testing.T) {
+	points := []Point{
+		{Time: time.Date(2024, 5, 4, 7, 0, 0, 0, time.UTC), Lat: 51.5, Lon: -0.1, HasCoords: true},
+		{Lat: 51.6, Lon: -0.1, HasCoords: true},
+	}
+
+	_, err := Summarize(FormatGPX, points, 1000)
+	if !errors.Is(err, ErrNotEnoughPoints) {
+		t.Errorf("Summarize() error = %v; want %v", err, ErrNotEnoughPoints)
+	}
+}
+
+func TestElevationGainIgnoresNoise(t *testing.T) {
+	elevations := []float64{50, 50.6, 50.1, 50.7, 50.2, 50.8, 50.3}
+
+	points := make([]Point, len(elevations))
+	for i := range elevations {
+		points[i].Elevation = &elevations[i]
+	}
+
+	if gain := elevationGain(points); gain != 0 {
+		t.Errorf("elevationGain() = %v; want 0", gain)
+	}
+}
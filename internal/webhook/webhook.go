@@ -0,0 +1,263 @@
+// Package webhook delivers signed event payloads to user-registered HTTP
+// endpoints.
+//
+// Every request carries the event name, a delivery id that stays the same
+// across retries (so receivers can discard duplicates) and a signature
+// header of the form
+//
+//	t=<unix timestamp>,v1=<hex HMAC-SHA256 of "<timestamp>.<body>">
+//
+// computed with the webhook's secret. Receivers should recompute the HMAC
+// and reject requests whose timestamp is too old, which Verify does.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// MaxAttempts is the number of times a delivery is tried before it is given
+// up as failed.
+const MaxAttempts = 8
+
+const (
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 6 * time.Hour
+)
+
+// Backoff returns how long to wait before retrying a delivery that has
+// failed attempts times: 30s, 1m, 2m, 4m and so on, capped at six hours.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+
+	delay := baseBackoff
+
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+
+	return delay
+}
+
+// Sign returns the signature header value for body sent at timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", ts, computeMAC(secret, ts, body))
+}
+
+// Verify reports whether header is a valid signature of body made with
+// secret no more than tolerance before now.
+func Verify(
+	secret, header string,
+	body []byte,
+	tolerance time.Duration,
+	now time.Time,
+) bool {
+	var ts, mac string
+
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			mac = value
+		}
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	if now.Sub(time.Unix(unix, 0)).Abs() > tolerance {
+		return false
+	}
+
+	expected := computeMAC(secret, ts, body)
+
+	return hmac.Equal([]byte(mac), []byte(expected))
+}
+
+func computeMAC(secret, ts string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(ts))
+	h.Write([]byte("."))
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Delivery is one event to send to one endpoint.
+type Delivery struct {
+	ID      int64
+	URL     string
+	Secret  string
+	Event   string
+	Payload []byte
+}
+
+// ErrForbiddenAddress is returned when a delivery would connect to an
+// address on the server's own host or network.
+var ErrForbiddenAddress = errors.New("webhook: destination address is not allowed")
+
+// nonPublicPrefixes are ranges that netip has no predicate for.
+var nonPublicPrefixes = []netip.Prefix{
+	// Carrier-grade NAT, which cloud providers also use for internal
+	// services.
+	netip.MustParsePrefix("100.64.0.0/10"),
+	// "This network", which some systems route to the local host.
+	netip.MustParsePrefix("0.0.0.0/8"),
+}
+
+// IsPublicAddr reports whether deliveries may be sent to ip: loopback,
+// private, shared, link-local, multicast and unspecified addresses are
+// refused so that a webhook can't be used to reach services behind the
+// server.
+func IsPublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+
+	if !ip.IsValid() ||
+		ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() {
+		return false
+	}
+
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkDialAddr refuses connections to addresses that aren't public. It
+// runs after DNS resolution, for every address tried, so a hostname that
+// resolves (or is later rebound) to a private address is caught as well.
+func checkDialAddr(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+
+	if !IsPublicAddr(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+	}
+
+	return nil
+}
+
+// Client sends deliveries over HTTP.
+type Client struct {
+	HTTP *http.Client
+}
+
+// NewClient returns a client whose requests time out after timeout. Unless
+// allowPrivate is set, which is meant for local development, it refuses to
+// connect to anything but public addresses.
+func NewClient(timeout time.Duration, allowPrivate bool) *Client {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if !allowPrivate {
+		dialer.Control = checkDialAddr
+		// A proxy would make the dialer see the proxy's address rather than
+		// the endpoint's, so deliveries always connect directly.
+		transport.Proxy = nil
+	}
+
+	transport.DialContext = dialer.DialContext
+
+	return &Client{
+		HTTP: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			// A webhook endpoint that redirects is misconfigured; following
+			// it could also send the payload somewhere unexpected.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Send posts the delivery and returns the response status code. Any status
+// outside 2xx is returned as an error along with the code; the status is 0
+// if no response was received at all.
+func (c *Client) Send(ctx context.Context, d Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		d.URL,
+		bytes.NewReader(d.Payload),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "workout-tracker-webhooks/1")
+	req.Header.Set(EventHeader, d.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(d.Secret, time.Now(), d.Payload))
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+
+	// Drain a little of the body so the connection can be reused, without
+	// letting a misbehaving receiver make us read forever.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf(
+			"webhook endpoint responded with status %d",
+			resp.StatusCode,
+		)
+	}
+
+	return resp.StatusCode, nil
+}
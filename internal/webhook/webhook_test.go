@@ -0,0 +1,241 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestSendSignsPayload(t *testing.T) {
+	const secret = "s3cret"
+
+	payload := []byte(`{"event":"workout.created","data":{"workout":{"id":1}}}`)
+
+	type received struct {
+		event, delivery string
+		verified        bool
+		body            []byte
+	}
+
+	got := make(chan received, 1)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		got <- received{
+			event:    r.Header.Get(EventHeader),
+			delivery: r.Header.Get(DeliveryHeader),
+			verified: Verify(secret, r.Header.Get(SignatureHeader), body, time.Minute, time.Now()),
+			body:     body,
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	client := NewClient(5*time.Second, true)
+
+	status, err := client.Send(context.Background(), Delivery{
+		ID:      42,
+		URL:     receiver.URL,
+		Secret:  secret,
+		Event:   "workout.created",
+		Payload: payload,
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if status != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", status, http.StatusNoContent)
+	}
+
+	r := <-got
+
+	if r.event != "workout.created" {
+		t.Errorf("event header = %q, want %q", r.event, "workout.created")
+	}
+
+	if r.delivery != "42" {
+		t.Errorf("delivery header = %q, want %q", r.delivery, "42")
+	}
+
+	if !r.verified {
+		t.Error("signature did not verify")
+	}
+
+	if string(r.body) != string(payload) {
+		t.Errorf("body = %s, want %s", r.body, payload)
+	}
+}
+
+func TestSendReportsFailures(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		wantStatus int
+	}{
+		{name: "server error", status: http.StatusInternalServerError, wantStatus: 500},
+		{name: "redirect is not followed", status: http.StatusFound, wantStatus: 302},
+		{name: "gone", status: http.StatusGone, wantStatus: 410},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.status == http.StatusFound {
+					w.Header().Set("Location", "http://example.com/")
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer receiver.Close()
+
+			status, err := NewClient(5*time.Second, true).Send(context.Background(), Delivery{
+				ID:      1,
+				URL:     receiver.URL,
+				Secret:  "s3cret",
+				Event:   "workout.deleted",
+				Payload: []byte(`{}`),
+			})
+			if err == nil {
+				t.Fatal("Send() error = nil, want an error")
+			}
+
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSendUnreachable(t *testing.T) {
+	receiver := httptest.NewServer(http.NotFoundHandler())
+	url := receiver.URL
+	receiver.Close()
+
+	status, err := NewClient(time.Second, true).Send(context.Background(), Delivery{
+		URL:     url,
+		Payload: []byte(`{}`),
+	})
+	if err == nil {
+		t.Fatal("Send() error = nil, want an error")
+	}
+
+	if status != 0 {
+		t.Errorf("status = %d, want 0", status)
+	}
+}
+
+func TestSendRefusesPrivateAddresses(t *testing.T) {
+	called := false
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer receiver.Close()
+
+	status, err := NewClient(time.Second, false).Send(context.Background(), Delivery{
+		URL:     receiver.URL,
+		Payload: []byte(`{}`),
+	})
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("Send() error = %v, want %v", err, ErrForbiddenAddress)
+	}
+
+	if status != 0 || called {
+		t.Errorf("status = %d, called = %t; want the request not to be made", status, called)
+	}
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+		{"100.64.0.1", false},
+		{"100.127.255.254", false},
+		{"::ffff:100.100.100.200", false},
+		{"100.63.255.255", true},
+		{"100.128.0.1", true},
+		{"0.1.2.3", false},
+		{"0.255.255.255", false},
+		{"1.0.0.1", true},
+	}
+
+	for _, tt := range tests {
+		if got := IsPublicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("IsPublicAddr(%s) = %t, want %t", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	now := time.Unix(1_700_000_000, 0)
+	header := Sign("s3cret", now, body)
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		now    time.Time
+		want   bool
+	}{
+		{name: "valid", secret: "s3cret", header: header, body: body, now: now, want: true},
+		{name: "wrong secret", secret: "other", header: header, body: body, now: now},
+		{name: "tampered body", secret: "s3cret", header: header, body: []byte(`{"id":2}`), now: now},
+		{name: "too old", secret: "s3cret", header: header, body: body, now: now.Add(10 * time.Minute)},
+		{name: "malformed", secret: "s3cret", header: "garbage", body: body, now: now},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Verify(tt.secret, tt.header, tt.body, 5*time.Minute, tt.now)
+			if got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{5, 8 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, 6 * time.Hour},
+		{50, 6 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+-- Drop the completion index if it exists
+DROP INDEX IF EXISTS idx_workouts_user_completed_at;
+
+-- Drop the added columns
+ALTER TABLE IF EXISTS workouts DROP COLUMN IF EXISTS completed_at;
+ALTER TABLE IF EXISTS users DROP COLUMN IF EXISTS timezone;
@@ -0,0 +1,8 @@
+-- Store each user's IANA time zone so daily stats line up with their calendar
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone text NOT NULL DEFAULT 'UTC';
+
+-- Record when a workout was actually performed
+ALTER TABLE workouts ADD COLUMN IF NOT EXISTS completed_at timestamp with time zone;
+
+-- Create an index for per-user completion lookups
+CREATE INDEX IF NOT EXISTS idx_workouts_user_completed_at ON workouts(user_id, completed_at);
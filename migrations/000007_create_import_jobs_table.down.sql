@@ -0,0 +1,5 @@
+-- Drop the index if it exists
+DROP INDEX IF EXISTS idx_import_jobs_user_id;
+
+-- Drop the import_jobs table
+DROP TABLE IF EXISTS import_jobs;
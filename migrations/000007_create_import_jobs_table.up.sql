@@ -0,0 +1,17 @@
+-- Create the import_jobs table
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    source text NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    workouts_found int NOT NULL DEFAULT 0,
+    workouts_imported int NOT NULL DEFAULT 0,
+    unmapped_exercises text[] NOT NULL DEFAULT '{}',
+    error text NOT NULL DEFAULT '',
+    created_at timestamp with time zone NOT NULL DEFAULT NOW(),
+    updated_at timestamp with time zone NOT NULL DEFAULT NOW(),
+    finished_at timestamp with time zone
+);
+
+-- Create an index on user_id for faster lookups
+CREATE INDEX IF NOT EXISTS idx_import_jobs_user_id ON import_jobs(user_id);
@@ -0,0 +1,5 @@
+-- Drop the index if it exists
+DROP INDEX IF EXISTS idx_measurements_user_measured_at;
+
+-- Drop the measurements table
+DROP TABLE IF EXISTS measurements;
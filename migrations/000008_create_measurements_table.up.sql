@@ -0,0 +1,22 @@
+-- Create the measurements table. Bodyweight is stored in kilograms and
+-- circumferences in centimetres.
+CREATE TABLE IF NOT EXISTS measurements (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    measured_at timestamp with time zone NOT NULL,
+    bodyweight float8,
+    body_fat_percentage float8,
+    neck float8,
+    chest float8,
+    waist float8,
+    hips float8,
+    arm float8,
+    thigh float8,
+    calf float8,
+    notes text NOT NULL DEFAULT '',
+    created_at timestamp with time zone NOT NULL DEFAULT NOW(),
+    updated_at timestamp with time zone NOT NULL DEFAULT NOW()
+);
+
+-- Create an index for per-user time series lookups
+CREATE INDEX IF NOT EXISTS idx_measurements_user_measured_at ON measurements(user_id, measured_at);
@@ -0,0 +1,4 @@
+-- Drop the unit preference columns
+ALTER TABLE IF EXISTS users DROP COLUMN IF EXISTS plate_rounding;
+ALTER TABLE IF EXISTS users DROP COLUMN IF EXISTS distance_unit;
+ALTER TABLE IF EXISTS users DROP COLUMN IF EXISTS weight_unit;
@@ -0,0 +1,5 @@
+-- Store each user's preferred presentation units. Values are always stored
+-- in kilograms and metres and converted when read or written.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS weight_unit text NOT NULL DEFAULT 'kg';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS distance_unit text NOT NULL DEFAULT 'km';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS plate_rounding boolean NOT NULL DEFAULT false;
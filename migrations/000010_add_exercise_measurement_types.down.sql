@@ -0,0 +1,4 @@
+-- Drop the cardio and timed exercise columns
+ALTER TABLE IF EXISTS workout_exercises DROP COLUMN IF EXISTS distance;
+ALTER TABLE IF EXISTS workout_exercises DROP COLUMN IF EXISTS duration_seconds;
+ALTER TABLE IF EXISTS exercises DROP COLUMN IF EXISTS measurement_type;
@@ -0,0 +1,16 @@
+-- Describe what is recorded for each exercise
+ALTER TABLE exercises ADD COLUMN IF NOT EXISTS measurement_type text NOT NULL DEFAULT 'reps_weight';
+
+-- Classify the seeded cardio, timed and bodyweight exercises
+UPDATE exercises SET measurement_type = 'distance_time'
+WHERE name IN ('Running', 'Rowing Machine');
+
+UPDATE exercises SET measurement_type = 'time'
+WHERE name IN ('Plank', 'Side Plank');
+
+UPDATE exercises SET measurement_type = 'reps_only'
+WHERE name IN ('Burpees', 'Mountain Climbers', 'Jumping Jacks', 'High Knees', 'Bicycle Crunches', 'Jump Squats');
+
+-- Record time and distance (in metres) alongside sets and repetitions
+ALTER TABLE workout_exercises ADD COLUMN IF NOT EXISTS duration_seconds int NOT NULL DEFAULT 0;
+ALTER TABLE workout_exercises ADD COLUMN IF NOT EXISTS distance float8 NOT NULL DEFAULT 0;
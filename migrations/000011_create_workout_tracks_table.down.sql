@@ -0,0 +1,5 @@
+-- Drop the index if it exists
+DROP INDEX IF EXISTS idx_workout_tracks_workout_id;
+
+-- Drop the workout_tracks table
+DROP TABLE IF EXISTS workout_tracks;
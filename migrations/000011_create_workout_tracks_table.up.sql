@@ -0,0 +1,17 @@
+-- Create the workout_tracks table for GPS activities attached to workouts.
+-- Distances and elevation are stored in metres.
+CREATE TABLE IF NOT EXISTS workout_tracks (
+    id bigserial PRIMARY KEY,
+    workout_id bigint NOT NULL REFERENCES workouts ON DELETE CASCADE,
+    exercise_id bigint NOT NULL REFERENCES exercises ON DELETE CASCADE,
+    format text NOT NULL,
+    started_at timestamp with time zone NOT NULL,
+    distance float8 NOT NULL,
+    duration_seconds int NOT NULL,
+    elevation_gain float8 NOT NULL DEFAULT 0,
+    splits jsonb NOT NULL DEFAULT '[]',
+    created_at timestamp with time zone NOT NULL DEFAULT NOW()
+);
+
+-- Create an index on workout_id for faster lookups
+CREATE INDEX IF NOT EXISTS idx_workout_tracks_workout_id ON workout_tracks(workout_id);
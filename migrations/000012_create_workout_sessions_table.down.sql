@@ -0,0 +1,6 @@
+-- Drop the indexes if they exist
+DROP INDEX IF EXISTS idx_workout_sessions_user_id;
+DROP INDEX IF EXISTS idx_workout_sessions_active;
+
+-- Drop the workout_sessions table
+DROP TABLE IF EXISTS workout_sessions;
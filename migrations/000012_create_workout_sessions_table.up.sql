@@ -0,0 +1,23 @@
+-- Create the workout_sessions table holding the live state of a workout in
+-- progress, so that the cursor and rest timers survive a server restart.
+CREATE TABLE IF NOT EXISTS workout_sessions (
+    id bigserial PRIMARY KEY,
+    workout_id bigint NOT NULL REFERENCES workouts ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    status text NOT NULL DEFAULT 'active',
+    exercise_index int NOT NULL DEFAULT 0,
+    set_number int NOT NULL DEFAULT 1,
+    rest_started_at timestamp with time zone,
+    rest_ends_at timestamp with time zone,
+    started_at timestamp with time zone NOT NULL DEFAULT NOW(),
+    finished_at timestamp with time zone,
+    updated_at timestamp with time zone NOT NULL DEFAULT NOW(),
+    version integer NOT NULL DEFAULT 1
+);
+
+-- Allow only one active session per workout
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workout_sessions_active
+ON workout_sessions(workout_id) WHERE status = 'active';
+
+-- Create an index on user_id for faster lookups
+CREATE INDEX IF NOT EXISTS idx_workout_sessions_user_id ON workout_sessions(user_id);
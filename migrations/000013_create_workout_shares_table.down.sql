@@ -0,0 +1,5 @@
+-- Drop the index if it exists
+DROP INDEX IF EXISTS idx_workout_shares_workout_id;
+
+-- Drop the workout_shares table
+DROP TABLE IF EXISTS workout_shares;
@@ -0,0 +1,14 @@
+-- Create the workout_shares table holding public read-only links to
+-- workouts. Only a hash of each share token is stored.
+CREATE TABLE IF NOT EXISTS workout_shares (
+    id bigserial PRIMARY KEY,
+    workout_id bigint NOT NULL REFERENCES workouts ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    hash bytea NOT NULL UNIQUE,
+    expires_at timestamp with time zone,
+    revoked_at timestamp with time zone,
+    created_at timestamp with time zone NOT NULL DEFAULT NOW()
+);
+
+-- Create an index on workout_id for listing a workout's shares
+CREATE INDEX IF NOT EXISTS idx_workout_shares_workout_id ON workout_shares(workout_id);
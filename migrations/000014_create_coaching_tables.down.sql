@@ -0,0 +1,8 @@
+-- Drop the indexes if they exist
+DROP INDEX IF EXISTS idx_workout_audit_workout_id;
+DROP INDEX IF EXISTS idx_coach_athletes_athlete_id;
+DROP INDEX IF EXISTS idx_coach_athletes_open;
+
+-- Drop the tables
+DROP TABLE IF EXISTS workout_audit;
+DROP TABLE IF EXISTS coach_athletes;
@@ -0,0 +1,34 @@
+-- Create the coach_athletes table linking a coach to the athletes whose
+-- workouts they may manage. A link starts as an invitation from the coach
+-- and only grants access once the athlete accepts it.
+CREATE TABLE IF NOT EXISTS coach_athletes (
+    id bigserial PRIMARY KEY,
+    coach_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    athlete_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    status text NOT NULL DEFAULT 'pending',
+    created_at timestamp with time zone NOT NULL DEFAULT NOW(),
+    accepted_at timestamp with time zone,
+    revoked_at timestamp with time zone,
+    CHECK (coach_id <> athlete_id)
+);
+
+-- Allow only one open link between a coach and an athlete
+CREATE UNIQUE INDEX IF NOT EXISTS idx_coach_athletes_open
+ON coach_athletes(coach_id, athlete_id) WHERE status IN ('pending', 'accepted');
+
+-- Create an index on athlete_id for listing an athlete's coaches
+CREATE INDEX IF NOT EXISTS idx_coach_athletes_athlete_id ON coach_athletes(athlete_id);
+
+-- Create the workout_audit table recording who changed each workout. Entries
+-- outlive the workout so that deletions are recorded too.
+CREATE TABLE IF NOT EXISTS workout_audit (
+    id bigserial PRIMARY KEY,
+    workout_id bigint NOT NULL,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    actor_id bigint REFERENCES users ON DELETE SET NULL,
+    action text NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT NOW()
+);
+
+-- Create an index on workout_id for listing a workout's history
+CREATE INDEX IF NOT EXISTS idx_workout_audit_workout_id ON workout_audit(workout_id);
@@ -0,0 +1,5 @@
+-- Drop the index if it exists
+DROP INDEX IF EXISTS idx_comments_workout_id;
+
+-- Drop the comments table
+DROP TABLE IF EXISTS comments;
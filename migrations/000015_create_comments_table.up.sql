@@ -0,0 +1,18 @@
+-- Create the comments table for discussing workouts. A comment may be about
+-- one exercise in the workout and may reply to another comment. Exercises are
+-- referenced directly rather than through workout_exercises because those
+-- rows are replaced whenever a workout is edited.
+CREATE TABLE IF NOT EXISTS comments (
+    id bigserial PRIMARY KEY,
+    workout_id bigint NOT NULL REFERENCES workouts ON DELETE CASCADE,
+    exercise_id bigint REFERENCES exercises ON DELETE SET NULL,
+    parent_id bigint REFERENCES comments ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    body text NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT NOW(),
+    edited_at timestamp with time zone,
+    deleted_at timestamp with time zone
+);
+
+-- Create an index for listing a workout's comments in order
+CREATE INDEX IF NOT EXISTS idx_comments_workout_id ON comments(workout_id, created_at, id);
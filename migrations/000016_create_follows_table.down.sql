@@ -0,0 +1,9 @@
+-- Drop the indexes if they exist
+DROP INDEX IF EXISTS idx_workouts_user_id_completed_at;
+DROP INDEX IF EXISTS idx_follows_followee_id;
+
+-- Drop the follows table
+DROP TABLE IF EXISTS follows;
+
+-- Remove the privacy setting from users
+ALTER TABLE users DROP COLUMN IF EXISTS privacy;
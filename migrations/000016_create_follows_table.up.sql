@@ -0,0 +1,23 @@
+-- Add a privacy setting controlling who can see a user's completed workouts.
+-- Existing users start out private so nothing is exposed without their
+-- consent.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS privacy text NOT NULL DEFAULT 'private';
+
+-- Create the follows table. Following a public user takes effect at once;
+-- following anyone else is a request they must approve.
+CREATE TABLE IF NOT EXISTS follows (
+    follower_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    followee_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    status text NOT NULL DEFAULT 'pending',
+    created_at timestamp with time zone NOT NULL DEFAULT NOW(),
+    accepted_at timestamp with time zone,
+    PRIMARY KEY (follower_id, followee_id),
+    CHECK (follower_id <> followee_id)
+);
+
+-- Create an index on followee_id for listing a user's followers
+CREATE INDEX IF NOT EXISTS idx_follows_followee_id ON follows(followee_id);
+
+-- Create an index for paging through completed workouts newest first
+CREATE INDEX IF NOT EXISTS idx_workouts_user_id_completed_at
+ON workouts(user_id, completed_at DESC, id DESC) WHERE completed_at IS NOT NULL;
@@ -0,0 +1,9 @@
+-- Drop the webhook delivery idempotency key
+DROP INDEX IF EXISTS idx_webhook_deliveries_idempotency_key;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS idempotency_key;
+
+-- Drop the index if it exists
+DROP INDEX IF EXISTS idx_outbox_pending;
+
+-- Drop the table
+DROP TABLE IF EXISTS outbox;
@@ -0,0 +1,26 @@
+-- Create the outbox table. Events are written here in the same transaction
+-- as the change they describe and relayed to their sinks afterwards, so no
+-- event is lost if the process dies right after a commit.
+CREATE TABLE IF NOT EXISTS outbox (
+    id bigserial PRIMARY KEY,
+    idempotency_key text NOT NULL UNIQUE,
+    user_id bigint NOT NULL,
+    event text NOT NULL,
+    payload jsonb NOT NULL,
+    attempts int NOT NULL DEFAULT 0,
+    next_attempt_at timestamp with time zone NOT NULL DEFAULT NOW(),
+    last_error text NOT NULL DEFAULT '',
+    created_at timestamp with time zone NOT NULL DEFAULT NOW(),
+    dispatched_at timestamp with time zone
+);
+
+-- Create an index for finding events waiting to be dispatched
+CREATE INDEX IF NOT EXISTS idx_outbox_pending
+ON outbox(next_attempt_at) WHERE dispatched_at IS NULL;
+
+-- Key webhook deliveries on the event they carry so that relaying an event
+-- twice does not deliver it twice
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS idempotency_key text;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_idempotency_key
+ON webhook_deliveries(webhook_id, idempotency_key);
@@ -0,0 +1,8 @@
+-- Drop the indexes if they exist
+DROP INDEX IF EXISTS idx_workouts_upcoming;
+DROP INDEX IF EXISTS idx_notifications_user_id;
+
+-- Drop the tables
+DROP TABLE IF EXISTS reminders_sent;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS notification_preferences;
@@ -0,0 +1,39 @@
+-- Create the notification_preferences table. Users without a row get the
+-- defaults: an in-app reminder an hour before each scheduled workout.
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id bigint PRIMARY KEY REFERENCES users ON DELETE CASCADE,
+    lead_minutes int[] NOT NULL DEFAULT '{60}',
+    channels text[] NOT NULL DEFAULT '{in_app}',
+    updated_at timestamp with time zone NOT NULL DEFAULT NOW()
+);
+
+-- Create the notifications table backing the in-app inbox
+CREATE TABLE IF NOT EXISTS notifications (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    kind text NOT NULL,
+    title text NOT NULL,
+    body text NOT NULL,
+    data jsonb NOT NULL DEFAULT '{}',
+    created_at timestamp with time zone NOT NULL DEFAULT NOW(),
+    read_at timestamp with time zone
+);
+
+-- Create an index for listing a user's notifications, newest first
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id, id DESC);
+
+-- Create the reminders_sent table. A row is claimed before a reminder is
+-- sent, so restarts and concurrent schedulers never send it twice. The
+-- scheduled time is part of the key so rescheduling a workout re-arms it.
+CREATE TABLE IF NOT EXISTS reminders_sent (
+    workout_id bigint NOT NULL REFERENCES workouts ON DELETE CASCADE,
+    scheduled_at timestamp with time zone NOT NULL,
+    lead_minutes int NOT NULL,
+    channel text NOT NULL,
+    sent_at timestamp with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (workout_id, scheduled_at, lead_minutes, channel)
+);
+
+-- Create an index for finding upcoming workouts that may need reminders
+CREATE INDEX IF NOT EXISTS idx_workouts_upcoming
+ON workouts(scheduled_at) WHERE completed_at IS NULL;
@@ -0,0 +1,2 @@
+-- Drop the unique constraint; merged duplicates are not restored
+ALTER TABLE exercises DROP CONSTRAINT IF EXISTS exercises_name_key;
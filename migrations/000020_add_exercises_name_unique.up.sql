@@ -0,0 +1,26 @@
+-- The seeder used to insert the whole catalogue again on every run. Point
+-- everything at the oldest copy of each exercise and remove the others.
+CREATE TEMPORARY TABLE exercise_duplicates AS
+SELECT id, min(id) OVER (PARTITION BY name) AS keep_id
+FROM exercises;
+
+DELETE FROM exercise_duplicates WHERE id = keep_id;
+
+UPDATE workout_exercises SET exercise_id = d.keep_id
+FROM exercise_duplicates d
+WHERE workout_exercises.exercise_id = d.id;
+
+UPDATE workout_tracks SET exercise_id = d.keep_id
+FROM exercise_duplicates d
+WHERE workout_tracks.exercise_id = d.id;
+
+UPDATE comments SET exercise_id = d.keep_id
+FROM exercise_duplicates d
+WHERE comments.exercise_id = d.id;
+
+DELETE FROM exercises WHERE id IN (SELECT id FROM exercise_duplicates);
+
+DROP TABLE exercise_duplicates;
+
+-- Exercise names identify the catalogue entries the seeder upserts
+ALTER TABLE exercises ADD CONSTRAINT exercises_name_key UNIQUE (name);
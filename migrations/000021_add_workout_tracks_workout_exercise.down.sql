@@ -0,0 +1,7 @@
+-- Drop the link between tracks and workout exercises; deleted tracks are not
+-- restored
+DROP INDEX IF EXISTS idx_workout_tracks_workout_exercise_id;
+
+ALTER TABLE workout_tracks DROP COLUMN IF EXISTS workout_exercise_id;
+
+ALTER TABLE workout_exercises DROP COLUMN IF EXISTS position;
@@ -0,0 +1,37 @@
+-- Keep the order exercises were given in, now that editing a workout updates
+-- the rows it keeps instead of inserting them all again.
+ALTER TABLE workout_exercises ADD COLUMN IF NOT EXISTS position int NOT NULL DEFAULT 0;
+
+UPDATE workout_exercises SET position = p.position
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY workout_id ORDER BY id) - 1 AS position
+    FROM workout_exercises
+) p
+WHERE workout_exercises.id = p.id;
+
+-- A track belongs to the cardio entry it recorded and goes with it. Pair the
+-- existing tracks with their workout's entries for the same exercise, in
+-- order, and drop the tracks whose entry has already been removed.
+ALTER TABLE workout_tracks ADD COLUMN IF NOT EXISTS workout_exercise_id bigint
+    REFERENCES workout_exercises ON DELETE CASCADE;
+
+WITH tracks AS (
+    SELECT id, workout_id, exercise_id,
+        row_number() OVER (PARTITION BY workout_id, exercise_id ORDER BY id) AS n
+    FROM workout_tracks
+), entries AS (
+    SELECT id, workout_id, exercise_id,
+        row_number() OVER (PARTITION BY workout_id, exercise_id ORDER BY id) AS n
+    FROM workout_exercises
+)
+UPDATE workout_tracks SET workout_exercise_id = entries.id
+FROM tracks
+JOIN entries USING (workout_id, exercise_id, n)
+WHERE workout_tracks.id = tracks.id;
+
+DELETE FROM workout_tracks WHERE workout_exercise_id IS NULL;
+
+ALTER TABLE workout_tracks ALTER COLUMN workout_exercise_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_workout_tracks_workout_exercise_id
+    ON workout_tracks(workout_exercise_id);
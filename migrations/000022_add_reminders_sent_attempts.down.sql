@@ -0,0 +1,5 @@
+-- Forget failed attempts; reminders waiting for a retry are dropped as if
+-- they had been sent
+ALTER TABLE reminders_sent DROP COLUMN IF EXISTS last_error;
+ALTER TABLE reminders_sent DROP COLUMN IF EXISTS retry_at;
+ALTER TABLE reminders_sent DROP COLUMN IF EXISTS attempts;
@@ -0,0 +1,6 @@
+-- Record failed reminder sends so they can be retried with a backoff and
+-- given up on after a number of attempts. retry_at is only set while a
+-- failed reminder is waiting to be tried again.
+ALTER TABLE reminders_sent ADD COLUMN IF NOT EXISTS attempts int NOT NULL DEFAULT 1;
+ALTER TABLE reminders_sent ADD COLUMN IF NOT EXISTS retry_at timestamp with time zone;
+ALTER TABLE reminders_sent ADD COLUMN IF NOT EXISTS last_error text NOT NULL DEFAULT '';
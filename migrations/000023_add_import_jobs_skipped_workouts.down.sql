@@ -0,0 +1,2 @@
+-- Forget which workouts imports skipped
+ALTER TABLE import_jobs DROP COLUMN IF EXISTS skipped_workouts;
@@ -0,0 +1,3 @@
+-- Record the workouts an import left out because they failed validation,
+-- with the reason for each, so the rest of the file can still be imported
+ALTER TABLE import_jobs ADD COLUMN IF NOT EXISTS skipped_workouts text[] NOT NULL DEFAULT '{}';
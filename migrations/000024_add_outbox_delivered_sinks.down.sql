@@ -0,0 +1,3 @@
+-- Forget which sinks have handled each event
+DROP INDEX IF EXISTS idx_outbox_dispatched;
+ALTER TABLE outbox DROP COLUMN IF EXISTS delivered_sinks;
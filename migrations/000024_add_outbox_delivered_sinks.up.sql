@@ -0,0 +1,7 @@
+-- Record the sinks that have handled an event so that when another sink
+-- fails only that one is offered the event again
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS delivered_sinks text[] NOT NULL DEFAULT '{}';
+
+-- Create an index for finding dispatched events old enough to delete
+CREATE INDEX IF NOT EXISTS idx_outbox_dispatched
+ON outbox(dispatched_at) WHERE dispatched_at IS NOT NULL;